@@ -43,6 +43,8 @@ func SetupHandlers(r *mux.Router) {
 	r.HandleFunc("/{component}/configs", componentConfigHandler).Methods("GET")
 	r.HandleFunc("/gui/csrf-token", getCSRFToken).Methods("GET")
 	r.HandleFunc("/config-check", getConfigCheck).Methods("GET")
+	r.HandleFunc("/checks/{name}/reload", reloadCheck).Methods("POST")
+	r.HandleFunc("/configs/reload", reloadConfigs).Methods("POST")
 }
 
 func stopAgent(w http.ResponseWriter, r *http.Request) {
@@ -218,3 +220,39 @@ func getConfigCheck(w http.ResponseWriter, r *http.Request) {
 
 	w.Write(json)
 }
+
+func reloadCheck(w http.ResponseWriter, r *http.Request) {
+	if err := apiutil.Validate(w, r); err != nil {
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+	w.Header().Set("Content-Type", "application/json")
+	scheduled, err := common.AC.ReloadCheck(name)
+	if err != nil {
+		log.Errorf("Error reloading check %s: %s", name, err)
+		code := 500
+		if err == autodiscovery.ErrNoCheckConfig {
+			code = 404
+		}
+		body, _ := json.Marshal(map[string]string{"error": err.Error()})
+		http.Error(w, string(body), code)
+		return
+	}
+
+	log.Infof("Reloaded check %s: scheduled %d instance(s)", name, scheduled)
+	j, _ := json.Marshal(map[string]int{"scheduled": scheduled})
+	w.Write(j)
+}
+
+func reloadConfigs(w http.ResponseWriter, r *http.Request) {
+	if err := apiutil.Validate(w, r); err != nil {
+		return
+	}
+
+	added, removed := common.AC.ReloadConfigs()
+	log.Infof("Reloaded configurations: %d added, %d removed", added, removed)
+	w.Header().Set("Content-Type", "application/json")
+	j, _ := json.Marshal(map[string]int{"added": added, "removed": removed})
+	w.Write(j)
+}
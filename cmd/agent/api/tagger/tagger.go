@@ -0,0 +1,142 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// Package tagger implements the api endpoints for the `/tagger` prefix.
+// This group of endpoints lets other processes query the tags the agent
+// collects for its entities.
+package tagger
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	log "github.com/cihub/seelog"
+	"github.com/gorilla/mux"
+
+	apiutil "github.com/DataDog/datadog-agent/pkg/api/util"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/tagger"
+	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
+)
+
+// For testing purpose
+var (
+	taggerSubscribe   = tagger.Subscribe
+	taggerUnsubscribe = tagger.Unsubscribe
+)
+
+// SetupHandlers adds the specific handlers for /tagger endpoints. The entity
+// IDs hold slashes, they are passed in the query as the router would clean
+// them from the path.
+func SetupHandlers(r *mux.Router) {
+	r.HandleFunc("/entity", getEntityTags).Methods("GET")
+	r.HandleFunc("/list", listEntities).Methods("GET")
+	r.HandleFunc("/subscribe", subscribe).Methods("GET")
+}
+
+// getEntityTags returns the tags of the entity given in the query, up to the
+// cardinality given in the query, low by default
+func getEntityTags(w http.ResponseWriter, r *http.Request) {
+	if err := apiutil.Validate(w, r); err != nil {
+		return
+	}
+
+	query := r.URL.Query()
+	entity := query.Get("id")
+	if entity == "" {
+		http.Error(w, "missing entity ID", 400)
+		return
+	}
+	var err error
+	cardinality := collectors.LowCardinality
+	if c := query.Get("cardinality"); c != "" {
+		cardinality, err = collectors.StringToTagCardinality(c)
+		if err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+	}
+
+	tags, err := tagger.Tag(entity, cardinality)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	if tags == nil {
+		tags = []string{}
+	}
+	writeJSON(w, tags)
+}
+
+// listEntities dumps the tagger store, with the source of each tag
+func listEntities(w http.ResponseWriter, r *http.Request) {
+	if err := apiutil.Validate(w, r); err != nil {
+		return
+	}
+	infos := tagger.List()
+	if infos == nil {
+		infos = []*collectors.TagInfo{}
+	}
+	writeJSON(w, infos)
+}
+
+// subscribe streams the updates of the tagger store, as a JSON array of
+// TagInfo per line. The stream ends before the server write timeout, or
+// when the client disconnects or lags behind: the client is then expected to
+// subscribe again and list the store to resync.
+func subscribe(w http.ResponseWriter, r *http.Request) {
+	if err := apiutil.Validate(w, r); err != nil {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", 500)
+		return
+	}
+
+	ch := taggerSubscribe()
+	defer taggerUnsubscribe(ch)
+	deadline := time.NewTimer(streamDuration())
+	defer deadline.Stop()
+
+	w.Header().Set("Content-Type", "application/json")
+	flusher.Flush()
+	encoder := json.NewEncoder(w)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-deadline.C:
+			return
+		case infos, open := <-ch:
+			if !open {
+				return
+			}
+			if err := encoder.Encode(infos); err != nil {
+				log.Debugf("tagger subscriber gone: %s", err)
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// streamDuration returns how long a subscription is served, the writes
+// failing past the server_timeout of the IPC server
+func streamDuration() time.Duration {
+	return config.Datadog.GetDuration("server_timeout") * time.Second * 9 / 10
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	j, err := json.Marshal(v)
+	if err != nil {
+		log.Errorf("Unable to marshal tagger response: %s", err)
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(j)
+}
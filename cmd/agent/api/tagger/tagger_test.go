@@ -0,0 +1,140 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package tagger
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apiutil "github.com/DataDog/datadog-agent/pkg/api/util"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/tagger"
+	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
+)
+
+func TestEntityRouting(t *testing.T) {
+	// same setup as the agent IPC server
+	r := mux.NewRouter()
+	SetupHandlers(r.PathPrefix("/tagger").Subrouter())
+
+	entity := url.QueryEscape("docker://abc")
+	for _, tc := range []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/tagger/entity?id=" + entity, 200, "[]"},
+		{"/tagger/entity?id=" + entity + "&cardinality=orchestrator", 200, "[]"},
+		{"/tagger/entity?id=" + entity + "&cardinality=all", 400, ""},
+		{"/tagger/entity", 400, ""},
+		{"/tagger/list", 200, "[]"},
+	} {
+		req := httptest.NewRequest("GET", tc.path, nil)
+		req.Header.Set("Authorization", "Bearer "+apiutil.GetAuthToken())
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, tc.status, w.Code, tc.path)
+		if tc.body != "" {
+			assert.Equal(t, tc.body, w.Body.String(), tc.path)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/tagger/list", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestSubscribe(t *testing.T) {
+	ch := make(chan []*collectors.TagInfo, 1)
+	unsubscribed := make(chan struct{})
+	taggerSubscribe = func() chan []*collectors.TagInfo { return ch }
+	taggerUnsubscribe = func(c chan []*collectors.TagInfo) {
+		assert.Equal(t, ch, c)
+		close(unsubscribed)
+	}
+	defer func() {
+		taggerSubscribe = tagger.Subscribe
+		taggerUnsubscribe = tagger.Unsubscribe
+	}()
+
+	r := mux.NewRouter()
+	SetupHandlers(r.PathPrefix("/tagger").Subrouter())
+	// the auth token is empty in tests, the header is set on the server
+	// side as its trailing space is trimmed when sent
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+apiutil.GetAuthToken())
+		r.ServeHTTP(w, req)
+	}))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/tagger/subscribe")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	// every update is sent as soon as it is received
+	decoder := json.NewDecoder(resp.Body)
+	for _, entity := range []string{"docker://abc", "docker://def"} {
+		update := []*collectors.TagInfo{{
+			Source:      "docker",
+			Entity:      entity,
+			LowCardTags: []string{"image_name:redis"},
+		}}
+		ch <- update
+		var infos []*collectors.TagInfo
+		require.NoError(t, decoder.Decode(&infos))
+		assert.Equal(t, update, infos)
+	}
+
+	// the stream ends when the tagger closes the channel
+	close(ch)
+	var infos []*collectors.TagInfo
+	assert.Error(t, decoder.Decode(&infos))
+	select {
+	case <-unsubscribed:
+	case <-time.After(time.Second):
+		assert.Fail(t, "the subscriber wasn't unsubscribed")
+	}
+}
+
+func TestSubscribeDeadline(t *testing.T) {
+	ch := make(chan []*collectors.TagInfo)
+	taggerSubscribe = func() chan []*collectors.TagInfo { return ch }
+	taggerUnsubscribe = func(c chan []*collectors.TagInfo) {}
+	defer func() {
+		taggerSubscribe = tagger.Subscribe
+		taggerUnsubscribe = tagger.Unsubscribe
+	}()
+	config.Datadog.Set("server_timeout", 1)
+	defer config.Datadog.Set("server_timeout", 15)
+
+	req := httptest.NewRequest("GET", "/tagger/subscribe", nil)
+	req.Header.Set("Authorization", "Bearer "+apiutil.GetAuthToken())
+	w := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		subscribe(w, req)
+		close(done)
+	}()
+
+	// the stream ends before the server write timeout
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		assert.Fail(t, "the stream didn't end before the server timeout")
+	}
+}
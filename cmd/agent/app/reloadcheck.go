@@ -17,8 +17,7 @@ import (
 )
 
 func init() {
-	// TODO: re-enable when the API endpoint is implemented
-	// AgentCmd.AddCommand(reloadCheckCommand)
+	AgentCmd.AddCommand(reloadCheckCommand)
 	checkCmd.SetArgs([]string{"checkName"})
 }
 
@@ -49,13 +48,19 @@ func doReloadCheck(checkName string) error {
 	}
 
 	c := util.GetClient(false) // FIX: get certificates right then make this true
-	urlstr := fmt.Sprintf("https://localhost:%v/check/%s/reload", config.Datadog.GetInt("cmd_port"), checkName)
+
+	// Set session token
+	if e := util.SetAuthToken(); e != nil {
+		return e
+	}
+
+	urlstr := fmt.Sprintf("https://localhost:%v/agent/checks/%s/reload", config.Datadog.GetInt("cmd_port"), checkName)
 
 	postbody := ""
 
 	body, e := util.DoPost(c, urlstr, "application/json", strings.NewReader(postbody))
 	if e != nil {
-		return fmt.Errorf("error getting check status for check %s: %v", checkName, e)
+		return fmt.Errorf("error reloading check %s: %v", checkName, e)
 	}
 
 	fmt.Printf("Reload check %s: %s\n", checkName, body)
@@ -0,0 +1,76 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/common"
+	"github.com/DataDog/datadog-agent/pkg/api/util"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
+)
+
+func init() {
+	AgentCmd.AddCommand(taggerListCommand)
+}
+
+var taggerListCommand = &cobra.Command{
+	Use:   "tagger-list",
+	Short: "Print the tags of the entities of a running agent, with their source",
+	Long:  ``,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		err := common.SetupConfig(confFilePath)
+		if err != nil {
+			return fmt.Errorf("unable to set up global agent configuration: %v", err)
+		}
+		if flagNoColor {
+			color.NoColor = true
+		}
+
+		c := util.GetClient(false) // FIX: get certificates right then make this true
+		if err = util.SetAuthToken(); err != nil {
+			return err
+		}
+		urlstr := fmt.Sprintf("https://localhost:%v/tagger/list", config.Datadog.GetInt("cmd_port"))
+		r, err := util.DoGet(c, urlstr)
+		if err != nil {
+			if r != nil && string(r) != "" {
+				return fmt.Errorf("the agent ran into an error while listing the tags: %s", string(r))
+			}
+			return fmt.Errorf("failed to query the agent (running?): %s", err)
+		}
+
+		var infos []*collectors.TagInfo
+		if err = json.Unmarshal(r, &infos); err != nil {
+			return err
+		}
+		printTaggerList(color.Output, infos)
+		return nil
+	},
+}
+
+// printTaggerList prints the tags by entity then source, the infos being
+// sorted by entity
+func printTaggerList(w io.Writer, infos []*collectors.TagInfo) {
+	entity := ""
+	for _, info := range infos {
+		if info.Entity != entity {
+			entity = info.Entity
+			fmt.Fprintln(w, fmt.Sprintf("\n=== Entity %s ===", color.GreenString(entity)))
+		}
+		fmt.Fprintln(w, fmt.Sprintf("%s %s", color.BlueString("Source:"), info.Source))
+		fmt.Fprintln(w, fmt.Sprintf("  %s %s", color.CyanString("Low:"), strings.Join(info.LowCardTags, ", ")))
+		fmt.Fprintln(w, fmt.Sprintf("  %s %s", color.CyanString("Orchestrator:"), strings.Join(info.OrchestratorCardTags, ", ")))
+		fmt.Fprintln(w, fmt.Sprintf("  %s %s", color.CyanString("High:"), strings.Join(info.HighCardTags, ", ")))
+	}
+}
@@ -47,7 +47,14 @@ func SetupAutoConfig(confdPath string) {
 		confdPath,
 		filepath.Join(GetDistPath(), "conf.d"),
 	}
-	AC.AddProvider(providers.NewFileConfigProvider(confSearchPaths), false)
+	fileProvider := providers.NewFileConfigProvider(confSearchPaths)
+	watchConfd := config.Datadog.GetBool("confd_watch")
+	if watchConfd {
+		if err := fileProvider.Watch(); err != nil {
+			log.Warnf("Unable to watch the configuration files: %s", err)
+		}
+	}
+	AC.AddProvider(fileProvider, watchConfd)
 
 	// Register additional configuration providers
 	var CP []config.ConfigurationProviders
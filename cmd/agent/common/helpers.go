@@ -31,5 +31,9 @@ func SetupConfig(confFilePath string) error {
 	if err != nil {
 		return fmt.Errorf("unable to load Datadog config file: %s", err)
 	}
+	// decrypt the ENC[] handles of the configuration
+	if err := config.ResolveSecrets(); err != nil {
+		return err
+	}
 	return nil
 }
@@ -18,6 +18,7 @@ import (
 	"github.com/DataDog/datadog-agent/cmd/agent/common"
 	"github.com/DataDog/datadog-agent/cmd/agent/common/signals"
 	apiutil "github.com/DataDog/datadog-agent/pkg/api/util"
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks"
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/flare"
 	"github.com/DataDog/datadog-agent/pkg/util"
@@ -40,7 +41,13 @@ func SetupHandlers(r *mux.Router) {
 	// r.HandleFunc("/status", getStatus).Methods("GET")
 	// r.HandleFunc("/status/formatted", getFormattedStatus).Methods("GET")
 	r.HandleFunc("/api/v1/metadata/{nodeName}/{podName}", getPodMetadata).Methods("GET")
+	r.HandleFunc("/api/v1/pods/{nodeName}/{podName}/metadata", getPodClusterMetadata).Methods("GET")
+	r.HandleFunc("/api/v1/nodes/{nodeName}/labels", getNodeLabels).Methods("GET")
+	r.HandleFunc("/api/v1/namespaces/{namespace}/labels", getNamespaceLabels).Methods("GET")
+	r.HandleFunc("/api/v1/clusterchecks/status/{nodeName}", postClusterCheckStatus).Methods("POST")
+	r.HandleFunc("/api/v1/clusterchecks/configs/{nodeName}", getClusterCheckConfigs).Methods("GET")
 	r.HandleFunc("/api/v1/{check}/events", getCheckLatestEvents).Methods("GET")
+	setupExternalMetricsHandlers(r)
 }
 
 // TODO: make sure it works for DCA
@@ -143,3 +150,88 @@ func getPodMetadata(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(fmt.Sprintf("Could not find associated services mapped to the pod: %s on node: %s", podName, nodeName)))
 
 }
+
+// getPodClusterMetadata returns the services and the owners of a pod
+func getPodClusterMetadata(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	nodeName := vars["nodeName"]
+	podName := vars["podName"]
+	metadata := as.GetPodMetadata(nodeName, podName)
+	if metadata == nil {
+		http.Error(w, fmt.Sprintf("Could not find metadata mapped to the pod: %s on node: %s", podName, nodeName), 404)
+		return
+	}
+	writeJSON(w, metadata)
+}
+
+func getNodeLabels(w http.ResponseWriter, r *http.Request) {
+	nodeName := mux.Vars(r)["nodeName"]
+	labels, found := as.GetNodeLabels(nodeName)
+	if !found {
+		http.Error(w, fmt.Sprintf("Could not find the labels of the node: %s", nodeName), 404)
+		return
+	}
+	writeJSON(w, labels)
+}
+
+func getNamespaceLabels(w http.ResponseWriter, r *http.Request) {
+	namespace := mux.Vars(r)["namespace"]
+	labels, found := as.GetNamespaceLabels(namespace)
+	if !found {
+		http.Error(w, fmt.Sprintf("Could not find the labels of the namespace: %s", namespace), 404)
+		return
+	}
+	writeJSON(w, labels)
+}
+
+// postClusterCheckStatus registers a node agent or refreshes its heartbeat
+func postClusterCheckStatus(w http.ResponseWriter, r *http.Request) {
+	dispatcher := getClusterCheckDispatcher(w, r)
+	if dispatcher == nil {
+		return
+	}
+	nodeName := mux.Vars(r)["nodeName"]
+	writeJSON(w, dispatcher.ProcessNodeStatus(nodeName))
+}
+
+// getClusterCheckConfigs returns the cluster checks dispatched to a node agent
+func getClusterCheckConfigs(w http.ResponseWriter, r *http.Request) {
+	dispatcher := getClusterCheckDispatcher(w, r)
+	if dispatcher == nil {
+		return
+	}
+	nodeName := mux.Vars(r)["nodeName"]
+	response, err := dispatcher.GetNodeConfigs(nodeName)
+	if err != nil {
+		http.Error(w, err.Error(), 404)
+		return
+	}
+	writeJSON(w, response)
+}
+
+// getClusterCheckDispatcher returns the cluster checks dispatcher, or writes
+// an error if they are disabled. The requests reaching a replica that isn't
+// the leader are forwarded to the leader.
+func getClusterCheckDispatcher(w http.ResponseWriter, r *http.Request) *clusterchecks.Dispatcher {
+	dispatcher := clusterchecks.GetDispatcher()
+	if dispatcher == nil {
+		http.Error(w, "Cluster checks are not enabled", 404)
+		return nil
+	}
+	if !dispatcher.IsLeader() {
+		dispatcher.ForwardToLeader(w, r)
+		return nil
+	}
+	return dispatcher
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	j, err := json.Marshal(v)
+	if err != nil {
+		log.Errorf("Could not marshal the response: %s", err)
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(j)
+}
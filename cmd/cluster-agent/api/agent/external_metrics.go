@@ -0,0 +1,105 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package agent
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	apiutil "github.com/DataDog/datadog-agent/pkg/api/util"
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/externalmetrics"
+)
+
+const (
+	externalMetricsGroupVersion = "external.metrics.k8s.io/v1beta1"
+	externalMetricsPath         = "/apis/" + externalMetricsGroupVersion
+)
+
+// externalMetricValue is the ExternalMetricValue of the external.metrics.k8s.io API
+type externalMetricValue struct {
+	MetricName   string            `json:"metricName"`
+	MetricLabels map[string]string `json:"metricLabels"`
+	Timestamp    string            `json:"timestamp"`
+	Value        string            `json:"value"`
+}
+
+// externalMetricValueList is the ExternalMetricValueList of the external.metrics.k8s.io API
+type externalMetricValueList struct {
+	Kind       string                `json:"kind"`
+	APIVersion string                `json:"apiVersion"`
+	Metadata   map[string]string     `json:"metadata"`
+	Items      []externalMetricValue `json:"items"`
+}
+
+// setupExternalMetricsHandlers adds the endpoints of the external.metrics.k8s.io
+// API, queried by the HPA controller through the APIService of the cluster agent
+func setupExternalMetricsHandlers(r *mux.Router) {
+	r.HandleFunc(externalMetricsPath, getExternalMetricsResources).Methods("GET")
+	r.HandleFunc(externalMetricsPath+"/namespaces/{namespace}/{metric}", getExternalMetric).Methods("GET")
+}
+
+// getExternalMetricsResources serves the discovery document of the API
+func getExternalMetricsResources(w http.ResponseWriter, r *http.Request) {
+	if err := apiutil.Validate(w, r); err != nil {
+		return
+	}
+	if externalmetrics.GetProvider() == nil {
+		http.Error(w, "The external metrics provider is not enabled", 404)
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"kind":         "APIResourceList",
+		"apiVersion":   "v1",
+		"groupVersion": externalMetricsGroupVersion,
+		"resources": []map[string]interface{}{
+			{
+				"name":       "*",
+				"namespaced": true,
+				"kind":       "ExternalMetricValueList",
+				"verbs":      []string{"get"},
+			},
+		},
+	})
+}
+
+// getExternalMetric serves the values of an external metric, the labels of
+// which match the labelSelector parameter
+func getExternalMetric(w http.ResponseWriter, r *http.Request) {
+	if err := apiutil.Validate(w, r); err != nil {
+		return
+	}
+	provider := externalmetrics.GetProvider()
+	if provider == nil {
+		http.Error(w, "The external metrics provider is not enabled", 404)
+		return
+	}
+	vars := mux.Vars(r)
+	selector, err := externalmetrics.ParseSelector(r.URL.Query().Get("labelSelector"))
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	list := externalMetricValueList{
+		Kind:       "ExternalMetricValueList",
+		APIVersion: externalMetricsGroupVersion,
+		Metadata:   map[string]string{},
+		Items:      []externalMetricValue{},
+	}
+	for _, value := range provider.GetExternalMetric(vars["namespace"], vars["metric"], selector) {
+		list.Items = append(list.Items, externalMetricValue{
+			MetricName:   value.MetricName,
+			MetricLabels: value.Labels,
+			Timestamp:    value.Timestamp.UTC().Format(time.RFC3339),
+			// quantities are serialized as strings, in milli-units to keep decimals
+			Value: fmt.Sprintf("%dm", int64(value.Value*1000)),
+		})
+	}
+	writeJSON(w, list)
+}
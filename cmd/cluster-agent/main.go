@@ -17,11 +17,14 @@ import (
 	"github.com/DataDog/datadog-agent/cmd/cluster-agent/api"
 	"github.com/DataDog/datadog-agent/pkg/aggregator"
 	"github.com/DataDog/datadog-agent/pkg/clusteragent"
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks"
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/externalmetrics"
 	_ "github.com/DataDog/datadog-agent/pkg/collector/corechecks/cluster"
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/forwarder"
 	"github.com/DataDog/datadog-agent/pkg/serializer"
 	"github.com/DataDog/datadog-agent/pkg/util"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver/leaderelection"
 	"github.com/DataDog/datadog-agent/pkg/version"
 	log "github.com/cihub/seelog"
 	"github.com/spf13/cobra"
@@ -98,6 +101,10 @@ func start(cmd *cobra.Command, args []string) error {
 	if confErr != nil {
 		log.Infof("unable to parse Datadog config file, running with env variables: %s", confErr)
 	}
+	if err := config.ResolveSecrets(); err != nil {
+		log.Criticalf("Unable to decrypt secrets from the configuration: %s", err)
+		return nil
+	}
 
 	if !config.Datadog.IsSet("api_key") {
 		log.Critical("no API key configured, exiting")
@@ -137,16 +144,51 @@ func start(cmd *cobra.Command, args []string) error {
 	// TODO: run the actual thing
 	clusterAgent, _ := clusteragent.Run(aggregatorInstance.GetChannels())
 
+	// start the leader election early for the followers to know the leader,
+	// the cluster checks start it anyway if the apiserver is not reachable yet
+	if config.Datadog.GetBool("leader_election") {
+		engine, err := leaderelection.GetLeaderEngine()
+		if err != nil {
+			log.Errorf("Could not start the leader election: %s", err)
+		} else {
+			engine.EnsureLeaderElectionRuns()
+		}
+	}
+
 	// Setup a channel to catch OS signals
 	signalCh := make(chan os.Signal, 1)
 	signal.Notify(signalCh, os.Interrupt, syscall.SIGTERM)
 	// create and setup the Autoconfig instance
 	common.SetupAutoConfig(config.Datadog.GetString("confd_dca_path"))
+	// dispatch the cluster checks to the node agents instead of running them
+	if config.Datadog.GetBool("cluster_checks.enabled") {
+		dispatcher, err := clusterchecks.StartDispatcher()
+		if err != nil {
+			return log.Errorf("Could not start the cluster checks dispatcher, exiting: %v", err)
+		}
+		common.AC.SetClusterCheckHandler(dispatcher)
+	}
 	// start the autoconfig, this will immediately run any configured check
 	common.StartAutoConfig()
+
+	// serve the external metrics the HorizontalPodAutoscalers scale on
+	var metricsProvider *externalmetrics.Provider
+	if config.Datadog.GetBool("external_metrics_provider.enabled") {
+		backend, err := externalmetrics.NewDatadogBackend()
+		if err == nil {
+			metricsProvider, err = externalmetrics.StartProvider(backend)
+		}
+		if err != nil {
+			log.Errorf("Could not start the external metrics provider: %s", err)
+		}
+	}
+
 	// Block here until we receive the interrupt signal
 	<-signalCh
 
+	if metricsProvider != nil {
+		metricsProvider.Stop()
+	}
 	clusterAgent.Stop()
 	log.Info("See ya!")
 	log.Flush()
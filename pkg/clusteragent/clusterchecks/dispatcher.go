@@ -0,0 +1,344 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// Package clusterchecks dispatches the cluster checks configurations found
+// by the cluster agent to the node agents. The node agents register and send
+// heartbeats to the cluster agent API, and poll the configurations assigned
+// to them. The configurations of a node that stops sending heartbeats are
+// dispatched to the other nodes.
+//
+// With leader_election, every replica of the cluster agent keeps the
+// configurations to be able to take over, but only the leader dispatches
+// them: the other replicas forget the nodes and forward the requests of the
+// node agents to the leader.
+package clusterchecks
+
+import (
+	"expvar"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/cihub/seelog"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver/leaderelection"
+)
+
+var (
+	globalDispatcher      *Dispatcher
+	globalDispatcherMutex sync.Mutex
+)
+
+// nodeStore holds the state of a registered node agent
+type nodeStore struct {
+	lastHeartbeat time.Time
+	// lastChange is updated when configurations are added or removed
+	lastChange int64
+	digests    map[string]struct{}
+}
+
+// Dispatcher assigns the cluster checks configurations to the node agents.
+// It implements the autodiscovery ClusterCheckHandler interface.
+type Dispatcher struct {
+	m sync.RWMutex
+	// configs holds all the cluster checks configurations, by digest
+	configs map[string]check.Config
+	// configNodes holds the node of each configuration, by digest,
+	// empty for the ones waiting for a node to register
+	configNodes    map[string]string
+	nodes          map[string]*nodeStore
+	nodeExpiration time.Duration
+	stop           chan struct{}
+	// isLeader and leaderIP come from the leader election, this replica is
+	// the leader if it is disabled
+	isLeader func() bool
+	leaderIP func() (string, error)
+	// leaderPort is the port of the API of the leader
+	leaderPort int
+}
+
+func newDispatcher(nodeExpiration time.Duration) *Dispatcher {
+	return &Dispatcher{
+		configs:        make(map[string]check.Config),
+		configNodes:    make(map[string]string),
+		nodes:          make(map[string]*nodeStore),
+		nodeExpiration: nodeExpiration,
+		stop:           make(chan struct{}),
+		isLeader:       func() bool { return true },
+		leaderIP: func() (string, error) {
+			return "", fmt.Errorf("leader election is disabled")
+		},
+		leaderPort: config.Datadog.GetInt("cmd_port"),
+	}
+}
+
+// StartDispatcher creates the shared dispatcher and starts expiring the
+// nodes that stop sending heartbeats, on the leader only
+func StartDispatcher() (*Dispatcher, error) {
+	globalDispatcherMutex.Lock()
+	defer globalDispatcherMutex.Unlock()
+	if globalDispatcher == nil {
+		timeout := time.Duration(config.Datadog.GetInt("cluster_checks.node_expiration_timeout")) * time.Second
+		if timeout <= 0 {
+			return nil, fmt.Errorf("invalid cluster_checks.node_expiration_timeout: %s", timeout)
+		}
+		dispatcher := newDispatcher(timeout)
+		if config.Datadog.GetBool("leader_election") {
+			dispatcher.isLeader = isLeader
+			dispatcher.leaderIP = getLeaderIP
+		}
+		globalDispatcher = dispatcher
+		go globalDispatcher.run()
+	}
+	return globalDispatcher, nil
+}
+
+// GetDispatcher returns the shared dispatcher, nil if the cluster checks
+// are not enabled
+func GetDispatcher() *Dispatcher {
+	globalDispatcherMutex.Lock()
+	defer globalDispatcherMutex.Unlock()
+	return globalDispatcher
+}
+
+// Stop stops expiring the nodes
+func (d *Dispatcher) Stop() {
+	close(d.stop)
+}
+
+// IsLeader returns whether this replica dispatches the configurations,
+// the other ones forward the requests of the node agents with ForwardToLeader
+func (d *Dispatcher) IsLeader() bool {
+	return d.isLeader()
+}
+
+// Schedule dispatches new configurations to the least busy nodes
+func (d *Dispatcher) Schedule(configs []check.Config) {
+	d.m.Lock()
+	defer d.m.Unlock()
+	for _, c := range configs {
+		digest := c.Digest()
+		if _, found := d.configs[digest]; found {
+			continue
+		}
+		d.configs[digest] = c
+		d.assign(digest)
+	}
+}
+
+// Unschedule removes configurations from their nodes
+func (d *Dispatcher) Unschedule(configs []check.Config) {
+	d.m.Lock()
+	defer d.m.Unlock()
+	now := changeStamp()
+	for _, c := range configs {
+		digest := c.Digest()
+		if node, found := d.nodes[d.configNodes[digest]]; found {
+			delete(node.digests, digest)
+			node.lastChange = now
+		}
+		delete(d.configs, digest)
+		delete(d.configNodes, digest)
+	}
+}
+
+// ProcessNodeStatus registers a node or refreshes its heartbeat, and
+// returns the last change of its configurations. The configurations
+// waiting for a node are dispatched when one registers.
+func (d *Dispatcher) ProcessNodeStatus(nodeName string) StatusResponse {
+	d.m.Lock()
+	defer d.m.Unlock()
+	node, found := d.nodes[nodeName]
+	if !found {
+		log.Infof("Node %s registered for cluster checks", nodeName)
+		node = &nodeStore{
+			lastChange: changeStamp(),
+			digests:    make(map[string]struct{}),
+		}
+		d.nodes[nodeName] = node
+		d.assignPending()
+	}
+	node.lastHeartbeat = time.Now()
+	return StatusResponse{LastChange: node.lastChange}
+}
+
+// GetNodeConfigs returns the configurations dispatched to a node, that
+// must have registered first
+func (d *Dispatcher) GetNodeConfigs(nodeName string) (ConfigResponse, error) {
+	d.m.RLock()
+	defer d.m.RUnlock()
+	node, found := d.nodes[nodeName]
+	if !found {
+		return ConfigResponse{}, fmt.Errorf("node %s is not registered", nodeName)
+	}
+
+	digests := make([]string, 0, len(node.digests))
+	for digest := range node.digests {
+		digests = append(digests, digest)
+	}
+	sort.Strings(digests)
+	configs := make([]check.Config, 0, len(digests))
+	for _, digest := range digests {
+		configs = append(configs, d.configs[digest])
+	}
+	return ConfigResponse{Configs: configs, LastChange: node.lastChange}, nil
+}
+
+func (d *Dispatcher) run() {
+	ticker := time.NewTicker(d.nodeExpiration / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			d.refresh(time.Now())
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+// refresh expires the nodes on the leader, and forgets them on the other
+// replicas: they register to the new leader with their next heartbeat
+func (d *Dispatcher) refresh(now time.Time) {
+	if d.isLeader() {
+		d.expireNodes(now)
+	} else {
+		d.reset()
+	}
+}
+
+// reset forgets the nodes, their configurations wait for them to register
+func (d *Dispatcher) reset() {
+	d.m.Lock()
+	defer d.m.Unlock()
+	if len(d.nodes) == 0 {
+		return
+	}
+	log.Infof("Not the leader anymore, forgetting the %d nodes registered for cluster checks", len(d.nodes))
+	d.nodes = make(map[string]*nodeStore)
+	for digest := range d.configNodes {
+		d.configNodes[digest] = ""
+	}
+}
+
+// expireNodes removes the nodes that didn't send a heartbeat within the
+// expiration timeout and dispatches their configurations to the others
+func (d *Dispatcher) expireNodes(now time.Time) {
+	d.m.Lock()
+	defer d.m.Unlock()
+	expired := false
+	for name, node := range d.nodes {
+		if now.Sub(node.lastHeartbeat) < d.nodeExpiration {
+			continue
+		}
+		log.Infof("Node %s expired, dispatching its %d cluster checks to the other nodes", name, len(node.digests))
+		delete(d.nodes, name)
+		for digest := range node.digests {
+			d.configNodes[digest] = ""
+		}
+		expired = true
+	}
+	if expired {
+		d.assignPending()
+	}
+}
+
+// assignPending dispatches the configurations waiting for a node
+func (d *Dispatcher) assignPending() {
+	digests := []string{}
+	for digest, node := range d.configNodes {
+		if node == "" {
+			digests = append(digests, digest)
+		}
+	}
+	sort.Strings(digests)
+	for _, digest := range digests {
+		d.assign(digest)
+	}
+}
+
+// assign dispatches a configuration to the node running the fewest ones,
+// or leaves it waiting for a node if none is registered
+func (d *Dispatcher) assign(digest string) {
+	target := ""
+	for name, node := range d.nodes {
+		if target == "" {
+			target = name
+			continue
+		}
+		least := len(d.nodes[target].digests)
+		if len(node.digests) < least || (len(node.digests) == least && name < target) {
+			target = name
+		}
+	}
+	d.configNodes[digest] = target
+	if target == "" {
+		log.Debugf("No node registered, cluster check %s is pending", d.configs[digest].Name)
+		return
+	}
+	node := d.nodes[target]
+	node.digests[digest] = struct{}{}
+	node.lastChange = changeStamp()
+	log.Debugf("Dispatching cluster check %s to node %s", d.configs[digest].Name, target)
+}
+
+// getStatus returns the number of configurations of each node and the
+// number of pending ones, for the agent status
+func (d *Dispatcher) getStatus() map[string]interface{} {
+	d.m.RLock()
+	defer d.m.RUnlock()
+	nodes := make(map[string]int, len(d.nodes))
+	for name, node := range d.nodes {
+		nodes[name] = len(node.digests)
+	}
+	pending := 0
+	for _, node := range d.configNodes {
+		if node == "" {
+			pending++
+		}
+	}
+	return map[string]interface{}{
+		"Nodes":   nodes,
+		"Pending": pending,
+	}
+}
+
+// isLeader returns whether this replica is the leader, the leader engine
+// being created once the apiserver is reachable
+func isLeader() bool {
+	engine, err := leaderelection.GetLeaderEngine()
+	if err != nil {
+		log.Debugf("Could not get the leader engine: %s", err)
+		return false
+	}
+	engine.EnsureLeaderElectionRuns()
+	return engine.IsLeader()
+}
+
+func getLeaderIP() (string, error) {
+	engine, err := leaderelection.GetLeaderEngine()
+	if err != nil {
+		return "", err
+	}
+	return engine.GetLeaderIP()
+}
+
+// changeStamp returns a new value for the lastChange of the nodes
+func changeStamp() int64 {
+	return time.Now().UnixNano()
+}
+
+func init() {
+	expvar.Publish("clusterchecks", expvar.Func(func() interface{} {
+		d := GetDispatcher()
+		if d == nil {
+			return nil
+		}
+		return d.getStatus()
+	}))
+}
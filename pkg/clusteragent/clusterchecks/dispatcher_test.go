@@ -0,0 +1,137 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package clusterchecks
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+func generateConfig(name string) check.Config {
+	return check.Config{
+		Name:         name,
+		Instances:    []check.ConfigData{check.ConfigData("host: " + name)},
+		ClusterCheck: true,
+	}
+}
+
+func nodeConfigNames(t *testing.T, d *Dispatcher, nodeName string) []string {
+	response, err := d.GetNodeConfigs(nodeName)
+	require.NoError(t, err)
+	names := []string{}
+	for _, c := range response.Configs {
+		names = append(names, c.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestDispatcherPending(t *testing.T) {
+	d := newDispatcher(30 * time.Second)
+	d.Schedule([]check.Config{generateConfig("a"), generateConfig("b")})
+	assert.Equal(t, 2, d.getStatus()["Pending"])
+
+	_, err := d.GetNodeConfigs("node1")
+	assert.Error(t, err)
+
+	// the pending configurations go to the first node
+	d.ProcessNodeStatus("node1")
+	assert.Equal(t, []string{"a", "b"}, nodeConfigNames(t, d, "node1"))
+	assert.Equal(t, 0, d.getStatus()["Pending"])
+}
+
+func TestDispatcherBalance(t *testing.T) {
+	d := newDispatcher(30 * time.Second)
+	d.ProcessNodeStatus("node1")
+	d.ProcessNodeStatus("node2")
+	d.Schedule([]check.Config{generateConfig("a"), generateConfig("b"), generateConfig("c")})
+	// scheduling the same configuration twice is a noop
+	d.Schedule([]check.Config{generateConfig("a")})
+
+	assert.Len(t, nodeConfigNames(t, d, "node1"), 2)
+	assert.Len(t, nodeConfigNames(t, d, "node2"), 1)
+	assert.Equal(t, map[string]int{"node1": 2, "node2": 1}, d.getStatus()["Nodes"])
+}
+
+func TestDispatcherLastChange(t *testing.T) {
+	d := newDispatcher(30 * time.Second)
+	status := d.ProcessNodeStatus("node1")
+	assert.Equal(t, status, d.ProcessNodeStatus("node1"))
+
+	d.Schedule([]check.Config{generateConfig("a")})
+	scheduled := d.ProcessNodeStatus("node1")
+	assert.NotEqual(t, status, scheduled)
+	response, err := d.GetNodeConfigs("node1")
+	require.NoError(t, err)
+	assert.Equal(t, scheduled.LastChange, response.LastChange)
+
+	d.Unschedule([]check.Config{generateConfig("a")})
+	assert.NotEqual(t, scheduled, d.ProcessNodeStatus("node1"))
+	assert.Len(t, nodeConfigNames(t, d, "node1"), 0)
+	assert.Len(t, d.configs, 0)
+}
+
+func TestDispatcherExpireNodes(t *testing.T) {
+	d := newDispatcher(30 * time.Second)
+	d.ProcessNodeStatus("node1")
+	d.ProcessNodeStatus("node2")
+	d.Schedule([]check.Config{generateConfig("a"), generateConfig("b")})
+	assert.Equal(t, []string{"a"}, nodeConfigNames(t, d, "node1"))
+	assert.Equal(t, []string{"b"}, nodeConfigNames(t, d, "node2"))
+
+	// node2 stops sending heartbeats
+	d.nodes["node2"].lastHeartbeat = time.Now().Add(-time.Minute)
+	d.expireNodes(time.Now())
+	assert.Equal(t, []string{"a", "b"}, nodeConfigNames(t, d, "node1"))
+	_, err := d.GetNodeConfigs("node2")
+	assert.Error(t, err)
+
+	// no node left, the configurations are pending
+	d.expireNodes(time.Now().Add(time.Minute))
+	assert.Equal(t, 2, d.getStatus()["Pending"])
+}
+
+func TestDispatcherFollower(t *testing.T) {
+	d := newDispatcher(30 * time.Second)
+	leader := true
+	d.isLeader = func() bool { return leader }
+	d.ProcessNodeStatus("node1")
+	d.Schedule([]check.Config{generateConfig("a"), generateConfig("b")})
+	d.refresh(time.Now())
+	assert.Equal(t, []string{"a", "b"}, nodeConfigNames(t, d, "node1"))
+
+	// the followers keep the configurations but forget the nodes, that
+	// register to the leader
+	leader = false
+	d.refresh(time.Now())
+	_, err := d.GetNodeConfigs("node1")
+	assert.Error(t, err)
+	assert.Len(t, d.configs, 2)
+	assert.Equal(t, 2, d.getStatus()["Pending"])
+
+	// the configurations are dispatched again when taking over
+	leader = true
+	d.refresh(time.Now())
+	d.ProcessNodeStatus("node2")
+	assert.Equal(t, []string{"a", "b"}, nodeConfigNames(t, d, "node2"))
+}
+
+func TestStartDispatcherInvalidTimeout(t *testing.T) {
+	config.Datadog.Set("cluster_checks.node_expiration_timeout", 0)
+	defer config.Datadog.Set("cluster_checks.node_expiration_timeout", 30)
+
+	d, err := StartDispatcher()
+	assert.Error(t, err)
+	assert.Nil(t, d)
+	assert.Nil(t, GetDispatcher())
+}
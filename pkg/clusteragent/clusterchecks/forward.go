@@ -0,0 +1,55 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package clusterchecks
+
+import (
+	"crypto/tls"
+	"fmt"
+	stdLog "log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+// forwardedHeader flags the requests forwarded by a follower, for the
+// replica receiving them to not forward them again if it isn't the leader
+const forwardedHeader = "X-DCA-Forwarded"
+
+// the replicas of the cluster agent serve their API with self-signed
+// certificates
+var forwardTransport = &http.Transport{
+	TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+}
+
+// ForwardToLeader forwards a request of a node agent to the leader. The node
+// agents reach any replica through the service of the cluster agent, the
+// followers proxy their requests for them to not depend on the leader.
+func (d *Dispatcher) ForwardToLeader(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get(forwardedHeader) != "" {
+		// the leader changed since the request was forwarded
+		http.Error(w, "Not the leader, the cluster checks are being taken over", 503)
+		return
+	}
+	ip, err := d.leaderIP()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Could not forward the request to the leader: %s", err), 503)
+		return
+	}
+
+	target := &url.URL{
+		Scheme: "https",
+		Host:   net.JoinHostPort(ip, strconv.Itoa(d.leaderPort)),
+	}
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.Transport = forwardTransport
+	proxy.ErrorLog = stdLog.New(&config.ErrorLogWriter{}, "", 0) // log errors to seelog
+	r.Header.Set(forwardedHeader, "true")
+	proxy.ServeHTTP(w, r)
+}
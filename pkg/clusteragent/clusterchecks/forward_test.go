@@ -0,0 +1,62 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package clusterchecks
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForwardToLeader(t *testing.T) {
+	// the leader answers the heartbeats of the nodes
+	leader := newDispatcher(30 * time.Second)
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/clusterchecks/status/node1", r.URL.Path)
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		assert.Equal(t, "true", r.Header.Get(forwardedHeader))
+		leader.ProcessNodeStatus("node1")
+		fmt.Fprint(w, `{"last_change": 42}`)
+	}))
+	defer ts.Close()
+	host, port, err := net.SplitHostPort(ts.Listener.Addr().String())
+	require.NoError(t, err)
+
+	follower := newDispatcher(30 * time.Second)
+	follower.isLeader = func() bool { return false }
+	follower.leaderIP = func() (string, error) { return host, nil }
+	follower.leaderPort, err = strconv.Atoi(port)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("POST", "/api/v1/clusterchecks/status/node1", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	w := httptest.NewRecorder()
+	follower.ForwardToLeader(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"last_change": 42}`, w.Body.String())
+	assert.Contains(t, leader.nodes, "node1")
+	assert.Len(t, follower.nodes, 0)
+
+	// the requests are forwarded once
+	w = httptest.NewRecorder()
+	follower.ForwardToLeader(w, req)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	// no leader
+	follower.leaderIP = func() (string, error) { return "", errors.New("no leader elected yet") }
+	req = httptest.NewRequest("GET", "/api/v1/clusterchecks/configs/node1", nil)
+	w = httptest.NewRecorder()
+	follower.ForwardToLeader(w, req)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
@@ -0,0 +1,23 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package clusterchecks
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+)
+
+// StatusResponse is the response of the cluster agent to the heartbeat of
+// a node agent
+type StatusResponse struct {
+	// LastChange changes when the configurations dispatched to the node do
+	LastChange int64 `json:"last_change"`
+}
+
+// ConfigResponse holds the configurations dispatched to a node agent
+type ConfigResponse struct {
+	Configs    []check.Config `json:"configs"`
+	LastChange int64          `json:"last_change"`
+}
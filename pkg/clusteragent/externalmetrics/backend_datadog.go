@@ -0,0 +1,128 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package externalmetrics
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util"
+)
+
+const (
+	// queryWindow is how far back the backend looks for the latest point
+	queryWindow = 5 * time.Minute
+)
+
+// DatadogBackend queries the metrics from the Datadog API, averaging the
+// series matching the labels as tags
+type DatadogBackend struct {
+	apiURL string
+	apiKey string
+	appKey string
+	client *http.Client
+}
+
+// NewDatadogBackend returns a DatadogBackend using the api_key and app_key
+// of the configuration
+func NewDatadogBackend() (*DatadogBackend, error) {
+	appKey := config.Datadog.GetString("app_key")
+	if appKey == "" {
+		return nil, errors.New("app_key is required to query the metrics from Datadog")
+	}
+	return &DatadogBackend{
+		apiURL: strings.TrimSuffix(config.Datadog.GetString("external_metrics_provider.api_url"), "/"),
+		apiKey: config.Datadog.GetString("api_key"),
+		appKey: appKey,
+		client: &http.Client{
+			Transport: util.CreateHTTPTransport(),
+			Timeout:   10 * time.Second,
+		},
+	}, nil
+}
+
+// datadogQueryResponse is the subset of the /api/v1/query response used
+type datadogQueryResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Series []struct {
+		// Pointlist holds [timestamp in ms, value] pairs, value can be null
+		Pointlist [][]*float64 `json:"pointlist"`
+	} `json:"series"`
+}
+
+// QueryMetric returns the latest point of the average of the series of a
+// metric matching the labels
+func (b *DatadogBackend) QueryMetric(metricName string, labels map[string]string) (float64, time.Time, error) {
+	now := time.Now()
+	params := url.Values{}
+	params.Set("from", strconv.FormatInt(now.Add(-queryWindow).Unix(), 10))
+	params.Set("to", strconv.FormatInt(now.Unix(), 10))
+	params.Set("query", datadogQuery(metricName, labels))
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/api/v1/query?%s", b.apiURL, params.Encode()), nil)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	// the keys are sent in headers, to not end up in the logged URL errors
+	req.Header.Set("DD-API-KEY", b.apiKey)
+	req.Header.Set("DD-APPLICATION-KEY", b.appKey)
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, time.Time{}, fmt.Errorf("unexpected status code from the Datadog API: %d", resp.StatusCode)
+	}
+
+	var response datadogQueryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return 0, time.Time{}, err
+	}
+	if response.Status != "ok" {
+		return 0, time.Time{}, fmt.Errorf("query failed: %s", response.Error)
+	}
+	return latestPoint(response)
+}
+
+// datadogQuery averages the series of the metric matching the labels
+func datadogQuery(metricName string, labels map[string]string) string {
+	tags := make([]string, 0, len(labels))
+	for k, v := range labels {
+		tags = append(tags, k+":"+v)
+	}
+	sort.Strings(tags)
+	scope := "*"
+	if len(tags) > 0 {
+		scope = strings.Join(tags, ",")
+	}
+	return fmt.Sprintf("avg:%s{%s}", metricName, scope)
+}
+
+// latestPoint returns the latest non null point of the first series
+func latestPoint(response datadogQueryResponse) (float64, time.Time, error) {
+	if len(response.Series) == 0 {
+		return 0, time.Time{}, errors.New("no series returned")
+	}
+	points := response.Series[0].Pointlist
+	for i := len(points) - 1; i >= 0; i-- {
+		if len(points[i]) != 2 || points[i][0] == nil || points[i][1] == nil {
+			continue
+		}
+		ms := int64(*points[i][0])
+		return *points[i][1], time.Unix(ms/1000, (ms%1000)*int64(time.Millisecond)), nil
+	}
+	return 0, time.Time{}, errors.New("no point returned")
+}
@@ -0,0 +1,57 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package externalmetrics
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatadogBackendQueryMetric(t *testing.T) {
+	var query string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query" || r.Header.Get("DD-API-KEY") != "api" || r.Header.Get("DD-APPLICATION-KEY") != "app" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		// the keys must not leak in the URL
+		assert.Empty(t, r.URL.Query().Get("api_key"))
+		assert.Empty(t, r.URL.Query().Get("application_key"))
+		query = r.URL.Query().Get("query")
+		fmt.Fprint(w, `{"status":"ok","series":[{"pointlist":[[1528700400000.0,12.5],[1528700460000.0,13.0],[1528700520000.0,null]]}]}`)
+	}))
+	defer ts.Close()
+
+	b := &DatadogBackend{apiURL: ts.URL, apiKey: "api", appKey: "app", client: http.DefaultClient}
+	value, timestamp, err := b.QueryMetric("nginx.net.request_per_s", map[string]string{"kube_service": "web", "env": "prod"})
+	require.NoError(t, err)
+	assert.Equal(t, "avg:nginx.net.request_per_s{env:prod,kube_service:web}", query)
+	assert.Equal(t, 13.0, value)
+	assert.Equal(t, time.Unix(1528700460, 0), timestamp)
+
+	b.appKey = "wrong"
+	_, _, err = b.QueryMetric("nginx.net.request_per_s", nil)
+	assert.Error(t, err)
+}
+
+func TestLatestPoint(t *testing.T) {
+	_, _, err := latestPoint(datadogQueryResponse{})
+	assert.Error(t, err)
+
+	v := 1.0
+	_, _, err = latestPoint(datadogQueryResponse{Series: []struct {
+		Pointlist [][]*float64 `json:"pointlist"`
+	}{{Pointlist: [][]*float64{{&v, nil}}}}})
+	assert.Error(t, err)
+
+	assert.Equal(t, "avg:queue.depth{*}", datadogQuery("queue.depth", nil))
+}
@@ -0,0 +1,115 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package externalmetrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	// metricsAnnotation holds the autoscaling/v2beta1 metric specs of the
+	// HorizontalPodAutoscalers read in autoscaling/v1
+	metricsAnnotation  = "autoscaling.alpha.kubernetes.io/metrics"
+	externalMetricType = "External"
+)
+
+// hpaSource is a HorizontalPodAutoscaler, reduced to the fields the
+// provider cares about
+type hpaSource struct {
+	namespace   string
+	name        string
+	annotations map[string]string
+}
+
+// hpaLister lists the HorizontalPodAutoscalers, it abstracts the apiserver
+// client for testing purposes
+type hpaLister interface {
+	listHPAs() ([]hpaSource, error)
+}
+
+// externalMetric is an external metric referenced by an HPA
+type externalMetric struct {
+	namespace  string
+	metricName string
+	labels     map[string]string
+}
+
+// metricSpec is the subset of the autoscaling/v2beta1 MetricSpec used for
+// external metrics
+type metricSpec struct {
+	Type     string `json:"type"`
+	External *struct {
+		MetricName     string `json:"metricName"`
+		MetricSelector *struct {
+			MatchLabels map[string]string `json:"matchLabels"`
+		} `json:"metricSelector"`
+	} `json:"external"`
+}
+
+// parseExternalMetrics returns the external metrics an HPA scales on
+func parseExternalMetrics(hpa hpaSource) ([]externalMetric, error) {
+	value, found := hpa.annotations[metricsAnnotation]
+	if !found {
+		return nil, nil
+	}
+	var specs []metricSpec
+	if err := json.Unmarshal([]byte(value), &specs); err != nil {
+		return nil, fmt.Errorf("in %s: %s", metricsAnnotation, err)
+	}
+
+	var metrics []externalMetric
+	for _, spec := range specs {
+		if spec.Type != externalMetricType || spec.External == nil {
+			continue
+		}
+		metric := externalMetric{
+			namespace:  hpa.namespace,
+			metricName: spec.External.MetricName,
+			labels:     map[string]string{},
+		}
+		if spec.External.MetricSelector != nil {
+			for k, v := range spec.External.MetricSelector.MatchLabels {
+				metric.labels[k] = v
+			}
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics, nil
+}
+
+// ParseSelector parses the equality based label selectors the HPA controller
+// sends, like `app=web,env==prod`
+func ParseSelector(selector string) (map[string]string, error) {
+	labels := map[string]string{}
+	if selector == "" {
+		return labels, nil
+	}
+	for _, requirement := range strings.Split(selector, ",") {
+		parts := strings.SplitN(requirement, "=", 2)
+		if len(parts) != 2 || strings.HasSuffix(parts[0], "!") {
+			return nil, fmt.Errorf("unsupported label selector requirement: %q", requirement)
+		}
+		key := strings.TrimSpace(parts[0])
+		value := strings.TrimSpace(strings.TrimPrefix(parts[1], "="))
+		if key == "" {
+			return nil, fmt.Errorf("unsupported label selector requirement: %q", requirement)
+		}
+		labels[key] = value
+	}
+	return labels, nil
+}
+
+// matchLabels returns whether the labels hold every label of the selector
+func matchLabels(labels, selector map[string]string) bool {
+	for k, v := range selector {
+		if value, found := labels[k]; !found || value != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,111 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build kubeapiserver
+
+package externalmetrics
+
+import (
+	"sync"
+
+	log "github.com/cihub/seelog"
+	"github.com/ericchiang/k8s"
+	autoscalingv1 "github.com/ericchiang/k8s/apis/autoscaling/v1"
+
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver"
+)
+
+// apiserverLister lists the HorizontalPodAutoscalers through the apiserver
+// client once, then keeps them up to date by watching their changes. They
+// are listed again when the watch ends.
+type apiserverLister struct {
+	m sync.Mutex
+	// hpas holds the HorizontalPodAutoscalers by namespace/name
+	hpas     map[string]hpaSource
+	watching bool
+}
+
+func (l *apiserverLister) listHPAs() ([]hpaSource, error) {
+	l.m.Lock()
+	defer l.m.Unlock()
+	if !l.watching {
+		if err := l.resync(); err != nil {
+			return nil, err
+		}
+	}
+	sources := make([]hpaSource, 0, len(l.hpas))
+	for _, source := range l.hpas {
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+// resync lists the HorizontalPodAutoscalers and starts watching their
+// changes since the list. If the watch fails, they are listed again at
+// the next call.
+func (l *apiserverLister) resync() error {
+	client, err := apiserver.GetAPIClient()
+	if err != nil {
+		return err
+	}
+	hpaList, err := client.ListHorizontalPodAutoscalers()
+	if err != nil {
+		return err
+	}
+	l.hpas = make(map[string]hpaSource, len(hpaList.Items))
+	for _, hpa := range hpaList.Items {
+		source := newHPASource(hpa)
+		l.hpas[source.namespace+"/"+source.name] = source
+	}
+
+	watcher, err := client.WatchHorizontalPodAutoscalers(hpaList.Metadata.GetResourceVersion())
+	if err != nil {
+		log.Debugf("Could not watch the HorizontalPodAutoscalers, listing them at every refresh: %s", err)
+		return nil
+	}
+	l.watching = true
+	go l.watch(watcher)
+	return nil
+}
+
+// watch applies the changes of the HorizontalPodAutoscalers until the
+// watch ends
+func (l *apiserverLister) watch(watcher *k8s.AutoscalingV1HorizontalPodAutoscalerWatcher) {
+	defer watcher.Close()
+	for {
+		event, hpa, err := watcher.Next()
+		if err != nil {
+			log.Debugf("The watch of the HorizontalPodAutoscalers ended: %s", err)
+			break
+		}
+		if event.GetType() == "ERROR" {
+			log.Debugf("The watch of the HorizontalPodAutoscalers failed, listing them again")
+			break
+		}
+
+		source := newHPASource(hpa)
+		key := source.namespace + "/" + source.name
+		l.m.Lock()
+		switch event.GetType() {
+		case "ADDED", "MODIFIED":
+			l.hpas[key] = source
+		case "DELETED":
+			delete(l.hpas, key)
+		}
+		l.m.Unlock()
+	}
+
+	l.m.Lock()
+	l.watching = false
+	l.m.Unlock()
+}
+
+func newHPASource(hpa *autoscalingv1.HorizontalPodAutoscaler) hpaSource {
+	return hpaSource{
+		namespace:   hpa.Metadata.GetNamespace(),
+		name:        hpa.Metadata.GetName(),
+		annotations: hpa.Metadata.GetAnnotations(),
+	}
+}
@@ -0,0 +1,190 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build kubeapiserver
+
+package externalmetrics
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ericchiang/k8s/api/v1"
+	autoscalingv1 "github.com/ericchiang/k8s/apis/autoscaling/v1"
+	metav1 "github.com/ericchiang/k8s/apis/meta/v1"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver/testutil"
+)
+
+const testKubeConfig = `{
+  "apiVersion": "v1",
+  "kind": "Config",
+  "clusters": [{"name": "test", "cluster": {"server": "%s"}}],
+  "contexts": [{"name": "test", "context": {"cluster": "test", "user": "test"}}],
+  "current-context": "test",
+  "users": [{"name": "test", "user": {}}]
+}`
+
+func newTestHPA(name string) *autoscalingv1.HorizontalPodAutoscaler {
+	return &autoscalingv1.HorizontalPodAutoscaler{
+		Metadata: &metav1.ObjectMeta{
+			Namespace:   proto.String("default"),
+			Name:        proto.String(name),
+			Annotations: map[string]string{metricsAnnotation: name},
+		},
+	}
+}
+
+// fakeAPIServer lists the HorizontalPodAutoscalers, and streams the watch
+// events sent to its events channel until it is closed
+type fakeAPIServer struct {
+	sync.Mutex
+	t        *testing.T
+	hpas     []*autoscalingv1.HorizontalPodAutoscaler
+	lists    int
+	versions []string
+	events   chan hpaEvent
+}
+
+type hpaEvent struct {
+	eventType string
+	hpa       *autoscalingv1.HorizontalPodAutoscaler
+}
+
+func (s *fakeAPIServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/version":
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"major": "1", "minor": "9", "gitVersion": "v1.9.0"}`)
+	case "/api/v1/events":
+		testutil.WriteProto(s.t, w, http.StatusOK, &v1.EventList{})
+	case "/apis/autoscaling/v1/horizontalpodautoscalers":
+		if r.URL.Query().Get("watch") == "true" {
+			s.watch(w, r)
+			return
+		}
+		s.Lock()
+		s.lists++
+		list := &autoscalingv1.HorizontalPodAutoscalerList{
+			Metadata: &metav1.ListMeta{ResourceVersion: proto.String("42")},
+			Items:    s.hpas,
+		}
+		s.Unlock()
+		testutil.WriteProto(s.t, w, http.StatusOK, list)
+	default:
+		s.t.Errorf("unexpected request to %s", r.URL.Path)
+		http.NotFound(w, r)
+	}
+}
+
+func (s *fakeAPIServer) watch(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	s.versions = append(s.versions, r.URL.Query().Get("resourceVersion"))
+	events := s.events
+	s.Unlock()
+	testutil.WriteWatchHeader(w)
+	for event := range events {
+		testutil.WriteWatchEvent(s.t, w, event.eventType, event.hpa)
+	}
+}
+
+func (s *fakeAPIServer) event(eventType string, hpa *autoscalingv1.HorizontalPodAutoscaler) {
+	s.events <- hpaEvent{eventType, hpa}
+}
+
+func hpaNames(t *testing.T, l *apiserverLister) []string {
+	sources, err := l.listHPAs()
+	require.NoError(t, err)
+	names := []string{}
+	for _, source := range sources {
+		names = append(names, source.name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// waitFor polls the condition for up to 5 seconds
+func waitFor(t *testing.T, condition func() bool) {
+	for i := 0; i < 500; i++ {
+		if condition() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("timed out")
+}
+
+func TestAPIServerLister(t *testing.T) {
+	apiServer := &fakeAPIServer{
+		t:      t,
+		hpas:   []*autoscalingv1.HorizontalPodAutoscaler{newTestHPA("web")},
+		events: make(chan hpaEvent),
+	}
+	ts := httptest.NewServer(apiServer)
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "hpa_apiserver_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	kubeConfigPath := filepath.Join(dir, "kubeconfig.json")
+	err = ioutil.WriteFile(kubeConfigPath, []byte(fmt.Sprintf(testKubeConfig, ts.URL)), 0600)
+	require.NoError(t, err)
+
+	config.Datadog.Set("kubernetes_kubeconfig_path", kubeConfigPath)
+	config.Datadog.Set("use_service_mapper", false)
+	defer config.Datadog.Set("kubernetes_kubeconfig_path", "")
+	defer config.Datadog.Set("use_service_mapper", true)
+
+	l := &apiserverLister{}
+	assert.Equal(t, []string{"web"}, hpaNames(t, l))
+
+	// the changes are watched from the version of the list
+	apiServer.event("ADDED", newTestHPA("worker"))
+	apiServer.event("DELETED", newTestHPA("web"))
+	modified := newTestHPA("worker")
+	modified.Metadata.Annotations[metricsAnnotation] = "modified"
+	apiServer.event("MODIFIED", modified)
+	apiServer.event("ADDED", newTestHPA("queue"))
+
+	waitFor(t, func() bool {
+		l.m.Lock()
+		defer l.m.Unlock()
+		return len(l.hpas) == 2 && l.hpas["default/worker"].annotations[metricsAnnotation] == "modified"
+	})
+	assert.Equal(t, []string{"queue", "worker"}, hpaNames(t, l))
+
+	apiServer.Lock()
+	assert.Equal(t, 1, apiServer.lists)
+	assert.Equal(t, []string{"42"}, apiServer.versions)
+	apiServer.Unlock()
+
+	// the HPAs are listed again when the watch ends
+	apiServer.Lock()
+	close(apiServer.events)
+	apiServer.events = make(chan hpaEvent)
+	apiServer.Unlock()
+	waitFor(t, func() bool {
+		l.m.Lock()
+		defer l.m.Unlock()
+		return !l.watching
+	})
+	assert.Equal(t, []string{"web"}, hpaNames(t, l))
+	apiServer.Lock()
+	assert.Equal(t, 2, apiServer.lists)
+	apiServer.Unlock()
+	close(apiServer.events)
+}
@@ -0,0 +1,20 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build !kubeapiserver
+
+package externalmetrics
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver"
+)
+
+// apiserverLister can't list the HorizontalPodAutoscalers without the
+// apiserver support
+type apiserverLister struct{}
+
+func (l *apiserverLister) listHPAs() ([]hpaSource, error) {
+	return nil, apiserver.ErrNotCompiled
+}
@@ -0,0 +1,59 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package externalmetrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseExternalMetrics(t *testing.T) {
+	hpa := newHPA("default", "web", `[
+		{"type":"Resource","resource":{"name":"cpu","targetAverageUtilization":80}},
+		{"type":"External","external":{"metricName":"nginx.net.request_per_s","metricSelector":{"matchLabels":{"kube_service":"web"}},"targetAverageValue":"10"}},
+		{"type":"External","external":{"metricName":"queue.depth","targetValue":"100"}}
+	]`)
+	metrics, err := parseExternalMetrics(hpa)
+	require.NoError(t, err)
+	assert.Equal(t, []externalMetric{
+		{namespace: "default", metricName: "nginx.net.request_per_s", labels: map[string]string{"kube_service": "web"}},
+		{namespace: "default", metricName: "queue.depth", labels: map[string]string{}},
+	}, metrics)
+
+	metrics, err = parseExternalMetrics(hpaSource{namespace: "default", name: "cpu"})
+	assert.NoError(t, err)
+	assert.Len(t, metrics, 0)
+
+	_, err = parseExternalMetrics(newHPA("default", "broken", "not json"))
+	assert.Error(t, err)
+}
+
+func TestParseSelector(t *testing.T) {
+	for _, tc := range []struct {
+		selector string
+		labels   map[string]string
+		err      bool
+	}{
+		{"", map[string]string{}, false},
+		{"app=web", map[string]string{"app": "web"}, false},
+		{"app==web, env=prod", map[string]string{"app": "web", "env": "prod"}, false},
+		{"app!=web", nil, true},
+		{"app in (web,api)", nil, true},
+		{"=web", nil, true},
+	} {
+		t.Run(tc.selector, func(t *testing.T) {
+			labels, err := ParseSelector(tc.selector)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.labels, labels)
+		})
+	}
+}
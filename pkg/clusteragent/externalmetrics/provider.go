@@ -0,0 +1,213 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// Package externalmetrics serves the values of the external metrics the
+// HorizontalPodAutoscalers scale on, for the cluster agent to implement the
+// external.metrics.k8s.io API. The HPAs are listed and watched, and the
+// values of the external metrics they reference are periodically queried
+// from a MetricsBackend and cached.
+package externalmetrics
+
+import (
+	"expvar"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/cihub/seelog"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+var (
+	globalProvider      *Provider
+	globalProviderMutex sync.Mutex
+)
+
+// Provider caches the values of the external metrics referenced by the HPAs
+type Provider struct {
+	backend       MetricsBackend
+	lister        hpaLister
+	refreshPeriod time.Duration
+	// maxAge is the age after which a value is not served anymore, for
+	// the HPAs to not scale on stale values when the backend fails
+	maxAge time.Duration
+	stop   chan struct{}
+
+	m      sync.RWMutex
+	values map[string]ExternalMetricValue
+}
+
+func newProvider(backend MetricsBackend, lister hpaLister, refreshPeriod, maxAge time.Duration) *Provider {
+	return &Provider{
+		backend:       backend,
+		lister:        lister,
+		refreshPeriod: refreshPeriod,
+		maxAge:        maxAge,
+		stop:          make(chan struct{}),
+		values:        make(map[string]ExternalMetricValue),
+	}
+}
+
+// StartProvider creates the shared provider, querying the given backend, and
+// starts refreshing the values
+func StartProvider(backend MetricsBackend) (*Provider, error) {
+	globalProviderMutex.Lock()
+	defer globalProviderMutex.Unlock()
+	if globalProvider == nil {
+		refreshPeriod := time.Duration(config.Datadog.GetInt("external_metrics_provider.refresh_period")) * time.Second
+		if refreshPeriod <= 0 {
+			return nil, fmt.Errorf("invalid external_metrics_provider.refresh_period: %s", refreshPeriod)
+		}
+		maxAge := time.Duration(config.Datadog.GetInt("external_metrics_provider.max_age")) * time.Second
+		if maxAge <= 0 {
+			return nil, fmt.Errorf("invalid external_metrics_provider.max_age: %s", maxAge)
+		}
+		globalProvider = newProvider(backend, &apiserverLister{}, refreshPeriod, maxAge)
+		go globalProvider.run()
+	}
+	return globalProvider, nil
+}
+
+// GetProvider returns the shared provider, nil if the external metrics
+// provider is not enabled
+func GetProvider() *Provider {
+	globalProviderMutex.Lock()
+	defer globalProviderMutex.Unlock()
+	return globalProvider
+}
+
+// Stop stops refreshing the values
+func (p *Provider) Stop() {
+	close(p.stop)
+}
+
+func (p *Provider) run() {
+	ticker := time.NewTicker(p.refreshPeriod)
+	defer ticker.Stop()
+	for {
+		p.refresh(time.Now())
+		select {
+		case <-ticker.C:
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// refresh lists the HPAs and queries the values of the external metrics
+// they reference. The metrics not referenced anymore are dropped, and the
+// values that cannot be queried are kept until they are older than maxAge.
+func (p *Provider) refresh(now time.Time) {
+	hpas, err := p.lister.listHPAs()
+	if err != nil {
+		log.Errorf("Could not list the HorizontalPodAutoscalers: %s", err)
+		return
+	}
+
+	var metrics []externalMetric
+	for _, hpa := range hpas {
+		m, err := parseExternalMetrics(hpa)
+		if err != nil {
+			log.Errorf("Can't parse the metrics of the HorizontalPodAutoscaler %s/%s: %s", hpa.namespace, hpa.name, err)
+			continue
+		}
+		metrics = append(metrics, m...)
+	}
+
+	p.m.RLock()
+	previous := p.values
+	p.m.RUnlock()
+
+	// several namespaces can reference the same metric, it's queried once
+	type result struct {
+		value     float64
+		timestamp time.Time
+		err       error
+	}
+	results := make(map[string]result)
+	values := make(map[string]ExternalMetricValue, len(metrics))
+	for _, metric := range metrics {
+		queryKey := metricKey("", metric.metricName, metric.labels)
+		res, found := results[queryKey]
+		if !found {
+			res.value, res.timestamp, res.err = p.backend.QueryMetric(metric.metricName, metric.labels)
+			if res.err != nil {
+				log.Debugf("Could not query the external metric %s: %s", queryKey, res.err)
+			}
+			results[queryKey] = res
+		}
+
+		key := metricKey(metric.namespace, metric.metricName, metric.labels)
+		if res.err != nil {
+			if old, found := previous[key]; found && now.Sub(old.Timestamp) < p.maxAge {
+				values[key] = old
+			}
+			continue
+		}
+		values[key] = ExternalMetricValue{
+			Namespace:  metric.namespace,
+			MetricName: metric.metricName,
+			Labels:     metric.labels,
+			Value:      res.value,
+			Timestamp:  res.timestamp,
+		}
+	}
+
+	p.m.Lock()
+	p.values = values
+	p.m.Unlock()
+}
+
+// GetExternalMetric returns the values of an external metric referenced by
+// the HPAs of a namespace, the labels of which match the selector
+func (p *Provider) GetExternalMetric(namespace, metricName string, selector map[string]string) []ExternalMetricValue {
+	p.m.RLock()
+	defer p.m.RUnlock()
+	now := time.Now()
+	matching := []ExternalMetricValue{}
+	for _, value := range p.values {
+		if value.Namespace != namespace || value.MetricName != metricName || !matchLabels(value.Labels, selector) {
+			continue
+		}
+		if now.Sub(value.Timestamp) >= p.maxAge {
+			log.Debugf("The value of the external metric %s is outdated, not serving it", metricKey(namespace, metricName, value.Labels))
+			continue
+		}
+		matching = append(matching, value)
+	}
+	return matching
+}
+
+// getStatus returns the number of cached values, for the agent status
+func (p *Provider) getStatus() map[string]interface{} {
+	p.m.RLock()
+	defer p.m.RUnlock()
+	return map[string]interface{}{
+		"Metrics": len(p.values),
+	}
+}
+
+// metricKey identifies a metric and its labels, in a namespace if not empty
+func metricKey(namespace, metricName string, labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, k+":"+v)
+	}
+	sort.Strings(pairs)
+	return fmt.Sprintf("%s/%s{%s}", namespace, metricName, strings.Join(pairs, ","))
+}
+
+func init() {
+	expvar.Publish("externalmetrics", expvar.Func(func() interface{} {
+		p := GetProvider()
+		if p == nil {
+			return nil
+		}
+		return p.getStatus()
+	}))
+}
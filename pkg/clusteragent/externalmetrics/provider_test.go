@@ -0,0 +1,145 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package externalmetrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+type fakeLister struct {
+	hpas []hpaSource
+	err  error
+}
+
+func (l *fakeLister) listHPAs() ([]hpaSource, error) {
+	return l.hpas, l.err
+}
+
+type fakeBackend struct {
+	values    map[string]float64
+	timestamp time.Time
+	err       error
+	queries   []string
+}
+
+func (b *fakeBackend) QueryMetric(metricName string, labels map[string]string) (float64, time.Time, error) {
+	key := metricKey("", metricName, labels)
+	b.queries = append(b.queries, key)
+	if b.err != nil {
+		return 0, time.Time{}, b.err
+	}
+	value, found := b.values[key]
+	if !found {
+		return 0, time.Time{}, errors.New("not found")
+	}
+	return value, b.timestamp, nil
+}
+
+func newHPA(namespace, name, metrics string) hpaSource {
+	return hpaSource{
+		namespace:   namespace,
+		name:        name,
+		annotations: map[string]string{metricsAnnotation: metrics},
+	}
+}
+
+const requestsMetrics = `[{"type":"External","external":{"metricName":"nginx.net.request_per_s","metricSelector":{"matchLabels":{"kube_service":"web"}},"targetAverageValue":"10"}}]`
+
+func TestProviderRefresh(t *testing.T) {
+	now := time.Now()
+	lister := &fakeLister{hpas: []hpaSource{
+		newHPA("default", "web", requestsMetrics),
+		newHPA("staging", "web", requestsMetrics),
+		newHPA("default", "broken", `{`),
+	}}
+	backend := &fakeBackend{
+		values:    map[string]float64{"/nginx.net.request_per_s{kube_service:web}": 42},
+		timestamp: now,
+	}
+	p := newProvider(backend, lister, time.Minute, 5*time.Minute)
+
+	p.refresh(now)
+	// referenced in two namespaces but queried once
+	assert.Equal(t, []string{"/nginx.net.request_per_s{kube_service:web}"}, backend.queries)
+
+	values := p.GetExternalMetric("default", "nginx.net.request_per_s", map[string]string{"kube_service": "web"})
+	require.Len(t, values, 1)
+	assert.Equal(t, ExternalMetricValue{
+		Namespace:  "default",
+		MetricName: "nginx.net.request_per_s",
+		Labels:     map[string]string{"kube_service": "web"},
+		Value:      42,
+		Timestamp:  now,
+	}, values[0])
+
+	assert.Len(t, p.GetExternalMetric("staging", "nginx.net.request_per_s", nil), 1)
+	assert.Len(t, p.GetExternalMetric("kube-system", "nginx.net.request_per_s", nil), 0)
+	assert.Len(t, p.GetExternalMetric("default", "nginx.net.request_per_s", map[string]string{"kube_service": "api"}), 0)
+	assert.Len(t, p.GetExternalMetric("default", "redis.net.clients", nil), 0)
+
+	// metrics not referenced anymore are dropped
+	lister.hpas = lister.hpas[1:]
+	p.refresh(now)
+	assert.Len(t, p.GetExternalMetric("default", "nginx.net.request_per_s", nil), 0)
+	assert.Len(t, p.GetExternalMetric("staging", "nginx.net.request_per_s", nil), 1)
+}
+
+func TestProviderBackendError(t *testing.T) {
+	start := time.Now().Add(-10 * time.Minute)
+	lister := &fakeLister{hpas: []hpaSource{newHPA("default", "web", requestsMetrics)}}
+	backend := &fakeBackend{
+		values:    map[string]float64{"/nginx.net.request_per_s{kube_service:web}": 42},
+		timestamp: start,
+	}
+	p := newProvider(backend, lister, time.Minute, 5*time.Minute)
+	p.refresh(start)
+
+	// the last value is kept while the backend fails
+	backend.err = errors.New("backend unavailable")
+	p.refresh(start.Add(time.Minute))
+	p.m.RLock()
+	assert.Len(t, p.values, 1)
+	p.m.RUnlock()
+
+	// but not served once older than maxAge
+	assert.Len(t, p.GetExternalMetric("default", "nginx.net.request_per_s", nil), 0)
+
+	// and dropped on the next refresh
+	p.refresh(start.Add(6 * time.Minute))
+	p.m.RLock()
+	assert.Len(t, p.values, 0)
+	p.m.RUnlock()
+
+	// failing to list the HPAs keeps the cache as is
+	backend.err = nil
+	backend.timestamp = time.Now()
+	p.refresh(time.Now())
+	lister.err = errors.New("forbidden")
+	p.refresh(time.Now())
+	assert.Len(t, p.GetExternalMetric("default", "nginx.net.request_per_s", nil), 1)
+}
+
+func TestStartProviderInvalidPeriods(t *testing.T) {
+	config.Datadog.Set("external_metrics_provider.refresh_period", 0)
+	p, err := StartProvider(&fakeBackend{})
+	assert.Error(t, err)
+	assert.Nil(t, p)
+	config.Datadog.Set("external_metrics_provider.refresh_period", 30)
+
+	config.Datadog.Set("external_metrics_provider.max_age", -1)
+	defer config.Datadog.Set("external_metrics_provider.max_age", 300)
+	p, err = StartProvider(&fakeBackend{})
+	assert.Error(t, err)
+	assert.Nil(t, p)
+	assert.Nil(t, GetProvider())
+}
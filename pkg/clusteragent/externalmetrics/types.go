@@ -0,0 +1,27 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package externalmetrics
+
+import (
+	"time"
+)
+
+// MetricsBackend queries the values of the external metrics
+type MetricsBackend interface {
+	// QueryMetric returns the latest value of a metric, aggregated over the
+	// series matching the labels, and its timestamp
+	QueryMetric(metricName string, labels map[string]string) (float64, time.Time, error)
+}
+
+// ExternalMetricValue is the value of an external metric referenced by the
+// HorizontalPodAutoscalers of a namespace
+type ExternalMetricValue struct {
+	Namespace  string
+	MetricName string
+	Labels     map[string]string
+	Value      float64
+	Timestamp  time.Time
+}
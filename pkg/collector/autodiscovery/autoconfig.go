@@ -6,6 +6,7 @@
 package autodiscovery
 
 import (
+	"errors"
 	"expvar"
 	"fmt"
 	"strings"
@@ -16,6 +17,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
 	"github.com/DataDog/datadog-agent/pkg/collector/listeners"
 	"github.com/DataDog/datadog-agent/pkg/collector/providers"
+	"github.com/DataDog/datadog-agent/pkg/secrets"
 	log "github.com/cihub/seelog"
 )
 
@@ -26,6 +28,10 @@ var (
 	errorStats      = newAcErrorStats()
 )
 
+// ErrNoCheckConfig is returned by ReloadCheck when the check has no
+// configuration to reload
+var ErrNoCheckConfig = errors.New("no configuration found")
+
 func init() {
 	acErrors = expvar.NewMap("autoconfig")
 	acErrors.Set("ConfigErrors", expvar.Func(func() interface{} {
@@ -64,12 +70,14 @@ type providerDescriptor struct {
 type AutoConfig struct {
 	collector             *collector.Collector
 	providers             []*providerDescriptor
+	clusterCheckHandler   ClusterCheckHandler
 	loaders               []check.Loader
 	templateCache         *TemplateCache
 	listeners             []listeners.ServiceListener
 	configResolver        *ConfigResolver
 	configsPollTicker     *time.Ticker
 	config2checks         map[string][]check.ID       // cache the ID of checks we load for each config
+	config2clusterChecks  map[string][]check.Config   // cache the cluster check configs passed to the handler for each config
 	name2jmxmetrics       map[string]check.ConfigData // holds the metrics to collect for JMX checks
 	providerLoadedConfigs map[string][]check.Config   // holds the resolved config per provider
 	stop                  chan bool
@@ -77,6 +85,13 @@ type AutoConfig struct {
 	m                     sync.RWMutex
 }
 
+// ClusterCheckHandler takes over the configurations flagged as cluster
+// checks, instead of AutoConfig scheduling them locally
+type ClusterCheckHandler interface {
+	Schedule(configs []check.Config)
+	Unschedule(configs []check.Config)
+}
+
 // NewAutoConfig creates an AutoConfig instance.
 func NewAutoConfig(collector *collector.Collector) *AutoConfig {
 	ac := &AutoConfig{
@@ -85,6 +100,7 @@ func NewAutoConfig(collector *collector.Collector) *AutoConfig {
 		loaders:               make([]check.Loader, 0, 5),
 		templateCache:         NewTemplateCache(),
 		config2checks:         make(map[string][]check.ID),
+		config2clusterChecks:  make(map[string][]check.Config),
 		name2jmxmetrics:       make(map[string]check.ConfigData),
 		providerLoadedConfigs: make(map[string][]check.Config),
 		stop: make(chan bool),
@@ -164,11 +180,70 @@ func (ac *AutoConfig) AddProvider(provider providers.ConfigProvider, shouldPoll
 // Check instances. Should always be run once so providers that don't need
 // polling will be queried at least once
 func (ac *AutoConfig) LoadAndRun() {
-	resolvedConfigs := ac.getAllConfigs()
+	resolvedConfigs := ac.getAllConfigs(true)
 	checks := ac.getChecksFromConfigs(resolvedConfigs, true)
 	ac.schedule(checks)
 }
 
+// SetClusterCheckHandler makes AutoConfig pass the cluster check
+// configurations to the handler, instead of scheduling them locally
+func (ac *AutoConfig) SetClusterCheckHandler(handler ClusterCheckHandler) {
+	ac.m.Lock()
+	defer ac.m.Unlock()
+	ac.clusterCheckHandler = handler
+}
+
+// dispatchClusterChecks passes the cluster check configurations resolved
+// from config to the cluster check handler, if any, and returns the other
+// ones. They are cached in config2clusterChecks, to be unscheduled with config.
+func (ac *AutoConfig) dispatchClusterChecks(config check.Config, resolved []check.Config) []check.Config {
+	if ac.clusterCheckHandler == nil {
+		return resolved
+	}
+	local := []check.Config{}
+	cluster := []check.Config{}
+	for _, rc := range resolved {
+		if rc.ClusterCheck {
+			cluster = append(cluster, rc)
+		} else {
+			local = append(local, rc)
+		}
+	}
+	if len(cluster) == 0 {
+		return local
+	}
+
+	digest := config.Digest()
+	for _, rc := range cluster {
+		cached := false
+		for _, c := range ac.config2clusterChecks[digest] {
+			if c.Equal(&rc) {
+				cached = true
+				break
+			}
+		}
+		if !cached {
+			ac.config2clusterChecks[digest] = append(ac.config2clusterChecks[digest], rc)
+		}
+	}
+	ac.clusterCheckHandler.Schedule(cluster)
+	return local
+}
+
+// unscheduleClusterChecks removes the cluster check configurations resolved
+// from config from the cluster check handler
+func (ac *AutoConfig) unscheduleClusterChecks(config check.Config) {
+	digest := config.Digest()
+	cluster, found := ac.config2clusterChecks[digest]
+	if !found {
+		return
+	}
+	if ac.clusterCheckHandler != nil {
+		ac.clusterCheckHandler.Unschedule(cluster)
+	}
+	delete(ac.config2clusterChecks, digest)
+}
+
 // GetChecksByName returns any Check instance we can load for the given
 // check name
 func (ac *AutoConfig) GetChecksByName(checkName string) []check.Check {
@@ -176,7 +251,7 @@ func (ac *AutoConfig) GetChecksByName(checkName string) []check.Check {
 	titleCheck := fmt.Sprintf("%s%s", strings.Title(checkName), "Check")
 	checks := []check.Check{}
 
-	for _, check := range ac.getChecksFromConfigs(ac.getAllConfigs(), false) {
+	for _, check := range ac.getChecksFromConfigs(ac.getAllConfigs(false), false) {
 		if checkName == check.String() || titleCheck == check.String() {
 			checks = append(checks, check)
 		}
@@ -186,37 +261,16 @@ func (ac *AutoConfig) GetChecksByName(checkName string) []check.Check {
 }
 
 // getAllConfigs queries all the providers and returns all the check
-// configurations found, resolving the ones it can
-func (ac *AutoConfig) getAllConfigs() []check.Config {
+// configurations found, resolving the ones it can. The cluster checks
+// are optionally passed to the cluster check handler instead of returned.
+func (ac *AutoConfig) getAllConfigs(dispatch bool) []check.Config {
 	resolvedConfigs := []check.Config{}
 
 	for _, pd := range ac.providers {
 		cfgs, _ := pd.provider.Collect()
 
 		if fileConfPd, ok := pd.provider.(*providers.FileConfigProvider); ok {
-			var goodConfs []check.Config
-			for _, cfg := range cfgs {
-				// JMX checks can have 2 YAML files: one containing the metrics to collect, one containing the
-				// instance configuration
-				// If the file provider finds any of these metric YAMLs, we store them in a map for future access
-				if cfg.MetricConfig != nil {
-					ac.name2jmxmetrics[cfg.Name] = cfg.MetricConfig
-					// We don't want to save metric files, it's enough to store them in the map
-					continue
-				}
-
-				goodConfs = append(goodConfs, cfg)
-
-				// Clear any old errors if a valid config file is found
-				errorStats.removeConfigError(cfg.Name)
-			}
-
-			// Grab any errors that occurred when reading the YAML file
-			for name, e := range fileConfPd.Errors {
-				errorStats.setConfigError(name, e)
-			}
-
-			cfgs = goodConfs
+			cfgs = ac.processFileConfigs(fileConfPd, cfgs)
 		}
 		// Store all raw configs in the provider
 		pd.configs = cfgs
@@ -224,6 +278,9 @@ func (ac *AutoConfig) getAllConfigs() []check.Config {
 		// resolve configs if needed
 		for _, config := range cfgs {
 			rc := ac.resolve(config, pd.provider.String())
+			if dispatch {
+				rc = ac.dispatchClusterChecks(config, rc)
+			}
 			resolvedConfigs = append(resolvedConfigs, rc...)
 		}
 	}
@@ -231,6 +288,34 @@ func (ac *AutoConfig) getAllConfigs() []check.Config {
 	return resolvedConfigs
 }
 
+// processFileConfigs stores the JMX metric configurations and the errors
+// found by the file provider, and returns the configurations to schedule
+func (ac *AutoConfig) processFileConfigs(fileConfPd *providers.FileConfigProvider, cfgs []check.Config) []check.Config {
+	var goodConfs []check.Config
+	for _, cfg := range cfgs {
+		// JMX checks can have 2 YAML files: one containing the metrics to collect, one containing the
+		// instance configuration
+		// If the file provider finds any of these metric YAMLs, we store them in a map for future access
+		if cfg.MetricConfig != nil {
+			ac.name2jmxmetrics[cfg.Name] = cfg.MetricConfig
+			// We don't want to save metric files, it's enough to store them in the map
+			continue
+		}
+
+		goodConfs = append(goodConfs, cfg)
+
+		// Clear any old errors if a valid config file is found
+		errorStats.removeConfigError(cfg.Name)
+	}
+
+	// Grab any errors that occurred when reading the YAML file
+	for name, e := range fileConfPd.Errors {
+		errorStats.setConfigError(name, e)
+	}
+
+	return goodConfs
+}
+
 // getChecksFromConfigs gets all the check instances for given configurations
 // optionally can populate ac cache config2checks
 func (ac *AutoConfig) getChecksFromConfigs(configs []check.Config, populateCache bool) []check.Check {
@@ -349,7 +434,7 @@ func (ac *AutoConfig) pollConfigs() {
 				}
 				return
 			case <-ac.configsPollTicker.C:
-				ac.m.RLock()
+				ac.m.Lock()
 				// invoke Collect on the known providers
 				for _, pd := range ac.providers {
 					// skip providers that don't want to be polled
@@ -367,58 +452,181 @@ func (ac *AutoConfig) pollConfigs() {
 						continue
 					}
 
-					// retrieve the list of newly added configurations as well
-					// as removed configurations
-					newConfigs, removedConfigs := ac.collect(pd)
-					for _, config := range newConfigs {
-						// store the checks we schedule for this config locally
-						resolvedConfigs := ac.resolve(config, pd.provider.String())
-						checks := ac.getChecksFromConfigs(resolvedConfigs, true)
-						ac.schedule(checks)
-					}
+					ac.processProviderChanges(pd)
+				}
+				ac.m.Unlock()
+			}
+		}
+	}()
+}
+
+// ReloadConfigs collects the configurations of every provider, polled or not,
+// and schedules or unschedules the check instances that changed since the
+// last collection. It returns the number of added and removed configurations.
+func (ac *AutoConfig) ReloadConfigs() (added, removed int) {
+	ac.m.Lock()
+	defer ac.m.Unlock()
 
-					for _, config := range removedConfigs {
-						// unschedule all the checks corresponding to this config
-						digest := config.Digest()
-						ids := ac.config2checks[digest]
-						stopped := map[check.ID]struct{}{}
-						for _, id := range ids {
-							// `StopCheck` might time out so we don't risk to block
-							// the polling loop forever
-							err := ac.collector.StopCheck(id)
-							if err != nil {
-								log.Errorf("Error stopping check %s: %s", id, err)
-								errorStats.setRunError(id, err.Error())
-							} else {
-								stopped[id] = struct{}{}
-							}
-						}
-
-						// remove the entry from `config2checks`
-						if len(stopped) == len(ac.config2checks[digest]) {
-							// we managed to stop all the checks for this config
-							delete(ac.config2checks, digest)
-						} else {
-							// keep the checks we failed to stop in `config2checks`
-							dangling := []check.ID{}
-							for _, id := range ac.config2checks[digest] {
-								if _, found := stopped[id]; !found {
-									dangling = append(dangling, id)
-								}
-							}
-							ac.config2checks[digest] = dangling
-						}
-
-						// if the config is a template, remove it from the cache
-						if config.IsTemplate() {
-							ac.templateCache.Del(config)
-						}
+	for _, pd := range ac.providers {
+		a, r := ac.processProviderChanges(pd)
+		added += a
+		removed += r
+	}
+	return
+}
+
+// ReloadCheck picks up the configuration changes, then stops and restarts
+// every instance of the given check coming from a non-template configuration.
+// It returns the number of instances scheduled, ErrNoCheckConfig if the check
+// has no configuration, or the instances that could not be loaded or run.
+func (ac *AutoConfig) ReloadCheck(checkName string) (int, error) {
+	ac.ReloadConfigs()
+
+	ac.m.Lock()
+	defer ac.m.Unlock()
+
+	found := false
+	scheduled := 0
+	var failures []string
+	for _, pd := range ac.providers {
+		for _, config := range pd.configs {
+			if config.Name != checkName || config.IsTemplate() {
+				continue
+			}
+			if config.ClusterCheck && ac.clusterCheckHandler != nil {
+				// dispatched to the node agents
+				continue
+			}
+			found = true
+			ac.unschedule(config, pd.provider.String(), nil)
+			for _, resolved := range ac.resolve(config, pd.provider.String()) {
+				checks, err := ac.GetChecks(resolved)
+				if err != nil {
+					failures = append(failures, err.Error())
+					continue
+				}
+				for _, c := range checks {
+					ac.config2checks[resolved.Digest()] = append(ac.config2checks[resolved.Digest()], c.ID())
+					log.Infof("Scheduling check %s", c)
+					if _, err := ac.collector.RunCheck(c); err != nil {
+						log.Errorf("Unable to run Check %s: %v", c, err)
+						errorStats.setRunError(c.ID(), err.Error())
+						failures = append(failures, fmt.Sprintf("unable to run check %s: %s", c, err))
+						continue
 					}
+					scheduled++
 				}
-				ac.m.RUnlock()
 			}
 		}
-	}()
+	}
+	if !found {
+		return 0, ErrNoCheckConfig
+	}
+	if len(failures) > 0 {
+		return scheduled, fmt.Errorf("%s", strings.Join(failures, ", "))
+	}
+	return scheduled, nil
+}
+
+// processProviderChanges collects the configurations of a provider and applies
+// the difference with the previous collection: check instances that belong to
+// both a removed and a new configuration (same check ID) are left running,
+// the others are unscheduled or scheduled.
+func (ac *AutoConfig) processProviderChanges(pd *providerDescriptor) (added, removed int) {
+	// retrieve the list of newly added configurations as well
+	// as removed configurations
+	newConfigs, removedConfigs := ac.collect(pd)
+
+	// cluster checks have no local instance to stop, unschedule
+	// still cleans up the caches for them
+	for _, config := range removedConfigs {
+		ac.unscheduleClusterChecks(config)
+	}
+
+	// store the checks we schedule for the new configs locally
+	newChecks := []check.Check{}
+	for _, config := range newConfigs {
+		resolvedConfigs := ac.dispatchClusterChecks(config, ac.resolve(config, pd.provider.String()))
+		newChecks = append(newChecks, ac.getChecksFromConfigs(resolvedConfigs, true)...)
+	}
+	newIDs := make(map[check.ID]struct{}, len(newChecks))
+	for _, c := range newChecks {
+		newIDs[c.ID()] = struct{}{}
+	}
+
+	kept := map[check.ID]struct{}{}
+	for _, config := range removedConfigs {
+		for id := range ac.unschedule(config, pd.provider.String(), newIDs) {
+			kept[id] = struct{}{}
+		}
+	}
+
+	toSchedule := []check.Check{}
+	for _, c := range newChecks {
+		if _, found := kept[c.ID()]; found {
+			log.Debugf("Check instance %s is unchanged, leaving it running", c.ID())
+			continue
+		}
+		toSchedule = append(toSchedule, c)
+	}
+	ac.schedule(toSchedule)
+
+	return len(newConfigs), len(removedConfigs)
+}
+
+// unschedule stops all the checks corresponding to a config, except the ones
+// listed in `keep`, that are left running and returned
+func (ac *AutoConfig) unschedule(config check.Config, provider string, keep map[check.ID]struct{}) map[check.ID]struct{} {
+	digest := config.Digest()
+	ids := ac.config2checks[digest]
+	stopped := map[check.ID]struct{}{}
+	kept := map[check.ID]struct{}{}
+	for _, id := range ids {
+		if _, found := keep[id]; found {
+			kept[id] = struct{}{}
+			stopped[id] = struct{}{}
+			continue
+		}
+		// `StopCheck` might time out so we don't risk to block
+		// the polling loop forever
+		err := ac.collector.StopCheck(id)
+		if err != nil {
+			log.Errorf("Error stopping check %s: %s", id, err)
+			errorStats.setRunError(id, err.Error())
+		} else {
+			stopped[id] = struct{}{}
+		}
+	}
+
+	// remove the entry from `config2checks`
+	if len(stopped) == len(ac.config2checks[digest]) {
+		// we managed to stop all the checks for this config
+		delete(ac.config2checks, digest)
+	} else {
+		// keep the checks we failed to stop in `config2checks`
+		dangling := []check.ID{}
+		for _, id := range ac.config2checks[digest] {
+			if _, found := stopped[id]; !found {
+				dangling = append(dangling, id)
+			}
+		}
+		ac.config2checks[digest] = dangling
+	}
+
+	// if the config is a template, remove it from the cache
+	if config.IsTemplate() {
+		ac.templateCache.Del(config)
+	} else {
+		loaded := ac.providerLoadedConfigs[provider]
+		for i := range loaded {
+			if loaded[i].Equal(&config) {
+				ac.providerLoadedConfigs[provider] = append(loaded[:i], loaded[i+1:]...)
+				break
+			}
+		}
+	}
+
+	return kept
 }
 
 // collect is just a convenient wrapper to fetch configurations from a provider and
@@ -432,6 +640,9 @@ func (ac *AutoConfig) collect(pd *providerDescriptor) (new, removed []check.Conf
 		log.Errorf("Unable to collect configurations from provider %s: %s", pd.provider, err)
 		return
 	}
+	if fileConfPd, ok := pd.provider.(*providers.FileConfigProvider); ok {
+		fetched = ac.processFileConfigs(fileConfPd, fetched)
+	}
 
 	for _, c := range fetched {
 		if !pd.contains(&c) {
@@ -456,6 +667,14 @@ func (ac *AutoConfig) collect(pd *providerDescriptor) (new, removed []check.Conf
 // GetChecks takes a check configuration and returns a slice of Check instances
 // along with any error it might happen during the process
 func (ac *AutoConfig) GetChecks(config check.Config) ([]check.Check, error) {
+	// secrets are decrypted at the last moment, so that the configs kept
+	// in the AC (and exposed by config-check) only contain the handles
+	config, err := decryptConfig(config)
+	if err != nil {
+		errorStats.setConfigError(config.Name, err.Error())
+		return []check.Check{}, fmt.Errorf("unable to decrypt secrets from config '%s': %s", config.Name, err)
+	}
+
 	for _, loader := range ac.loaders {
 		res, err := loader.Load(config)
 		if err == nil {
@@ -476,6 +695,27 @@ func (ac *AutoConfig) GetChecks(config check.Config) ([]check.Check, error) {
 	return []check.Check{}, fmt.Errorf("unable to load any check from config '%s'", config.Name)
 }
 
+// decryptConfig returns a copy of the config with the ENC[] handles of its
+// instances and init_config replaced by their decrypted value
+func decryptConfig(conf check.Config) (check.Config, error) {
+	decrypted := conf
+	decrypted.Instances = make([]check.ConfigData, len(conf.Instances))
+	for i, inst := range conf.Instances {
+		data, err := secrets.Decrypt(inst, conf.Name)
+		if err != nil {
+			return conf, err
+		}
+		decrypted.Instances[i] = data
+	}
+
+	data, err := secrets.Decrypt(conf.InitConfig, conf.Name)
+	if err != nil {
+		return conf, err
+	}
+	decrypted.InitConfig = data
+	return decrypted, nil
+}
+
 // GetProviderLoadedConfigs returns configs loaded by provider
 func (ac *AutoConfig) GetProviderLoadedConfigs() map[string][]check.Config {
 	return ac.providerLoadedConfigs
@@ -6,8 +6,11 @@
 package autodiscovery
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
+	"github.com/DataDog/datadog-agent/pkg/collector"
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
 	"github.com/DataDog/datadog-agent/pkg/collector/listeners"
 	"github.com/stretchr/testify/assert"
@@ -100,3 +103,171 @@ func TestStop(t *testing.T) {
 	assert.True(t, ml.stopReceived)
 	assert.True(t, ml.stopReceived)
 }
+
+type staticProvider struct {
+	configs []check.Config
+}
+
+func (p *staticProvider) Collect() ([]check.Config, error) { return p.configs, nil }
+func (p *staticProvider) String() string                   { return "static" }
+func (p *staticProvider) IsUpToDate() (bool, error)        { return false, nil }
+
+type fakeCheck struct {
+	name string
+	id   check.ID
+}
+
+func (c *fakeCheck) Run() error                                          { return nil }
+func (c *fakeCheck) Stop()                                               {}
+func (c *fakeCheck) String() string                                      { return c.name }
+func (c *fakeCheck) Configure(config, initConfig check.ConfigData) error { return nil }
+func (c *fakeCheck) Interval() time.Duration                             { return time.Hour }
+func (c *fakeCheck) ID() check.ID                                        { return c.id }
+func (c *fakeCheck) GetWarnings() []error                                { return nil }
+func (c *fakeCheck) GetMetricStats() (map[string]int64, error)           { return nil, nil }
+
+// instanceLoader loads one fakeCheck per instance
+type instanceLoader struct{}
+
+func (l *instanceLoader) Load(config check.Config) ([]check.Check, error) {
+	checks := []check.Check{}
+	for _, i := range config.Instances {
+		checks = append(checks, &fakeCheck{name: config.Name, id: check.BuildID(config.Name, i, config.InitConfig)})
+	}
+	return checks, nil
+}
+
+func TestProcessProviderChanges(t *testing.T) {
+	coll := collector.NewCollector()
+	defer coll.Stop()
+	ac := NewAutoConfig(coll)
+	ac.AddLoader(&instanceLoader{})
+
+	instanceA := check.ConfigData("host: a")
+	instanceB := check.ConfigData("host: b")
+	instanceC := check.ConfigData("host: c")
+	idA := check.BuildID("foo", instanceA, nil)
+	idB := check.BuildID("foo", instanceB, nil)
+	idC := check.BuildID("foo", instanceC, nil)
+
+	oldConfig := check.Config{Name: "foo", Instances: []check.ConfigData{instanceA, instanceB}}
+	provider := &staticProvider{configs: []check.Config{oldConfig}}
+	ac.AddProvider(provider, true)
+	pd := ac.providers[0]
+
+	added, removed := ac.processProviderChanges(pd)
+	assert.Equal(t, 1, added)
+	assert.Equal(t, 0, removed)
+	assert.Equal(t, []check.ID{idA, idB}, ac.config2checks[oldConfig.Digest()])
+
+	// nothing changed
+	added, removed = ac.processProviderChanges(pd)
+	assert.Equal(t, 0, added)
+	assert.Equal(t, 0, removed)
+
+	// instance b is replaced by instance c, instance a keeps running
+	newConfig := check.Config{Name: "foo", Instances: []check.ConfigData{instanceA, instanceC}}
+	provider.configs = []check.Config{newConfig}
+	added, removed = ac.processProviderChanges(pd)
+	assert.Equal(t, 1, added)
+	assert.Equal(t, 1, removed)
+	assert.NotContains(t, ac.config2checks, oldConfig.Digest())
+	assert.Equal(t, []check.ID{idA, idC}, ac.config2checks[newConfig.Digest()])
+	// instance a wasn't scheduled twice
+	assert.NotContains(t, errorStats.getRunErrors(), idA)
+	assert.Len(t, ac.providerLoadedConfigs["static"], 1)
+
+	// the config is removed
+	provider.configs = []check.Config{}
+	added, removed = ac.processProviderChanges(pd)
+	assert.Equal(t, 0, added)
+	assert.Equal(t, 1, removed)
+	assert.Len(t, ac.config2checks, 0)
+	assert.Len(t, ac.providerLoadedConfigs["static"], 0)
+	assert.NotContains(t, errorStats.getRunErrors(), idB)
+}
+
+func TestReloadCheck(t *testing.T) {
+	coll := collector.NewCollector()
+	defer coll.Stop()
+	ac := NewAutoConfig(coll)
+	ac.AddLoader(&instanceLoader{})
+	config := check.Config{Name: "bar", Instances: []check.ConfigData{check.ConfigData("host: a")}}
+	ac.AddProvider(&staticProvider{configs: []check.Config{config}}, false)
+	ac.LoadAndRun()
+
+	scheduled, err := ac.ReloadCheck("bar")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, scheduled)
+	assert.Len(t, ac.config2checks[config.Digest()], 1)
+	assert.Len(t, ac.providerLoadedConfigs["static"], 1)
+
+	_, err = ac.ReloadCheck("unknown")
+	assert.Equal(t, ErrNoCheckConfig, err)
+}
+
+// failingLoader can't load any check
+type failingLoader struct{}
+
+func (l *failingLoader) Load(config check.Config) ([]check.Check, error) {
+	return nil, fmt.Errorf("no check %s", config.Name)
+}
+
+func TestReloadCheckLoadError(t *testing.T) {
+	coll := collector.NewCollector()
+	defer coll.Stop()
+	ac := NewAutoConfig(coll)
+	ac.AddLoader(&failingLoader{})
+	config := check.Config{Name: "bar", Instances: []check.ConfigData{check.ConfigData("host: a")}}
+	ac.AddProvider(&staticProvider{configs: []check.Config{config}}, false)
+	ac.LoadAndRun()
+
+	scheduled, err := ac.ReloadCheck("bar")
+	assert.Error(t, err)
+	assert.NotEqual(t, ErrNoCheckConfig, err)
+	assert.Equal(t, 0, scheduled)
+}
+
+type clusterCheckHandler struct {
+	scheduled   []check.Config
+	unscheduled []check.Config
+}
+
+func (h *clusterCheckHandler) Schedule(configs []check.Config) {
+	h.scheduled = append(h.scheduled, configs...)
+}
+
+func (h *clusterCheckHandler) Unschedule(configs []check.Config) {
+	h.unscheduled = append(h.unscheduled, configs...)
+}
+
+func TestClusterCheckHandler(t *testing.T) {
+	coll := collector.NewCollector()
+	defer coll.Stop()
+	ac := NewAutoConfig(coll)
+	ac.AddLoader(&instanceLoader{})
+	handler := &clusterCheckHandler{}
+	ac.SetClusterCheckHandler(handler)
+
+	local := check.Config{Name: "foo", Instances: []check.ConfigData{check.ConfigData("host: a")}}
+	cluster := check.Config{Name: "bar", Instances: []check.ConfigData{check.ConfigData("host: b")}, ClusterCheck: true}
+	provider := &staticProvider{configs: []check.Config{local, cluster}}
+	ac.AddProvider(provider, true)
+	pd := ac.providers[0]
+
+	added, removed := ac.processProviderChanges(pd)
+	assert.Equal(t, 2, added)
+	assert.Equal(t, 0, removed)
+	assert.Len(t, ac.config2checks[local.Digest()], 1)
+	assert.NotContains(t, ac.config2checks, cluster.Digest())
+	require.Len(t, handler.scheduled, 1)
+	assert.Equal(t, "bar", handler.scheduled[0].Name)
+
+	provider.configs = []check.Config{local}
+	added, removed = ac.processProviderChanges(pd)
+	assert.Equal(t, 0, added)
+	assert.Equal(t, 1, removed)
+	require.Len(t, handler.unscheduled, 1)
+	assert.Equal(t, "bar", handler.unscheduled[0].Name)
+	assert.Len(t, ac.providerLoadedConfigs["static"], 1)
+}
@@ -27,6 +27,10 @@ var (
 		"pid":            getPid,
 		"port":           getPort,
 		"container-name": getContainerName,
+		"env":            getEnvvar,
+		"hostname":       getHostname,
+		"kube":           getKubeMetadata,
+		"label":          getLabel,
 	}
 )
 
@@ -34,30 +38,32 @@ var (
 // services it hears about with templates to create valid configs.
 // It is also responsible to send scheduling orders to AutoConfig
 type ConfigResolver struct {
-	ac              *AutoConfig
-	collector       *collector.Collector
-	templates       *TemplateCache
-	services        map[listeners.ID]listeners.Service // Service.ID --> []Service
-	serviceToChecks map[listeners.ID][]check.ID        // Service.ID --> []CheckID
-	adIDToServices  map[string][]listeners.ID          // AD id --> services that have it
-	newService      chan listeners.Service
-	delService      chan listeners.Service
-	stop            chan bool
-	m               sync.Mutex
+	ac                     *AutoConfig
+	collector              *collector.Collector
+	templates              *TemplateCache
+	services               map[listeners.ID]listeners.Service // Service.ID --> []Service
+	serviceToChecks        map[listeners.ID][]check.ID        // Service.ID --> []CheckID
+	serviceToClusterChecks map[listeners.ID][]check.Config    // Service.ID --> []Config passed to the cluster check handler
+	adIDToServices         map[string][]listeners.ID          // AD id --> services that have it
+	newService             chan listeners.Service
+	delService             chan listeners.Service
+	stop                   chan bool
+	m                      sync.Mutex
 }
 
 // NewConfigResolver returns a config resolver
 func newConfigResolver(coll *collector.Collector, ac *AutoConfig, tc *TemplateCache) *ConfigResolver {
 	cr := &ConfigResolver{
-		ac:              ac,
-		collector:       coll,
-		templates:       tc,
-		services:        make(map[listeners.ID]listeners.Service),
-		serviceToChecks: make(map[listeners.ID][]check.ID, 0),
-		adIDToServices:  make(map[string][]listeners.ID),
-		newService:      make(chan listeners.Service),
-		delService:      make(chan listeners.Service),
-		stop:            make(chan bool),
+		ac:                     ac,
+		collector:              coll,
+		templates:              tc,
+		services:               make(map[listeners.ID]listeners.Service),
+		serviceToChecks:        make(map[listeners.ID][]check.ID, 0),
+		serviceToClusterChecks: make(map[listeners.ID][]check.Config),
+		adIDToServices:         make(map[string][]listeners.ID),
+		newService:             make(chan listeners.Service),
+		delService:             make(chan listeners.Service),
+		stop:                   make(chan bool),
 	}
 
 	// start listening
@@ -146,6 +152,7 @@ func (cr *ConfigResolver) resolve(tpl check.Config, svc listeners.Service) (chec
 		InitConfig:    make(check.ConfigData, len(tpl.InitConfig)),
 		MetricConfig:  tpl.MetricConfig,
 		ADIdentifiers: tpl.ADIdentifiers,
+		ClusterCheck:  tpl.ClusterCheck,
 	}
 	copy(resolvedConfig.InitConfig, tpl.InitConfig)
 	copy(resolvedConfig.Instances, tpl.Instances)
@@ -222,6 +229,13 @@ func (cr *ConfigResolver) processNewService(svc listeners.Service) {
 		}
 		errorStats.removeResolveWarnings(config.Name)
 
+		// cluster checks are passed to the cluster check handler, if any,
+		// instead of being scheduled locally
+		if len(cr.ac.dispatchClusterChecks(template, []check.Config{config})) == 0 {
+			cr.serviceToClusterChecks[svc.GetID()] = append(cr.serviceToClusterChecks[svc.GetID()], config)
+			continue
+		}
+
 		// load the checks for this config using Autoconfig
 		checks, err := cr.ac.GetChecks(config)
 		if err != nil {
@@ -249,6 +263,13 @@ func (cr *ConfigResolver) processDelService(svc listeners.Service) {
 	cr.m.Lock()
 	defer cr.m.Unlock()
 
+	if configs, ok := cr.serviceToClusterChecks[svc.GetID()]; ok {
+		if cr.ac.clusterCheckHandler != nil {
+			cr.ac.clusterCheckHandler.Unschedule(configs)
+		}
+		delete(cr.serviceToClusterChecks, svc.GetID())
+	}
+
 	if checks, ok := cr.serviceToChecks[svc.GetID()]; ok {
 		stopped := map[check.ID]struct{}{}
 		for _, id := range checks {
@@ -286,12 +307,11 @@ func getHost(tplVar []byte, svc listeners.Service) ([]byte, error) {
 	}
 
 	// a network was specified
-	if bytes.Contains(tplVar, []byte("_")) {
-		network := bytes.SplitN(tplVar, []byte("_"), 2)[1]
-		if ip, ok := hosts[string(network)]; ok {
+	if len(tplVar) > 0 {
+		if ip, ok := hosts[string(tplVar)]; ok {
 			return []byte(ip), nil
 		}
-		log.Warnf("network %s not found, trying bridge IP instead", string(network))
+		log.Warnf("network %s not found, trying bridge IP instead", string(tplVar))
 	}
 	// otherwise use fallback policy
 	ip, err := getFallbackHost(hosts)
@@ -330,9 +350,8 @@ func getPort(tplVar []byte, svc listeners.Service) ([]byte, error) {
 		return nil, fmt.Errorf("no port found for container %s - ignoring it", svc.GetID())
 	}
 
-	if bytes.Contains(tplVar, []byte("_")) {
-		idxStr := string(bytes.SplitN(tplVar, []byte("_"), 2)[1])
-		idx, err := strconv.Atoi((string(idxStr)))
+	if len(tplVar) > 0 {
+		idx, err := strconv.Atoi(string(tplVar))
 		if err != nil {
 			return nil, fmt.Errorf("index given for the port template var is not an int, skipping container %s", svc.GetID())
 		}
@@ -359,8 +378,76 @@ func getContainerName(tplVar []byte, svc listeners.Service) ([]byte, error) {
 	return []byte("test-container-name"), nil
 }
 
-// parseTemplateVar extracts the name of the var
-// and the key (or index if it can be cast to an int)
+// getEnvvar returns the value of an environment variable of the service
+func getEnvvar(tplVar []byte, svc listeners.Service) ([]byte, error) {
+	if len(tplVar) == 0 {
+		return nil, fmt.Errorf("envvar name is missing, skipping service %s", svc.GetID())
+	}
+	env, err := svc.GetEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get environment variables for service %s, skipping config - %s", svc.GetID(), err)
+	}
+	value, found := env[string(tplVar)]
+	if !found {
+		if partial, ok := svc.(listeners.PartialEnvService); ok {
+			if err := partial.GetEnvResolutionError(string(tplVar)); err != nil {
+				return nil, fmt.Errorf("envvar %s can't be resolved for service %s, skipping config - %s", tplVar, svc.GetID(), err)
+			}
+		}
+		return nil, fmt.Errorf("envvar %s not found for service %s, skipping config", tplVar, svc.GetID())
+	}
+	return []byte(value), nil
+}
+
+// getHostname returns the hostname of the service
+func getHostname(tplVar []byte, svc listeners.Service) ([]byte, error) {
+	name, err := svc.GetHostname()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hostname for service %s, skipping config - %s", svc.GetID(), err)
+	}
+	if name == "" {
+		return nil, fmt.Errorf("empty hostname for service %s, skipping config", svc.GetID())
+	}
+	return []byte(name), nil
+}
+
+// getKubeMetadata returns the kubernetes namespace or pod name of the service
+func getKubeMetadata(tplVar []byte, svc listeners.Service) ([]byte, error) {
+	var value string
+	var err error
+	switch string(tplVar) {
+	case "namespace":
+		value, err = svc.GetKubeNamespace()
+	case "pod_name":
+		value, err = svc.GetKubePodName()
+	default:
+		return nil, fmt.Errorf("unknown kube template variable %q, skipping service %s", tplVar, svc.GetID())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kube %s for service %s, skipping config - %s", tplVar, svc.GetID(), err)
+	}
+	return []byte(value), nil
+}
+
+// getLabel returns the value of a label of the service
+func getLabel(tplVar []byte, svc listeners.Service) ([]byte, error) {
+	if len(tplVar) == 0 {
+		return nil, fmt.Errorf("label name is missing, skipping service %s", svc.GetID())
+	}
+	labels, err := svc.GetLabels()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get labels for service %s, skipping config - %s", svc.GetID(), err)
+	}
+	value, found := labels[string(tplVar)]
+	if !found {
+		return nil, fmt.Errorf("label %s not found for service %s, skipping config", tplVar, svc.GetID())
+	}
+	return []byte(value), nil
+}
+
+// parseTemplateVar extracts the name of the var and its key, which is
+// everything after the first underscore (network name, port index,
+// envvar name, label name...)
 func parseTemplateVar(v []byte) (name, key []byte) {
 	stripped := bytes.Map(func(r rune) rune {
 		if unicode.IsSpace(r) || r == '%' {
@@ -368,7 +455,7 @@ func parseTemplateVar(v []byte) (name, key []byte) {
 		}
 		return r
 	}, v)
-	split := bytes.Split(stripped, []byte("_"))
+	split := bytes.SplitN(stripped, []byte("_"), 2)
 	name = split[0]
 	if len(split) == 2 {
 		key = split[1]
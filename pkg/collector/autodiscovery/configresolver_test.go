@@ -8,12 +8,14 @@
 package autodiscovery
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
 	"github.com/DataDog/datadog-agent/pkg/collector/corechecks"
 	"github.com/DataDog/datadog-agent/pkg/collector/listeners"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	// we need some valid check in the catalog to run tests
 	_ "github.com/DataDog/datadog-agent/pkg/collector/corechecks/system"
@@ -68,7 +70,15 @@ func TestParseTemplateVar(t *testing.T) {
 
 	name, key = parseTemplateVar([]byte("%%host_0_1%%"))
 	assert.Equal(t, "host", string(name))
-	assert.Equal(t, "", string(key))
+	assert.Equal(t, "0_1", string(key))
+
+	name, key = parseTemplateVar([]byte("%%env_MY_VAR%%"))
+	assert.Equal(t, "env", string(name))
+	assert.Equal(t, "MY_VAR", string(key))
+
+	name, key = parseTemplateVar([]byte("%%kube_pod_name%%"))
+	assert.Equal(t, "kube", string(name))
+	assert.Equal(t, "pod_name", string(key))
 }
 
 func TestResolve(t *testing.T) {
@@ -110,6 +120,95 @@ func TestResolve(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+func TestResolveMetadataVars(t *testing.T) {
+	ac := &AutoConfig{
+		providerLoadedConfigs: make(map[string][]check.Config),
+	}
+	cr := newConfigResolver(nil, ac, NewTemplateCache())
+	service := listeners.DockerService{
+		ID:            "a5901276aed16ae9ea11660a41fecd674da47e8f5d8d5bce0080a611feed2be9",
+		ADIdentifiers: []string{"redis"},
+		Hosts:         map[string]string{"bridge": "127.0.0.1", "my_net": "10.0.0.2"},
+		Ports:         []int{6379, 8080},
+		Env:           map[string]string{"REDIS_PASSWORD": "secret", "PORT": "6380"},
+		Hostname:      "redis-7c9f",
+		Labels: map[string]string{
+			"app":                         "cache",
+			"io.kubernetes.pod.name":      "redis-7c9f",
+			"io.kubernetes.pod.namespace": "backend",
+		},
+	}
+	cr.processNewService(&service)
+
+	tpl := check.Config{
+		Name:          "cpu",
+		ADIdentifiers: []string{"redis"},
+	}
+
+	for _, tc := range []struct {
+		tpl      string
+		expected string
+	}{
+		{"host: %%host_my_net%%", "host: 10.0.0.2"},
+		{"port: %%port_0%%", "port: 6379"},
+		{"password: %%env_REDIS_PASSWORD%%", "password: secret"},
+		{"url: http://%%hostname%%:%%env_PORT%%", "url: http://redis-7c9f:6380"},
+		{"namespace: %%kube_namespace%%", "namespace: backend"},
+		{"pod: %%kube_pod_name%%", "pod: redis-7c9f"},
+		{"app: %%label_app%%", "app: cache"},
+	} {
+		t.Run(tc.tpl, func(t *testing.T) {
+			tpl.Instances = []check.ConfigData{check.ConfigData(tc.tpl)}
+			config, err := cr.resolve(tpl, &service)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected, string(config.Instances[0]))
+		})
+	}
+
+	for _, tplStr := range []string{
+		"password: %%env_UNKNOWN%%",
+		"app: %%label_unknown%%",
+		"meta: %%kube_unknown%%",
+	} {
+		t.Run(tplStr, func(t *testing.T) {
+			tpl.Instances = []check.ConfigData{check.ConfigData(tplStr)}
+			_, err := cr.resolve(tpl, &service)
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+// partialEnvService doesn't know the value of its SECRET variable
+type partialEnvService struct {
+	listeners.DockerService
+}
+
+func (s *partialEnvService) GetEnvResolutionError(name string) error {
+	if name == "SECRET" {
+		return errors.New("SECRET is set from a reference")
+	}
+	return nil
+}
+
+func TestGetEnvvarUnresolved(t *testing.T) {
+	service := &partialEnvService{listeners.DockerService{
+		ID:  "a5901276aed16ae9ea11660a41fecd674da47e8f5d8d5bce0080a611feed2be9",
+		Env: map[string]string{"MODE": "fast"},
+	}}
+
+	value, err := getEnvvar([]byte("MODE"), service)
+	assert.NoError(t, err)
+	assert.Equal(t, "fast", string(value))
+
+	_, err = getEnvvar([]byte("SECRET"), service)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "can't be resolved")
+
+	_, err = getEnvvar([]byte("UNKNOWN"), service)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not found")
+}
+
 func TestGetFallbackHost(t *testing.T) {
 	ip, err := getFallbackHost(map[string]string{"bridge": "172.17.0.1"})
 	assert.Equal(t, "172.17.0.1", ip)
@@ -127,3 +226,46 @@ func TestGetFallbackHost(t *testing.T) {
 	assert.Equal(t, "", ip)
 	assert.NotNil(t, err)
 }
+
+func TestResolveClusterCheckTemplate(t *testing.T) {
+	ac := NewAutoConfig(nil)
+	handler := &clusterCheckHandler{}
+	ac.SetClusterCheckHandler(handler)
+	cr := ac.configResolver
+	service := listeners.DockerService{
+		ID:            "a5901276aed16ae9ea11660a41fecd674da47e8f5d8d5bce0080a611feed2be9",
+		ADIdentifiers: []string{"redis"},
+		Hosts:         map[string]string{"bridge": "127.0.0.1"},
+	}
+	tpl := check.Config{
+		Name:          "redisdb",
+		ADIdentifiers: []string{"redis"},
+		Instances:     []check.ConfigData{check.ConfigData("host: %%host%%")},
+		ClusterCheck:  true,
+	}
+	provider := &staticProvider{configs: []check.Config{tpl}}
+	ac.AddProvider(provider, true)
+	pd := ac.providers[0]
+
+	// the templates resolved for new services are passed to the handler
+	cr.processNewService(&service)
+	ac.processProviderChanges(pd)
+	assert.Len(t, handler.scheduled, 1)
+	cr.processNewService(&service)
+	assert.Len(t, handler.scheduled, 2)
+	for _, config := range handler.scheduled {
+		assert.True(t, config.ClusterCheck)
+		assert.Equal(t, "host: 127.0.0.1", string(config.Instances[0]))
+	}
+
+	// they are unscheduled with the digest they were scheduled with
+	cr.processDelService(&service)
+	provider.configs = []check.Config{}
+	ac.processProviderChanges(pd)
+	assert.Len(t, handler.unscheduled, 2)
+	for _, config := range handler.unscheduled {
+		assert.Equal(t, handler.scheduled[0].Digest(), config.Digest())
+	}
+	assert.Empty(t, ac.config2clusterChecks)
+	assert.Empty(t, cr.serviceToClusterChecks)
+}
@@ -63,6 +63,7 @@ type Config struct {
 	MetricConfig  ConfigData   `json:"metric_config"`  // the metric config in Yaml (jmx check only)
 	LogsConfig    ConfigData   `json:"log_config"`     // the logs config in Yaml (logs-agent only)
 	ADIdentifiers []string     `json:"ad_identifiers"` // the list of AutoDiscovery identifiers (optional)
+	ClusterCheck  bool         `json:"cluster_check"`  // dispatched by the cluster agent to a node agent (optional)
 }
 
 // Check is an interface for types capable to run checks
@@ -346,6 +347,9 @@ func (c *Config) Digest() string {
 	for _, i := range c.ADIdentifiers {
 		h.Write([]byte(i))
 	}
+	if c.ClusterCheck {
+		h.Write([]byte("cluster_check"))
+	}
 
 	return strconv.FormatUint(h.Sum64(), 16)
 }
@@ -18,6 +18,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
 	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver/leaderelection"
 	log "github.com/cihub/seelog"
 	"github.com/ericchiang/k8s/api/v1"
 	yaml "gopkg.in/yaml.v2"
@@ -68,6 +69,18 @@ func (k *KubeASCheck) Configure(config, initConfig check.ConfigData) error {
 
 // Run executes the check.
 func (k *KubeASCheck) Run() error {
+	leader, err := isLeader()
+	if err != nil {
+		k.Warnf("Could not get the leader engine: %s", err.Error())
+		return err
+	}
+	if !leader {
+		// The token is read again from the ConfigMap on leadership
+		// acquisition, the leader updating it in the meantime
+		k.latestEventToken = ""
+		return nil
+	}
+
 	sender, err := aggregator.GetSender(k.ID())
 	if err != nil {
 		return err
@@ -142,6 +155,24 @@ func (k *KubeASCheck) Run() error {
 	return nil
 }
 
+// isLeader returns whether the cluster level checks should run on this
+// replica: when the leader election is enabled, only the leader runs them
+func isLeader() (bool, error) {
+	if !config.Datadog.GetBool("leader_election") {
+		return true, nil
+	}
+	engine, err := leaderelection.GetLeaderEngine()
+	if err != nil {
+		return false, err
+	}
+	engine.EnsureLeaderElectionRuns()
+	if !engine.IsLeader() {
+		log.Debugf("Leader is %q, skipping the check", engine.GetLeader())
+		return false, nil
+	}
+	return true, nil
+}
+
 // KubernetesASFactory is exported for integration testing.
 func KubernetesASFactory() check.Check {
 	return &KubeASCheck{
@@ -0,0 +1,289 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build kubeapiserver
+
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	log "github.com/cihub/seelog"
+	metav1 "github.com/ericchiang/k8s/apis/meta/v1"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver"
+)
+
+const (
+	kubernetesStateCheckName = "kubernetes_state_core"
+	kubeStateMetricPrefix    = "kubernetes_state."
+)
+
+// nodeConditionChecks are the service checks sent for the node conditions,
+// and the status of the condition that is healthy
+var nodeConditionChecks = map[string]struct {
+	name    string
+	healthy string
+}{
+	"Ready":              {"node.ready", "True"},
+	"OutOfDisk":          {"node.out_of_disk", "False"},
+	"DiskPressure":       {"node.disk_pressure", "False"},
+	"MemoryPressure":     {"node.memory_pressure", "False"},
+	"NetworkUnavailable": {"node.network_unavailable", "False"},
+}
+
+// KubeStateConfig is the config of the kubernetes_state_core check
+type KubeStateConfig struct {
+	Tags []string `yaml:"tags"`
+}
+
+// KubeStateCheck reports the state of the Kubernetes objects listed from the
+// apiserver, in place of the kube-state-metrics based kubernetes_state check
+type KubeStateCheck struct {
+	core.CheckBase
+	instance *KubeStateConfig
+}
+
+// Configure parses the check configuration and init the check
+func (k *KubeStateCheck) Configure(config, initConfig check.ConfigData) error {
+	return yaml.Unmarshal(config, k.instance)
+}
+
+// Run executes the check
+func (k *KubeStateCheck) Run() error {
+	leader, err := isLeader()
+	if err != nil {
+		k.Warnf("Could not get the leader engine: %s", err.Error())
+		return err
+	}
+	if !leader {
+		return nil
+	}
+
+	sender, err := aggregator.GetSender(k.ID())
+	if err != nil {
+		return err
+	}
+
+	asclient, err := apiserver.GetAPIClient()
+	if err != nil {
+		log.Errorf("could not connect to apiserver: %s", err)
+		return err
+	}
+
+	state, err := asclient.GetClusterState()
+	if err != nil {
+		// report the resources that could be listed anyway
+		k.Warnf("Could not collect the state of the cluster: %s", err.Error())
+	}
+	k.reportState(sender, state)
+	sender.Commit()
+	return nil
+}
+
+// reportState sends the metrics and service checks of the cluster objects
+func (k *KubeStateCheck) reportState(sender aggregator.Sender, state *apiserver.ClusterState) {
+	gauges := stateGauges{}
+	owners := newOwnerResolver(state)
+
+	for _, d := range state.Deployments {
+		tags := k.tags(d.Metadata, "kube_deployment:"+d.Metadata.GetName())
+		gauges.add("deployment.replicas_desired", float64(d.Spec.GetReplicas()), tags)
+		gauges.add("deployment.replicas", float64(d.Status.GetReplicas()), tags)
+		gauges.add("deployment.replicas_available", float64(d.Status.GetAvailableReplicas()), tags)
+		gauges.add("deployment.replicas_unavailable", float64(d.Status.GetUnavailableReplicas()), tags)
+		gauges.add("deployment.replicas_updated", float64(d.Status.GetUpdatedReplicas()), tags)
+	}
+
+	for _, pod := range state.Pods {
+		podTags := k.tags(pod.Metadata, owners.tags(pod.Metadata)...)
+		gauges.add("pod.status_phase", 1, append(podTags, "pod_phase:"+strings.ToLower(pod.Status.GetPhase())))
+
+		for _, container := range pod.Status.GetContainerStatuses() {
+			containerTags := append(podTags,
+				"pod_name:"+pod.Metadata.GetName(),
+				"kube_container_name:"+container.GetName())
+			gauges.add("container.restarts", float64(container.GetRestartCount()), containerTags)
+			if waiting := container.GetState().GetWaiting(); waiting != nil {
+				gauges.add("container.waiting", 1, append(containerTags, "reason:"+strings.ToLower(waiting.GetReason())))
+			}
+		}
+	}
+
+	for _, node := range state.Nodes {
+		nodeTag := "node:" + node.Metadata.GetName()
+		for _, condition := range node.Status.GetConditions() {
+			gauges.add("nodes.by_condition", 1, k.tags(nil,
+				"condition:"+strings.ToLower(condition.GetType()),
+				"status:"+strings.ToLower(condition.GetStatus())))
+
+			sc, found := nodeConditionChecks[condition.GetType()]
+			if !found {
+				continue
+			}
+			status := metrics.ServiceCheckUnknown
+			switch {
+			case condition.GetStatus() == sc.healthy:
+				status = metrics.ServiceCheckOK
+			case condition.GetStatus() == "True" || condition.GetStatus() == "False":
+				status = metrics.ServiceCheckCritical
+			}
+			sender.ServiceCheck(kubeStateMetricPrefix+sc.name, status, "", k.tags(nil, nodeTag), condition.GetMessage())
+		}
+	}
+
+	for _, job := range state.Jobs {
+		tags := k.tags(job.Metadata, owners.jobTags(job.Metadata)...)
+		gauges.add("job.succeeded", float64(job.Status.GetSucceeded()), tags)
+		gauges.add("job.failed", float64(job.Status.GetFailed()), tags)
+	}
+
+	for _, pvc := range state.PersistentVolumeClaims {
+		gauges.add("persistentvolumeclaim.status", 1, k.tags(pvc.Metadata,
+			"persistentvolumeclaim:"+pvc.Metadata.GetName(),
+			"phase:"+strings.ToLower(pvc.Status.GetPhase())))
+	}
+
+	for _, hpa := range state.HorizontalPodAutoscalers {
+		tags := k.tags(hpa.Metadata, "hpa:"+hpa.Metadata.GetName())
+		gauges.add("hpa.min_replicas", float64(hpa.Spec.GetMinReplicas()), tags)
+		gauges.add("hpa.max_replicas", float64(hpa.Spec.GetMaxReplicas()), tags)
+		gauges.add("hpa.current_replicas", float64(hpa.Status.GetCurrentReplicas()), tags)
+		gauges.add("hpa.desired_replicas", float64(hpa.Status.GetDesiredReplicas()), tags)
+	}
+
+	gauges.submit(sender)
+}
+
+// tags returns the tags of the instance, the kube_namespace tag of the
+// object if it is namespaced, and the extra tags
+func (k *KubeStateCheck) tags(meta *metav1.ObjectMeta, extra ...string) []string {
+	tags := make([]string, 0, len(k.instance.Tags)+len(extra)+1)
+	tags = append(tags, k.instance.Tags...)
+	if ns := meta.GetNamespace(); ns != "" {
+		tags = append(tags, "kube_namespace:"+ns)
+	}
+	return append(tags, extra...)
+}
+
+// stateGauge is a gauge summed over the objects sharing its tags
+type stateGauge struct {
+	name  string
+	tags  []string
+	value float64
+}
+
+// stateGauges sums the gauges sharing their name and tags before submitting
+// them, like the pods of a deployment in the same phase
+type stateGauges map[string]*stateGauge
+
+func (g stateGauges) add(name string, value float64, tags []string) {
+	sorted := make([]string, len(tags))
+	copy(sorted, tags)
+	sort.Strings(sorted)
+	key := name + "|" + strings.Join(sorted, ",")
+	if gauge, found := g[key]; found {
+		gauge.value += value
+		return
+	}
+	g[key] = &stateGauge{name: name, tags: sorted, value: value}
+}
+
+func (g stateGauges) submit(sender aggregator.Sender) {
+	for _, gauge := range g {
+		sender.Gauge(kubeStateMetricPrefix+gauge.name, gauge.value, "", gauge.tags)
+	}
+}
+
+// ownerResolver maps the ReplicaSets and Jobs to the Deployment or CronJob
+// controlling them, for the objects they own to be tagged with the latter
+type ownerResolver map[string]string
+
+func newOwnerResolver(state *apiserver.ClusterState) ownerResolver {
+	r := ownerResolver{}
+	for _, rs := range state.ReplicaSets {
+		r.addParent("ReplicaSet", "Deployment", rs.Metadata)
+	}
+	for _, job := range state.Jobs {
+		r.addParent("Job", "CronJob", job.Metadata)
+	}
+	return r
+}
+
+func (r ownerResolver) addParent(kind, parentKind string, meta *metav1.ObjectMeta) {
+	for _, owner := range meta.GetOwnerReferences() {
+		if owner.GetController() && owner.GetKind() == parentKind {
+			r[ownerResolverKey(kind, meta.GetNamespace(), meta.GetName())] = owner.GetName()
+			return
+		}
+	}
+}
+
+func ownerResolverKey(kind, namespace, name string) string {
+	return fmt.Sprintf("%s/%s/%s", kind, namespace, name)
+}
+
+// tags returns the tag of the controller of an object, or of the controller
+// of the latter for ReplicaSets and Jobs
+func (r ownerResolver) tags(meta *metav1.ObjectMeta) []string {
+	for _, owner := range meta.GetOwnerReferences() {
+		if !owner.GetController() {
+			continue
+		}
+		name := owner.GetName()
+		switch owner.GetKind() {
+		case "ReplicaSet":
+			if deployment, found := r[ownerResolverKey("ReplicaSet", meta.GetNamespace(), name)]; found {
+				return []string{"kube_deployment:" + deployment}
+			}
+			return []string{"kube_replica_set:" + name}
+		case "Job":
+			if cronjob, found := r[ownerResolverKey("Job", meta.GetNamespace(), name)]; found {
+				return []string{"kube_cronjob:" + cronjob}
+			}
+			return []string{"kube_job:" + name}
+		case "Deployment":
+			return []string{"kube_deployment:" + name}
+		case "DaemonSet":
+			return []string{"kube_daemon_set:" + name}
+		case "StatefulSet":
+			return []string{"kube_stateful_set:" + name}
+		case "ReplicationController":
+			return []string{"kube_replication_controller:" + name}
+		default:
+			log.Debugf("Unknown owner kind %s for %s/%s", owner.GetKind(), meta.GetNamespace(), meta.GetName())
+		}
+		return nil
+	}
+	return nil
+}
+
+// jobTags returns the tags of a job: its CronJob if any, the job otherwise,
+// to not create a context per run of the CronJobs
+func (r ownerResolver) jobTags(meta *metav1.ObjectMeta) []string {
+	if cronjob, found := r[ownerResolverKey("Job", meta.GetNamespace(), meta.GetName())]; found {
+		return []string{"kube_cronjob:" + cronjob}
+	}
+	return []string{"kube_job:" + meta.GetName()}
+}
+
+// KubernetesStateFactory is exported for integration testing
+func KubernetesStateFactory() check.Check {
+	return &KubeStateCheck{
+		CheckBase: core.NewCheckBase(kubernetesStateCheckName),
+		instance:  &KubeStateConfig{},
+	}
+}
+
+func init() {
+	core.RegisterCheck(kubernetesStateCheckName, KubernetesStateFactory)
+}
@@ -0,0 +1,160 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build kubeapiserver
+
+package cluster
+
+import (
+	"testing"
+
+	"github.com/ericchiang/k8s/api/v1"
+	autoscalingv1 "github.com/ericchiang/k8s/apis/autoscaling/v1"
+	batchv1 "github.com/ericchiang/k8s/apis/batch/v1"
+	"github.com/ericchiang/k8s/apis/extensions/v1beta1"
+	obj "github.com/ericchiang/k8s/apis/meta/v1"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver"
+)
+
+func toInt32(i int32) *int32 {
+	return &i
+}
+
+func toBool(b bool) *bool {
+	return &b
+}
+
+func objectMeta(namespace, name, ownerKind, ownerName string) *obj.ObjectMeta {
+	meta := &obj.ObjectMeta{
+		Name:      toStr(name),
+		Namespace: toStr(namespace),
+	}
+	if ownerKind != "" {
+		meta.OwnerReferences = []*obj.OwnerReference{
+			{Kind: toStr(ownerKind), Name: toStr(ownerName), Controller: toBool(true)},
+		}
+	}
+	return meta
+}
+
+func createStatePod(name, ownerKind, ownerName, phase string, restarts int32, waitingReason string) *v1.Pod {
+	container := &v1.ContainerStatus{
+		Name:         toStr("app"),
+		RestartCount: toInt32(restarts),
+		State:        &v1.ContainerState{},
+	}
+	if waitingReason != "" {
+		container.State.Waiting = &v1.ContainerStateWaiting{Reason: toStr(waitingReason)}
+	}
+	return &v1.Pod{
+		Metadata: objectMeta("default", name, ownerKind, ownerName),
+		Status: &v1.PodStatus{
+			Phase:             toStr(phase),
+			ContainerStatuses: []*v1.ContainerStatus{container},
+		},
+	}
+}
+
+func TestReportState(t *testing.T) {
+	state := &apiserver.ClusterState{
+		Pods: []*v1.Pod{
+			createStatePod("web-5d6b8-x2x9s", "ReplicaSet", "web-5d6b8", "Running", 0, ""),
+			createStatePod("web-5d6b8-8xkzl", "ReplicaSet", "web-5d6b8", "Running", 3, ""),
+			createStatePod("web-5d6b8-k2n4x", "ReplicaSet", "web-5d6b8", "Pending", 0, "ImagePullBackOff"),
+			createStatePod("backup-1528700400-b7x2x", "Job", "backup-1528700400", "Succeeded", 0, ""),
+			createStatePod("redis-0", "StatefulSet", "redis", "Running", 1, ""),
+		},
+		Nodes: []*v1.Node{
+			{
+				Metadata: &obj.ObjectMeta{Name: toStr("node1")},
+				Status: &v1.NodeStatus{
+					Conditions: []*v1.NodeCondition{
+						{Type: toStr("Ready"), Status: toStr("True")},
+						{Type: toStr("DiskPressure"), Status: toStr("True"), Message: toStr("disk full")},
+					},
+				},
+			},
+		},
+		Deployments: []*v1beta1.Deployment{
+			{
+				Metadata: objectMeta("default", "web", "", ""),
+				Spec:     &v1beta1.DeploymentSpec{Replicas: toInt32(3)},
+				Status: &v1beta1.DeploymentStatus{
+					Replicas:            toInt32(3),
+					AvailableReplicas:   toInt32(2),
+					UnavailableReplicas: toInt32(1),
+					UpdatedReplicas:     toInt32(3),
+				},
+			},
+		},
+		ReplicaSets: []*v1beta1.ReplicaSet{
+			{Metadata: objectMeta("default", "web-5d6b8", "Deployment", "web")},
+		},
+		Jobs: []*batchv1.Job{
+			{
+				Metadata: objectMeta("default", "backup-1528700400", "CronJob", "backup"),
+				Status:   &batchv1.JobStatus{Succeeded: toInt32(1), Failed: toInt32(2)},
+			},
+			{
+				Metadata: objectMeta("default", "migration", "", ""),
+				Status:   &batchv1.JobStatus{Failed: toInt32(1)},
+			},
+		},
+		PersistentVolumeClaims: []*v1.PersistentVolumeClaim{
+			{
+				Metadata: objectMeta("default", "data-redis-0", "", ""),
+				Status:   &v1.PersistentVolumeClaimStatus{Phase: toStr("Bound")},
+			},
+		},
+		HorizontalPodAutoscalers: []*autoscalingv1.HorizontalPodAutoscaler{
+			{
+				Metadata: objectMeta("default", "web", "", ""),
+				Spec:     &autoscalingv1.HorizontalPodAutoscalerSpec{MinReplicas: toInt32(2), MaxReplicas: toInt32(10)},
+				Status:   &autoscalingv1.HorizontalPodAutoscalerStatus{CurrentReplicas: toInt32(3), DesiredReplicas: toInt32(4)},
+			},
+		},
+	}
+
+	kubeStateCheck := &KubeStateCheck{
+		CheckBase: core.NewCheckBase(kubernetesStateCheckName),
+		instance:  &KubeStateConfig{Tags: []string{"test"}},
+	}
+	mocked := mocksender.NewMockSender(kubeStateCheck.ID())
+	mocked.SetupAcceptAll()
+	kubeStateCheck.reportState(mocked, state)
+
+	deploymentTags := []string{"test", "kube_namespace:default", "kube_deployment:web"}
+	mocked.AssertMetric(t, "Gauge", "kubernetes_state.deployment.replicas_desired", 3, "", deploymentTags)
+	mocked.AssertMetric(t, "Gauge", "kubernetes_state.deployment.replicas_available", 2, "", deploymentTags)
+	mocked.AssertMetric(t, "Gauge", "kubernetes_state.deployment.replicas_unavailable", 1, "", deploymentTags)
+
+	// pods are counted by phase and owner, replicasets resolved to their deployment
+	mocked.AssertMetric(t, "Gauge", "kubernetes_state.pod.status_phase", 2, "", append(deploymentTags, "pod_phase:running"))
+	mocked.AssertMetric(t, "Gauge", "kubernetes_state.pod.status_phase", 1, "", append(deploymentTags, "pod_phase:pending"))
+	mocked.AssertMetric(t, "Gauge", "kubernetes_state.pod.status_phase", 1, "", []string{"kube_cronjob:backup", "pod_phase:succeeded"})
+	mocked.AssertMetric(t, "Gauge", "kubernetes_state.pod.status_phase", 1, "", []string{"kube_stateful_set:redis", "pod_phase:running"})
+
+	mocked.AssertMetric(t, "Gauge", "kubernetes_state.container.restarts", 3, "", []string{"kube_deployment:web", "pod_name:web-5d6b8-8xkzl", "kube_container_name:app"})
+	mocked.AssertMetric(t, "Gauge", "kubernetes_state.container.waiting", 1, "", []string{"pod_name:web-5d6b8-k2n4x", "reason:imagepullbackoff"})
+
+	mocked.AssertServiceCheck(t, "kubernetes_state.node.ready", metrics.ServiceCheckOK, "", []string{"test", "node:node1"}, "")
+	mocked.AssertServiceCheck(t, "kubernetes_state.node.disk_pressure", metrics.ServiceCheckCritical, "", []string{"test", "node:node1"}, "disk full")
+	mocked.AssertMetric(t, "Gauge", "kubernetes_state.nodes.by_condition", 1, "", []string{"condition:ready", "status:true"})
+
+	mocked.AssertMetric(t, "Gauge", "kubernetes_state.job.succeeded", 1, "", []string{"kube_namespace:default", "kube_cronjob:backup"})
+	mocked.AssertMetric(t, "Gauge", "kubernetes_state.job.failed", 2, "", []string{"kube_namespace:default", "kube_cronjob:backup"})
+	mocked.AssertMetric(t, "Gauge", "kubernetes_state.job.failed", 1, "", []string{"kube_namespace:default", "kube_job:migration"})
+
+	mocked.AssertMetric(t, "Gauge", "kubernetes_state.persistentvolumeclaim.status", 1, "", []string{"persistentvolumeclaim:data-redis-0", "phase:bound"})
+
+	hpaTags := []string{"test", "kube_namespace:default", "hpa:web"}
+	mocked.AssertMetric(t, "Gauge", "kubernetes_state.hpa.current_replicas", 3, "", hpaTags)
+	mocked.AssertMetric(t, "Gauge", "kubernetes_state.hpa.desired_replicas", 4, "", hpaTags)
+	mocked.AssertMetric(t, "Gauge", "kubernetes_state.hpa.max_replicas", 10, "", hpaTags)
+}
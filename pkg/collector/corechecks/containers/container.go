@@ -0,0 +1,176 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package containers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	log "github.com/cihub/seelog"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagger"
+	"github.com/DataDog/datadog-agent/pkg/util/container"
+)
+
+const (
+	containerCheckName = "container"
+	// ContainerRuntimeUp is the service check reporting the runtime connectivity
+	ContainerRuntimeUp = "container.runtime.can_connect"
+)
+
+// ContainerConfig holds the configuration of the container check
+type ContainerConfig struct {
+	// Runtimes restricts the monitored runtimes, all the available ones if empty
+	Runtimes []string `yaml:"runtimes"`
+	Tags     []string `yaml:"tags"`
+}
+
+// Parse parses the check instance configuration
+func (c *ContainerConfig) Parse(data []byte) error {
+	return yaml.Unmarshal(data, c)
+}
+
+// ContainerCheck reports the resource usage of the containers of any
+// container runtime: docker, or containerd and cri-o through the CRI
+type ContainerCheck struct {
+	core.CheckBase
+	instance *ContainerConfig
+	// getRuntimes is overridden in unit tests
+	getRuntimes func() []container.Runtime
+}
+
+// Run executes the check
+func (c *ContainerCheck) Run() error {
+	sender, err := aggregator.GetSender(c.ID())
+	if err != nil {
+		return err
+	}
+
+	runtimes := c.getRuntimes()
+	if len(runtimes) == 0 {
+		return errors.New("no container runtime is available")
+	}
+
+	for _, rt := range runtimes {
+		runtimeTags := append([]string{fmt.Sprintf("container_runtime:%s", rt.Name())}, c.instance.Tags...)
+		if err := c.collectRuntime(sender, rt, runtimeTags); err != nil {
+			log.Warnf("can't collect %s containers: %s", rt.Name(), err)
+			sender.ServiceCheck(ContainerRuntimeUp, metrics.ServiceCheckCritical, "", runtimeTags, err.Error())
+			continue
+		}
+		sender.ServiceCheck(ContainerRuntimeUp, metrics.ServiceCheckOK, "", runtimeTags, "")
+	}
+
+	sender.Commit()
+	return nil
+}
+
+func (c *ContainerCheck) collectRuntime(sender aggregator.Sender, rt container.Runtime, runtimeTags []string) error {
+	containers, err := rt.List(false)
+	if err != nil {
+		return err
+	}
+	ids := make([]string, 0, len(containers))
+	for _, co := range containers {
+		ids = append(ids, co.ID)
+	}
+	stats, err := rt.Stats(ids)
+	if err != nil {
+		return err
+	}
+
+	for _, co := range containers {
+		s, found := stats[co.ID]
+		if !found {
+			log.Debugf("no statistics for container %s", co.ID)
+			continue
+		}
+		tags, err := tagger.Tag(co.EntityID, tagger.ChecksCardinality)
+		if err != nil {
+			log.Debugf("Could not collect tags for container %s: %s", co.ID, err)
+		}
+		tags = append(tags, runtimeTags...)
+		reportContainerStats(sender, s, tags)
+	}
+	sender.Gauge("container.running", float64(len(containers)), "", runtimeTags)
+	return nil
+}
+
+// reportContainerStats sends the metrics of a container. Runtimes don't
+// all report the same statistics: unset values are skipped.
+func reportContainerStats(sender aggregator.Sender, s *container.Stats, tags []string) {
+	if s.CPU != nil {
+		sender.Rate("container.cpu.usage", float64(s.CPU.Total), "", tags)
+		if s.CPU.User > 0 || s.CPU.System > 0 {
+			sender.Rate("container.cpu.user", float64(s.CPU.User), "", tags)
+			sender.Rate("container.cpu.system", float64(s.CPU.System), "", tags)
+		}
+		sender.Rate("container.cpu.throttled", float64(s.CPU.NrThrottled), "", tags)
+	}
+	if s.CPULimit > 0 {
+		sender.Gauge("container.cpu.limit", s.CPULimit, "", tags)
+	}
+
+	if s.Memory != nil {
+		if s.Memory.Usage > 0 {
+			sender.Gauge("container.memory.usage", float64(s.Memory.Usage), "", tags)
+		}
+		sender.Gauge("container.memory.working_set", float64(s.Memory.WorkingSet), "", tags)
+		if s.Memory.RSS > 0 || s.Memory.Cache > 0 {
+			sender.Gauge("container.memory.rss", float64(s.Memory.RSS), "", tags)
+			sender.Gauge("container.memory.cache", float64(s.Memory.Cache), "", tags)
+		}
+		if s.Memory.Limit > 0 && s.Memory.Limit < uint64(math.Pow(2, 60)) {
+			sender.Gauge("container.memory.limit", float64(s.Memory.Limit), "", tags)
+		}
+	}
+
+	if s.IO != nil {
+		sender.Rate("container.io.read", float64(s.IO.ReadBytes), "", tags)
+		sender.Rate("container.io.write", float64(s.IO.WriteBytes), "", tags)
+	}
+}
+
+// Configure parses the check configuration and init the check
+func (c *ContainerCheck) Configure(config, initConfig check.ConfigData) error {
+	if err := c.instance.Parse(config); err != nil {
+		return err
+	}
+	if len(c.instance.Runtimes) > 0 {
+		names := c.instance.Runtimes
+		c.getRuntimes = func() []container.Runtime {
+			var runtimes []container.Runtime
+			for _, name := range names {
+				rt, err := container.GetRuntime(name)
+				if err != nil {
+					log.Debugf("runtime %s is not available: %s", name, err)
+					continue
+				}
+				runtimes = append(runtimes, rt)
+			}
+			return runtimes
+		}
+	}
+	return nil
+}
+
+func containerFactory() check.Check {
+	return &ContainerCheck{
+		CheckBase:   core.NewCheckBase(containerCheckName),
+		instance:    &ContainerConfig{},
+		getRuntimes: container.GetRuntimes,
+	}
+}
+
+func init() {
+	core.RegisterCheck(containerCheckName, containerFactory)
+}
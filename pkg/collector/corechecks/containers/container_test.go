@@ -0,0 +1,115 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package containers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/container"
+)
+
+// fakeRuntime returns canned containers and statistics
+type fakeRuntime struct {
+	name       string
+	containers []*container.Details
+	stats      map[string]*container.Stats
+	err        error
+}
+
+func (f *fakeRuntime) Name() string                             { return f.name }
+func (f *fakeRuntime) ContainerIDToEntityName(id string) string { return f.name + "://" + id }
+func (f *fakeRuntime) List(bool) ([]*container.Details, error)  { return f.containers, f.err }
+func (f *fakeRuntime) Inspect(string) (*container.Details, error) {
+	return nil, errors.New("not implemented")
+}
+func (f *fakeRuntime) SubscribeToEvents(string) (<-chan *container.Event, <-chan error, error) {
+	return nil, nil, errors.New("not implemented")
+}
+func (f *fakeRuntime) UnsubscribeFromEvents(string) error { return nil }
+func (f *fakeRuntime) Stats([]string) (map[string]*container.Stats, error) {
+	return f.stats, nil
+}
+
+func TestContainerCheck(t *testing.T) {
+	docker := &fakeRuntime{
+		name: "docker",
+		containers: []*container.Details{
+			{ID: "abc", EntityID: "docker://abc"},
+			{ID: "gone", EntityID: "docker://gone"},
+		},
+		stats: map[string]*container.Stats{
+			"abc": {
+				ContainerID: "abc",
+				CPULimit:    50,
+				CPU:         &container.CPUStats{Total: 3000, User: 2000, System: 1000, NrThrottled: 2},
+				Memory:      &container.MemoryStats{Usage: 2048, WorkingSet: 1024, RSS: 512, Cache: 256, Limit: 4096},
+				IO:          &container.IOStats{ReadBytes: 10, WriteBytes: 20},
+			},
+		},
+	}
+	cri := &fakeRuntime{
+		name:       "cri",
+		containers: []*container.Details{{ID: "def", EntityID: "containerd://def"}},
+		stats: map[string]*container.Stats{
+			"def": {
+				ContainerID: "def",
+				CPU:         &container.CPUStats{Total: 5000},
+				Memory:      &container.MemoryStats{WorkingSet: 4096},
+			},
+		},
+	}
+	broken := &fakeRuntime{name: "broken", err: errors.New("connection refused")}
+
+	check := containerFactory().(*ContainerCheck)
+	check.Configure([]byte("tags: [\"env:test\"]"), nil)
+	check.getRuntimes = func() []container.Runtime {
+		return []container.Runtime{docker, cri, broken}
+	}
+
+	mockSender := mocksender.NewMockSender(check.ID())
+	mockSender.SetupAcceptAll()
+	err := check.Run()
+	assert.NoError(t, err)
+
+	dockerTags := []string{"container_runtime:docker", "env:test"}
+	mockSender.AssertMetric(t, "Rate", "container.cpu.usage", 3000, "", dockerTags)
+	mockSender.AssertMetric(t, "Rate", "container.cpu.user", 2000, "", dockerTags)
+	mockSender.AssertMetric(t, "Rate", "container.cpu.system", 1000, "", dockerTags)
+	mockSender.AssertMetric(t, "Rate", "container.cpu.throttled", 2, "", dockerTags)
+	mockSender.AssertMetric(t, "Gauge", "container.cpu.limit", 50, "", dockerTags)
+	mockSender.AssertMetric(t, "Gauge", "container.memory.usage", 2048, "", dockerTags)
+	mockSender.AssertMetric(t, "Gauge", "container.memory.working_set", 1024, "", dockerTags)
+	mockSender.AssertMetric(t, "Gauge", "container.memory.rss", 512, "", dockerTags)
+	mockSender.AssertMetric(t, "Gauge", "container.memory.cache", 256, "", dockerTags)
+	mockSender.AssertMetric(t, "Gauge", "container.memory.limit", 4096, "", dockerTags)
+	mockSender.AssertMetric(t, "Rate", "container.io.read", 10, "", dockerTags)
+	mockSender.AssertMetric(t, "Rate", "container.io.write", 20, "", dockerTags)
+	mockSender.AssertMetric(t, "Gauge", "container.running", 2, "", dockerTags)
+	mockSender.AssertServiceCheck(t, ContainerRuntimeUp, metrics.ServiceCheckOK, "", dockerTags, "")
+
+	// the CRI only reports CPU usage and working set
+	criTags := []string{"container_runtime:cri", "env:test"}
+	mockSender.AssertMetric(t, "Rate", "container.cpu.usage", 5000, "", criTags)
+	mockSender.AssertMetric(t, "Gauge", "container.memory.working_set", 4096, "", criTags)
+	mockSender.AssertNotCalled(t, "Rate", "container.cpu.user", mock.Anything, "", criTags)
+	mockSender.AssertNotCalled(t, "Gauge", "container.memory.rss", mock.Anything, "", criTags)
+	mockSender.AssertNotCalled(t, "Rate", "container.io.read", mock.Anything, "", criTags)
+	mockSender.AssertNotCalled(t, "Gauge", "container.cpu.limit", mock.Anything, "", criTags)
+
+	brokenTags := []string{"container_runtime:broken", "env:test"}
+	mockSender.AssertServiceCheck(t, ContainerRuntimeUp, metrics.ServiceCheckCritical, "", brokenTags, "connection refused")
+	mockSender.AssertNumberOfCalls(t, "Commit", 1)
+
+	// no runtime available
+	check.getRuntimes = func() []container.Runtime { return nil }
+	assert.Error(t, check.Run())
+}
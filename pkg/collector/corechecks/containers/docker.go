@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"math"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -22,6 +23,7 @@ import (
 	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/DataDog/datadog-agent/pkg/tagger"
+	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
 	"github.com/DataDog/datadog-agent/pkg/util/docker"
 )
 
@@ -30,6 +32,7 @@ const dockerCheckName = "docker"
 type DockerConfig struct {
 	CollectContainerSize bool               `yaml:"collect_container_size"`
 	CollectExitCodes     bool               `yaml:"collect_exit_codes"`
+	CollectState         bool               `yaml:"collect_container_state"`
 	CollectImagesStats   bool               `yaml:"collect_images_stats"`
 	CollectImageSize     bool               `yaml:"collect_image_size"`
 	CollectDiskStats     bool               `yaml:"collect_disk_stats"`
@@ -38,13 +41,18 @@ type DockerConfig struct {
 	CollectEvent         bool               `yaml:"collect_events"`
 	FilteredEventType    []string           `yaml:"filtered_event_types"`
 	CappedMetrics        map[string]float64 `yaml:"capped_metrics"`
+	CappedEvents         map[string]int     `yaml:"capped_events"`
 }
 
 const (
 	DockerServiceUp string = "docker.service_up"
 	DockerExit      string = "docker.exit"
+	DockerHealth    string = "docker.container_health"
 )
 
+// oomKillAction is the action of the events created from the cgroup oom_kill counter
+const oomKillAction = "oom_kill"
+
 type containerPerImage struct {
 	tags    []string
 	running int64
@@ -68,10 +76,14 @@ type DockerCheck struct {
 	lastEventTime  time.Time
 	dockerHostname string
 	cappedSender   *cappedSender
+	// lastOOMKills holds the cgroup oom_kill counter of the running containers
+	lastOOMKills map[string]uint64
+	// lastHealth holds the health of the running containers with a health check
+	lastHealth map[string]string
 }
 
 func updateContainerRunningCount(images map[string]*containerPerImage, c *docker.Container) {
-	imageTags, err := tagger.Tag(c.EntityID, false)
+	imageTags, err := tagger.Tag(c.EntityID, collectors.LowCardinality)
 	if err != nil {
 		log.Errorf("Could not collect tags for container %s: %s", c.ID[:12], err)
 		return
@@ -141,22 +153,32 @@ func (d *DockerCheck) Run() error {
 	}
 
 	images := map[string]*containerPerImage{}
+	var oomEvents []*docker.ContainerEvent
+	seenContainers := make(map[string]bool)
 	for _, c := range containers {
 		updateContainerRunningCount(images, c)
-		if c.State != docker.ContainerRunningState || c.Excluded {
+		if c.Excluded {
+			continue
+		}
+		if c.State == docker.ContainerExitedState && d.instance.CollectState {
+			d.reportContainerState(sender, du, c, append(exitedContainerTags(c), d.instance.Tags...))
+		}
+		if c.State != docker.ContainerRunningState {
 			continue
 		}
-		tags, err := tagger.Tag(c.EntityID, true)
+		tags, err := tagger.Tag(c.EntityID, tagger.ChecksCardinality)
 		if err != nil {
 			log.Errorf("Could not collect tags for container %s: %s", c.ID[:12], err)
 			tags = []string{}
 		}
 		tags = append(tags, d.instance.Tags...)
+		seenContainers[c.ID] = true
 
 		sender.Rate("docker.cpu.system", float64(c.CPU.System), "", tags)
 		sender.Rate("docker.cpu.user", float64(c.CPU.User), "", tags)
 		sender.Rate("docker.cpu.usage", c.CPU.UsageTotal, "", tags)
 		sender.Rate("docker.cpu.throttled", float64(c.CPUNrThrottled), "", tags)
+		sender.Rate("docker.cpu.throttled.time", float64(c.CPUThrottledTime), "", tags)
 		sender.Gauge("docker.mem.cache", float64(c.Memory.Cache), "", tags)
 		sender.Gauge("docker.mem.rss", float64(c.Memory.RSS), "", tags)
 		if c.Memory.SwapPresent == true {
@@ -178,9 +200,23 @@ func (d *DockerCheck) Run() error {
 			}
 		}
 
+		if c.MemEvents != nil {
+			sender.MonotonicCount("docker.mem.oom_events", float64(c.MemEvents.OOM), "", tags)
+			sender.MonotonicCount("docker.mem.oom_kills", float64(c.MemEvents.OOMKill), "", tags)
+			if ev := d.oomKillEvent(c); ev != nil {
+				oomEvents = append(oomEvents, ev)
+			}
+		}
+
 		sender.Rate("docker.io.read_bytes", float64(c.IO.ReadBytes), "", tags)
 		sender.Rate("docker.io.write_bytes", float64(c.IO.WriteBytes), "", tags)
 
+		if c.Pressure != nil {
+			docker.SendPressure(sender, "docker.cpu", c.Pressure.CPU, tags)
+			docker.SendPressure(sender, "docker.mem", c.Pressure.Memory, tags)
+			docker.SendPressure(sender, "docker.io", c.Pressure.IO, tags)
+		}
+
 		if c.Network != nil {
 			for _, netStat := range c.Network {
 				if netStat.NetworkName == "" {
@@ -193,6 +229,16 @@ func (d *DockerCheck) Run() error {
 			}
 		}
 
+		if c.StartedAt > 0 {
+			sender.Gauge("docker.container.uptime", float64(time.Now().Unix()-c.StartedAt), "", tags)
+		}
+		if c.Health != "" {
+			d.reportHealth(sender, c, tags)
+		}
+		if d.instance.CollectState {
+			d.reportContainerState(sender, du, c, tags)
+		}
+
 		if d.instance.CollectContainerSize {
 			info, err := du.Inspect(c.ID, true)
 			if err != nil {
@@ -223,22 +269,34 @@ func (d *DockerCheck) Run() error {
 	}
 	sender.ServiceCheck(DockerServiceUp, metrics.ServiceCheckOK, "", d.instance.Tags, "")
 
+	for id := range d.lastOOMKills {
+		if !seenContainers[id] {
+			delete(d.lastOOMKills, id)
+		}
+	}
+	for id := range d.lastHealth {
+		if !seenContainers[id] {
+			delete(d.lastHealth, id)
+		}
+	}
+
 	if d.instance.CollectEvent || d.instance.CollectExitCodes {
 		events, err := d.retrieveEvents(du)
 		if err != nil {
 			log.Warn(err.Error())
-		} else {
-			if d.instance.CollectEvent {
-				err = d.reportEvents(events, sender)
-				if err != nil {
-					log.Warn(err.Error())
-				}
+			events = nil
+		} else if d.instance.CollectExitCodes {
+			err = d.reportExitCodes(events, sender)
+			if err != nil {
+				log.Warn(err.Error())
 			}
-			if d.instance.CollectExitCodes {
-				err = d.reportExitCodes(events, sender)
-				if err != nil {
-					log.Warn(err.Error())
-				}
+		}
+		if d.instance.CollectEvent {
+			// The OOM killer can kill processes without stopping the container:
+			// these kills are only visible in the cgroup counters
+			err = d.reportEvents(append(events, oomEvents...), sender)
+			if err != nil {
+				log.Warn(err.Error())
 			}
 		}
 	}
@@ -284,6 +342,80 @@ func (d *DockerCheck) Run() error {
 	return nil
 }
 
+// oomKillEvent returns an oom_kill event if the OOM killer killed processes
+// of the container since the last run. The first run only records the counter.
+func (d *DockerCheck) oomKillEvent(c *docker.Container) *docker.ContainerEvent {
+	if d.lastOOMKills == nil {
+		d.lastOOMKills = make(map[string]uint64)
+	}
+	last, found := d.lastOOMKills[c.ID]
+	d.lastOOMKills[c.ID] = c.MemEvents.OOMKill
+	if !found || c.MemEvents.OOMKill <= last {
+		return nil
+	}
+	return &docker.ContainerEvent{
+		ContainerID:   c.ID,
+		ContainerName: strings.TrimPrefix(c.Name, "/"),
+		ImageName:     c.Image,
+		Action:        oomKillAction,
+		Timestamp:     time.Now(),
+		Attributes:    map[string]string{"count": strconv.FormatUint(c.MemEvents.OOMKill-last, 10)},
+	}
+}
+
+// reportHealth sends the health check status of a container, and counts the
+// status changes since the last run
+func (d *DockerCheck) reportHealth(sender aggregator.Sender, c *docker.Container, tags []string) {
+	var status metrics.ServiceCheckStatus
+	switch c.Health {
+	case "healthy":
+		status = metrics.ServiceCheckOK
+	case "starting":
+		status = metrics.ServiceCheckWarning
+	case "unhealthy":
+		status = metrics.ServiceCheckCritical
+	default:
+		status = metrics.ServiceCheckUnknown
+	}
+	message := ""
+	if status != metrics.ServiceCheckOK {
+		message = fmt.Sprintf("Container %s is %s", strings.TrimPrefix(c.Name, "/"), c.Health)
+	}
+	sender.ServiceCheck(DockerHealth, status, "", tags, message)
+
+	if d.lastHealth == nil {
+		d.lastHealth = make(map[string]string)
+	}
+	if last, found := d.lastHealth[c.ID]; found && last != c.Health {
+		sender.Count("docker.container.health_transitions", 1, "", append([]string{"health:" + c.Health}, tags...))
+	}
+	d.lastHealth[c.ID] = c.Health
+}
+
+// reportContainerState sends the restart count of a container and, once it
+// exited, its exit code, read from the container inspect
+func (d *DockerCheck) reportContainerState(sender aggregator.Sender, du *docker.DockerUtil, c *docker.Container, tags []string) {
+	info, err := du.Inspect(c.ID, false)
+	if err != nil {
+		log.Debugf("Failed to inspect container %s - %s", c.ID[:12], err)
+		return
+	}
+	sender.Gauge("docker.container.restarts", float64(info.RestartCount), "", tags)
+	if c.State == docker.ContainerExitedState && info.State != nil {
+		sender.Gauge("docker.container.exit_code", float64(info.State.ExitCode), "", tags)
+	}
+}
+
+// exitedContainerTags returns the tags of an exited container, that the
+// tagger may have forgotten
+func exitedContainerTags(c *docker.Container) []string {
+	tags, err := tagger.Tag(c.EntityID, tagger.ChecksCardinality)
+	if err != nil || len(tags) == 0 {
+		tags = []string{"container_name:" + strings.TrimPrefix(c.Name, "/"), "docker_image:" + c.Image}
+	}
+	return tags
+}
+
 // Configure parses the check configuration and init the check
 func (d *DockerCheck) Configure(config, initConfig check.ConfigData) error {
 	d.instance.Parse(config)
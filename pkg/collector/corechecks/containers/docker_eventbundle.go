@@ -17,6 +17,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/DataDog/datadog-agent/pkg/tagger"
+	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
 	"github.com/DataDog/datadog-agent/pkg/util/docker"
 )
 
@@ -28,6 +29,8 @@ type dockerEventBundle struct {
 	events        []*docker.ContainerEvent
 	maxTimestamp  time.Time
 	countByAction map[string]int
+	// cappedByType counts the events dropped by the event capping
+	cappedByType map[string]int
 }
 
 func newDockerEventBundler(imageName string) *dockerEventBundle {
@@ -35,6 +38,7 @@ func newDockerEventBundler(imageName string) *dockerEventBundle {
 		imageName:     imageName,
 		events:        []*docker.ContainerEvent{},
 		countByAction: make(map[string]int),
+		cappedByType:  make(map[string]int),
 	}
 }
 
@@ -51,6 +55,11 @@ func (b *dockerEventBundle) addEvent(event *docker.ContainerEvent) error {
 	return nil
 }
 
+// addCappedEvent counts an event dropped by the event capping, to mention it
+func (b *dockerEventBundle) addCappedEvent(event *docker.ContainerEvent) {
+	b.cappedByType[dockerEventType(event.Action)]++
+}
+
 func (b *dockerEventBundle) toDatadogEvent(hostname string) (metrics.Event, error) {
 	output := metrics.Event{
 		Priority:       metrics.EventPriorityNormal,
@@ -72,14 +81,23 @@ func (b *dockerEventBundle) toDatadogEvent(hostname string) (metrics.Event, erro
 	textLines := []string{"%%% ", output.Title, "```"}
 
 	for _, ev := range b.events {
+		if ev.ContainerID == "" {
+			// Image pull events have no container
+			textLines = append(textLines, fmt.Sprintf("%s\t%s", strings.ToUpper(ev.Action), ev.ImageName))
+			continue
+		}
 		textLines = append(textLines, fmt.Sprintf("%s\t%s", strings.ToUpper(ev.Action), ev.ContainerName))
 		seenContainers[ev.ContainerID] = true // Emulating a set with a map
 	}
-	textLines = append(textLines, "```", " %%%")
+	textLines = append(textLines, "```")
+	if len(b.cappedByType) > 0 {
+		textLines = append(textLines, fmt.Sprintf("Not listed over the event caps: %s", formatStringIntMap(b.cappedByType)))
+	}
+	textLines = append(textLines, " %%%")
 	output.Text = strings.Join(textLines, "\n")
 
 	for cid := range seenContainers {
-		tags, err := tagger.Tag(docker.ContainerIDToEntityName(cid), true)
+		tags, err := tagger.Tag(docker.ContainerIDToEntityName(cid), collectors.HighCardinality)
 		if err != nil {
 			log.Debugf("no tags for %s: %s", cid, err)
 		} else {
@@ -87,7 +105,7 @@ func (b *dockerEventBundle) toDatadogEvent(hostname string) (metrics.Event, erro
 		}
 	}
 
-	if b.countByAction["oom"]+b.countByAction["kill"] > 0 {
+	if b.countByAction["oom"]+b.countByAction[oomKillAction]+b.countByAction["kill"]+b.countByAction["health_status: unhealthy"] > 0 {
 		output.AlertType = "error"
 	}
 
@@ -62,7 +62,7 @@ func (d *DockerCheck) reportExitCodes(events []*docker.ContainerEvent, sender ag
 		if exitCodeInt != 0 {
 			status = metrics.ServiceCheckCritical
 		}
-		tags, err := tagger.Tag(ev.ContainerEntityName(), true)
+		tags, err := tagger.Tag(ev.ContainerEntityName(), tagger.ChecksCardinality)
 		tags = append(tags, d.instance.Tags...)
 		if err != nil {
 			log.Debugf("no tags for %s: %s", ev.ContainerID, err)
@@ -98,15 +98,21 @@ func (d *DockerCheck) aggregateEvents(events []*docker.ContainerEvent) (map[stri
 	// Pre-aggregate container events by image
 	eventsByImage := make(map[string]*dockerEventBundle)
 	filteredByType := make(map[string]int)
+	var cappedEvents []*docker.ContainerEvent
 
 ITER_EVENT:
 	for _, event := range events {
+		eventType := dockerEventType(event.Action)
 		for _, action := range d.instance.FilteredEventType {
-			if event.Action == action {
+			if event.Action == action || eventType == action {
 				filteredByType[action] = filteredByType[action] + 1
 				continue ITER_EVENT
 			}
 		}
+		if !d.underEventCap(event) {
+			cappedEvents = append(cappedEvents, event)
+			continue
+		}
 		bundle, found := eventsByImage[event.ImageName]
 		if found == false {
 			bundle = newDockerEventBundler(event.ImageName)
@@ -118,9 +124,27 @@ ITER_EVENT:
 	if len(filteredByType) > 0 {
 		log.Debugf("filtered out the following events: %s", formatStringIntMap(filteredByType))
 	}
+	if len(cappedEvents) > 0 {
+		log.Debugf("dropped %d events over the capped_events limits", len(cappedEvents))
+	}
+	// Mention the capped events in the bundles sent for their image
+	for _, event := range cappedEvents {
+		if bundle, found := eventsByImage[event.ImageName]; found {
+			bundle.addCappedEvent(event)
+		}
+	}
 	return eventsByImage, nil
 }
 
+// dockerEventType returns the type of an event action, as some actions
+// hold details, like `health_status: unhealthy` or `exec_start: sh`
+func dockerEventType(action string) string {
+	if i := strings.IndexByte(action, ':'); i >= 0 {
+		return action[:i]
+	}
+	return action
+}
+
 func formatStringIntMap(input map[string]int) string {
 	var parts []string
 	for k, v := range input {
@@ -15,6 +15,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/cache"
 	"github.com/DataDog/datadog-agent/pkg/util/docker"
 )
 
@@ -72,3 +73,113 @@ func TestReportExitCodes(t *testing.T) {
 	mockSender.AssertExpectations(t)
 	mockSender.AssertNumberOfCalls(t, "ServiceCheck", 2)
 }
+
+func TestOOMKillEvent(t *testing.T) {
+	dockerCheck := &DockerCheck{
+		instance: &DockerConfig{},
+	}
+	co := &docker.Container{
+		ID:        "fcc487ac70446287ae0dc79fb72368d824ff6198cd1166a405bc5a7fc111d3a8",
+		Name:      "/leaky",
+		Image:     "leaky:latest",
+		MemEvents: &docker.CgroupMemEvents{OOM: 2, OOMKill: 2},
+	}
+
+	// The first run only records the counter
+	assert.Nil(t, dockerCheck.oomKillEvent(co))
+	assert.Nil(t, dockerCheck.oomKillEvent(co))
+
+	co.MemEvents = &docker.CgroupMemEvents{OOM: 3, OOMKill: 5}
+	ev := dockerCheck.oomKillEvent(co)
+	if assert.NotNil(t, ev) {
+		assert.Equal(t, "oom_kill", ev.Action)
+		assert.Equal(t, "leaky", ev.ContainerName)
+		assert.Equal(t, "leaky:latest", ev.ImageName)
+		assert.Equal(t, "3", ev.Attributes["count"])
+	}
+	assert.Nil(t, dockerCheck.oomKillEvent(co))
+
+	bundle := newDockerEventBundler("leaky:latest")
+	bundle.addEvent(ev)
+	datadogEv, err := bundle.toDatadogEvent("host")
+	assert.NoError(t, err)
+	assert.Equal(t, metrics.EventAlertTypeError, datadogEv.AlertType)
+}
+
+func TestReportHealth(t *testing.T) {
+	dockerCheck := &DockerCheck{
+		instance: &DockerConfig{},
+	}
+	tags := []string{"container_name:web"}
+	co := &docker.Container{
+		ID:     "fcc487ac70446287ae0dc79fb72368d824ff6198cd1166a405bc5a7fc111d3a8",
+		Name:   "/web",
+		Health: "starting",
+	}
+
+	for _, tc := range []struct {
+		health     string
+		status     metrics.ServiceCheckStatus
+		message    string
+		transition bool
+	}{
+		{"starting", metrics.ServiceCheckWarning, "Container web is starting", false},
+		{"healthy", metrics.ServiceCheckOK, "", true},
+		{"unhealthy", metrics.ServiceCheckCritical, "Container web is unhealthy", true},
+		// No transition without a status change
+		{"unhealthy", metrics.ServiceCheckCritical, "Container web is unhealthy", false},
+	} {
+		mockSender := mocksender.NewMockSender(dockerCheck.ID())
+		mockSender.SetupAcceptAll()
+		co.Health = tc.health
+		dockerCheck.reportHealth(mockSender, co, tags)
+
+		mockSender.AssertServiceCheck(t, DockerHealth, tc.status, "", tags, tc.message)
+		if tc.transition {
+			mockSender.AssertMetric(t, "Count", "docker.container.health_transitions", 1, "", []string{"health:" + tc.health, "container_name:web"})
+		} else {
+			mockSender.AssertNumberOfCalls(t, "Count", 0)
+		}
+	}
+}
+
+func TestAggregateEvents(t *testing.T) {
+	cache.Cache.Flush()
+	dockerCheck := &DockerCheck{
+		instance: &DockerConfig{
+			FilteredEventType: []string{"exec_start"},
+			CappedEvents:      map[string]int{"die": 1},
+		},
+	}
+	cid := "fcc487ac70446287ae0dc79fb72368d824ff6198cd1166a405bc5a7fc111d3a8"
+	events := []*docker.ContainerEvent{
+		{ContainerID: cid, ContainerName: "crashy", ImageName: "crashy:latest", Action: "die"},
+		{ContainerID: cid, ContainerName: "crashy", ImageName: "crashy:latest", Action: "start"},
+		{ContainerID: cid, ContainerName: "crashy", ImageName: "crashy:latest", Action: "die"},
+		{ContainerID: cid, ContainerName: "crashy", ImageName: "crashy:latest", Action: "health_status: unhealthy"},
+		{ContainerID: cid, ContainerName: "crashy", ImageName: "crashy:latest", Action: "exec_start: sh -c true"},
+		{ImageName: "redis:latest", Action: "pull"},
+	}
+
+	bundles, err := dockerCheck.aggregateEvents(events)
+	assert.NoError(t, err)
+	assert.Len(t, bundles, 2)
+
+	crashy := bundles["crashy:latest"]
+	if assert.NotNil(t, crashy) {
+		assert.Len(t, crashy.events, 3)
+		assert.Equal(t, map[string]int{"die": 1}, crashy.cappedByType)
+		ev, err := crashy.toDatadogEvent("host")
+		assert.NoError(t, err)
+		assert.Contains(t, ev.Text, "Not listed over the event caps: 1 die")
+		assert.Equal(t, metrics.EventAlertTypeError, ev.AlertType)
+	}
+
+	redis := bundles["redis:latest"]
+	if assert.NotNil(t, redis) {
+		ev, err := redis.toDatadogEvent("host")
+		assert.NoError(t, err)
+		assert.Contains(t, ev.Text, "PULL\tredis:latest")
+		assert.Empty(t, ev.Tags)
+	}
+}
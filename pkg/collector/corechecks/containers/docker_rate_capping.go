@@ -15,6 +15,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/pkg/aggregator"
 	"github.com/DataDog/datadog-agent/pkg/util/cache"
+	"github.com/DataDog/datadog-agent/pkg/util/docker"
 )
 
 /*
@@ -30,6 +31,25 @@ const (
 	dockerRateCachingDuration = time.Minute
 )
 
+/*
+ * Event capping logic, to keep crash looping containers from flooding the
+ * event stream with their die, oom or health_status events. The number of
+ * events of a type is capped per image over a minute.
+ *
+ * This is triggered by users setting the `capped_events` section in docker.yaml
+ */
+
+const (
+	dockerEventCacheKey      = "docker_event_count"
+	dockerEventCappingWindow = time.Minute
+)
+
+// eventCount counts the events of a type and image submitted in the
+// current capping window
+type eventCount struct {
+	count int
+}
+
 // cappedSender wraps around the standard Sender and overrides
 // the Rate method to implement rate capping
 type cappedSender struct {
@@ -103,6 +123,33 @@ func (s *cappedSender) storePoint(cacheKey string, value float64, timestamp time
 	cache.Cache.Set(cacheKey, point, dockerRateCachingDuration)
 }
 
+// underEventCap checks the event against the `capped_events` configuration
+// and counts it if it can be submitted. The count of an image and type is
+// reset a minute after its first event.
+func (d *DockerCheck) underEventCap(event *docker.ContainerEvent) bool {
+	eventType := dockerEventType(event.Action)
+	capValue, found := d.instance.CappedEvents[eventType]
+	if !found {
+		return true
+	}
+
+	cacheKey := cache.BuildAgentKey(dockerEventCacheKey, event.ImageName, eventType)
+	var counter *eventCount
+	if cached, found := cache.Cache.Get(cacheKey); found {
+		counter, _ = cached.(*eventCount)
+	}
+	if counter == nil {
+		// The counter is updated in place to keep the expiration of the window
+		counter = &eventCount{}
+		cache.Cache.Set(cacheKey, counter, dockerEventCappingWindow)
+	}
+	if counter.count >= capValue {
+		return false
+	}
+	counter.count++
+	return true
+}
+
 func (d *DockerCheck) GetSender() (aggregator.Sender, error) {
 	sender, err := aggregator.GetSender(d.ID())
 	if err != nil {
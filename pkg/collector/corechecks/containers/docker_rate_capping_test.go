@@ -11,11 +11,13 @@ import (
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 
 	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
 	"github.com/DataDog/datadog-agent/pkg/util/cache"
+	"github.com/DataDog/datadog-agent/pkg/util/docker"
 )
 
 type dockerRateCappingSuite struct {
@@ -103,3 +105,30 @@ func (s *dockerRateCappingSuite) TestTagging() {
 func TestDockerRateCappingSuite(t *testing.T) {
 	suite.Run(t, &dockerRateCappingSuite{})
 }
+
+func TestEventCapping(t *testing.T) {
+	cache.Cache.Flush()
+	dockerCheck := &DockerCheck{
+		instance: &DockerConfig{
+			CappedEvents: map[string]int{"die": 2, "health_status": 1},
+		},
+	}
+	event := func(image, action string) *docker.ContainerEvent {
+		return &docker.ContainerEvent{ImageName: image, Action: action}
+	}
+
+	assert.True(t, dockerCheck.underEventCap(event("redis", "die")))
+	assert.True(t, dockerCheck.underEventCap(event("redis", "die")))
+	assert.False(t, dockerCheck.underEventCap(event("redis", "die")))
+	// The events are capped per image
+	assert.True(t, dockerCheck.underEventCap(event("nginx", "die")))
+
+	// The actions with details are capped by type
+	assert.True(t, dockerCheck.underEventCap(event("redis", "health_status: unhealthy")))
+	assert.False(t, dockerCheck.underEventCap(event("redis", "health_status: healthy")))
+
+	// Uncapped types
+	for i := 0; i < 5; i++ {
+		assert.True(t, dockerCheck.underEventCap(event("redis", "start")))
+	}
+}
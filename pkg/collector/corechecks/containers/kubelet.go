@@ -0,0 +1,358 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build kubelet
+
+package containers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	log "github.com/cihub/seelog"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagger"
+	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/kubelet"
+)
+
+const (
+	kubeletCheckName = "kubelet"
+	// KubeletHealth is the service check reporting the kubelet health
+	KubeletHealth = "kubernetes.kubelet.check"
+
+	kubeletHealthPath   = "/healthz"
+	kubeletCadvisorPath = "/metrics/cadvisor"
+)
+
+// resourceMetrics are the resources of the container specs reported as
+// kubernetes.<name>.requests and kubernetes.<name>.limits
+var resourceMetrics = map[string]string{
+	"cpu":               "cpu",
+	"memory":            "memory",
+	"ephemeral-storage": "ephemeral_storage",
+}
+
+// KubeletConfig is the config of the kubelet check
+type KubeletConfig struct {
+	CadvisorMetrics bool     `yaml:"cadvisor_metrics"`
+	Tags            []string `yaml:"tags"`
+}
+
+// Parse parses the check instance configuration
+func (c *KubeletConfig) Parse(data []byte) error {
+	// default values
+	c.CadvisorMetrics = true
+
+	return yaml.Unmarshal(data, c)
+}
+
+// KubeletCheck reports the resource usage of the pods and containers of the
+// node from the kubelet /stats/summary and /metrics/cadvisor endpoints
+type KubeletCheck struct {
+	core.CheckBase
+	instance *KubeletConfig
+	// tag is overridden in unit tests
+	tag func(entity string, cardinality collectors.TagCardinality) ([]string, error)
+}
+
+// Configure parses the check configuration and init the check
+func (k *KubeletCheck) Configure(config, initConfig check.ConfigData) error {
+	return k.instance.Parse(config)
+}
+
+// Run executes the check
+func (k *KubeletCheck) Run() error {
+	sender, err := aggregator.GetSender(k.ID())
+	if err != nil {
+		return err
+	}
+	defer sender.Commit()
+
+	ku, err := kubelet.GetKubeUtil()
+	if err != nil {
+		sender.ServiceCheck(KubeletHealth, metrics.ServiceCheckCritical, "", k.instance.Tags, err.Error())
+		return err
+	}
+	k.reportHealth(sender, ku)
+
+	pods, err := ku.GetLocalPodList()
+	if err != nil {
+		return err
+	}
+	index := newPodIndex(pods)
+	k.reportResources(sender, pods)
+
+	summary, err := ku.GetStatsSummary()
+	if err != nil {
+		k.Warnf("Could not get the stats summary from the kubelet: %s", err)
+	} else {
+		k.reportSummary(sender, summary, index)
+	}
+
+	if k.instance.CadvisorMetrics {
+		if err := k.collectCadvisor(sender, ku, index); err != nil {
+			k.Warnf("Could not get the cadvisor metrics from the kubelet: %s", err)
+		}
+	}
+	return nil
+}
+
+// reportHealth sends the kubelet health service check from /healthz
+func (k *KubeletCheck) reportHealth(sender aggregator.Sender, ku *kubelet.KubeUtil) {
+	data, code, err := ku.QueryKubelet(kubeletHealthPath)
+	switch {
+	case err != nil:
+		sender.ServiceCheck(KubeletHealth, metrics.ServiceCheckCritical, "", k.instance.Tags, err.Error())
+	case code != http.StatusOK || strings.TrimSpace(string(data)) != "ok":
+		message := fmt.Sprintf("unexpected response from %s, status code %d: %s", kubeletHealthPath, code, string(data))
+		sender.ServiceCheck(KubeletHealth, metrics.ServiceCheckCritical, "", k.instance.Tags, message)
+	default:
+		sender.ServiceCheck(KubeletHealth, metrics.ServiceCheckOK, "", k.instance.Tags, "")
+	}
+}
+
+// reportResources sends the requests and limits of the running containers
+// from the pod specs, in cores for CPU and bytes for memory and storage
+func (k *KubeletCheck) reportResources(sender aggregator.Sender, pods []*kubelet.Pod) {
+	for _, pod := range pods {
+		for _, spec := range pod.Spec.Containers {
+			containerID := ""
+			for _, status := range pod.Status.Containers {
+				if status.Name == spec.Name {
+					containerID = status.ID
+				}
+			}
+			if containerID == "" {
+				// not started yet, the tagger doesn't know it
+				continue
+			}
+			tags := k.entityTags(containerID)
+			k.reportQuantities(sender, "requests", spec.Resources.Requests, tags)
+			k.reportQuantities(sender, "limits", spec.Resources.Limits, tags)
+		}
+	}
+}
+
+func (k *KubeletCheck) reportQuantities(sender aggregator.Sender, kind string, quantities map[string]string, tags []string) {
+	for resource, quantity := range quantities {
+		name, found := resourceMetrics[resource]
+		if !found {
+			continue
+		}
+		value, err := kubelet.ParseQuantity(quantity)
+		if err != nil {
+			log.Debugf("Could not parse the %s %s: %s", resource, kind, err)
+			continue
+		}
+		sender.Gauge(fmt.Sprintf("kubernetes.%s.%s", name, kind), value, "", tags)
+	}
+}
+
+// reportSummary sends the container and pod usage from /stats/summary
+func (k *KubeletCheck) reportSummary(sender aggregator.Sender, summary *kubelet.Summary, index podIndex) {
+	for _, pod := range summary.Pods {
+		podTags := k.entityTags(kubelet.PodUIDToEntityName(pod.PodRef.UID))
+
+		for _, c := range pod.Containers {
+			containerID, found := index.containerID(pod.PodRef.Namespace, pod.PodRef.Name, c.Name)
+			if !found {
+				log.Debugf("Container %s of pod %s/%s not found in the pod list", c.Name, pod.PodRef.Namespace, pod.PodRef.Name)
+				continue
+			}
+			tags := k.entityTags(containerID)
+			if c.CPU != nil {
+				sendRate(sender, "kubernetes.cpu.usage.total", c.CPU.UsageCoreNanoSeconds, tags)
+			}
+			if c.Memory != nil {
+				sendGauge(sender, "kubernetes.memory.usage", c.Memory.UsageBytes, tags)
+				sendGauge(sender, "kubernetes.memory.working_set", c.Memory.WorkingSetBytes, tags)
+				sendGauge(sender, "kubernetes.memory.rss", c.Memory.RSSBytes, tags)
+			}
+			if c.Rootfs != nil {
+				sendGauge(sender, "kubernetes.filesystem.usage", c.Rootfs.UsedBytes, tags)
+				if c.Rootfs.UsedBytes != nil && c.Rootfs.CapacityBytes != nil && *c.Rootfs.CapacityBytes > 0 {
+					sender.Gauge("kubernetes.filesystem.usage_pct", float64(*c.Rootfs.UsedBytes)/float64(*c.Rootfs.CapacityBytes), "", tags)
+				}
+			}
+		}
+
+		if pod.Network != nil {
+			sendRate(sender, "kubernetes.network.rx_bytes", pod.Network.RxBytes, podTags)
+			sendRate(sender, "kubernetes.network.tx_bytes", pod.Network.TxBytes, podTags)
+			sendRate(sender, "kubernetes.network.rx_errors", pod.Network.RxErrors, podTags)
+			sendRate(sender, "kubernetes.network.tx_errors", pod.Network.TxErrors, podTags)
+		}
+		if pod.EphemeralStorage != nil {
+			sendGauge(sender, "kubernetes.ephemeral_storage.usage", pod.EphemeralStorage.UsedBytes, podTags)
+		}
+
+		for _, volume := range pod.Volumes {
+			volumeTags := append([]string{"volume:" + volume.Name}, podTags...)
+			if volume.PVCRef != nil {
+				volumeTags = append(volumeTags, "persistentvolumeclaim:"+volume.PVCRef.Name)
+			}
+			sendGauge(sender, "kubernetes.kubelet.volume.stats.used_bytes", volume.UsedBytes, volumeTags)
+			sendGauge(sender, "kubernetes.kubelet.volume.stats.capacity_bytes", volume.CapacityBytes, volumeTags)
+			sendGauge(sender, "kubernetes.kubelet.volume.stats.available_bytes", volume.AvailableBytes, volumeTags)
+			sendGauge(sender, "kubernetes.kubelet.volume.stats.inodes_used", volume.InodesUsed, volumeTags)
+			sendGauge(sender, "kubernetes.kubelet.volume.stats.inodes", volume.Inodes, volumeTags)
+		}
+	}
+}
+
+// collectCadvisor sends the container metrics from /metrics/cadvisor that
+// the stats summary doesn't report
+func (k *KubeletCheck) collectCadvisor(sender aggregator.Sender, ku *kubelet.KubeUtil, index podIndex) error {
+	data, code, err := ku.QueryKubelet(kubeletCadvisorPath)
+	if err != nil {
+		return err
+	}
+	if code != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d on %s", code, kubeletCadvisorPath)
+	}
+	samples, err := parsePrometheusText(data)
+	if err != nil {
+		return err
+	}
+	k.reportCadvisor(sender, samples, index)
+	return nil
+}
+
+// cadvisorMetric is a cadvisor metric reported by the check
+type cadvisorMetric struct {
+	name string
+	rate bool
+}
+
+// cadvisorMetrics maps the cadvisor metrics to the metrics of the check
+var cadvisorMetrics = map[string]cadvisorMetric{
+	"container_cpu_cfs_periods_total":           {"kubernetes.cpu.cfs.periods", true},
+	"container_cpu_cfs_throttled_periods_total": {"kubernetes.cpu.cfs.throttled.periods", true},
+	"container_cpu_cfs_throttled_seconds_total": {"kubernetes.cpu.cfs.throttled.seconds", true},
+	"container_memory_cache":                    {"kubernetes.memory.cache", false},
+	"container_memory_swap":                     {"kubernetes.memory.swap", false},
+	"container_fs_reads_bytes_total":            {"kubernetes.io.read_bytes", true},
+	"container_fs_writes_bytes_total":           {"kubernetes.io.write_bytes", true},
+}
+
+// reportCadvisor sends the cadvisor metrics of the containers, summed over
+// their devices
+func (k *KubeletCheck) reportCadvisor(sender aggregator.Sender, samples []promSample, index podIndex) {
+	type containerValue struct {
+		metric      cadvisorMetric
+		containerID string
+		value       float64
+	}
+	values := make(map[string]*containerValue)
+	var keys []string
+	for _, s := range samples {
+		metric, found := cadvisorMetrics[s.name]
+		if !found {
+			continue
+		}
+		// the labels were renamed in Kubernetes 1.16
+		containerName := firstLabel(s.labels, "container", "container_name")
+		if containerName == "" || containerName == "POD" {
+			// cgroups of the pods and of the pause containers
+			continue
+		}
+		containerID, found := index.containerID(s.labels["namespace"], firstLabel(s.labels, "pod", "pod_name"), containerName)
+		if !found {
+			continue
+		}
+		key := s.name + "|" + containerID
+		if v, found := values[key]; found {
+			v.value += s.value
+			continue
+		}
+		values[key] = &containerValue{metric: metric, containerID: containerID, value: s.value}
+		keys = append(keys, key)
+	}
+
+	for _, key := range keys {
+		v := values[key]
+		tags := k.entityTags(v.containerID)
+		if v.metric.rate {
+			sender.Rate(v.metric.name, v.value, "", tags)
+		} else {
+			sender.Gauge(v.metric.name, v.value, "", tags)
+		}
+	}
+}
+
+// entityTags returns the tags of a container or pod from the tagger, and
+// the tags of the instance
+func (k *KubeletCheck) entityTags(entity string) []string {
+	tags, err := k.tag(entity, tagger.ChecksCardinality)
+	if err != nil {
+		log.Debugf("Could not collect tags for %s: %s", entity, err)
+	}
+	return append(tags, k.instance.Tags...)
+}
+
+func sendGauge(sender aggregator.Sender, name string, value *uint64, tags []string) {
+	if value != nil {
+		sender.Gauge(name, float64(*value), "", tags)
+	}
+}
+
+func sendRate(sender aggregator.Sender, name string, value *uint64, tags []string) {
+	if value != nil {
+		sender.Rate(name, float64(*value), "", tags)
+	}
+}
+
+func firstLabel(labels map[string]string, names ...string) string {
+	for _, name := range names {
+		if value := labels[name]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// podIndex maps the containers of the pod list, by namespace, pod and
+// container name, to their ID
+type podIndex map[string]string
+
+func newPodIndex(pods []*kubelet.Pod) podIndex {
+	index := make(podIndex)
+	for _, pod := range pods {
+		for _, c := range pod.Status.Containers {
+			if c.ID != "" {
+				index[podIndexKey(pod.Metadata.Namespace, pod.Metadata.Name, c.Name)] = c.ID
+			}
+		}
+	}
+	return index
+}
+
+func (i podIndex) containerID(namespace, podName, containerName string) (string, bool) {
+	id, found := i[podIndexKey(namespace, podName, containerName)]
+	return id, found
+}
+
+func podIndexKey(namespace, podName, containerName string) string {
+	return namespace + "/" + podName + "/" + containerName
+}
+
+func kubeletFactory() check.Check {
+	return &KubeletCheck{
+		CheckBase: core.NewCheckBase(kubeletCheckName),
+		instance:  &KubeletConfig{},
+		tag:       tagger.Tag,
+	}
+}
+
+func init() {
+	core.RegisterCheck(kubeletCheckName, kubeletFactory)
+}
@@ -0,0 +1,181 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build kubelet
+
+package containers
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/kubelet"
+)
+
+// fakeKubelet serves the fixtures of testdata/kubelet, and a canned healthz
+type fakeKubelet struct {
+	t       *testing.T
+	healthz string
+}
+
+var kubeletFixtures = map[string]string{
+	"/pods":             "testdata/kubelet/pods.json",
+	"/stats/summary":    "testdata/kubelet/summary.json",
+	"/metrics/cadvisor": "testdata/kubelet/cadvisor.txt",
+}
+
+func (f *fakeKubelet) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/healthz" {
+		w.Write([]byte(f.healthz))
+		return
+	}
+	file, found := kubeletFixtures[r.URL.Path]
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	data, err := ioutil.ReadFile(file)
+	require.NoError(f.t, err)
+	w.Write(data)
+}
+
+// setupKubeUtil points the KubeUtil to the test kubelet
+func setupKubeUtil(t *testing.T, ts *httptest.Server) {
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+	config.Datadog.Set("kubernetes_kubelet_host", u.Hostname())
+	config.Datadog.Set("kubernetes_https_kubelet_port", u.Port())
+	config.Datadog.Set("kubelet_tls_verify", false)
+	kubelet.ResetGlobalKubeUtil()
+	kubelet.ResetCache()
+}
+
+// fakeTags returns a tag identifying the entity, as the tagger would
+func fakeTags(entity string, cardinality collectors.TagCardinality) ([]string, error) {
+	tags := map[string][]string{
+		"docker://3e5a7f2b9c4d":                                 {"kube_deployment:web", "kube_container_name:nginx"},
+		"docker://9b8c7d6e5f4a":                                 {"kube_stateful_set:redis", "kube_container_name:redis"},
+		"kubernetes_pod://4a5b1c64-6d6b-11e8-9c7a-42010a840130": {"kube_deployment:web", "pod_name:web-5d6b8-x2x9s"},
+		"kubernetes_pod://8e1f0c2a-6d6b-11e8-9c7a-42010a840130": {"kube_stateful_set:redis", "pod_name:redis-0"},
+	}
+	return append([]string{}, tags[entity]...), nil
+}
+
+func TestKubeletCheck(t *testing.T) {
+	ts := httptest.NewTLSServer(&fakeKubelet{t: t, healthz: "ok"})
+	defer ts.Close()
+	setupKubeUtil(t, ts)
+
+	kubeletCheck := &KubeletCheck{
+		CheckBase: core.NewCheckBase(kubeletCheckName),
+		instance:  &KubeletConfig{},
+		tag:       fakeTags,
+	}
+	require.NoError(t, kubeletCheck.Configure([]byte("tags: [\"test\"]"), nil))
+	mocked := mocksender.NewMockSender(kubeletCheck.ID())
+	mocked.SetupAcceptAll()
+	require.NoError(t, kubeletCheck.Run())
+
+	mocked.AssertServiceCheck(t, KubeletHealth, metrics.ServiceCheckOK, "", []string{"test"}, "")
+
+	nginxTags := []string{"kube_deployment:web", "kube_container_name:nginx", "test"}
+	redisTags := []string{"kube_stateful_set:redis", "kube_container_name:redis", "test"}
+
+	// requests and limits, from the pod spec
+	mocked.AssertMetric(t, "Gauge", "kubernetes.cpu.requests", 0.25, "", nginxTags)
+	mocked.AssertMetric(t, "Gauge", "kubernetes.cpu.limits", 0.5, "", nginxTags)
+	mocked.AssertMetric(t, "Gauge", "kubernetes.memory.requests", 64*1024*1024, "", nginxTags)
+	mocked.AssertMetric(t, "Gauge", "kubernetes.memory.limits", 128*1024*1024, "", nginxTags)
+	mocked.AssertMetric(t, "Gauge", "kubernetes.cpu.requests", 1, "", redisTags)
+	mocked.AssertMetric(t, "Gauge", "kubernetes.ephemeral_storage.requests", 1024*1024*1024, "", redisTags)
+	// the pending pod has no container to tag
+	mocked.AssertNotCalled(t, "Gauge", "kubernetes.cpu.requests", 0.1, "", mock.Anything)
+
+	// container usage, from the stats summary
+	mocked.AssertMetric(t, "Rate", "kubernetes.cpu.usage.total", 5400000000, "", nginxTags)
+	mocked.AssertMetric(t, "Gauge", "kubernetes.memory.usage", 5242880, "", nginxTags)
+	mocked.AssertMetric(t, "Gauge", "kubernetes.memory.working_set", 4194304, "", nginxTags)
+	mocked.AssertMetric(t, "Gauge", "kubernetes.memory.rss", 2097152, "", nginxTags)
+	mocked.AssertMetric(t, "Gauge", "kubernetes.filesystem.usage", 25000000000, "", nginxTags)
+	mocked.AssertMetric(t, "Gauge", "kubernetes.filesystem.usage_pct", 0.25, "", nginxTags)
+	mocked.AssertMetric(t, "Gauge", "kubernetes.memory.usage", 10485760, "", redisTags)
+	mocked.AssertMetric(t, "Gauge", "kubernetes.filesystem.usage", 20480, "", redisTags)
+
+	// pod network, ephemeral storage and volumes, tagged with the pod tags
+	webPodTags := []string{"kube_deployment:web", "pod_name:web-5d6b8-x2x9s", "test"}
+	mocked.AssertMetric(t, "Rate", "kubernetes.network.rx_bytes", 104857600, "", webPodTags)
+	mocked.AssertMetric(t, "Rate", "kubernetes.network.tx_errors", 2, "", webPodTags)
+	mocked.AssertMetric(t, "Gauge", "kubernetes.ephemeral_storage.usage", 40960, "", webPodTags)
+	mocked.AssertMetric(t, "Gauge", "kubernetes.kubelet.volume.stats.used_bytes", 10000, "", append(webPodTags, "volume:default-token-x2x9s"))
+	redisVolumeTags := []string{"kube_stateful_set:redis", "pod_name:redis-0", "test", "volume:data", "persistentvolumeclaim:data-redis-0"}
+	mocked.AssertMetric(t, "Gauge", "kubernetes.kubelet.volume.stats.used_bytes", 1000000000, "", redisVolumeTags)
+	mocked.AssertMetric(t, "Gauge", "kubernetes.kubelet.volume.stats.capacity_bytes", 10000000000, "", redisVolumeTags)
+	mocked.AssertMetric(t, "Gauge", "kubernetes.kubelet.volume.stats.inodes_used", 5360, "", redisVolumeTags)
+
+	// cadvisor metrics, summed over the devices, with the pre and post 1.16 labels
+	mocked.AssertMetric(t, "Rate", "kubernetes.cpu.cfs.throttled.periods", 56, "", nginxTags)
+	mocked.AssertMetric(t, "Rate", "kubernetes.cpu.cfs.throttled.seconds", 3.2, "", nginxTags)
+	mocked.AssertMetric(t, "Rate", "kubernetes.io.read_bytes", 5120, "", nginxTags)
+	mocked.AssertMetric(t, "Gauge", "kubernetes.memory.cache", 2621440, "", redisTags)
+	// the pod and pause container cgroups, and the unknown containers are skipped
+	mocked.AssertNumberOfCalls(t, "Rate", 10)
+}
+
+func TestKubeletCheckUnhealthy(t *testing.T) {
+	ts := httptest.NewTLSServer(&fakeKubelet{t: t, healthz: "[-]etcd failed"})
+	defer ts.Close()
+	setupKubeUtil(t, ts)
+
+	kubeletCheck := &KubeletCheck{
+		CheckBase: core.NewCheckBase(kubeletCheckName),
+		instance:  &KubeletConfig{},
+		tag:       fakeTags,
+	}
+	require.NoError(t, kubeletCheck.Configure([]byte("cadvisor_metrics: false"), nil))
+	mocked := mocksender.NewMockSender(kubeletCheck.ID())
+	mocked.SetupAcceptAll()
+	require.NoError(t, kubeletCheck.Run())
+
+	mocked.AssertServiceCheck(t, KubeletHealth, metrics.ServiceCheckCritical, "", []string{}, "unexpected response from /healthz, status code 200: [-]etcd failed")
+	mocked.AssertMetric(t, "Gauge", "kubernetes.memory.usage", 5242880, "", []string{"kube_container_name:nginx"})
+	mocked.AssertNotCalled(t, "Rate", "kubernetes.io.read_bytes", mock.Anything, "", mock.Anything)
+}
+
+func TestParsePrometheusText(t *testing.T) {
+	samples, err := parsePrometheusText([]byte(`# HELP up Whether the target is up
+# TYPE up gauge
+up 1
+http_requests_total{method="post",code="200"} 1027 1395066363000
+escaped{path="C:\\dir\\",msg="say \"hi\"\n", empty=""} -1.5e3
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []promSample{
+		{name: "up", labels: map[string]string{}, value: 1},
+		{name: "http_requests_total", labels: map[string]string{"method": "post", "code": "200"}, value: 1027},
+		{name: "escaped", labels: map[string]string{"path": `C:\dir\`, "msg": "say \"hi\"\n", "empty": ""}, value: -1500},
+	}, samples)
+
+	for _, invalid := range []string{
+		"no_value",
+		"bad_value{a=\"b\"} abc",
+		"unterminated{a=\"b\" 1",
+		"unquoted{a=b} 1",
+	} {
+		_, err := parsePrometheusText([]byte(invalid))
+		assert.Error(t, err, invalid)
+	}
+}
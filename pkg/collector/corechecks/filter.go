@@ -0,0 +1,57 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package corechecks
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// RegexFilter holds the compiled include and exclude regexes of a check
+// option. A value is kept if it matches an include regex, or if there is
+// none, and doesn't match any exclude regex.
+type RegexFilter struct {
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+}
+
+// NewRegexFilter compiles the include and exclude patterns
+func NewRegexFilter(include, exclude []string) (*RegexFilter, error) {
+	f := &RegexFilter{}
+	for _, pattern := range include {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include pattern %q: %s", pattern, err)
+		}
+		f.include = append(f.include, re)
+	}
+	for _, pattern := range exclude {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern %q: %s", pattern, err)
+		}
+		f.exclude = append(f.exclude, re)
+	}
+	return f, nil
+}
+
+// Keep returns whether the value passes the filter
+func (f *RegexFilter) Keep(value string) bool {
+	for _, re := range f.exclude {
+		if re.MatchString(value) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, re := range f.include {
+		if re.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package corechecks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegexFilter(t *testing.T) {
+	f, err := NewRegexFilter(nil, nil)
+	require.NoError(t, err)
+	assert.True(t, f.Keep("eth0"))
+
+	f, err = NewRegexFilter([]string{"^eth", "^docker"}, []string{"^docker"})
+	require.NoError(t, err)
+	assert.True(t, f.Keep("eth0"))
+	assert.False(t, f.Keep("docker0"))
+	assert.False(t, f.Keep("lo"))
+
+	f, err = NewRegexFilter(nil, []string{"^lo$"})
+	require.NoError(t, err)
+	assert.True(t, f.Keep("eth0"))
+	assert.False(t, f.Keep("lo"))
+
+	_, err = NewRegexFilter([]string{"("}, nil)
+	assert.Error(t, err)
+	_, err = NewRegexFilter(nil, []string{"("})
+	assert.Error(t, err)
+}
@@ -0,0 +1,394 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build linux
+
+package network
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	log "github.com/cihub/seelog"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/tagger"
+	"github.com/DataDog/datadog-agent/pkg/util/container"
+	"github.com/DataDog/datadog-agent/pkg/util/docker"
+	"github.com/DataDog/datadog-agent/pkg/util/netns"
+)
+
+const networkCheckName = "network"
+
+// Metric name suffixes of the protocol counters, by /proc/net/snmp and /proc/net/netstat section and field
+var protocolCounters = map[string]map[string]string{
+	"Tcp": {
+		"RetransSegs": "tcp.retrans_segs",
+		"InSegs":      "tcp.in_segs",
+		"OutSegs":     "tcp.out_segs",
+	},
+	"Udp": {
+		"InDatagrams":  "udp.in_datagrams",
+		"NoPorts":      "udp.no_ports",
+		"InErrors":     "udp.in_errors",
+		"OutDatagrams": "udp.out_datagrams",
+		"RcvbufErrors": "udp.rcv_buf_errors",
+		"SndbufErrors": "udp.snd_buf_errors",
+		"InCsumErrors": "udp.in_csum_errors",
+	},
+	"TcpExt": {
+		"ListenOverflows": "tcp.listen_overflows",
+		"ListenDrops":     "tcp.listen_drops",
+		"TCPBacklogDrop":  "tcp.backlog_drops",
+		"TCPRetransFail":  "tcp.failed_retransmits",
+	},
+}
+
+// tcpStates maps the hexadecimal states of /proc/net/tcp to the reported states
+var tcpStates = map[string]string{
+	"01": "established",
+	"02": "opening",   // SYN_SENT
+	"03": "opening",   // SYN_RECV
+	"04": "closing",   // FIN_WAIT1
+	"05": "closing",   // FIN_WAIT2
+	"06": "time_wait", // TIME_WAIT
+	"07": "closing",   // CLOSE
+	"08": "closing",   // CLOSE_WAIT
+	"09": "closing",   // LAST_ACK
+	"0A": "listening", // LISTEN
+	"0B": "closing",   // CLOSING
+}
+
+type networkConfig struct {
+	CollectConnectionState *bool    `yaml:"collect_connection_state"`
+	CollectNamespaces      bool     `yaml:"collect_namespaces"`
+	NetnsPath              string   `yaml:"netns_path"`
+	InterfaceInclude       []string `yaml:"interface_include"`
+	InterfaceExclude       []string `yaml:"interface_exclude"`
+	Tags                   []string `yaml:"tags"`
+}
+
+// containerTags is overridden in unit tests
+var containerTags = defaultContainerTags
+
+// defaultContainerTags returns the tags of the container a process runs in,
+// nil if the tagger doesn't know it
+func defaultContainerTags(runtimes []container.Runtime, pid int) []string {
+	cid, err := docker.ContainerIDForPID(pid)
+	if err != nil || cid == "" {
+		return nil
+	}
+	for _, entity := range containerEntities(runtimes, cid) {
+		tags, err := tagger.Tag(entity, tagger.ChecksCardinality)
+		if err != nil {
+			log.Debugf("system.NetworkCheck: cannot get the tags of %s: %s", entity, err)
+			continue
+		}
+		if len(tags) > 0 {
+			return tags
+		}
+	}
+	return nil
+}
+
+// containerEntities returns the tagger entities a container ID can have,
+// eg. docker://<id> or containerd://<id>, one for each available runtime.
+// The cgroup of a process doesn't tell which runtime started it.
+func containerEntities(runtimes []container.Runtime, cid string) []string {
+	if len(runtimes) == 0 {
+		return []string{docker.ContainerIDToEntityName(cid)}
+	}
+	entities := make([]string, 0, len(runtimes))
+	for _, rt := range runtimes {
+		entities = append(entities, rt.ContainerIDToEntityName(cid))
+	}
+	return entities
+}
+
+// NetworkCheck reports the interface and protocol counters and the
+// TCP connection states of the host
+type NetworkCheck struct {
+	core.CheckBase
+	procRoot               string
+	netnsPath              string
+	collectConnectionState bool
+	collectNamespaces      bool
+	// runtimes are the container runtimes available on the host, used to
+	// resolve the containers owning the namespaces
+	runtimes   []container.Runtime
+	interfaces *core.RegexFilter
+	tags       []string
+}
+
+// Run executes the check
+func (c *NetworkCheck) Run() error {
+	sender, err := aggregator.GetSender(c.ID())
+	if err != nil {
+		return err
+	}
+
+	if err := c.reportInterfaces(sender, c.procRoot, "system.net", c.tags); err != nil {
+		log.Errorf("system.NetworkCheck: could not read the interface counters: %s", err)
+		return err
+	}
+	reportProtocolCounters(sender, c.procRoot, "system.net", c.tags)
+
+	if c.collectConnectionState {
+		for _, proto := range []string{"tcp4", "tcp6"} {
+			file := strings.TrimSuffix(proto, "4")
+			counts, err := countTCPStates(filepath.Join(c.procRoot, "net", file))
+			if err != nil {
+				log.Debugf("system.NetworkCheck: could not read %s: %s", file, err)
+				continue
+			}
+			for _, state := range []string{"established", "opening", "closing", "listening", "time_wait"} {
+				sender.Gauge(fmt.Sprintf("system.net.%s.%s", proto, state), float64(counts[state]), "", c.tags)
+			}
+		}
+	}
+
+	if c.collectNamespaces {
+		c.reportNamespaces(sender)
+	}
+
+	sender.Commit()
+	return nil
+}
+
+// reportNamespaces reports the interface and protocol counters of the
+// network namespaces other than the host one, read through one of their
+// processes
+func (c *NetworkCheck) reportNamespaces(sender aggregator.Sender) {
+	namespaces, err := netns.GetNamespaces(c.procRoot, c.netnsPath)
+	if err != nil {
+		log.Warnf("system.NetworkCheck: could not list the network namespaces: %s", err)
+		return
+	}
+	// without the host namespace, its interfaces would be reported twice
+	hostInode, err := netns.GetInode(c.procRoot, 1)
+	if err != nil {
+		log.Warnf("system.NetworkCheck: could not get the host network namespace: %s", err)
+		return
+	}
+	if len(c.runtimes) == 0 {
+		c.runtimes = container.GetRuntimes()
+	}
+
+	for _, ns := range namespaces {
+		if ns.Inode == hostInode {
+			continue
+		}
+		tags := append(c.namespaceTags(ns), c.tags...)
+		procRoot := filepath.Join(c.procRoot, strconv.Itoa(ns.PIDs[0]))
+		if err := c.reportInterfaces(sender, procRoot, "system.net.ns", tags); err != nil {
+			// the process probably exited
+			log.Debugf("system.NetworkCheck: could not read the interface counters of namespace %d: %s", ns.Inode, err)
+			continue
+		}
+		reportProtocolCounters(sender, procRoot, "system.net.ns", tags)
+	}
+}
+
+// namespaceTags returns the tags of the container owning a namespace, or
+// else its name or inode
+func (c *NetworkCheck) namespaceTags(ns *netns.Namespace) []string {
+	for _, pid := range ns.PIDs {
+		if tags := containerTags(c.runtimes, pid); len(tags) > 0 {
+			return tags
+		}
+	}
+	if ns.Name != "" {
+		return []string{"netns:" + ns.Name}
+	}
+	return []string{fmt.Sprintf("netns_inode:%d", ns.Inode)}
+}
+
+// reportInterfaces parses /proc/net/dev. Format:
+//
+// Inter-|   Receive                                                |  Transmit
+//  face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+//   eth0: 1296830   1523    0    0    0     0          0         0   221472    1410    0    0    0     0       0          0
+//
+func (c *NetworkCheck) reportInterfaces(sender aggregator.Sender, procRoot, prefix string, tags []string) error {
+	path := filepath.Join(procRoot, "net", "dev")
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) != 2 {
+			// header lines
+			continue
+		}
+		iface := strings.TrimSpace(parts[0])
+		if !c.interfaces.Keep(iface) {
+			continue
+		}
+		fields := strings.Fields(parts[1])
+		if len(fields) < 12 {
+			log.Debugf("system.NetworkCheck: unexpected format for interface %s in %s", iface, path)
+			continue
+		}
+		values := make([]float64, 12)
+		for i := range values {
+			v, err := strconv.ParseUint(fields[i], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid counter for interface %s in %s: %s", iface, path, err)
+			}
+			values[i] = float64(v)
+		}
+
+		ifaceTags := append([]string{"device:" + iface}, tags...)
+		sender.Rate(prefix+".bytes_rcvd", values[0], "", ifaceTags)
+		sender.Rate(prefix+".packets_in.count", values[1], "", ifaceTags)
+		sender.Rate(prefix+".packets_in.error", values[2], "", ifaceTags)
+		sender.Rate(prefix+".packets_in.drop", values[3], "", ifaceTags)
+		sender.Rate(prefix+".bytes_sent", values[8], "", ifaceTags)
+		sender.Rate(prefix+".packets_out.count", values[9], "", ifaceTags)
+		sender.Rate(prefix+".packets_out.error", values[10], "", ifaceTags)
+		sender.Rate(prefix+".packets_out.drop", values[11], "", ifaceTags)
+	}
+	return scanner.Err()
+}
+
+// reportProtocolCounters reports the TCP and UDP counters of /proc/net/snmp
+// and /proc/net/netstat
+func reportProtocolCounters(sender aggregator.Sender, procRoot, prefix string, tags []string) {
+	for _, file := range []string{"snmp", "netstat"} {
+		counters, err := readProtocolCounters(filepath.Join(procRoot, "net", file))
+		if err != nil {
+			log.Debugf("system.NetworkCheck: could not read %s: %s", file, err)
+			continue
+		}
+		for section, fields := range protocolCounters {
+			for field, name := range fields {
+				if value, found := counters[section][field]; found {
+					sender.Rate(prefix+"."+name, value, "", tags)
+				}
+			}
+		}
+	}
+}
+
+// readProtocolCounters parses /proc/net/snmp or /proc/net/netstat, made of
+// pairs of header and value lines for each section. Format:
+//
+// Tcp: RtoAlgorithm RtoMin RtoMax MaxConn ActiveOpens PassiveOpens ...
+// Tcp: 1 200 120000 -1 451 60 ...
+//
+func readProtocolCounters(path string) (map[string]map[string]float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	counters := make(map[string]map[string]float64)
+	var header []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		if header == nil || header[0] != fields[0] {
+			header = fields
+			continue
+		}
+		if len(fields) != len(header) {
+			return nil, fmt.Errorf("mismatching header and values for %s in %s", fields[0], path)
+		}
+		section := strings.TrimSuffix(fields[0], ":")
+		counters[section] = make(map[string]float64, len(fields)-1)
+		for i := 1; i < len(fields); i++ {
+			// MaxConn is signed
+			v, err := strconv.ParseInt(fields[i], 10, 64)
+			if err != nil {
+				continue
+			}
+			counters[section][header[i]] = float64(v)
+		}
+		header = nil
+	}
+	return counters, scanner.Err()
+}
+
+// countTCPStates counts the sockets of /proc/net/tcp or /proc/net/tcp6 by state. Format:
+//
+//   sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+//    0: 00000000:0016 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 17294 1 ...
+//
+func countTCPStates(path string) (map[string]int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	counts := make(map[string]int)
+	scanner := bufio.NewScanner(f)
+	// skip the header
+	scanner.Scan()
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		if state, found := tcpStates[strings.ToUpper(fields[3])]; found {
+			counts[state]++
+		}
+	}
+	return counts, scanner.Err()
+}
+
+// Configure parses the check configuration and compiles the interface filters
+func (c *NetworkCheck) Configure(data check.ConfigData, initConfig check.ConfigData) error {
+	cfg := &networkConfig{}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return err
+	}
+
+	var err error
+	if c.interfaces, err = core.NewRegexFilter(cfg.InterfaceInclude, cfg.InterfaceExclude); err != nil {
+		return fmt.Errorf("invalid interface filter: %s", err)
+	}
+	c.collectConnectionState = cfg.CollectConnectionState == nil || *cfg.CollectConnectionState
+	c.collectNamespaces = cfg.CollectNamespaces
+	c.netnsPath = cfg.NetnsPath
+	if c.netnsPath == "" {
+		c.netnsPath = "/var/run/netns"
+	}
+	c.tags = cfg.Tags
+
+	c.procRoot = config.Datadog.GetString("procfs_path")
+	if c.procRoot == "" {
+		c.procRoot = "/proc"
+	}
+
+	c.BuildID(data, initConfig)
+	return nil
+}
+
+func networkFactory() check.Check {
+	return &NetworkCheck{
+		CheckBase: core.NewCheckBase(networkCheckName),
+	}
+}
+
+func init() {
+	core.RegisterCheck(networkCheckName, networkFactory)
+}
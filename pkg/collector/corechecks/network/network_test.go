@@ -0,0 +1,201 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build linux
+
+package network
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/util/container"
+)
+
+func TestNetworkCheck(t *testing.T) {
+	networkCheck := new(NetworkCheck)
+	require.NoError(t, networkCheck.Configure([]byte(`tags: ["env:test"]`), nil))
+	networkCheck.procRoot = "testdata/proc"
+
+	mockSender := mocksender.NewMockSender(networkCheck.ID())
+	mockSender.SetupAcceptAll()
+	require.NoError(t, networkCheck.Run())
+
+	eth0Tags := []string{"device:eth0", "env:test"}
+	mockSender.AssertMetric(t, "Rate", "system.net.bytes_rcvd", 1296830, "", eth0Tags)
+	mockSender.AssertMetric(t, "Rate", "system.net.packets_in.count", 1523, "", eth0Tags)
+	mockSender.AssertMetric(t, "Rate", "system.net.packets_in.error", 2, "", eth0Tags)
+	mockSender.AssertMetric(t, "Rate", "system.net.packets_in.drop", 1, "", eth0Tags)
+	mockSender.AssertMetric(t, "Rate", "system.net.bytes_sent", 221472, "", eth0Tags)
+	mockSender.AssertMetric(t, "Rate", "system.net.packets_out.count", 1410, "", eth0Tags)
+	mockSender.AssertMetric(t, "Rate", "system.net.packets_out.error", 3, "", eth0Tags)
+	mockSender.AssertMetric(t, "Rate", "system.net.packets_out.drop", 4, "", eth0Tags)
+	mockSender.AssertMetric(t, "Rate", "system.net.bytes_rcvd", 8400, "", []string{"device:docker0", "env:test"})
+	mockSender.AssertMetric(t, "Rate", "system.net.bytes_rcvd", 45890, "", []string{"device:lo", "env:test"})
+
+	tags := []string{"env:test"}
+	mockSender.AssertMetric(t, "Rate", "system.net.tcp.retrans_segs", 3, "", tags)
+	mockSender.AssertMetric(t, "Rate", "system.net.tcp.in_segs", 17264, "", tags)
+	mockSender.AssertMetric(t, "Rate", "system.net.tcp.out_segs", 19323, "", tags)
+	mockSender.AssertMetric(t, "Rate", "system.net.udp.no_ports", 5, "", tags)
+	mockSender.AssertMetric(t, "Rate", "system.net.udp.in_errors", 1, "", tags)
+	mockSender.AssertMetric(t, "Rate", "system.net.tcp.listen_overflows", 12, "", tags)
+	mockSender.AssertMetric(t, "Rate", "system.net.tcp.listen_drops", 14, "", tags)
+	mockSender.AssertMetric(t, "Rate", "system.net.tcp.backlog_drops", 1, "", tags)
+	mockSender.AssertMetric(t, "Rate", "system.net.tcp.failed_retransmits", 0, "", tags)
+
+	mockSender.AssertMetric(t, "Gauge", "system.net.tcp4.listening", 2, "", tags)
+	mockSender.AssertMetric(t, "Gauge", "system.net.tcp4.established", 1, "", tags)
+	mockSender.AssertMetric(t, "Gauge", "system.net.tcp4.time_wait", 1, "", tags)
+	mockSender.AssertMetric(t, "Gauge", "system.net.tcp4.opening", 1, "", tags)
+	mockSender.AssertMetric(t, "Gauge", "system.net.tcp4.closing", 1, "", tags)
+	mockSender.AssertMetric(t, "Gauge", "system.net.tcp6.listening", 1, "", tags)
+	mockSender.AssertMetric(t, "Gauge", "system.net.tcp6.established", 0, "", tags)
+
+	// 3 interfaces, 3 tcp, 7 udp and 4 tcp extended counters
+	mockSender.AssertNumberOfCalls(t, "Rate", 3*8+3+7+4)
+	mockSender.AssertNumberOfCalls(t, "Gauge", 2*5)
+	mockSender.AssertNumberOfCalls(t, "Commit", 1)
+}
+
+func TestNetworkCheckFilters(t *testing.T) {
+	networkCheck := new(NetworkCheck)
+	require.NoError(t, networkCheck.Configure([]byte(`
+collect_connection_state: false
+interface_include: ["^eth", "^docker"]
+interface_exclude: ["^docker"]
+`), nil))
+	networkCheck.procRoot = "testdata/proc"
+
+	mockSender := mocksender.NewMockSender(networkCheck.ID())
+	mockSender.SetupAcceptAll()
+	require.NoError(t, networkCheck.Run())
+
+	mockSender.AssertMetric(t, "Rate", "system.net.bytes_rcvd", 1296830, "", []string{"device:eth0"})
+	mockSender.AssertNotCalled(t, "Rate", "system.net.bytes_rcvd", mock.Anything, "", []string{"device:lo"})
+	mockSender.AssertNotCalled(t, "Rate", "system.net.bytes_rcvd", mock.Anything, "", []string{"device:docker0"})
+	mockSender.AssertNumberOfCalls(t, "Gauge", 0)
+
+	networkCheck = new(NetworkCheck)
+	assert.Error(t, networkCheck.Configure([]byte(`interface_exclude: ["("]`), nil))
+}
+
+func TestNetworkCheckMissingProc(t *testing.T) {
+	networkCheck := new(NetworkCheck)
+	require.NoError(t, networkCheck.Configure(nil, nil))
+	networkCheck.procRoot = "testdata/nonexistent"
+
+	mockSender := mocksender.NewMockSender(networkCheck.ID())
+	mockSender.SetupAcceptAll()
+	assert.Error(t, networkCheck.Run())
+	mockSender.AssertNumberOfCalls(t, "Commit", 0)
+}
+
+func copyFile(t *testing.T, src, dst string) {
+	data, err := ioutil.ReadFile(src)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Dir(dst), 0755))
+	require.NoError(t, ioutil.WriteFile(dst, data, 0644))
+}
+
+func TestNetworkCheckNamespaces(t *testing.T) {
+	dir, err := ioutil.TempDir("", "network")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// regular files stand for the namespace bind mounts
+	netnsPath := filepath.Join(dir, "netns")
+	copyFile(t, "testdata/proc/net/dev", filepath.Join(netnsPath, "blue"))
+	var stat syscall.Stat_t
+	require.NoError(t, syscall.Stat(filepath.Join(netnsPath, "blue"), &stat))
+
+	// pid 1 is in the host namespace, 500 and 501 in a container and 600 in
+	// the blue namespace
+	procRoot := filepath.Join(dir, "proc")
+	for pid, target := range map[string]string{
+		"1":   "net:[1000]",
+		"500": "net:[2000]",
+		"501": "net:[2000]",
+		"600": fmt.Sprintf("net:[%d]", stat.Ino),
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Join(procRoot, pid, "ns"), 0755))
+		require.NoError(t, os.Symlink(target, filepath.Join(procRoot, pid, "ns", "net")))
+	}
+	for _, file := range []string{"dev", "snmp", "netstat"} {
+		copyFile(t, "testdata/proc/net/"+file, filepath.Join(procRoot, "net", file))
+		copyFile(t, "testdata/proc/net/"+file, filepath.Join(procRoot, "500", "net", file))
+	}
+	copyFile(t, "testdata/proc/net/dev", filepath.Join(procRoot, "600", "net", "dev"))
+
+	containerTags = func(runtimes []container.Runtime, pid int) []string {
+		if pid == 501 {
+			return []string{"container_name:web"}
+		}
+		return nil
+	}
+	defer func() { containerTags = defaultContainerTags }()
+
+	networkCheck := new(NetworkCheck)
+	require.NoError(t, networkCheck.Configure([]byte(fmt.Sprintf(`
+collect_connection_state: false
+collect_namespaces: true
+netns_path: %s
+interface_exclude: ["^lo$"]
+tags: ["env:test"]
+`, netnsPath)), nil))
+	networkCheck.procRoot = procRoot
+
+	mockSender := mocksender.NewMockSender(networkCheck.ID())
+	mockSender.SetupAcceptAll()
+	require.NoError(t, networkCheck.Run())
+
+	mockSender.AssertMetric(t, "Rate", "system.net.ns.bytes_rcvd", 1296830, "", []string{"device:eth0", "container_name:web", "env:test"})
+	mockSender.AssertMetric(t, "Rate", "system.net.ns.tcp.retrans_segs", 3, "", []string{"container_name:web", "env:test"})
+	mockSender.AssertMetric(t, "Rate", "system.net.ns.bytes_rcvd", 1296830, "", []string{"device:eth0", "netns:blue", "env:test"})
+	mockSender.AssertNotCalled(t, "Rate", "system.net.ns.tcp.retrans_segs", mock.Anything, "", []string{"netns:blue", "env:test"})
+	mockSender.AssertMetric(t, "Rate", "system.net.bytes_rcvd", 1296830, "", []string{"device:eth0", "env:test"})
+
+	// host, container and blue: 2 interfaces and 14 counters each, without
+	// protocol counters for blue
+	mockSender.AssertNumberOfCalls(t, "Rate", 3*2*8+2*14)
+
+	// the namespaces are skipped if the host one is unknown, instead of
+	// reporting the host interfaces twice
+	require.NoError(t, os.Remove(filepath.Join(procRoot, "1", "ns", "net")))
+	mockSender = mocksender.NewMockSender(networkCheck.ID())
+	mockSender.SetupAcceptAll()
+	require.NoError(t, networkCheck.Run())
+	mockSender.AssertNumberOfCalls(t, "Rate", 2*8+14)
+}
+
+// fakeRuntime only implements the entity naming of container.Runtime
+type fakeRuntime struct {
+	container.Runtime
+	prefix string
+}
+
+func (r *fakeRuntime) ContainerIDToEntityName(id string) string {
+	return r.prefix + id
+}
+
+func TestContainerEntities(t *testing.T) {
+	// docker is assumed when no runtime is available
+	assert.Equal(t, []string{"docker://abc"}, containerEntities(nil, "abc"))
+
+	runtimes := []container.Runtime{
+		&fakeRuntime{prefix: "containerd://"},
+		&fakeRuntime{prefix: "docker://"},
+	}
+	assert.Equal(t, []string{"containerd://abc", "docker://abc"}, containerEntities(runtimes, "abc"))
+}
@@ -0,0 +1,303 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build linux
+
+package system
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	log "github.com/cihub/seelog"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+const (
+	diskCheckName = "disk"
+	// DiskReadWrite is the service check reporting read-only filesystems
+	DiskReadWrite = "disk.read_write"
+)
+
+// For testing purpose
+var (
+	readMounts = readMountsFile
+	statfs     = syscall.Statfs
+	labelsDir  = "/dev/disk/by-label"
+)
+
+// mount is an entry of the mount table
+type mount struct {
+	device     string
+	mountPoint string
+	fsType     string
+	options    []string
+}
+
+func (m *mount) readOnly() bool {
+	for _, opt := range m.options {
+		if opt == "ro" {
+			return true
+		}
+	}
+	return false
+}
+
+type diskConfig struct {
+	// AllPartitions includes the pseudo filesystems (tmpfs, overlay...)
+	AllPartitions     bool     `yaml:"all_partitions"`
+	ServiceCheckRW    *bool    `yaml:"service_check_rw"`
+	DeviceInclude     []string `yaml:"device_include"`
+	DeviceExclude     []string `yaml:"device_exclude"`
+	MountPointInclude []string `yaml:"mount_point_include"`
+	MountPointExclude []string `yaml:"mount_point_exclude"`
+	FileSystemInclude []string `yaml:"file_system_include"`
+	FileSystemExclude []string `yaml:"file_system_exclude"`
+	Tags              []string `yaml:"tags"`
+}
+
+// DiskCheck reports the space and inode usage of the mounted filesystems
+type DiskCheck struct {
+	core.CheckBase
+	cfg              *diskConfig
+	serviceCheckRW   bool
+	deviceFilter     *core.RegexFilter
+	mountPointFilter *core.RegexFilter
+	fileSystemFilter *core.RegexFilter
+}
+
+// Run executes the check
+func (c *DiskCheck) Run() error {
+	sender, err := aggregator.GetSender(c.ID())
+	if err != nil {
+		return err
+	}
+
+	mounts, err := readMounts()
+	if err != nil {
+		log.Errorf("system.DiskCheck: could not read the mount table: %s", err)
+		return err
+	}
+	labels := deviceLabels()
+
+	// mounts are deduplicated on device and mount point: a device mounted
+	// twice on the same mount point is reported once, but its bind mounts
+	// on other mount points are reported for each mount point
+	seen := make(map[string]bool)
+	for _, m := range mounts {
+		if !c.keep(m) || seen[m.device+m.mountPoint] {
+			continue
+		}
+		seen[m.device+m.mountPoint] = true
+
+		var stat syscall.Statfs_t
+		if err := statfs(m.mountPoint, &stat); err != nil {
+			log.Debugf("system.DiskCheck: could not stat %s: %s", m.mountPoint, err)
+			continue
+		}
+		// pseudo filesystems (proc, sysfs...) report no block
+		if stat.Blocks == 0 {
+			continue
+		}
+
+		tags := append([]string{"device:" + m.device, "device_name:" + filepath.Base(m.device)}, c.cfg.Tags...)
+		if label, found := labels[m.device]; found {
+			tags = append(tags, "device_label:"+label)
+		}
+		c.reportUsage(sender, &stat, tags)
+
+		if c.serviceCheckRW {
+			if m.readOnly() {
+				sender.ServiceCheck(DiskReadWrite, metrics.ServiceCheckCritical, "", tags,
+					fmt.Sprintf("%s is mounted read-only on %s", m.device, m.mountPoint))
+			} else {
+				sender.ServiceCheck(DiskReadWrite, metrics.ServiceCheckOK, "", tags, "")
+			}
+		}
+	}
+
+	sender.Commit()
+	return nil
+}
+
+// keep applies the filters to a mount. Unless all_partitions is set, only
+// the filesystems backed by a device are kept.
+func (c *DiskCheck) keep(m *mount) bool {
+	if !c.cfg.AllPartitions && !strings.HasPrefix(m.device, "/") {
+		return false
+	}
+	return c.deviceFilter.Keep(m.device) &&
+		c.mountPointFilter.Keep(m.mountPoint) &&
+		c.fileSystemFilter.Keep(m.fsType)
+}
+
+// reportUsage sends the space usage in kB, and the inode usage
+func (c *DiskCheck) reportUsage(sender aggregator.Sender, stat *syscall.Statfs_t, tags []string) {
+	blockSize := uint64(stat.Bsize)
+	total := stat.Blocks * blockSize / kB
+	free := stat.Bavail * blockSize / kB
+	used := (stat.Blocks - stat.Bfree) * blockSize / kB
+
+	sender.Gauge("system.disk.total", float64(total), "", tags)
+	sender.Gauge("system.disk.used", float64(used), "", tags)
+	sender.Gauge("system.disk.free", float64(free), "", tags)
+	// the space reserved to root is neither used nor available
+	if used+free > 0 {
+		sender.Gauge("system.disk.in_use", float64(used)/float64(used+free), "", tags)
+	}
+
+	if stat.Files > 0 {
+		sender.Gauge("system.fs.inodes.total", float64(stat.Files), "", tags)
+		sender.Gauge("system.fs.inodes.used", float64(stat.Files-stat.Ffree), "", tags)
+		sender.Gauge("system.fs.inodes.free", float64(stat.Ffree), "", tags)
+		sender.Gauge("system.fs.inodes.in_use", float64(stat.Files-stat.Ffree)/float64(stat.Files), "", tags)
+	}
+}
+
+// readMountsFile reads the mount table of the agent process, honoring procfs_path
+func readMountsFile() ([]*mount, error) {
+	procRoot := config.Datadog.GetString("procfs_path")
+	if procRoot == "" {
+		procRoot = "/proc"
+	}
+	f, err := os.Open(filepath.Join(procRoot, "self", "mounts"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseMounts(f)
+}
+
+// parseMounts parses a mount table in the fstab format. Format:
+//
+// /dev/sda1 / ext4 rw,relatime,errors=remount-ro 0 0
+// tmpfs /run tmpfs rw,nosuid,noexec,relatime,size=812456k,mode=755 0 0
+//
+func parseMounts(r io.Reader) ([]*mount, error) {
+	var mounts []*mount
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		mounts = append(mounts, &mount{
+			device:     unescapeMountField(fields[0]),
+			mountPoint: unescapeMountField(fields[1]),
+			fsType:     fields[2],
+			options:    strings.Split(fields[3], ","),
+		})
+	}
+	return mounts, scanner.Err()
+}
+
+// unescapeMountField decodes the octal escapes of spaces, tabs,
+// newlines and backslashes in the mount table, eg. `\040`
+func unescapeMountField(field string) string {
+	if !strings.Contains(field, `\`) {
+		return field
+	}
+	var out bytes.Buffer
+	for i := 0; i < len(field); i++ {
+		if field[i] == '\\' && i+3 < len(field) {
+			if v, err := strconv.ParseUint(field[i+1:i+4], 8, 8); err == nil {
+				out.WriteByte(byte(v))
+				i += 3
+				continue
+			}
+		}
+		out.WriteByte(field[i])
+	}
+	return out.String()
+}
+
+// deviceLabels maps the device paths to their filesystem label, read
+// from the udev symlinks of /dev/disk/by-label
+func deviceLabels() map[string]string {
+	labels := make(map[string]string)
+	entries, err := ioutil.ReadDir(labelsDir)
+	if err != nil {
+		log.Debugf("system.DiskCheck: could not list filesystem labels: %s", err)
+		return labels
+	}
+	for _, entry := range entries {
+		target, err := os.Readlink(filepath.Join(labelsDir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(labelsDir, target)
+		}
+		labels[filepath.Clean(target)] = unescapeLabel(entry.Name())
+	}
+	return labels
+}
+
+// unescapeLabel decodes the `\xHH` escapes udev uses in the label symlinks
+func unescapeLabel(label string) string {
+	if !strings.Contains(label, `\x`) {
+		return label
+	}
+	var out bytes.Buffer
+	for i := 0; i < len(label); i++ {
+		if strings.HasPrefix(label[i:], `\x`) && i+4 <= len(label) {
+			if v, err := strconv.ParseUint(label[i+2:i+4], 16, 8); err == nil {
+				out.WriteByte(byte(v))
+				i += 3
+				continue
+			}
+		}
+		out.WriteByte(label[i])
+	}
+	return out.String()
+}
+
+// Configure parses the check configuration and compiles the filters
+func (c *DiskCheck) Configure(data check.ConfigData, initConfig check.ConfigData) error {
+	cfg := &diskConfig{}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return err
+	}
+
+	var err error
+	if c.deviceFilter, err = core.NewRegexFilter(cfg.DeviceInclude, cfg.DeviceExclude); err != nil {
+		return fmt.Errorf("invalid device filter: %s", err)
+	}
+	if c.mountPointFilter, err = core.NewRegexFilter(cfg.MountPointInclude, cfg.MountPointExclude); err != nil {
+		return fmt.Errorf("invalid mount point filter: %s", err)
+	}
+	if c.fileSystemFilter, err = core.NewRegexFilter(cfg.FileSystemInclude, cfg.FileSystemExclude); err != nil {
+		return fmt.Errorf("invalid file system filter: %s", err)
+	}
+	c.serviceCheckRW = cfg.ServiceCheckRW == nil || *cfg.ServiceCheckRW
+
+	c.BuildID(data, initConfig)
+	c.cfg = cfg
+	return nil
+}
+
+func diskFactory() check.Check {
+	return &DiskCheck{
+		CheckBase: core.NewCheckBase(diskCheckName),
+	}
+}
+
+func init() {
+	core.RegisterCheck(diskCheckName, diskFactory)
+}
@@ -0,0 +1,177 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build linux
+
+package system
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+const fakeMountTable = `sysfs /sys sysfs rw,nosuid,nodev,noexec,relatime 0 0
+proc /proc proc rw,nosuid,nodev,noexec,relatime 0 0
+/dev/sda1 / ext4 rw,relatime,errors=remount-ro 0 0
+tmpfs /run tmpfs rw,nosuid,noexec,relatime,size=812456k,mode=755 0 0
+/dev/sdb1 /mnt/my\040backup xfs ro,relatime 0 0
+/dev/sda1 / ext4 rw,relatime,errors=remount-ro 0 0
+/dev/loop0 /snap/core/4917 squashfs ro,nodev,relatime 0 0
+`
+
+func fakeMounts() ([]*mount, error) {
+	return parseMounts(strings.NewReader(fakeMountTable))
+}
+
+func fakeStatfs(path string, stat *syscall.Statfs_t) error {
+	switch path {
+	case "/", "/run", "/snap/core/4917":
+		stat.Bsize = 4096
+		stat.Blocks = 1000
+		stat.Bfree = 300
+		stat.Bavail = 250
+		stat.Files = 100
+		stat.Ffree = 75
+	case "/mnt/my backup":
+		stat.Bsize = 1024
+		stat.Blocks = 2048
+		stat.Bfree = 2048
+		stat.Bavail = 2048
+	case "/sys", "/proc":
+		// pseudo filesystems have no block
+	default:
+		return fmt.Errorf("unexpected path %s", path)
+	}
+	return nil
+}
+
+func setupDiskTest(t *testing.T) func() {
+	readMounts = fakeMounts
+	statfs = fakeStatfs
+
+	// dev/disk/by-label/my\x20backup -> ../../sdb1
+	dir, err := ioutil.TempDir("", "dev")
+	require.NoError(t, err)
+	labelsDir = filepath.Join(dir, "disk", "by-label")
+	require.NoError(t, os.MkdirAll(labelsDir, 0755))
+	require.NoError(t, os.Symlink("../../sdb1", filepath.Join(labelsDir, `my\x20backup`)))
+
+	return func() {
+		os.RemoveAll(dir)
+		readMounts = readMountsFile
+		statfs = syscall.Statfs
+		labelsDir = "/dev/disk/by-label"
+	}
+}
+
+func TestParseMounts(t *testing.T) {
+	mounts, err := fakeMounts()
+	require.NoError(t, err)
+	require.Len(t, mounts, 7)
+	assert.Equal(t, &mount{
+		device:     "/dev/sdb1",
+		mountPoint: "/mnt/my backup",
+		fsType:     "xfs",
+		options:    []string{"ro", "relatime"},
+	}, mounts[4])
+	assert.True(t, mounts[4].readOnly())
+	assert.False(t, mounts[2].readOnly())
+}
+
+func TestDiskCheck(t *testing.T) {
+	defer setupDiskTest(t)()
+
+	diskCheck := diskFactory().(*DiskCheck)
+	require.NoError(t, diskCheck.Configure([]byte("tags: [\"env:test\"]"), nil))
+	// the labels are resolved relatively to the by-label directory
+	labels := deviceLabels()
+	dev := filepath.Join(filepath.Dir(filepath.Dir(labelsDir)), "sdb1")
+	assert.Equal(t, map[string]string{dev: "my backup"}, labels)
+	readMounts = func() ([]*mount, error) {
+		mounts, err := fakeMounts()
+		// point the backup mount to the labelled fake device
+		mounts[4].device = dev
+		return mounts, err
+	}
+
+	mockSender := mocksender.NewMockSender(diskCheck.ID())
+	mockSender.SetupAcceptAll()
+	require.NoError(t, diskCheck.Run())
+
+	rootTags := []string{"device:/dev/sda1", "device_name:sda1", "env:test"}
+	mockSender.AssertMetric(t, "Gauge", "system.disk.total", 4000, "", rootTags)
+	mockSender.AssertMetric(t, "Gauge", "system.disk.used", 2800, "", rootTags)
+	mockSender.AssertMetric(t, "Gauge", "system.disk.free", 1000, "", rootTags)
+	mockSender.AssertMetric(t, "Gauge", "system.disk.in_use", 2800.0/3800.0, "", rootTags)
+	mockSender.AssertMetric(t, "Gauge", "system.fs.inodes.total", 100, "", rootTags)
+	mockSender.AssertMetric(t, "Gauge", "system.fs.inodes.used", 25, "", rootTags)
+	mockSender.AssertMetric(t, "Gauge", "system.fs.inodes.free", 75, "", rootTags)
+	mockSender.AssertMetric(t, "Gauge", "system.fs.inodes.in_use", 0.25, "", rootTags)
+	mockSender.AssertServiceCheck(t, DiskReadWrite, metrics.ServiceCheckOK, "", rootTags, "")
+
+	backupTags := []string{"device:" + dev, "device_name:sdb1", "env:test", "device_label:my backup"}
+	mockSender.AssertMetric(t, "Gauge", "system.disk.total", 2048, "", backupTags)
+	mockSender.AssertMetric(t, "Gauge", "system.disk.in_use", 0, "", backupTags)
+	mockSender.AssertNotCalled(t, "Gauge", "system.fs.inodes.total", mock.Anything, "", backupTags)
+	mockSender.AssertServiceCheck(t, DiskReadWrite, metrics.ServiceCheckCritical, "", backupTags,
+		dev+" is mounted read-only on /mnt/my backup")
+
+	// sda1, sdb1 and loop0 are reported, tmpfs and the pseudo filesystems are skipped
+	mockSender.AssertNumberOfCalls(t, "Gauge", 8+4+8)
+	mockSender.AssertNumberOfCalls(t, "ServiceCheck", 3)
+	mockSender.AssertNumberOfCalls(t, "Commit", 1)
+}
+
+func TestDiskCheckFilters(t *testing.T) {
+	defer setupDiskTest(t)()
+	dev := filepath.Join(filepath.Dir(filepath.Dir(labelsDir)), "sdb1")
+	readMounts = func() ([]*mount, error) {
+		mounts, err := fakeMounts()
+		// point the backup mount to the labelled fake device
+		mounts[4].device = dev
+		return mounts, err
+	}
+
+	diskCheck := diskFactory().(*DiskCheck)
+	require.NoError(t, diskCheck.Configure([]byte(`
+all_partitions: true
+service_check_rw: false
+file_system_exclude: ["^squashfs$", "sysfs"]
+mount_point_exclude: ["^/proc"]
+`), nil))
+
+	mockSender := mocksender.NewMockSender(diskCheck.ID())
+	mockSender.SetupAcceptAll()
+	require.NoError(t, diskCheck.Run())
+
+	mockSender.AssertMetric(t, "Gauge", "system.disk.total", 4000, "", []string{"device:tmpfs", "device_name:tmpfs"})
+	mockSender.AssertMetric(t, "Gauge", "system.disk.total", 2048, "",
+		[]string{"device:" + dev, "device_name:sdb1", "device_label:my backup"})
+	mockSender.AssertNotCalled(t, "Gauge", "system.disk.total", mock.Anything, "", []string{"device:/dev/loop0", "device_name:loop0"})
+	// sda1 and tmpfs with inodes, sdb1 without
+	mockSender.AssertNumberOfCalls(t, "Gauge", 8+8+4)
+	mockSender.AssertNumberOfCalls(t, "ServiceCheck", 0)
+
+	// include filters
+	diskCheck = diskFactory().(*DiskCheck)
+	require.NoError(t, diskCheck.Configure([]byte(`device_include: ["sda"]`), nil))
+	assert.True(t, diskCheck.keep(&mount{device: "/dev/sda1"}))
+	assert.False(t, diskCheck.keep(&mount{device: "/dev/sdb1"}))
+	assert.False(t, diskCheck.keep(&mount{device: "tmpfs"}))
+
+	assert.Error(t, diskCheck.Configure([]byte(`device_exclude: ["("]`), nil))
+}
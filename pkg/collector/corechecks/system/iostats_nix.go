@@ -144,6 +144,7 @@ func (c *IOCheck) Run() error {
 	err = c.nixIO()
 
 	if err == nil {
+		reportPressure(sender, "system.io", "io")
 		sender.Commit()
 	}
 	return err
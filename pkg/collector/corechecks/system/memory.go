@@ -42,6 +42,7 @@ func (c *MemoryCheck) linuxSpecificVirtualMemoryCheck(v *mem.VirtualMemoryStat)
 	sender.Gauge("system.mem.slab", float64(v.Slab)/mbSize, "", nil)
 	sender.Gauge("system.mem.page_tables", float64(v.PageTables)/mbSize, "", nil)
 	sender.Gauge("system.swap.cached", float64(v.SwapCached)/mbSize, "", nil)
+	reportPressure(sender, "system.mem", "memory")
 	return nil
 }
 
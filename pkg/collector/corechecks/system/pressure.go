@@ -0,0 +1,40 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package system
+
+import (
+	"os"
+	"path/filepath"
+
+	log "github.com/cihub/seelog"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/docker"
+)
+
+// For testing purpose
+var readPressure = docker.ReadPressureFile
+
+// reportPressure sends the system-wide pressure stall information of a
+// resource (cpu, memory or io). PSI requires Linux 4.20+, nothing is sent
+// if the kernel doesn't expose it. The total stall time is reported as
+// microseconds per second.
+func reportPressure(sender aggregator.Sender, prefix, resource string) {
+	procRoot := config.Datadog.GetString("procfs_path")
+	if procRoot == "" {
+		procRoot = "/proc"
+	}
+	p, err := readPressure(filepath.Join(procRoot, "pressure", resource))
+	if os.IsNotExist(err) {
+		return
+	} else if err != nil {
+		log.Debugf("could not read %s pressure: %s", resource, err)
+		return
+	}
+
+	docker.SendPressure(sender, prefix, p, nil)
+}
@@ -0,0 +1,59 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package system
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/util/docker"
+)
+
+func init() {
+	// Don't read the host's /proc/pressure in the other checks' tests
+	readPressure = missingPressure
+}
+
+func missingPressure(path string) (*docker.Pressure, error) {
+	return nil, os.ErrNotExist
+}
+
+func TestReportPressure(t *testing.T) {
+	defer func() { readPressure = missingPressure }()
+	var readPath string
+	readPressure = func(path string) (*docker.Pressure, error) {
+		readPath = path
+		return &docker.Pressure{
+			Some: &docker.PressureStat{Avg10: 2.5, Avg60: 1.5, Avg300: 0.5, Total: 3000},
+			Full: &docker.PressureStat{Avg10: 1, Avg60: 0.5, Avg300: 0.25, Total: 1000},
+		}, nil
+	}
+	memCheck := new(MemoryCheck)
+	mock := mocksender.NewMockSender(memCheck.ID())
+	mock.SetupAcceptAll()
+
+	reportPressure(mock, "system.mem", "memory")
+	assert.Contains(t, readPath, "pressure/memory")
+	mock.AssertMetric(t, "Gauge", "system.mem.pressure.some.avg10", 2.5, "", nil)
+	mock.AssertMetric(t, "Gauge", "system.mem.pressure.some.avg60", 1.5, "", nil)
+	mock.AssertMetric(t, "Gauge", "system.mem.pressure.some.avg300", 0.5, "", nil)
+	mock.AssertMetric(t, "Rate", "system.mem.pressure.some.total", 3000, "", nil)
+	mock.AssertMetric(t, "Gauge", "system.mem.pressure.full.avg10", 1, "", nil)
+	mock.AssertMetric(t, "Rate", "system.mem.pressure.full.total", 1000, "", nil)
+	mock.AssertNumberOfCalls(t, "Gauge", 6)
+	mock.AssertNumberOfCalls(t, "Rate", 2)
+
+	// PSI not supported by the kernel, or unreadable
+	for _, err := range []error{os.ErrNotExist, fmt.Errorf("permission denied")} {
+		readPressure = func(string) (*docker.Pressure, error) { return nil, err }
+		reportPressure(mock, "system.cpu", "cpu")
+	}
+	mock.AssertNumberOfCalls(t, "Gauge", 6)
+}
@@ -0,0 +1,408 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build linux
+
+package system
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/cihub/seelog"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/pidfile"
+)
+
+const (
+	processCheckName = "process"
+	// ProcessUp is the service check comparing the number of matching processes to the thresholds
+	ProcessUp = "process.up"
+)
+
+// For testing purpose
+var pageSize = uint64(os.Getpagesize())
+
+type processConfig struct {
+	Name string `yaml:"name"`
+	// SearchString holds process names, compared to the process comm and
+	// to the basename of its first argument
+	SearchString []string `yaml:"search_string"`
+	CmdlineRegex string   `yaml:"cmdline_regex"`
+	PidFile      string   `yaml:"pid_file"`
+	// User restricts the matching to the processes of a user, or matches
+	// all its processes if no other criteria is set
+	User       string               `yaml:"user"`
+	Thresholds map[string][]float64 `yaml:"thresholds"`
+	Tags       []string             `yaml:"tags"`
+}
+
+// processStats holds the statistics of a process read from /proc
+type processStats struct {
+	pid        int
+	cpuTicks   uint64
+	rss        uint64
+	threads    uint64
+	fds        uint64
+	fdsKnown   bool
+	readBytes  uint64
+	writeBytes uint64
+	ioKnown    bool
+}
+
+// ProcessCheck reports the resource usage of a group of processes
+type ProcessCheck struct {
+	core.CheckBase
+	cfg      *processConfig
+	procRoot string
+	cmdline  *regexp.Regexp
+	uid      string
+	// CPU ticks of the matching processes at the last run, by PID
+	lastTicks map[int]uint64
+	lastRun   time.Time
+}
+
+// Run executes the check
+func (c *ProcessCheck) Run() error {
+	sender, err := aggregator.GetSender(c.ID())
+	if err != nil {
+		return err
+	}
+
+	pids, err := c.matchingPIDs()
+	if err != nil {
+		log.Errorf("system.ProcessCheck: could not list the %s processes: %s", c.cfg.Name, err)
+		return err
+	}
+
+	now := time.Now()
+	tags := append([]string{"process_name:" + c.cfg.Name}, c.cfg.Tags...)
+	var total processStats
+	var cpuDelta uint64
+	ticks := make(map[int]uint64, len(pids))
+	for _, pid := range pids {
+		stats, err := c.readProcessStats(pid)
+		if err != nil {
+			// the process exited since the listing
+			log.Debugf("system.ProcessCheck: could not read the stats of process %d: %s", pid, err)
+			continue
+		}
+		ticks[pid] = stats.cpuTicks
+		if last, found := c.lastTicks[pid]; found && stats.cpuTicks >= last {
+			cpuDelta += stats.cpuTicks - last
+		}
+		total.rss += stats.rss
+		total.threads += stats.threads
+		if stats.fdsKnown {
+			total.fds += stats.fds
+			total.fdsKnown = true
+		}
+		if stats.ioKnown {
+			total.readBytes += stats.readBytes
+			total.writeBytes += stats.writeBytes
+			total.ioKnown = true
+		}
+	}
+
+	count := float64(len(ticks))
+	sender.Gauge("system.processes.number", count, "", tags)
+	if len(ticks) > 0 {
+		sender.Gauge("system.processes.mem.rss", float64(total.rss), "", tags)
+		sender.Gauge("system.processes.threads", float64(total.threads), "", tags)
+		if total.fdsKnown {
+			sender.Gauge("system.processes.open_file_descriptors", float64(total.fds), "", tags)
+		}
+		if total.ioKnown {
+			sender.Rate("system.processes.ioread_bytes", float64(total.readBytes), "", tags)
+			sender.Rate("system.processes.iowrite_bytes", float64(total.writeBytes), "", tags)
+		}
+		if elapsed := now.Sub(c.lastRun).Seconds(); !c.lastRun.IsZero() && elapsed > 0 && hz > 0 {
+			sender.Gauge("system.processes.cpu.pct", float64(cpuDelta)/float64(hz)/elapsed*100, "", tags)
+		}
+	}
+	c.lastTicks = ticks
+	c.lastRun = now
+
+	status, message := c.thresholdsStatus(count)
+	sender.ServiceCheck(ProcessUp, status, "", append([]string{"process:" + c.cfg.Name}, c.cfg.Tags...), message)
+
+	sender.Commit()
+	return nil
+}
+
+// thresholdsStatus compares the number of processes to the critical and
+// warning [min, max] ranges. Without thresholds, no process is critical.
+func (c *ProcessCheck) thresholdsStatus(count float64) (metrics.ServiceCheckStatus, string) {
+	for _, level := range []struct {
+		name   string
+		status metrics.ServiceCheckStatus
+	}{
+		{"critical", metrics.ServiceCheckCritical},
+		{"warning", metrics.ServiceCheckWarning},
+	} {
+		bounds, found := c.cfg.Thresholds[level.name]
+		if !found {
+			if level.name != "critical" {
+				continue
+			}
+			bounds = []float64{1, math.Inf(1)}
+		}
+		if count < bounds[0] || count > bounds[1] {
+			return level.status, fmt.Sprintf("Found %d processes, expected between %g and %g",
+				int(count), bounds[0], bounds[1])
+		}
+	}
+	return metrics.ServiceCheckOK, ""
+}
+
+// matchingPIDs returns the PIDs of the processes matching the configuration
+func (c *ProcessCheck) matchingPIDs() ([]int, error) {
+	if c.cfg.PidFile != "" {
+		pid, err := pidfile.ReadPID(c.cfg.PidFile)
+		if os.IsNotExist(err) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(filepath.Join(c.procRoot, strconv.Itoa(pid))); err != nil || !c.matchUser(pid) {
+			return nil, nil
+		}
+		return []int{pid}, nil
+	}
+
+	entries, err := ioutil.ReadDir(c.procRoot)
+	if err != nil {
+		return nil, err
+	}
+	var pids []int
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		if c.match(pid) {
+			pids = append(pids, pid)
+		}
+	}
+	return pids, nil
+}
+
+func (c *ProcessCheck) match(pid int) bool {
+	if !c.matchUser(pid) {
+		return false
+	}
+	if len(c.cfg.SearchString) > 0 {
+		return c.matchName(pid)
+	}
+	if c.cmdline != nil {
+		cmdline, err := ioutil.ReadFile(filepath.Join(c.procRoot, strconv.Itoa(pid), "cmdline"))
+		if err != nil {
+			return false
+		}
+		return c.cmdline.MatchString(strings.TrimSpace(strings.Replace(string(cmdline), "\x00", " ", -1)))
+	}
+	// user only
+	return c.uid != ""
+}
+
+func (c *ProcessCheck) matchName(pid int) bool {
+	var names []string
+	if comm, err := ioutil.ReadFile(filepath.Join(c.procRoot, strconv.Itoa(pid), "comm")); err == nil {
+		names = append(names, strings.TrimSpace(string(comm)))
+	}
+	if cmdline, err := ioutil.ReadFile(filepath.Join(c.procRoot, strconv.Itoa(pid), "cmdline")); err == nil && len(cmdline) > 0 {
+		args := strings.SplitN(string(cmdline), "\x00", 2)
+		names = append(names, filepath.Base(args[0]))
+	}
+	for _, name := range names {
+		for _, search := range c.cfg.SearchString {
+			if name == search {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchUser compares the real UID of the process, from /proc/<pid>/status
+func (c *ProcessCheck) matchUser(pid int) bool {
+	if c.uid == "" {
+		return true
+	}
+	f, err := os.Open(filepath.Join(c.procRoot, strconv.Itoa(pid), "status"))
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 1 && fields[0] == "Uid:" {
+			return fields[1] == c.uid
+		}
+	}
+	return false
+}
+
+// readProcessStats reads /proc/<pid>/stat, fd and io. The fd and io
+// statistics require the agent to run as the process user or root.
+func (c *ProcessCheck) readProcessStats(pid int) (*processStats, error) {
+	dir := filepath.Join(c.procRoot, strconv.Itoa(pid))
+	stat, err := ioutil.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return nil, err
+	}
+	stats, err := parseProcessStat(string(stat))
+	if err != nil {
+		return nil, err
+	}
+	stats.pid = pid
+
+	if fds, err := ioutil.ReadDir(filepath.Join(dir, "fd")); err == nil {
+		stats.fds = uint64(len(fds))
+		stats.fdsKnown = true
+	}
+
+	if f, err := os.Open(filepath.Join(dir, "io")); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) != 2 {
+				continue
+			}
+			v, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				continue
+			}
+			switch fields[0] {
+			case "read_bytes:":
+				stats.readBytes = v
+				stats.ioKnown = true
+			case "write_bytes:":
+				stats.writeBytes = v
+				stats.ioKnown = true
+			}
+		}
+		f.Close()
+	}
+	return stats, nil
+}
+
+// parseProcessStat parses /proc/<pid>/stat. The command name can contain
+// spaces and parentheses: the fields are counted after its last parenthesis.
+//
+// 1234 (my process) S 1 1234 1234 0 -1 4194560 2360 0 0 0 150 37 0 0 20 0 4 0 7321 25735168 1570 ...
+//
+func parseProcessStat(stat string) (*processStats, error) {
+	end := strings.LastIndex(stat, ")")
+	if end < 0 {
+		return nil, errors.New("invalid stat format")
+	}
+	// fields[0] is the state, the 3rd field of the file
+	fields := strings.Fields(stat[end+1:])
+	if len(fields) < 22 {
+		return nil, errors.New("invalid stat format")
+	}
+	values := make(map[int]uint64)
+	for _, i := range []int{14, 15, 20, 24} {
+		v, err := strconv.ParseUint(fields[i-3], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid stat field %d: %s", i, err)
+		}
+		values[i] = v
+	}
+	return &processStats{
+		cpuTicks: values[14] + values[15],
+		threads:  values[20],
+		rss:      values[24] * pageSize,
+	}, nil
+}
+
+// Configure parses the check configuration
+func (c *ProcessCheck) Configure(data check.ConfigData, initConfig check.ConfigData) error {
+	cfg := &processConfig{}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return err
+	}
+	if cfg.Name == "" {
+		return errors.New("missing name")
+	}
+
+	criteria := 0
+	if len(cfg.SearchString) > 0 {
+		criteria++
+	}
+	if cfg.CmdlineRegex != "" {
+		criteria++
+		re, err := regexp.Compile(cfg.CmdlineRegex)
+		if err != nil {
+			return fmt.Errorf("invalid cmdline_regex: %s", err)
+		}
+		c.cmdline = re
+	}
+	if cfg.PidFile != "" {
+		criteria++
+	}
+	if criteria > 1 {
+		return errors.New("search_string, cmdline_regex and pid_file are mutually exclusive")
+	}
+	if criteria == 0 && cfg.User == "" {
+		return errors.New("one of search_string, cmdline_regex, pid_file or user is required")
+	}
+
+	if cfg.User != "" {
+		if u, err := user.Lookup(cfg.User); err == nil {
+			c.uid = u.Uid
+		} else if _, err := strconv.Atoi(cfg.User); err == nil {
+			c.uid = cfg.User
+		} else {
+			return fmt.Errorf("unknown user %s: %s", cfg.User, err)
+		}
+	}
+
+	for level, bounds := range cfg.Thresholds {
+		if level != "critical" && level != "warning" {
+			return fmt.Errorf("unknown threshold level %s", level)
+		}
+		if len(bounds) != 2 || bounds[0] > bounds[1] {
+			return fmt.Errorf("the %s threshold must be a [min, max] range", level)
+		}
+	}
+
+	c.procRoot = config.Datadog.GetString("procfs_path")
+	if c.procRoot == "" {
+		c.procRoot = "/proc"
+	}
+
+	c.BuildID(data, initConfig)
+	c.cfg = cfg
+	return nil
+}
+
+func processFactory() check.Check {
+	return &ProcessCheck{
+		CheckBase: core.NewCheckBase(processCheckName),
+	}
+}
+
+func init() {
+	core.RegisterCheck(processCheckName, processFactory)
+}
@@ -0,0 +1,140 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build linux
+
+package system
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+func TestParseProcessStat(t *testing.T) {
+	pageSize = 4096
+	stats, err := parseProcessStat("300 (my (weird) proc) R 1 300 300 0 -1 4194304 10 0 0 0 5 7 0 0 20 0 3 0 9500 1000000 100 18446744073709551615")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(12), stats.cpuTicks)
+	assert.Equal(t, uint64(3), stats.threads)
+	assert.Equal(t, uint64(100*4096), stats.rss)
+
+	_, err = parseProcessStat("300 (truncated) R 1 300")
+	assert.Error(t, err)
+}
+
+func TestProcessCheckByName(t *testing.T) {
+	pageSize = 4096
+	hz = 100
+	processCheck := new(ProcessCheck)
+	require.NoError(t, processCheck.Configure([]byte(`
+name: nginx
+search_string: ["nginx"]
+tags: ["env:test"]
+`), nil))
+	processCheck.procRoot = "testdata/proc"
+	mockSender := mocksender.NewMockSender(processCheck.ID())
+	mockSender.SetupAcceptAll()
+	require.NoError(t, processCheck.Run())
+
+	tags := []string{"process_name:nginx", "env:test"}
+	mockSender.AssertMetric(t, "Gauge", "system.processes.number", 2, "", tags)
+	mockSender.AssertMetric(t, "Gauge", "system.processes.mem.rss", 1500*4096, "", tags)
+	mockSender.AssertMetric(t, "Gauge", "system.processes.threads", 3, "", tags)
+	// only the fds and io of the master are readable
+	mockSender.AssertMetric(t, "Gauge", "system.processes.open_file_descriptors", 3, "", tags)
+	mockSender.AssertMetric(t, "Rate", "system.processes.ioread_bytes", 4096, "", tags)
+	mockSender.AssertMetric(t, "Rate", "system.processes.iowrite_bytes", 8192, "", tags)
+	mockSender.AssertServiceCheck(t, ProcessUp, metrics.ServiceCheckOK, "", []string{"process:nginx", "env:test"}, "")
+	// no CPU percentage on the first run
+	mockSender.AssertNotCalled(t, "Gauge", "system.processes.cpu.pct", 0.0, "", tags)
+
+	// 100 ticks on 10 seconds is 10% of a core
+	processCheck.lastTicks = map[int]uint64{100: 150, 101: 350}
+	processCheck.lastRun = time.Now().Add(-10 * time.Second)
+	mockSender = mocksender.NewMockSender(processCheck.ID())
+	mockSender.SetupAcceptAll()
+	require.NoError(t, processCheck.Run())
+	var pct float64
+	for _, call := range mockSender.Calls {
+		if call.Method == "Gauge" && call.Arguments.String(0) == "system.processes.cpu.pct" {
+			pct = call.Arguments.Get(1).(float64)
+		}
+	}
+	assert.InDelta(t, 10.0, pct, 0.1)
+}
+
+func TestProcessCheckSelectors(t *testing.T) {
+	for _, tc := range []struct {
+		config string
+		count  float64
+	}{
+		{"name: app\ncmdline_regex: 'server\\.py --port \\d+'", 1},
+		{"name: app\nsearch_string: ['python3']", 1},
+		// the basename of the first argument
+		{"name: weird\nsearch_string: ['weird']", 1},
+		{"name: weird\nsearch_string: ['my (weird) proc']", 1},
+		{"name: nginx\npid_file: testdata/nginx.pid", 1},
+		{"name: gone\npid_file: testdata/gone.pid", 0},
+		{"name: missing\npid_file: testdata/missing.pid", 0},
+		{"name: user\nuser: '1000'", 2},
+		{"name: nginx\nsearch_string: ['nginx']\nuser: '33'", 1},
+	} {
+		processCheck := new(ProcessCheck)
+		require.NoError(t, processCheck.Configure([]byte(tc.config), nil))
+		processCheck.procRoot = "testdata/proc"
+		mockSender := mocksender.NewMockSender(processCheck.ID())
+		mockSender.SetupAcceptAll()
+		require.NoError(t, processCheck.Run(), tc.config)
+		mockSender.AssertMetric(t, "Gauge", "system.processes.number", tc.count, "", []string{"process_name:" + processCheck.cfg.Name})
+	}
+}
+
+func TestProcessCheckThresholds(t *testing.T) {
+	processCheck := new(ProcessCheck)
+	require.NoError(t, processCheck.Configure([]byte(`
+name: nginx
+search_string: ["nginx"]
+thresholds:
+  critical: [1, 4]
+  warning: [3, 5]
+`), nil))
+	processCheck.procRoot = "testdata/proc"
+	mockSender := mocksender.NewMockSender(processCheck.ID())
+	mockSender.SetupAcceptAll()
+	require.NoError(t, processCheck.Run())
+	mockSender.AssertServiceCheck(t, ProcessUp, metrics.ServiceCheckWarning, "", []string{"process:nginx"},
+		"Found 2 processes, expected between 3 and 5")
+
+	// no process, critical by default
+	processCheck = new(ProcessCheck)
+	require.NoError(t, processCheck.Configure([]byte("name: nothing\nsearch_string: ['nothing']"), nil))
+	processCheck.procRoot = "testdata/proc"
+	mockSender = mocksender.NewMockSender(processCheck.ID())
+	mockSender.SetupAcceptAll()
+	require.NoError(t, processCheck.Run())
+	mockSender.AssertServiceCheck(t, ProcessUp, metrics.ServiceCheckCritical, "", []string{"process:nothing"},
+		"Found 0 processes, expected between 1 and +Inf")
+	mockSender.AssertNumberOfCalls(t, "Gauge", 1)
+}
+
+func TestProcessCheckConfigure(t *testing.T) {
+	for _, config := range []string{
+		"search_string: ['nginx']",
+		"name: nginx",
+		"name: nginx\nsearch_string: ['nginx']\npid_file: /run/nginx.pid",
+		"name: nginx\ncmdline_regex: '('",
+		"name: nginx\nuser: not-a-user-for-sure",
+		"name: nginx\nsearch_string: ['nginx']\nthresholds: {critical: [4, 1]}",
+		"name: nginx\nsearch_string: ['nginx']\nthresholds: {unknown: [1, 4]}",
+	} {
+		assert.Error(t, processFactory().Configure([]byte(config), nil), config)
+	}
+}
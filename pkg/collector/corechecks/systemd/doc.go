@@ -0,0 +1,11 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+/*
+Package systemd provides a core check reporting the state of the systemd
+units, queried over D-Bus. It requires the systemd build tag.
+
+*/
+package systemd
@@ -0,0 +1,251 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build systemd
+
+package systemd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/cihub/seelog"
+	"github.com/coreos/go-systemd/dbus"
+	godbus "github.com/godbus/dbus"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+const (
+	systemdCheckName = "systemd"
+
+	// SystemdCanConnect is the service check reporting the connectivity to systemd
+	SystemdCanConnect = "systemd.can_connect"
+	// SystemdUnitState is the service check reporting the state of each unit
+	SystemdUnitState = "systemd.unit.state"
+
+	defaultSocketPath = "/var/run/dbus/system_bus_socket"
+)
+
+// activeStates lists the active states of the units, to report a count of
+// zero for the states no unit is in
+var activeStates = []string{"active", "reloading", "inactive", "failed", "activating", "deactivating"}
+
+type systemdConfig struct {
+	// SocketPath is the path of the system bus socket, mounted from the host
+	// when the agent runs in a container
+	SocketPath  string   `yaml:"socket_path"`
+	UnitInclude []string `yaml:"unit_include"`
+	UnitExclude []string `yaml:"unit_exclude"`
+	// RequiredUnits are the units expected to be active, the other units
+	// can be inactive, like oneshot services and timer triggered units
+	RequiredUnits []string `yaml:"required_units"`
+	UptimeUnits   []string `yaml:"uptime_units"`
+	Tags          []string `yaml:"tags"`
+}
+
+// SystemdCheck reports the state of the systemd units
+type SystemdCheck struct {
+	core.CheckBase
+	cfg      *systemdConfig
+	units    *core.RegexFilter
+	required map[string]bool
+	conn     *dbus.Conn
+}
+
+// Run executes the check
+func (c *SystemdCheck) Run() error {
+	sender, err := aggregator.GetSender(c.ID())
+	if err != nil {
+		return err
+	}
+
+	units, err := c.listUnits()
+	if err != nil {
+		sender.ServiceCheck(SystemdCanConnect, metrics.ServiceCheckCritical, "", c.cfg.Tags, err.Error())
+		sender.Commit()
+		return err
+	}
+	sender.ServiceCheck(SystemdCanConnect, metrics.ServiceCheckOK, "", c.cfg.Tags, "")
+
+	counts := make(map[string]int)
+	for _, state := range activeStates {
+		counts[state] = 0
+	}
+	for _, unit := range units {
+		if !c.units.Keep(unit.Name) {
+			continue
+		}
+		counts[unit.ActiveState]++
+
+		tags := append([]string{"unit:" + unit.Name}, c.cfg.Tags...)
+		status, message := unitStatus(unit, c.required[unit.Name])
+		sender.ServiceCheck(SystemdUnitState, status, "", tags, message)
+
+		if strings.HasSuffix(unit.Name, ".service") {
+			c.reportRestarts(sender, unit.Name, tags)
+		}
+	}
+	for state, count := range counts {
+		sender.Gauge("systemd.units.count", float64(count), "", append([]string{"active_state:" + state}, c.cfg.Tags...))
+	}
+
+	for _, name := range c.cfg.UptimeUnits {
+		c.reportUptime(sender, name, units)
+	}
+
+	sender.Commit()
+	return nil
+}
+
+// listUnits lists the loaded units, connecting to the bus if needed. The
+// connection is dropped on error, to reconnect on the next run.
+func (c *SystemdCheck) listUnits() ([]dbus.UnitStatus, error) {
+	if c.conn == nil {
+		conn, err := dbus.NewConnection(c.dialBus)
+		if err != nil {
+			return nil, fmt.Errorf("cannot connect to the system bus on %s: %s", c.cfg.SocketPath, err)
+		}
+		c.conn = conn
+	}
+	units, err := c.conn.ListUnits()
+	if err != nil {
+		c.conn.Close()
+		c.conn = nil
+		return nil, fmt.Errorf("cannot list the systemd units: %s", err)
+	}
+	return units, nil
+}
+
+// dialBus opens an authenticated connection to the bus socket
+func (c *SystemdCheck) dialBus() (*godbus.Conn, error) {
+	conn, err := godbus.Dial("unix:path=" + c.cfg.SocketPath)
+	if err != nil {
+		return nil, err
+	}
+	// Hardcode the uid to avoid a username lookup
+	if err = conn.Auth([]godbus.Auth{godbus.AuthExternal(strconv.Itoa(os.Getuid()))}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err = conn.Hello(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// reportRestarts reports the number of automatic restarts of a service,
+// exposed by systemd 235 and later
+func (c *SystemdCheck) reportRestarts(sender aggregator.Sender, name string, tags []string) {
+	prop, err := c.conn.GetServiceProperty(name, "NRestarts")
+	if err != nil {
+		log.Debugf("systemd.SystemdCheck: cannot get the restart count of %s: %s", name, err)
+		return
+	}
+	if restarts, ok := prop.Value.Value().(uint32); ok {
+		sender.Gauge("systemd.service.restarts", float64(restarts), "", tags)
+	}
+}
+
+// reportUptime reports the time since a unit entered the active state
+func (c *SystemdCheck) reportUptime(sender aggregator.Sender, name string, units []dbus.UnitStatus) {
+	tags := append([]string{"unit:" + name}, c.cfg.Tags...)
+	active := false
+	for _, unit := range units {
+		if unit.Name == name {
+			active = unit.ActiveState == "active" || unit.ActiveState == "reloading"
+			break
+		}
+	}
+	if !active {
+		sender.Gauge("systemd.unit.uptime", 0, "", tags)
+		return
+	}
+
+	prop, err := c.conn.GetUnitProperty(name, "ActiveEnterTimestamp")
+	if err != nil {
+		log.Debugf("systemd.SystemdCheck: cannot get the activation time of %s: %s", name, err)
+		return
+	}
+	// microseconds since the epoch
+	since, ok := prop.Value.Value().(uint64)
+	if !ok || since == 0 {
+		return
+	}
+	uptime := time.Since(time.Unix(0, int64(since)*int64(time.Microsecond)))
+	sender.Gauge("systemd.unit.uptime", uptime.Seconds(), "", tags)
+}
+
+// unitStatus maps the active state of a unit to a service check status.
+// Inactive units are only critical if they are required to be active.
+func unitStatus(unit dbus.UnitStatus, required bool) (metrics.ServiceCheckStatus, string) {
+	var status metrics.ServiceCheckStatus
+	switch unit.ActiveState {
+	case "active", "reloading":
+		return metrics.ServiceCheckOK, ""
+	case "activating", "deactivating":
+		status = metrics.ServiceCheckWarning
+	case "inactive":
+		if !required {
+			return metrics.ServiceCheckOK, ""
+		}
+		status = metrics.ServiceCheckCritical
+	case "failed":
+		status = metrics.ServiceCheckCritical
+	default:
+		status = metrics.ServiceCheckUnknown
+	}
+	return status, fmt.Sprintf("Unit %s is %s (%s)", unit.Name, unit.ActiveState, unit.SubState)
+}
+
+// Configure parses the check configuration and compiles the unit filters
+func (c *SystemdCheck) Configure(data check.ConfigData, initConfig check.ConfigData) error {
+	cfg := &systemdConfig{}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return err
+	}
+	if cfg.SocketPath == "" {
+		cfg.SocketPath = defaultSocketPath
+	}
+
+	var err error
+	if c.units, err = core.NewRegexFilter(cfg.UnitInclude, cfg.UnitExclude); err != nil {
+		return fmt.Errorf("invalid unit filter: %s", err)
+	}
+	c.required = make(map[string]bool, len(cfg.RequiredUnits))
+	for _, name := range cfg.RequiredUnits {
+		c.required[name] = true
+	}
+	c.cfg = cfg
+
+	c.BuildID(data, initConfig)
+	return nil
+}
+
+// Stop closes the connection to the bus
+func (c *SystemdCheck) Stop() {
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+}
+
+func systemdFactory() check.Check {
+	return &SystemdCheck{
+		CheckBase: core.NewCheckBase(systemdCheckName),
+	}
+}
+
+func init() {
+	core.RegisterCheck(systemdCheckName, systemdFactory)
+}
@@ -0,0 +1,220 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build systemd
+
+package systemd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-systemd/dbus"
+	godbus "github.com/godbus/dbus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+const busConfig = `<!DOCTYPE busconfig PUBLIC "-//freedesktop//DTD D-Bus Bus Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/busconfig.dtd">
+<busconfig>
+  <type>session</type>
+  <listen>unix:path=%s</listen>
+  <auth>EXTERNAL</auth>
+  <policy context="default">
+    <allow send_destination="*" eavesdrop="true"/>
+    <allow eavesdrop="true"/>
+    <allow own="*"/>
+  </policy>
+</busconfig>
+`
+
+// fakeUnitStatus has the signature of the ListUnits items: (ssssssouso)
+type fakeUnitStatus struct {
+	Name        string
+	Description string
+	LoadState   string
+	ActiveState string
+	SubState    string
+	Followed    string
+	Path        godbus.ObjectPath
+	JobID       uint32
+	JobType     string
+	JobPath     godbus.ObjectPath
+}
+
+// fakeManager implements the ListUnits method of org.freedesktop.systemd1.Manager
+type fakeManager struct {
+	units []fakeUnitStatus
+}
+
+func (m *fakeManager) ListUnits() ([]fakeUnitStatus, *godbus.Error) {
+	return m.units, nil
+}
+
+// fakeUnit implements org.freedesktop.DBus.Properties for a unit
+type fakeUnit struct {
+	props map[string]map[string]godbus.Variant
+}
+
+func (u *fakeUnit) Get(iface, name string) (godbus.Variant, *godbus.Error) {
+	if v, found := u.props[iface][name]; found {
+		return v, nil
+	}
+	return godbus.Variant{}, godbus.NewError("org.freedesktop.DBus.Error.UnknownProperty", []interface{}{name})
+}
+
+func (u *fakeUnit) GetAll(iface string) (map[string]godbus.Variant, *godbus.Error) {
+	return u.props[iface], nil
+}
+
+// startFakeSystemd starts a private bus, on which a fake systemd exposes the
+// given units and their properties
+func startFakeSystemd(t *testing.T, units []fakeUnitStatus, props map[string]map[string]map[string]godbus.Variant) (string, func()) {
+	daemon, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon is not available")
+	}
+	dir, err := ioutil.TempDir("", "systemd")
+	require.NoError(t, err)
+	socket := filepath.Join(dir, "bus")
+	configFile := filepath.Join(dir, "bus.conf")
+	require.NoError(t, ioutil.WriteFile(configFile, []byte(fmt.Sprintf(busConfig, socket)), 0644))
+
+	cmd := exec.Command(daemon, "--nofork", "--config-file="+configFile)
+	require.NoError(t, cmd.Start())
+	cleanup := func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+		os.RemoveAll(dir)
+	}
+
+	var conn *godbus.Conn
+	for i := 0; i < 50; i++ {
+		if conn, err = godbus.Dial("unix:path=" + socket); err == nil {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if err != nil {
+		cleanup()
+		t.Fatalf("cannot connect to the test bus: %s", err)
+	}
+	require.NoError(t, conn.Auth(nil))
+	require.NoError(t, conn.Hello())
+
+	for i := range units {
+		units[i].Path = godbus.ObjectPath("/org/freedesktop/systemd1/unit/" + dbus.PathBusEscape(units[i].Name))
+		// systemd uses / when there is no job, an empty path is invalid
+		units[i].JobPath = "/"
+		unit := &fakeUnit{props: props[units[i].Name]}
+		require.NoError(t, conn.Export(unit, units[i].Path, "org.freedesktop.DBus.Properties"))
+	}
+	require.NoError(t, conn.Export(&fakeManager{units: units}, "/org/freedesktop/systemd1", "org.freedesktop.systemd1.Manager"))
+	reply, err := conn.RequestName("org.freedesktop.systemd1", godbus.NameFlagDoNotQueue)
+	require.NoError(t, err)
+	require.Equal(t, godbus.RequestNameReplyPrimaryOwner, reply)
+
+	return socket, func() {
+		conn.Close()
+		cleanup()
+	}
+}
+
+func TestSystemdCheck(t *testing.T) {
+	activeSince := uint64(time.Now().Add(-time.Hour).UnixNano() / int64(time.Microsecond))
+	socket, stop := startFakeSystemd(t, []fakeUnitStatus{
+		{Name: "nginx.service", LoadState: "loaded", ActiveState: "active", SubState: "running"},
+		{Name: "cron.service", LoadState: "loaded", ActiveState: "failed", SubState: "failed"},
+		{Name: "backup.timer", LoadState: "loaded", ActiveState: "activating", SubState: "waiting"},
+		{Name: "systemd-journald.service", LoadState: "loaded", ActiveState: "active", SubState: "running"},
+		{Name: "logrotate.service", LoadState: "loaded", ActiveState: "inactive", SubState: "dead"},
+		{Name: "postgresql.service", LoadState: "loaded", ActiveState: "inactive", SubState: "dead"},
+	}, map[string]map[string]map[string]godbus.Variant{
+		"nginx.service": {
+			"org.freedesktop.systemd1.Unit":    {"ActiveEnterTimestamp": godbus.MakeVariant(activeSince)},
+			"org.freedesktop.systemd1.Service": {"NRestarts": godbus.MakeVariant(uint32(3))},
+		},
+	})
+	defer stop()
+
+	systemdCheck := systemdFactory().(*SystemdCheck)
+	require.NoError(t, systemdCheck.Configure([]byte(fmt.Sprintf(`
+socket_path: %s
+unit_exclude: ["^systemd-"]
+required_units: ["postgresql.service"]
+uptime_units: ["nginx.service", "cron.service"]
+tags: ["env:test"]
+`, socket)), nil))
+	defer systemdCheck.Stop()
+
+	mockSender := mocksender.NewMockSender(systemdCheck.ID())
+	mockSender.SetupAcceptAll()
+	require.NoError(t, systemdCheck.Run())
+
+	mockSender.AssertServiceCheck(t, SystemdCanConnect, metrics.ServiceCheckOK, "", []string{"env:test"}, "")
+	mockSender.AssertServiceCheck(t, SystemdUnitState, metrics.ServiceCheckOK, "", []string{"unit:nginx.service", "env:test"}, "")
+	mockSender.AssertServiceCheck(t, SystemdUnitState, metrics.ServiceCheckCritical, "", []string{"unit:cron.service", "env:test"},
+		"Unit cron.service is failed (failed)")
+	mockSender.AssertServiceCheck(t, SystemdUnitState, metrics.ServiceCheckWarning, "", []string{"unit:backup.timer", "env:test"},
+		"Unit backup.timer is activating (waiting)")
+	mockSender.AssertNotCalled(t, "ServiceCheck", SystemdUnitState, mock.Anything, "", []string{"unit:systemd-journald.service", "env:test"}, mock.Anything)
+	// inactive units are only critical if required
+	mockSender.AssertServiceCheck(t, SystemdUnitState, metrics.ServiceCheckOK, "", []string{"unit:logrotate.service", "env:test"}, "")
+	mockSender.AssertServiceCheck(t, SystemdUnitState, metrics.ServiceCheckCritical, "", []string{"unit:postgresql.service", "env:test"},
+		"Unit postgresql.service is inactive (dead)")
+
+	mockSender.AssertMetric(t, "Gauge", "systemd.units.count", 1, "", []string{"active_state:active", "env:test"})
+	mockSender.AssertMetric(t, "Gauge", "systemd.units.count", 1, "", []string{"active_state:failed", "env:test"})
+	mockSender.AssertMetric(t, "Gauge", "systemd.units.count", 1, "", []string{"active_state:activating", "env:test"})
+	mockSender.AssertMetric(t, "Gauge", "systemd.units.count", 2, "", []string{"active_state:inactive", "env:test"})
+
+	// NRestarts is unknown for cron
+	mockSender.AssertMetric(t, "Gauge", "systemd.service.restarts", 3, "", []string{"unit:nginx.service", "env:test"})
+	mockSender.AssertNotCalled(t, "Gauge", "systemd.service.restarts", mock.Anything, "", []string{"unit:cron.service", "env:test"})
+
+	mockSender.AssertMetric(t, "Gauge", "systemd.unit.uptime", 0, "", []string{"unit:cron.service", "env:test"})
+	var uptime float64
+	for _, call := range mockSender.Calls {
+		if call.Method == "Gauge" && call.Arguments.String(0) == "systemd.unit.uptime" && call.Arguments.Get(3).([]string)[0] == "unit:nginx.service" {
+			uptime = call.Arguments.Get(1).(float64)
+		}
+	}
+	assert.InDelta(t, 3600, uptime, 60)
+
+	// 6 service checks, 6 states, 1 restart count and 2 uptimes
+	mockSender.AssertNumberOfCalls(t, "ServiceCheck", 6)
+	mockSender.AssertNumberOfCalls(t, "Gauge", 6+1+2)
+}
+
+func TestSystemdCheckCannotConnect(t *testing.T) {
+	systemdCheck := systemdFactory().(*SystemdCheck)
+	require.NoError(t, systemdCheck.Configure([]byte("socket_path: /nonexistent/bus"), nil))
+
+	mockSender := mocksender.NewMockSender(systemdCheck.ID())
+	mockSender.SetupAcceptAll()
+	assert.Error(t, systemdCheck.Run())
+	mockSender.AssertNumberOfCalls(t, "ServiceCheck", 1)
+	mockSender.AssertNotCalled(t, "Gauge", "systemd.units.count", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSystemdCheckConfigure(t *testing.T) {
+	systemdCheck := systemdFactory().(*SystemdCheck)
+	require.NoError(t, systemdCheck.Configure([]byte(`unit_include: ["\\.service$"]`), nil))
+	assert.Equal(t, defaultSocketPath, systemdCheck.cfg.SocketPath)
+	assert.True(t, systemdCheck.units.Keep("nginx.service"))
+	assert.False(t, systemdCheck.units.Keep("backup.timer"))
+
+	assert.Error(t, systemdCheck.Configure([]byte(`unit_exclude: ["("]`), nil))
+}
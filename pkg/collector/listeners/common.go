@@ -6,17 +6,23 @@
 package listeners
 
 import (
+	"strings"
+
 	"github.com/DataDog/datadog-agent/pkg/util/docker"
 	log "github.com/cihub/seelog"
 )
 
 const (
 	identifierLabel string = "io.datadog.check.id"
+
+	// labels set by the kubelet on the containers it runs
+	kubePodNameLabel      string = "io.kubernetes.pod.name"
+	kubePodNamespaceLabel string = "io.kubernetes.pod.namespace"
 )
 
-// ComputeContainerServiceIDs takes a container ID, an image (resolved to an actual name) and labels
-// and computes the service IDs for this container service.
-func ComputeContainerServiceIDs(cid string, image string, labels map[string]string) []string {
+// ComputeContainerServiceIDs takes a container entity name, an image (resolved to an actual name)
+// and labels and computes the service IDs for this container service.
+func ComputeContainerServiceIDs(entity string, image string, labels map[string]string) []string {
 	ids := []string{}
 
 	// check for an identifier label
@@ -29,7 +35,7 @@ func ComputeContainerServiceIDs(cid string, image string, labels map[string]stri
 	}
 
 	// add the container ID for templates in labels/annotations
-	ids = append(ids, docker.ContainerIDToEntityName(cid))
+	ids = append(ids, entity)
 
 	// add the image names (long then short if different)
 	long, short, _, err := docker.SplitImageName(image)
@@ -45,3 +51,16 @@ func ComputeContainerServiceIDs(cid string, image string, labels map[string]stri
 
 	return ids
 }
+
+// parseEnvList converts a list of KEY=VALUE strings to a map
+func parseEnvList(envs []string) map[string]string {
+	env := make(map[string]string, len(envs))
+	for _, e := range envs {
+		parts := strings.SplitN(e, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			continue
+		}
+		env[parts[0]] = parts[1]
+	}
+	return env
+}
@@ -3,12 +3,9 @@
 // This product includes software developed at Datadog (https://www.datadoghq.com/).
 // Copyright 2018 Datadog, Inc.
 
-// +build docker
-
 package listeners
 
 import (
-	"context"
 	"fmt"
 	"io"
 	"sort"
@@ -16,20 +13,21 @@ import (
 	"sync"
 
 	log "github.com/cihub/seelog"
-	"github.com/docker/docker/api/types"
-	"github.com/docker/go-connections/nat"
 
 	"github.com/DataDog/datadog-agent/cmd/agent/common/signals"
 	"github.com/DataDog/datadog-agent/pkg/tagger"
+	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
+	"github.com/DataDog/datadog-agent/pkg/util/container"
 	"github.com/DataDog/datadog-agent/pkg/util/docker"
 )
 
 // DockerListener implements the ServiceListener interface.
-// It listens for Docker events and reports container updates to Auto Discovery
+// It listens for container events of a container runtime (docker, or a CRI
+// runtime for the `cri` listener) and reports container updates to Auto Discovery
 // It also holds a cache of services that the ConfigResolver can query to
 // match templates against.
 type DockerListener struct {
-	dockerUtil *docker.DockerUtil
+	runtime    container.Runtime
 	services   map[ID]Service
 	newService chan<- Service
 	delService chan<- Service
@@ -44,23 +42,39 @@ type DockerService struct {
 	Hosts         map[string]string
 	Ports         []int
 	Pid           int
+	Env           map[string]string
+	Hostname      string
+	Labels        map[string]string
+	// runtime running the container, docker if nil
+	runtime container.Runtime
 }
 
 func init() {
 	Register("docker", NewDockerListener)
+	Register("cri", NewCRIListener)
 }
 
 // NewDockerListener creates a client connection to Docker and instantiate a DockerListener with it
 // TODO: TLS support
 func NewDockerListener() (ServiceListener, error) {
-	d, err := docker.GetDockerUtil()
+	return newRuntimeListener(container.DockerRuntimeName)
+}
+
+// NewCRIListener instantiates a DockerListener watching the containers of
+// the CRI runtime configured with `cri_socket_path`
+func NewCRIListener() (ServiceListener, error) {
+	return newRuntimeListener(container.CRIRuntimeName)
+}
+
+func newRuntimeListener(runtimeName string) (ServiceListener, error) {
+	rt, err := container.GetRuntime(runtimeName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to Docker, auto discovery will not work: %s", err)
+		return nil, fmt.Errorf("failed to connect to %s, auto discovery will not work: %s", runtimeName, err)
 	}
 	return &DockerListener{
-		dockerUtil: d,
-		services:   make(map[ID]Service),
-		stop:       make(chan bool),
+		runtime:  rt,
+		services: make(map[ID]Service),
+		stop:     make(chan bool),
 	}, nil
 }
 
@@ -73,9 +87,9 @@ func (l *DockerListener) Listen(newSvc chan<- Service, delSvc chan<- Service) {
 	// process containers that might be already running
 	l.init()
 
-	messages, errs, err := l.dockerUtil.SubscribeToContainerEvents("DockerListener")
+	messages, errs, err := l.runtime.SubscribeToEvents("DockerListener")
 	if err != nil {
-		log.Errorf("can't listen to docker events: %v", err)
+		log.Errorf("can't listen to %s events: %v", l.runtime.Name(), err)
 		signals.ErrorStopper <- true
 		return
 	}
@@ -84,13 +98,13 @@ func (l *DockerListener) Listen(newSvc chan<- Service, delSvc chan<- Service) {
 		for {
 			select {
 			case <-l.stop:
-				l.dockerUtil.UnsubscribeFromContainerEvents("DockerListener")
+				l.runtime.UnsubscribeFromEvents("DockerListener")
 				return
 			case msg := <-messages:
 				l.processEvent(msg)
 			case err := <-errs:
 				if err != nil && err != io.EOF {
-					log.Errorf("docker listener error: %v", err)
+					log.Errorf("%s listener error: %v", l.runtime.Name(), err)
 					signals.ErrorStopper <- true
 				}
 				return
@@ -104,14 +118,14 @@ func (l *DockerListener) Stop() {
 	l.stop <- true
 }
 
-// init looks at currently running Docker containers,
+// init looks at currently running containers,
 // creates services for them, and pass them to the ConfigResolver.
 // It is typically called at start up.
 func (l *DockerListener) init() {
 	l.m.Lock()
 	defer l.m.Unlock()
 
-	containers, err := l.dockerUtil.ContainerList(context.Background(), types.ContainerListOptions{})
+	containers, err := l.runtime.List(false)
 	if err != nil {
 		log.Errorf("Couldn't retrieve container list - %s", err)
 	}
@@ -125,6 +139,7 @@ func (l *DockerListener) init() {
 				DockerService: DockerService{
 					ID:            id,
 					ADIdentifiers: l.getConfigIDFromPs(co),
+					runtime:       l.runtime,
 					// Host and Ports will be looked up when needed
 				},
 			}
@@ -134,6 +149,8 @@ func (l *DockerListener) init() {
 				ADIdentifiers: l.getConfigIDFromPs(co),
 				Hosts:         l.getHostsFromPs(co),
 				Ports:         l.getPortsFromPs(co),
+				Labels:        co.Labels,
+				runtime:       l.runtime,
 			}
 		}
 		l.newService <- svc
@@ -156,7 +173,7 @@ func (l *DockerListener) GetServices() map[ID]Service {
 
 // processEvent takes a ContainerEvent, tries to find a service linked to it, and
 // figure out if the ConfigResolver could be interested to inspect it.
-func (l *DockerListener) processEvent(e *docker.ContainerEvent) {
+func (l *DockerListener) processEvent(e *container.Event) {
 	cID := ID(e.ContainerID)
 
 	l.m.RLock()
@@ -164,7 +181,7 @@ func (l *DockerListener) processEvent(e *docker.ContainerEvent) {
 	l.m.RUnlock()
 
 	if found {
-		if e.Action == "die" {
+		if e.Action == container.EventDie {
 			l.removeService(cID)
 		} else {
 			// FIXME sometimes the agent's container's events are picked up twice at startup
@@ -174,7 +191,7 @@ func (l *DockerListener) processEvent(e *docker.ContainerEvent) {
 	} else {
 		// we might receive a `die` event for an unrelated container we don't
 		// care about, let's ignore it.
-		if e.Action == "start" {
+		if e.Action == container.EventStart {
 			l.createService(cID)
 		}
 	}
@@ -186,19 +203,24 @@ func (l *DockerListener) createService(cID ID) {
 	var svc Service
 
 	// Detect whether that container is managed by Kubernetes
-	cInspect, err := l.dockerUtil.Inspect(string(cID), false)
+	var labels map[string]string
+	co, err := l.runtime.Inspect(string(cID))
 	if err != nil {
 		log.Errorf("Failed to inspect container %s - %s", cID[:12], err)
+	} else {
+		labels = co.Labels
 	}
-	if findKubernetesInLabels(cInspect.Config.Labels) {
+	if findKubernetesInLabels(labels) {
 		svc = &DockerKubeletService{
 			DockerService: DockerService{
-				ID: cID,
+				ID:      cID,
+				runtime: l.runtime,
 			},
 		}
 	} else {
 		svc = &DockerService{
-			ID: cID,
+			ID:      cID,
+			runtime: l.runtime,
 		}
 	}
 
@@ -259,23 +281,15 @@ func (l *DockerListener) removeService(cID ID) {
 // If the special label was not set, the priority order is the following:
 //   1. Long image name
 //   2. Short image name
-func (l *DockerListener) getConfigIDFromPs(co types.Container) []string {
-	image, err := l.dockerUtil.ResolveImageName(co.Image)
-	if err != nil {
-		log.Warnf("error while resolving image name: %s", err)
-	}
-	return ComputeContainerServiceIDs(co.ID, image, co.Labels)
+func (l *DockerListener) getConfigIDFromPs(co *container.Details) []string {
+	return ComputeContainerServiceIDs(co.EntityID, co.Image, co.Labels)
 }
 
 // getHostsFromPs gets the addresss (for now IP address only) of a container on all its networks.
-func (l *DockerListener) getHostsFromPs(co types.Container) map[string]string {
+func (l *DockerListener) getHostsFromPs(co *container.Details) map[string]string {
 	ips := make(map[string]string)
-	if co.NetworkSettings != nil {
-		for net, settings := range co.NetworkSettings.Networks {
-			if len(settings.IPAddress) > 0 {
-				ips[net] = settings.IPAddress
-			}
-		}
+	for net, ip := range co.Networks {
+		ips[net] = ip
 	}
 
 	rancherIP, found := docker.FindRancherIPInLabels(co.Labels)
@@ -286,15 +300,13 @@ func (l *DockerListener) getHostsFromPs(co types.Container) map[string]string {
 }
 
 // getPortsFromPs gets the service ports of a container.
-func (l *DockerListener) getPortsFromPs(co types.Container) []int {
-	// Nil array by default, we'll need to inspect the container
-	// later if we don't find any port in the PS
-	var ports []int
-
-	for _, p := range co.Ports {
-		ports = append(ports, int(p.PrivatePort))
+func (l *DockerListener) getPortsFromPs(co *container.Details) []int {
+	// Nil array if the runtime doesn't list the ports, we'll need to
+	// inspect the container later
+	if len(co.Ports) == 0 {
+		return nil
 	}
-	return ports
+	return append([]int{}, co.Ports...)
 }
 
 // GetID returns the service ID
@@ -315,19 +327,11 @@ func (s *DockerService) GetID() ID {
 //   2. Short image name
 func (s *DockerService) GetADIdentifiers() ([]string, error) {
 	if len(s.ADIdentifiers) == 0 {
-		du, err := docker.GetDockerUtil()
+		co, err := s.inspect()
 		if err != nil {
 			return []string{}, err
 		}
-		cj, err := du.Inspect(string(s.ID), false)
-		if err != nil {
-			return []string{}, err
-		}
-		image, err := du.ResolveImageName(cj.Image)
-		if err != nil {
-			log.Warnf("error while resolving image name: %s", err)
-		}
-		s.ADIdentifiers = ComputeContainerServiceIDs(string(s.ID), image, cj.Config.Labels)
+		s.ADIdentifiers = ComputeContainerServiceIDs(co.EntityID, co.Image, co.Labels)
 	}
 
 	return s.ADIdentifiers, nil
@@ -339,22 +343,16 @@ func (s *DockerService) GetHosts() (map[string]string, error) {
 		return s.Hosts, nil
 	}
 
-	ips := make(map[string]string)
-	du, err := docker.GetDockerUtil()
+	co, err := s.inspect()
 	if err != nil {
 		return nil, err
 	}
-	cInspect, err := du.Inspect(string(s.ID), false)
-	if err != nil {
-		return nil, fmt.Errorf("failed to inspect container %s", string(s.ID)[:12])
-	}
-	for net, settings := range cInspect.NetworkSettings.Networks {
-		if len(settings.IPAddress) > 0 {
-			ips[net] = settings.IPAddress
-		}
+	ips := make(map[string]string)
+	for net, ip := range co.Networks {
+		ips[net] = ip
 	}
 
-	rancherIP, found := docker.FindRancherIPInLabels(cInspect.Config.Labels)
+	rancherIP, found := docker.FindRancherIPInLabels(co.Labels)
 	if found {
 		ips["rancher"] = rancherIP
 	}
@@ -369,71 +367,26 @@ func (s *DockerService) GetPorts() ([]int, error) {
 		return s.Ports, nil
 	}
 
-	// Make a non-nil array to avoid re-running if we find zero port
-	ports := []int{}
-
-	du, err := docker.GetDockerUtil()
-	if err != nil {
-		return ports, err
-	}
-	cInspect, err := du.Inspect(string(s.ID), false)
+	co, err := s.inspect()
 	if err != nil {
-		return nil, fmt.Errorf("failed to inspect container %s", string(s.ID)[:12])
-	}
-
-	switch {
-	case cInspect.NetworkSettings != nil && len(cInspect.NetworkSettings.Ports) > 0:
-		for p := range cInspect.NetworkSettings.Ports {
-			out, err := parseDockerPort(p)
-			if err != nil {
-				log.Warn(err.Error())
-				continue
-			}
-			ports = append(ports, out...)
-		}
-	case cInspect.Config != nil && len(cInspect.Config.ExposedPorts) > 0:
-		log.Infof("using ExposedPorts for container %s as no port bindings are listed", string(s.ID)[:12])
-		for p := range cInspect.Config.ExposedPorts {
-			out, err := parseDockerPort(p)
-			if err != nil {
-				log.Warn(err.Error())
-				continue
-			}
-			ports = append(ports, out...)
-		}
+		// Make a non-nil array to avoid re-running if we find zero port
+		return []int{}, err
 	}
 
+	ports := append([]int{}, co.Ports...)
 	sort.Ints(ports)
 	s.Ports = ports
 	return ports, nil
 }
 
-func parseDockerPort(port nat.Port) ([]int, error) {
-	var output []int
-
-	// Try to parse a port range, eg. 22-25
-	first, last, err := port.Range()
-	if err == nil && last > first {
-		for p := first; p <= last; p++ {
-			output = append(output, p)
-		}
-		return output, nil
-	}
-
-	// Try to parse a single port (most common case)
-	p := port.Int()
-	if p > 0 {
-		output = append(output, p)
-		return output, nil
-	}
-
-	return output, fmt.Errorf("failed to extract port from: %v", port)
-}
-
 // GetTags retrieves tags using the Tagger
 func (s *DockerService) GetTags() ([]string, error) {
+	// building the entity name doesn't need a connection to the runtime
 	entity := docker.ContainerIDToEntityName(string(s.ID))
-	tags, err := tagger.Tag(entity, false)
+	if s.runtime != nil {
+		entity = s.runtime.ContainerIDToEntityName(string(s.ID))
+	}
+	tags, err := tagger.Tag(entity, collectors.LowCardinality)
 	if err != nil {
 		return []string{}, err
 	}
@@ -445,20 +398,110 @@ func (s *DockerService) GetTags() ([]string, error) {
 func (s *DockerService) GetPid() (int, error) {
 	// Try to inspect container to get the pid if not defined
 	if s.Pid <= 0 {
-		du, err := docker.GetDockerUtil()
-		if err != nil {
-			return -1, err
-		}
-		cj, err := du.Inspect(string(s.ID), false)
+		co, err := s.inspect()
 		if err != nil {
 			return -1, err
 		}
-		s.Pid = cj.State.Pid
+		s.Pid = co.Pid
 	}
 
 	return s.Pid, nil
 }
 
+// GetEnv returns the environment variables of the container
+func (s *DockerService) GetEnv() (map[string]string, error) {
+	if s.Env != nil {
+		return s.Env, nil
+	}
+
+	co, err := s.inspect()
+	if err != nil {
+		return nil, err
+	}
+
+	s.Env = parseEnvList(co.Env)
+	return s.Env, nil
+}
+
+// GetHostname returns the hostname of the container
+func (s *DockerService) GetHostname() (string, error) {
+	if s.Hostname != "" {
+		return s.Hostname, nil
+	}
+
+	co, err := s.inspect()
+	if err != nil {
+		return "", err
+	}
+
+	s.Hostname = co.Hostname
+	return s.Hostname, nil
+}
+
+// GetLabels returns the labels of the container
+func (s *DockerService) GetLabels() (map[string]string, error) {
+	if s.Labels != nil {
+		return s.Labels, nil
+	}
+
+	co, err := s.inspect()
+	if err != nil {
+		return nil, err
+	}
+
+	s.Labels = co.Labels
+	if s.Labels == nil {
+		// Make a non-nil map to avoid re-running if we find zero label
+		s.Labels = make(map[string]string)
+	}
+	return s.Labels, nil
+}
+
+// getRuntime returns the runtime running the container
+func (s *DockerService) getRuntime() (container.Runtime, error) {
+	if s.runtime != nil {
+		return s.runtime, nil
+	}
+	return container.GetRuntime(container.DockerRuntimeName)
+}
+
+// inspect returns the details of the container
+func (s *DockerService) inspect() (*container.Details, error) {
+	rt, err := s.getRuntime()
+	if err != nil {
+		return nil, err
+	}
+	co, err := rt.Inspect(string(s.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect container %s: %s", s.ID, err)
+	}
+	return co, nil
+}
+
+// GetKubeNamespace returns the namespace of the pod, based on the labels
+// the kubelet sets on the containers it runs
+func (s *DockerService) GetKubeNamespace() (string, error) {
+	return s.getLabel(kubePodNamespaceLabel)
+}
+
+// GetKubePodName returns the name of the pod, based on the labels the
+// kubelet sets on the containers it runs
+func (s *DockerService) GetKubePodName() (string, error) {
+	return s.getLabel(kubePodNameLabel)
+}
+
+func (s *DockerService) getLabel(name string) (string, error) {
+	labels, err := s.GetLabels()
+	if err != nil {
+		return "", err
+	}
+	value, found := labels[name]
+	if !found {
+		return "", ErrNotSupported
+	}
+	return value, nil
+}
+
 // findKubernetesInLabels traverses a map of container labels and
 // returns true if a kubernetes label is detected
 func findKubernetesInLabels(labels map[string]string) bool {
@@ -3,7 +3,7 @@
 // This product includes software developed at Datadog (https://www.datadoghq.com/).
 // Copyright 2018 Datadog, Inc.
 
-// +build docker,kubelet
+// +build kubelet
 
 package listeners
 
@@ -15,7 +15,6 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/DataDog/datadog-agent/pkg/util/docker"
 	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/kubelet"
 )
 
@@ -37,7 +36,11 @@ func (s *DockerKubeletService) getPod() (*kubelet.Pod, error) {
 			return nil, err
 		}
 	}
-	searchedId := docker.ContainerIDToEntityName(string(s.GetID()))
+	rt, err := s.getRuntime()
+	if err != nil {
+		return nil, err
+	}
+	searchedId := rt.ContainerIDToEntityName(string(s.GetID()))
 	return s.kubeUtil.GetPodForContainerID(searchedId)
 }
 
@@ -8,8 +8,6 @@
 package listeners
 
 import (
-	"errors"
-	"fmt"
 	"os"
 	"testing"
 	"time"
@@ -19,9 +17,9 @@ import (
 	"github.com/docker/docker/api/types/network"
 	"github.com/docker/go-connections/nat"
 	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/require"
 
 	"github.com/DataDog/datadog-agent/pkg/util/cache"
+	ddcontainer "github.com/DataDog/datadog-agent/pkg/util/container"
 	"github.com/DataDog/datadog-agent/pkg/util/docker"
 )
 
@@ -34,52 +32,57 @@ func TestMain(m *testing.M) {
 }
 
 func TestGetConfigIDFromPs(t *testing.T) {
-	co := types.Container{
-		ID:    "deadbeef",
-		Image: "test",
+	co := &ddcontainer.Details{
+		ID:       "deadbeef",
+		EntityID: "docker://deadbeef",
+		Image:    "test",
 	}
 	dl := DockerListener{}
 
 	ids := dl.getConfigIDFromPs(co)
 	assert.Equal(t, []string{"docker://deadbeef", "test"}, ids)
 
-	prefixCo := types.Container{
-		ID:    "deadbeef",
-		Image: "org/test",
+	prefixCo := &ddcontainer.Details{
+		ID:       "deadbeef",
+		EntityID: "docker://deadbeef",
+		Image:    "org/test",
 	}
 	ids = dl.getConfigIDFromPs(prefixCo)
 	assert.Equal(t, []string{"docker://deadbeef", "org/test", "test"}, ids)
 
-	labeledCo := types.Container{
-		ID:     "deadbeef",
-		Image:  "test",
-		Labels: map[string]string{"io.datadog.check.id": "w00tw00t"},
+	labeledCo := &ddcontainer.Details{
+		ID:       "deadbeef",
+		EntityID: "docker://deadbeef",
+		Image:    "test",
+		Labels:   map[string]string{"io.datadog.check.id": "w00tw00t"},
 	}
 	ids = dl.getConfigIDFromPs(labeledCo)
 	assert.Equal(t, []string{"w00tw00t"}, ids)
+
+	criCo := &ddcontainer.Details{
+		ID:       "deadbeef",
+		EntityID: "containerd://deadbeef",
+		Image:    "docker.io/library/redis:4.0",
+	}
+	ids = dl.getConfigIDFromPs(criCo)
+	assert.Equal(t, []string{"containerd://deadbeef", "docker.io/library/redis", "redis"}, ids)
 }
 
 func TestGetHostsFromPs(t *testing.T) {
 	dl := DockerListener{}
 
-	co := types.Container{
+	co := &ddcontainer.Details{
 		ID:    "foo",
 		Image: "test",
 	}
 
 	assert.Empty(t, dl.getHostsFromPs(co))
 
-	nets := make(map[string]*network.EndpointSettings)
-	nets["bridge"] = &network.EndpointSettings{IPAddress: "172.17.0.2"}
-	nets["foo"] = &network.EndpointSettings{IPAddress: "172.17.0.3"}
-	networkSettings := types.SummaryNetworkSettings{
-		Networks: nets}
-
-	co = types.Container{
-		ID:              "deadbeef",
-		Image:           "test",
-		NetworkSettings: &networkSettings,
-		Ports:           []types.Port{{PrivatePort: 1337}, {PrivatePort: 42}},
+	co = &ddcontainer.Details{
+		ID:       "deadbeef",
+		Image:    "test",
+		Networks: map[string]string{"bridge": "172.17.0.2", "foo": "172.17.0.3"},
+		Ports:    []int{1337, 42},
 	}
 	hosts := dl.getHostsFromPs(co)
 
@@ -91,23 +94,18 @@ func TestGetHostsFromPs(t *testing.T) {
 func TestGetRancherIPFromPs(t *testing.T) {
 	dl := DockerListener{}
 
-	co := types.Container{
+	co := &ddcontainer.Details{
 		ID:    "foo",
 		Image: "test",
 	}
 
 	assert.Empty(t, dl.getHostsFromPs(co))
 
-	nets := make(map[string]*network.EndpointSettings)
-	nets["none"] = &network.EndpointSettings{}
-	networkSettings := types.SummaryNetworkSettings{
-		Networks: nets}
-
-	co = types.Container{
-		ID:              "deadbeef",
-		Image:           "test",
-		NetworkSettings: &networkSettings,
-		Ports:           []types.Port{{PrivatePort: 1337}, {PrivatePort: 42}},
+	co = &ddcontainer.Details{
+		ID:       "deadbeef",
+		Image:    "test",
+		Networks: map[string]string{},
+		Ports:    []int{1337, 42},
 		Labels: map[string]string{
 			"io.rancher.container.ip": "10.42.90.224/16",
 		},
@@ -121,18 +119,18 @@ func TestGetRancherIPFromPs(t *testing.T) {
 func TestGetPortsFromPs(t *testing.T) {
 	dl := DockerListener{}
 
-	co := types.Container{
+	co := &ddcontainer.Details{
 		ID:    "foo",
 		Image: "test",
 	}
 	assert.Empty(t, dl.getPortsFromPs(co))
 	assert.Nil(t, dl.getPortsFromPs(co)) // return must be nil to trigger GetPorts on resolution
 
-	co.Ports = make([]types.Port, 0)
+	co.Ports = make([]int, 0)
 	assert.Empty(t, dl.getPortsFromPs(co))
 
-	co.Ports = append(co.Ports, types.Port{PrivatePort: 1234})
-	co.Ports = append(co.Ports, types.Port{PrivatePort: 4321})
+	co.Ports = append(co.Ports, 1234)
+	co.Ports = append(co.Ports, 4321)
 	ports := dl.getPortsFromPs(co)
 	assert.Equal(t, 2, len(ports))
 	assert.Contains(t, ports, 1234)
@@ -401,48 +399,3 @@ func TestGetPid(t *testing.T) {
 	assert.Equal(t, 1337, pid)
 	assert.Nil(t, err)
 }
-
-func TestParseDockerPort(t *testing.T) {
-	testCases := []struct {
-		proto         string
-		port          string
-		expectedPorts []int
-		expectedError error
-	}{
-		{
-			proto:         "tcp",
-			port:          "42",
-			expectedPorts: []int{42},
-			expectedError: nil,
-		},
-		{
-			proto:         "udp",
-			port:          "500-503",
-			expectedPorts: []int{500, 501, 502, 503},
-			expectedError: nil,
-		},
-		{
-			proto:         "tcp",
-			port:          "0",
-			expectedPorts: nil,
-			expectedError: errors.New("failed to extract port from: 0/tcp"),
-		},
-	}
-
-	for i, test := range testCases {
-		t.Run(fmt.Sprintf("case %d: %s/%s", i, test.port, test.proto), func(t *testing.T) {
-			p, err := nat.NewPort(test.proto, test.port)
-			assert.Nil(t, err)
-
-			ports, err := parseDockerPort(p)
-			if test.expectedError == nil {
-				assert.Nil(t, err)
-			} else {
-				require.NotNil(t, err)
-				assert.Equal(t, test.expectedError.Error(), err.Error())
-			}
-
-			assert.Equal(t, test.expectedPorts, ports)
-		})
-	}
-}
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/tagger"
+	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
 	"github.com/DataDog/datadog-agent/pkg/util/docker"
 	"github.com/DataDog/datadog-agent/pkg/util/ecs"
 	log "github.com/cihub/seelog"
@@ -38,6 +39,7 @@ type ECSService struct {
 	Ports         []int
 	Pid           int
 	Tags          []string
+	Labels        map[string]string
 	clusterName   string
 	taskFamily    string
 	taskVersion   string
@@ -143,7 +145,8 @@ func (l *ECSListener) createService(c ecs.Container) (ECSService, error) {
 	// ADIdentifiers
 	image := c.Image
 	labels := c.Labels
-	svc.ADIdentifiers = ComputeContainerServiceIDs(c.DockerID, image, labels)
+	svc.ADIdentifiers = ComputeContainerServiceIDs(docker.ContainerIDToEntityName(c.DockerID), image, labels)
+	svc.Labels = labels
 
 	// Host
 	ips := make(map[string]string)
@@ -157,7 +160,7 @@ func (l *ECSListener) createService(c ecs.Container) (ECSService, error) {
 
 	// Tags
 	entity := docker.ContainerIDToEntityName(string(c.DockerID))
-	tags, err := tagger.Tag(entity, false)
+	tags, err := tagger.Tag(entity, collectors.LowCardinality)
 	if err != nil {
 		log.Errorf("Failed to extract tags for container %s - %s", cID[:12], err)
 	}
@@ -211,3 +214,28 @@ func (s *ECSService) GetTags() ([]string, error) {
 func (s *ECSService) GetPid() (int, error) {
 	return -1, ErrNotSupported
 }
+
+// GetEnv returns nil and an error because environment variables are not in the metadata api
+func (s *ECSService) GetEnv() (map[string]string, error) {
+	return nil, ErrNotSupported
+}
+
+// GetHostname returns nil and an error because the hostname is not in the metadata api
+func (s *ECSService) GetHostname() (string, error) {
+	return "", ErrNotSupported
+}
+
+// GetLabels returns the container's labels
+func (s *ECSService) GetLabels() (map[string]string, error) {
+	return s.Labels, nil
+}
+
+// GetKubeNamespace returns an error because kubernetes is not supported in Fargate-based ECS
+func (s *ECSService) GetKubeNamespace() (string, error) {
+	return "", ErrNotSupported
+}
+
+// GetKubePodName returns an error because kubernetes is not supported in Fargate-based ECS
+func (s *ECSService) GetKubePodName() (string, error) {
+	return "", ErrNotSupported
+}
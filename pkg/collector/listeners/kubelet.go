@@ -9,10 +9,12 @@ package listeners
 
 import (
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/tagger"
+	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
 	"github.com/DataDog/datadog-agent/pkg/util/docker"
 	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/kubelet"
 
@@ -36,6 +38,13 @@ type PodContainerService struct {
 	ADIdentifiers []string
 	Hosts         map[string]string
 	Ports         []int
+	Env           map[string]string
+	UnresolvedEnv map[string]bool
+	EnvFrom       bool
+	Hostname      string
+	Labels        map[string]string
+	Namespace     string
+	PodName       string
 }
 
 func init() {
@@ -85,6 +94,10 @@ func (l *KubeletListener) Listen(newSvc chan<- Service, delSvc chan<- Service) {
 					continue
 				}
 				for _, containerID := range expiredContainerList {
+					if strings.HasPrefix(containerID, kubelet.KubePodPrefix) {
+						// pods are not services
+						continue
+					}
 					l.removeService(ID(containerID))
 				}
 			}
@@ -105,10 +118,19 @@ func (l *KubeletListener) processNewPod(pod *kubelet.Pod) {
 
 func (l *KubeletListener) createService(id ID, pod *kubelet.Pod) {
 	svc := PodContainerService{
-		ID: id,
+		ID:        id,
+		Labels:    pod.Metadata.Labels,
+		Namespace: pod.Metadata.Namespace,
+		PodName:   pod.Metadata.Name,
 	}
 	podName := pod.Metadata.Name
 
+	// Hostname, defaults to the pod name like in the pod's containers
+	svc.Hostname = pod.Spec.Hostname
+	if svc.Hostname == "" {
+		svc.Hostname = podName
+	}
+
 	// AD Identifiers
 	var containerName string
 	for _, container := range pod.Status.Containers {
@@ -133,16 +155,32 @@ func (l *KubeletListener) createService(id ID, pod *kubelet.Pod) {
 	}
 	svc.Hosts = map[string]string{"pod": podIp}
 
-	// Ports
+	// Ports and Env
 	var ports []int
+	env := make(map[string]string)
+	unresolvedEnv := make(map[string]bool)
 	for _, container := range pod.Spec.Containers {
 		if container.Name == containerName {
 			for _, port := range container.Ports {
 				ports = append(ports, port.ContainerPort)
 			}
+			// only literal values are available, the pod spec doesn't
+			// hold the values of valueFrom references and envFrom sources
+			for _, e := range container.Env {
+				if e.ValueFrom != nil {
+					unresolvedEnv[e.Name] = true
+					delete(env, e.Name)
+				} else {
+					env[e.Name] = e.Value
+					delete(unresolvedEnv, e.Name)
+				}
+			}
+			svc.EnvFrom = len(container.EnvFrom) > 0
 			break
 		}
 	}
+	svc.Env = env
+	svc.UnresolvedEnv = unresolvedEnv
 	svc.Ports = ports
 	if len(svc.Ports) == 0 {
 		// Port might not be specified in pod spec
@@ -199,5 +237,42 @@ func (s *PodContainerService) GetPorts() ([]int, error) {
 
 // GetTags retrieves tags using the Tagger
 func (s *PodContainerService) GetTags() ([]string, error) {
-	return tagger.Tag(string(s.ID), false)
+	return tagger.Tag(string(s.ID), collectors.LowCardinality)
+}
+
+// GetEnv returns the literal environment variables declared in the container spec
+func (s *PodContainerService) GetEnv() (map[string]string, error) {
+	return s.Env, nil
+}
+
+// GetEnvResolutionError returns an error for the variables set from a
+// valueFrom reference, or that an envFrom source may set
+func (s *PodContainerService) GetEnvResolutionError(name string) error {
+	if s.UnresolvedEnv[name] {
+		return fmt.Errorf("%s is set from a valueFrom reference, which is not resolved in the pod spec", name)
+	}
+	if s.EnvFrom {
+		return fmt.Errorf("%s may be set by an envFrom source, which is not resolved in the pod spec", name)
+	}
+	return nil
+}
+
+// GetHostname returns the hostname of the pod
+func (s *PodContainerService) GetHostname() (string, error) {
+	return s.Hostname, nil
+}
+
+// GetLabels returns the labels of the pod
+func (s *PodContainerService) GetLabels() (map[string]string, error) {
+	return s.Labels, nil
+}
+
+// GetKubeNamespace returns the namespace of the pod
+func (s *PodContainerService) GetKubeNamespace() (string, error) {
+	return s.Namespace, nil
+}
+
+// GetKubePodName returns the name of the pod
+func (s *PodContainerService) GetKubePodName() (string, error) {
+	return s.PodName, nil
 }
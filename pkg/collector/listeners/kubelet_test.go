@@ -33,6 +33,11 @@ func getMockedPod() *kubelet.Pod {
 					Protocol:      "UDP",
 				},
 			},
+			Env: []kubelet.EnvVar{
+				{Name: "FOO_MODE", Value: "fast"},
+				{Name: "FOO_EMPTY"},
+				{Name: "FOO_SECRET", ValueFrom: &kubelet.EnvVarSource{}},
+			},
 		},
 		{
 			Name:  "bar",
@@ -74,7 +79,9 @@ func getMockedPod() *kubelet.Pod {
 		Spec:   kubeletSpec,
 		Status: kubeletStatus,
 		Metadata: kubelet.PodMetadata{
-			Name: "mock-pod",
+			Name:      "mock-pod",
+			Namespace: "mock-namespace",
+			Labels:    map[string]string{"app": "mock"},
 		},
 	}
 }
@@ -101,6 +108,25 @@ func TestProcessNewPod(t *testing.T) {
 		assert.Equal(t, []int{1337, 1339}, ports)
 		_, err = service.GetPid()
 		assert.Equal(t, ErrNotSupported, err)
+		env, err := service.GetEnv()
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]string{"FOO_MODE": "fast", "FOO_EMPTY": ""}, env)
+		partial, ok := service.(PartialEnvService)
+		assert.True(t, ok)
+		assert.Error(t, partial.GetEnvResolutionError("FOO_SECRET"))
+		assert.NoError(t, partial.GetEnvResolutionError("FOO_UNKNOWN"))
+		hostname, err := service.GetHostname()
+		assert.Nil(t, err)
+		assert.Equal(t, "mock-pod", hostname)
+		labels, err := service.GetLabels()
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]string{"app": "mock"}, labels)
+		namespace, err := service.GetKubeNamespace()
+		assert.Nil(t, err)
+		assert.Equal(t, "mock-namespace", namespace)
+		podName, err := service.GetKubePodName()
+		assert.Nil(t, err)
+		assert.Equal(t, "mock-pod", podName)
 	default:
 		t.FailNow()
 	}
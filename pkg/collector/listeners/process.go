@@ -0,0 +1,521 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build linux
+
+package listeners
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	log "github.com/cihub/seelog"
+)
+
+const (
+	// processIdentifierEnv can be set in the environment of a process to
+	// override the AD identifiers computed from its command name
+	processIdentifierEnv = "DD_CHECK_ID"
+	// tcpListenState is the hex code of the LISTEN state in /proc/net/tcp
+	tcpListenState = "0A"
+)
+
+// processPattern maps a command line regex to an AD identifier
+type processPattern struct {
+	identifier string
+	regex      *regexp.Regexp
+}
+
+// ProcessListener implements the ServiceListener interface for processes
+// running directly on the host. It periodically scans procfs for processes
+// holding listening TCP sockets and reports them as services.
+type ProcessListener struct {
+	procRoot   string
+	patterns   []processPattern
+	services   map[ID]Service
+	newService chan<- Service
+	delService chan<- Service
+	stop       chan bool
+	m          sync.RWMutex
+	t          *time.Ticker
+}
+
+// ProcessService implements and store results from the Service interface for the process listener
+type ProcessService struct {
+	ID            ID
+	ADIdentifiers []string
+	Hosts         map[string]string
+	Ports         []int
+	Pid           int
+	Tags          []string
+	Env           map[string]string
+}
+
+// listeningSocket holds the address of a listening socket found in /proc/net/tcp{,6}
+type listeningSocket struct {
+	ip   string
+	port int
+}
+
+func init() {
+	Register("process", NewProcessListener)
+}
+
+// NewProcessListener creates a ProcessListener
+func NewProcessListener() (ServiceListener, error) {
+	procRoot := config.Datadog.GetString("procfs_path")
+	if procRoot == "" {
+		procRoot = "/proc"
+	}
+
+	var rawPatterns []config.ProcessADPattern
+	if err := config.Datadog.UnmarshalKey("ad_process_patterns", &rawPatterns); err != nil {
+		return nil, fmt.Errorf("unable to parse ad_process_patterns: %s", err)
+	}
+	patterns, err := compileProcessPatterns(rawPatterns)
+	if err != nil {
+		return nil, err
+	}
+
+	interval := config.Datadog.GetInt("ad_process_poll_interval")
+	if interval <= 0 {
+		interval = 10
+	}
+
+	return newProcessListener(procRoot, patterns, time.Duration(interval)*time.Second), nil
+}
+
+func newProcessListener(procRoot string, patterns []processPattern, interval time.Duration) *ProcessListener {
+	return &ProcessListener{
+		procRoot: procRoot,
+		patterns: patterns,
+		services: make(map[ID]Service),
+		stop:     make(chan bool),
+		t:        time.NewTicker(interval),
+	}
+}
+
+func compileProcessPatterns(raw []config.ProcessADPattern) ([]processPattern, error) {
+	patterns := make([]processPattern, 0, len(raw))
+	for _, p := range raw {
+		if p.ADIdentifier == "" {
+			return nil, fmt.Errorf("missing ad_identifier for process pattern %q", p.CommandRegex)
+		}
+		re, err := regexp.Compile(p.CommandRegex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid command_regex for %s: %s", p.ADIdentifier, err)
+		}
+		patterns = append(patterns, processPattern{identifier: p.ADIdentifier, regex: re})
+	}
+	return patterns, nil
+}
+
+// Listen scans procfs at startup then periodically, and reports the
+// processes listening on a TCP port as Services.
+func (l *ProcessListener) Listen(newSvc chan<- Service, delSvc chan<- Service) {
+	// setup the I/O channels
+	l.newService = newSvc
+	l.delService = delSvc
+
+	go func() {
+		l.refreshServices()
+		for {
+			select {
+			case <-l.t.C:
+				l.refreshServices()
+			case <-l.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop queues a shutdown of ProcessListener
+func (l *ProcessListener) Stop() {
+	l.t.Stop()
+	l.stop <- true
+}
+
+// refreshServices scans procfs, compares the processes found with the local
+// cache and sends new/dead services over newService and delService accordingly
+func (l *ProcessListener) refreshServices() {
+	current, err := l.scanProcesses()
+	if err != nil {
+		log.Errorf("failed to scan processes, not refreshing services - %s", err)
+		return
+	}
+
+	l.m.Lock()
+	var added, removed []Service
+	for id, svc := range current {
+		if _, found := l.services[id]; !found {
+			l.services[id] = svc
+			added = append(added, svc)
+		}
+	}
+	for id, svc := range l.services {
+		if _, found := current[id]; !found {
+			delete(l.services, id)
+			removed = append(removed, svc)
+		}
+	}
+	l.m.Unlock()
+
+	for _, svc := range removed {
+		l.delService <- svc
+	}
+	for _, svc := range added {
+		l.newService <- svc
+	}
+}
+
+// processSockets holds the inodes of the listening sockets of a process
+type processSockets struct {
+	pid       int
+	startTime uint64
+	inodes    []string
+}
+
+// scanProcesses returns a service for every process holding at least one
+// listening socket, indexed by service ID. Pre-fork servers share their
+// listening sockets between the parent and the workers, each socket is only
+// reported for the oldest process holding it.
+func (l *ProcessListener) scanProcesses() (map[ID]Service, error) {
+	sockets, err := l.listeningSockets()
+	if err != nil {
+		return nil, err
+	}
+	services := make(map[ID]Service)
+	if len(sockets) == 0 {
+		return services, nil
+	}
+
+	entries, err := ioutil.ReadDir(l.procRoot)
+	if err != nil {
+		return nil, err
+	}
+	var procs []processSockets
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		inodes := l.socketInodes(pid, sockets)
+		if len(inodes) > 0 {
+			procs = append(procs, processSockets{pid: pid, startTime: l.startTime(pid), inodes: inodes})
+		}
+	}
+	sort.Slice(procs, func(i, j int) bool {
+		if procs[i].startTime != procs[j].startTime {
+			return procs[i].startTime < procs[j].startTime
+		}
+		return procs[i].pid < procs[j].pid
+	})
+
+	reported := make(map[string]bool)
+	for _, proc := range procs {
+		var owned []listeningSocket
+		for _, inode := range proc.inodes {
+			if !reported[inode] {
+				reported[inode] = true
+				owned = append(owned, sockets[inode])
+			}
+		}
+		if len(owned) == 0 {
+			continue
+		}
+		svc := l.createService(proc.pid, owned)
+		if svc != nil {
+			services[svc.ID] = svc
+		}
+	}
+	return services, nil
+}
+
+// socketInodes returns the inodes of the listening sockets held by a process
+func (l *ProcessListener) socketInodes(pid int, sockets map[string]listeningSocket) []string {
+	fdDir := filepath.Join(l.procRoot, strconv.Itoa(pid), "fd")
+	fds, err := ioutil.ReadDir(fdDir)
+	if err != nil {
+		// process exited or we lack the permissions, skip it
+		return nil
+	}
+
+	var inodes []string
+	seen := make(map[string]bool)
+	for _, fd := range fds {
+		target, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+		if err != nil || !strings.HasPrefix(target, "socket:[") {
+			continue
+		}
+		inode := strings.TrimSuffix(strings.TrimPrefix(target, "socket:["), "]")
+		if _, found := sockets[inode]; found && !seen[inode] {
+			seen[inode] = true
+			inodes = append(inodes, inode)
+		}
+	}
+	return inodes
+}
+
+// startTime returns the start time of a process in clock ticks since boot,
+// read from the 22nd field of /proc/<pid>/stat. Processes of unknown start
+// time are sorted last.
+func (l *ProcessListener) startTime(pid int) uint64 {
+	raw, err := ioutil.ReadFile(filepath.Join(l.procRoot, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return math.MaxUint64
+	}
+	// the command name in the 2nd field can contain spaces and parentheses
+	stat := string(raw)
+	fields := strings.Fields(stat[strings.LastIndex(stat, ")")+1:])
+	if len(fields) < 20 {
+		return math.MaxUint64
+	}
+	startTime, err := strconv.ParseUint(fields[19], 10, 64)
+	if err != nil {
+		return math.MaxUint64
+	}
+	return startTime
+}
+
+// createService builds a ProcessService for the given pid and listening
+// sockets, or returns nil if the process can't be identified
+func (l *ProcessListener) createService(pid int, sockets []listeningSocket) *ProcessService {
+	portSet := make(map[int]struct{})
+	for _, sock := range sockets {
+		portSet[sock.port] = struct{}{}
+	}
+	ports := make([]int, 0, len(portSet))
+	for p := range portSet {
+		ports = append(ports, p)
+	}
+	sort.Ints(ports)
+
+	env := l.readEnviron(pid)
+	ids := l.computeProcessIdentifiers(pid, env)
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return &ProcessService{
+		ID:            ID(fmt.Sprintf("process://%d", pid)),
+		ADIdentifiers: ids,
+		Hosts:         map[string]string{"host": hostAddress(sockets)},
+		Ports:         ports,
+		Pid:           pid,
+		Tags:          []string{},
+		Env:           env,
+	}
+}
+
+// hostAddress returns the address of the lowest listening port, preferring
+// IPv4 addresses that templates can use as is, unlike IPv6 ones which need
+// brackets in URLs
+func hostAddress(sockets []listeningSocket) string {
+	best := sockets[0]
+	for _, sock := range sockets[1:] {
+		bestV4, sockV4 := isIPv4(best.ip), isIPv4(sock.ip)
+		switch {
+		case sockV4 != bestV4:
+			if sockV4 {
+				best = sock
+			}
+		case sock.port != best.port:
+			if sock.port < best.port {
+				best = sock
+			}
+		case sock.ip < best.ip:
+			best = sock
+		}
+	}
+	return best.ip
+}
+
+func isIPv4(ip string) bool {
+	parsed := net.ParseIP(ip)
+	return parsed != nil && parsed.To4() != nil
+}
+
+// readEnviron returns the environment variables of a process
+func (l *ProcessListener) readEnviron(pid int) map[string]string {
+	raw, err := ioutil.ReadFile(filepath.Join(l.procRoot, strconv.Itoa(pid), "environ"))
+	if err != nil {
+		return map[string]string{}
+	}
+	return parseEnvList(strings.Split(string(raw), "\x00"))
+}
+
+// computeProcessIdentifiers returns the AD identifiers of a process.
+// The priority order is the following:
+//   1. the value of the DD_CHECK_ID environment variable of the process
+//   2. the identifiers of the ad_process_patterns matching its command line
+//   3. the command name
+func (l *ProcessListener) computeProcessIdentifiers(pid int, env map[string]string) []string {
+	pidDir := filepath.Join(l.procRoot, strconv.Itoa(pid))
+
+	if id := env[processIdentifierEnv]; id != "" {
+		return []string{id}
+	}
+
+	var ids []string
+	if len(l.patterns) > 0 {
+		if raw, err := ioutil.ReadFile(filepath.Join(pidDir, "cmdline")); err == nil {
+			cmdline := strings.TrimSpace(string(bytes.Replace(raw, []byte{0}, []byte{' '}, -1)))
+			for _, p := range l.patterns {
+				if p.regex.MatchString(cmdline) {
+					ids = append(ids, p.identifier)
+				}
+			}
+		}
+	}
+
+	if comm, err := ioutil.ReadFile(filepath.Join(pidDir, "comm")); err == nil {
+		if name := strings.TrimSpace(string(comm)); name != "" {
+			ids = append(ids, name)
+		}
+	}
+	return ids
+}
+
+// listeningSockets parses /proc/net/tcp and /proc/net/tcp6 and returns the
+// sockets in LISTEN state, indexed by inode
+func (l *ProcessListener) listeningSockets() (map[string]listeningSocket, error) {
+	sockets := make(map[string]listeningSocket)
+	found := false
+	for _, name := range []string{"tcp", "tcp6"} {
+		f, err := os.Open(filepath.Join(l.procRoot, "net", name))
+		if err != nil {
+			continue
+		}
+		found = true
+		parseListeningSockets(f, sockets)
+		f.Close()
+	}
+	if !found {
+		return nil, fmt.Errorf("no tcp socket table found in %s", filepath.Join(l.procRoot, "net"))
+	}
+	return sockets, nil
+}
+
+// parseListeningSockets reads a /proc/net/tcp formatted table:
+//
+//  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+//   0: 00000000:1F90 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 12345 ...
+func parseListeningSockets(f *os.File, sockets map[string]listeningSocket) {
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // skip header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[3] != tcpListenState {
+			continue
+		}
+		ip, port, err := parseHexAddress(fields[1])
+		if err != nil {
+			log.Debugf("unable to parse address %s: %s", fields[1], err)
+			continue
+		}
+		sockets[fields[9]] = listeningSocket{ip: ip, port: port}
+	}
+}
+
+// parseHexAddress decodes an IP:port address in the little-endian hex
+// format used by /proc/net/tcp{,6}. Wildcard addresses are resolved to the
+// loopback address so that checks can connect to them.
+func parseHexAddress(addr string) (string, int, error) {
+	parts := strings.Split(addr, ":")
+	if len(parts) != 2 {
+		return "", 0, fmt.Errorf("invalid address")
+	}
+	port, err := strconv.ParseUint(parts[1], 16, 16)
+	if err != nil {
+		return "", 0, err
+	}
+	raw, err := hex.DecodeString(parts[0])
+	if err != nil || (len(raw) != net.IPv4len && len(raw) != net.IPv6len) {
+		return "", 0, fmt.Errorf("invalid ip")
+	}
+	// the address is stored as host-endian 32 bit words
+	for i := 0; i < len(raw); i += 4 {
+		raw[i], raw[i+1], raw[i+2], raw[i+3] = raw[i+3], raw[i+2], raw[i+1], raw[i]
+	}
+	ip := net.IP(raw)
+	if ip.IsUnspecified() {
+		if len(raw) == net.IPv4len {
+			ip = net.IPv4(127, 0, 0, 1)
+		} else {
+			ip = net.IPv6loopback
+		}
+	}
+	return ip.String(), int(port), nil
+}
+
+// GetID returns the service ID
+func (s *ProcessService) GetID() ID {
+	return s.ID
+}
+
+// GetADIdentifiers returns a set of AD identifiers for a process.
+func (s *ProcessService) GetADIdentifiers() ([]string, error) {
+	return s.ADIdentifiers, nil
+}
+
+// GetHosts returns the address the process is listening on, under the `host` network
+func (s *ProcessService) GetHosts() (map[string]string, error) {
+	return s.Hosts, nil
+}
+
+// GetPorts returns the sorted list of ports the process is listening on
+func (s *ProcessService) GetPorts() ([]int, error) {
+	return s.Ports, nil
+}
+
+// GetTags returns the tags of the process
+func (s *ProcessService) GetTags() ([]string, error) {
+	return s.Tags, nil
+}
+
+// GetPid returns the process identifier
+func (s *ProcessService) GetPid() (int, error) {
+	return s.Pid, nil
+}
+
+// GetEnv returns the environment variables of the process
+func (s *ProcessService) GetEnv() (map[string]string, error) {
+	return s.Env, nil
+}
+
+// GetHostname returns the hostname of the host the process runs on
+func (s *ProcessService) GetHostname() (string, error) {
+	return os.Hostname()
+}
+
+// GetLabels returns an error because processes don't have labels
+func (s *ProcessService) GetLabels() (map[string]string, error) {
+	return nil, ErrNotSupported
+}
+
+// GetKubeNamespace returns an error because host processes don't run in kubernetes
+func (s *ProcessService) GetKubeNamespace() (string, error) {
+	return "", ErrNotSupported
+}
+
+// GetKubePodName returns an error because host processes don't run in kubernetes
+func (s *ProcessService) GetKubePodName() (string, error) {
+	return "", ErrNotSupported
+}
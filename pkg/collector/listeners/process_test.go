@@ -0,0 +1,269 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build linux
+
+package listeners
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	fakeTCP = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 0100007F:18EB 00000000:0000 0A 00000000:00000000 00:00000000 00000000   999        0 1001 1 0000000000000000 100 0 0 10 0
+   1: 00000000:1F90 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 2001 1 0000000000000000 100 0 0 10 0
+   2: 0100007F:D2F0 0100007F:18EB 01 00000000:00000000 00:00000000 00000000   999        0 3001 1 0000000000000000 20 4 30 10 -1
+`
+	fakeTCP6 = `  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000000000000000000000000000:1F91 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 2002 1 0000000000000000 100 0 0 10 0
+`
+)
+
+type fakeProcess struct {
+	pid       int
+	comm      string
+	cmdline   []string
+	environ   []string
+	sockets   []string
+	startTime int
+}
+
+func writeFakeProc(t *testing.T, procs []fakeProcess) string {
+	root, err := ioutil.TempDir("", "fake-procfs")
+	require.NoError(t, err)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "net"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(root, "net", "tcp"), []byte(fakeTCP), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(root, "net", "tcp6"), []byte(fakeTCP6), 0644))
+
+	for _, p := range procs {
+		dir := filepath.Join(root, fmt.Sprintf("%d", p.pid))
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "fd"), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "comm"), []byte(p.comm+"\n"), 0644))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "cmdline"), []byte(joinNull(p.cmdline)), 0644))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "environ"), []byte(joinNull(p.environ)), 0644))
+		if p.startTime > 0 {
+			stat := fmt.Sprintf("%d (%s) S 1 %d %d 0 -1 4194560 0 0 0 0 0 0 0 0 20 0 1 0 %d 0 0", p.pid, p.comm, p.pid, p.pid, p.startTime)
+			require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "stat"), []byte(stat), 0644))
+		}
+		require.NoError(t, os.Symlink("/dev/null", filepath.Join(dir, "fd", "0")))
+		for i, inode := range p.sockets {
+			link := filepath.Join(dir, "fd", fmt.Sprintf("%d", i+3))
+			require.NoError(t, os.Symlink(fmt.Sprintf("socket:[%s]", inode), link))
+		}
+	}
+	return root
+}
+
+func joinNull(parts []string) string {
+	s := ""
+	for _, p := range parts {
+		s += p + "\x00"
+	}
+	return s
+}
+
+func TestParseHexAddress(t *testing.T) {
+	for _, tc := range []struct {
+		addr string
+		ip   string
+		port int
+		err  bool
+	}{
+		{"0100007F:18EB", "127.0.0.1", 6379, false},
+		{"00000000:1F90", "127.0.0.1", 8080, false},
+		{"0B00000A:0050", "10.0.0.11", 80, false},
+		{"00000000000000000000000000000000:1F91", "::1", 8081, false},
+		{"0000000000000000FFFF00000B00000A:0050", "10.0.0.11", 80, false},
+		{"0100007F", "", 0, true},
+		{"ZZ00007F:0050", "", 0, true},
+	} {
+		t.Run(tc.addr, func(t *testing.T) {
+			ip, port, err := parseHexAddress(tc.addr)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.ip, ip)
+			assert.Equal(t, tc.port, port)
+		})
+	}
+}
+
+func TestProcessListenerScan(t *testing.T) {
+	root := writeFakeProc(t, []fakeProcess{
+		{
+			pid:     42,
+			comm:    "redis-server",
+			cmdline: []string{"/usr/bin/redis-server", "127.0.0.1:6379"},
+			sockets: []string{"1001", "3001"},
+		},
+		{
+			pid:     43,
+			comm:    "java",
+			cmdline: []string{"/usr/bin/java", "-jar", "/opt/tomcat/bootstrap.jar"},
+			sockets: []string{"2002", "2001"},
+		},
+		{
+			pid:     44,
+			comm:    "python",
+			cmdline: []string{"python", "app.py"},
+			environ: []string{"PATH=/usr/bin", "DD_CHECK_ID=my-app"},
+			sockets: []string{"9999"},
+		},
+		{
+			pid:     45,
+			comm:    "gunicorn",
+			environ: []string{"DD_CHECK_ID=gunicorn-app"},
+			sockets: []string{"9999"},
+		},
+	})
+	defer os.RemoveAll(root)
+
+	patterns := []processPattern{
+		{identifier: "tomcat", regex: regexp.MustCompile("tomcat/bootstrap.jar")},
+	}
+	l := newProcessListener(root, patterns, time.Hour)
+	defer l.t.Stop()
+
+	services, err := l.scanProcesses()
+	require.NoError(t, err)
+	// pid 44 and 45 have no listening socket
+	require.Len(t, services, 2)
+
+	redis, found := services["process://42"]
+	require.True(t, found)
+	ids, _ := redis.GetADIdentifiers()
+	assert.Equal(t, []string{"redis-server"}, ids)
+	hosts, _ := redis.GetHosts()
+	assert.Equal(t, map[string]string{"host": "127.0.0.1"}, hosts)
+	ports, _ := redis.GetPorts()
+	assert.Equal(t, []int{6379}, ports)
+	pid, err := redis.GetPid()
+	assert.NoError(t, err)
+	assert.Equal(t, 42, pid)
+
+	tomcat, found := services["process://43"]
+	require.True(t, found)
+	ids, _ = tomcat.GetADIdentifiers()
+	assert.Equal(t, []string{"tomcat", "java"}, ids)
+	ports, _ = tomcat.GetPorts()
+	assert.Equal(t, []int{8080, 8081}, ports)
+	// the IPv4 address is preferred over the IPv6 one of its first fd
+	hosts, _ = tomcat.GetHosts()
+	assert.Equal(t, map[string]string{"host": "127.0.0.1"}, hosts)
+}
+
+func TestProcessListenerSharedSockets(t *testing.T) {
+	root := writeFakeProc(t, []fakeProcess{
+		// the pid of the nginx master wrapped around after its workers
+		{pid: 50, comm: "nginx", sockets: []string{"2001"}, startTime: 300},
+		{pid: 60, comm: "nginx", sockets: []string{"2001", "2002"}, startTime: 100},
+		{pid: 51, comm: "nginx", sockets: []string{"2002", "2001"}, startTime: 300},
+		// the start time of the redis processes is unknown
+		{pid: 43, comm: "redis-server", sockets: []string{"1001"}},
+		{pid: 42, comm: "redis-server", sockets: []string{"1001"}},
+	})
+	defer os.RemoveAll(root)
+
+	l := newProcessListener(root, nil, time.Hour)
+	defer l.t.Stop()
+
+	services, err := l.scanProcesses()
+	require.NoError(t, err)
+	require.Len(t, services, 2)
+
+	nginx, found := services["process://60"]
+	require.True(t, found)
+	ports, _ := nginx.GetPorts()
+	assert.Equal(t, []int{8080, 8081}, ports)
+
+	_, found = services["process://42"]
+	assert.True(t, found)
+}
+
+func TestProcessListenerEnvIdentifier(t *testing.T) {
+	root := writeFakeProc(t, []fakeProcess{
+		{
+			pid:     44,
+			comm:    "python",
+			cmdline: []string{"python", "app.py"},
+			environ: []string{"PATH=/usr/bin", "DD_CHECK_ID=my-app"},
+			sockets: []string{"2001"},
+		},
+	})
+	defer os.RemoveAll(root)
+
+	l := newProcessListener(root, nil, time.Hour)
+	defer l.t.Stop()
+
+	services, err := l.scanProcesses()
+	require.NoError(t, err)
+	require.Len(t, services, 1)
+	ids, _ := services["process://44"].GetADIdentifiers()
+	assert.Equal(t, []string{"my-app"}, ids)
+}
+
+func TestProcessListenerRefresh(t *testing.T) {
+	root := writeFakeProc(t, []fakeProcess{
+		{pid: 42, comm: "redis-server", sockets: []string{"1001"}},
+		{pid: 43, comm: "nginx", sockets: []string{"2001"}},
+	})
+	defer os.RemoveAll(root)
+
+	newSvc := make(chan Service, 10)
+	delSvc := make(chan Service, 10)
+	l := newProcessListener(root, nil, time.Hour)
+	defer l.t.Stop()
+	l.newService = newSvc
+	l.delService = delSvc
+
+	l.refreshServices()
+	assert.Len(t, newSvc, 2)
+	assert.Len(t, delSvc, 0)
+	for len(newSvc) > 0 {
+		<-newSvc
+	}
+
+	// nothing changed, nothing is sent
+	l.refreshServices()
+	assert.Len(t, newSvc, 0)
+	assert.Len(t, delSvc, 0)
+
+	// nginx exits
+	require.NoError(t, os.RemoveAll(filepath.Join(root, "43")))
+	l.refreshServices()
+	assert.Len(t, newSvc, 0)
+	require.Len(t, delSvc, 1)
+	svc := <-delSvc
+	assert.Equal(t, ID("process://43"), svc.GetID())
+}
+
+func TestCompileProcessPatterns(t *testing.T) {
+	patterns, err := compileProcessPatterns([]config.ProcessADPattern{
+		{ADIdentifier: "redis", CommandRegex: "redis-server"},
+	})
+	assert.NoError(t, err)
+	require.Len(t, patterns, 1)
+	assert.Equal(t, "redis", patterns[0].identifier)
+
+	_, err = compileProcessPatterns([]config.ProcessADPattern{{CommandRegex: "redis-server"}})
+	assert.Error(t, err)
+
+	_, err = compileProcessPatterns([]config.ProcessADPattern{{ADIdentifier: "redis", CommandRegex: "redis-server("}})
+	assert.Error(t, err)
+}
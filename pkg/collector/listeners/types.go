@@ -18,12 +18,25 @@ type ID string
 // It should be matched with a check template by the ConfigResolver using the
 // ADIdentifiers field.
 type Service interface {
-	GetID() ID                            // unique ID
-	GetADIdentifiers() ([]string, error)  // identifiers on which templates will be matched
-	GetHosts() (map[string]string, error) // network --> IP address
-	GetPorts() ([]int, error)             // network ports
-	GetTags() ([]string, error)           // tags
-	GetPid() (int, error)                 // process identifier
+	GetID() ID                             // unique ID
+	GetADIdentifiers() ([]string, error)   // identifiers on which templates will be matched
+	GetHosts() (map[string]string, error)  // network --> IP address
+	GetPorts() ([]int, error)              // network ports
+	GetTags() ([]string, error)            // tags
+	GetPid() (int, error)                  // process identifier
+	GetEnv() (map[string]string, error)    // environment variables
+	GetHostname() (string, error)          // hostname
+	GetLabels() (map[string]string, error) // labels or pod labels
+	GetKubeNamespace() (string, error)     // kubernetes namespace
+	GetKubePodName() (string, error)       // kubernetes pod name
+}
+
+// PartialEnvService is implemented by the services that don't know the value
+// of all their environment variables
+type PartialEnvService interface {
+	// GetEnvResolutionError returns why a variable missing from GetEnv can't
+	// be resolved, or nil if it is not set
+	GetEnvResolutionError(name string) error
 }
 
 // ServiceListener monitors running services and triggers check (un)scheduling
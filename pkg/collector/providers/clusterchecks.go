@@ -0,0 +1,109 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package providers
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util"
+	"github.com/DataDog/datadog-agent/pkg/util/clusteragent"
+)
+
+// clusterChecksClient queries the cluster checks dispatched by the cluster
+// agent, it abstracts the DCAClient for testing purposes
+type clusterChecksClient interface {
+	PostClusterCheckStatus(nodeName string) (clusterchecks.StatusResponse, error)
+	GetClusterCheckConfigs(nodeName string) (clusterchecks.ConfigResponse, error)
+}
+
+// ClusterChecksConfigProvider implements the ConfigProvider interface for
+// the cluster checks the cluster agent dispatches to this node agent. Its
+// polling doubles as the heartbeat of the node, it must be polled.
+type ClusterChecksConfigProvider struct {
+	client     clusterChecksClient
+	nodeName   string
+	registered bool
+	// lastChange of the configurations returned by the last Collect
+	lastChange int64
+}
+
+// NewClusterChecksConfigProvider returns a new ConfigProvider polling the
+// cluster agent. Connectivity is not checked at this stage to allow for
+// retries, the client is created on the first use.
+func NewClusterChecksConfigProvider(cfg config.ConfigurationProviders) (ConfigProvider, error) {
+	nodeName, err := util.GetHostname()
+	if err != nil {
+		return nil, err
+	}
+	return &ClusterChecksConfigProvider{nodeName: nodeName}, nil
+}
+
+// String returns a string representation of the ClusterChecksConfigProvider
+func (c *ClusterChecksConfigProvider) String() string {
+	return "Cluster checks"
+}
+
+// IsUpToDate sends the heartbeat of the node to the cluster agent, and
+// returns whether the configurations dispatched to it changed since the last
+// Collect
+func (c *ClusterChecksConfigProvider) IsUpToDate() (bool, error) {
+	status, err := c.postStatus()
+	if err != nil {
+		return false, err
+	}
+	return status.LastChange == c.lastChange, nil
+}
+
+// Collect retrieves the configurations dispatched to the node, registering
+// it first if needed
+func (c *ClusterChecksConfigProvider) Collect() ([]check.Config, error) {
+	if !c.registered {
+		if _, err := c.postStatus(); err != nil {
+			return []check.Config{}, err
+		}
+	}
+	if err := c.initClient(); err != nil {
+		return []check.Config{}, err
+	}
+	response, err := c.client.GetClusterCheckConfigs(c.nodeName)
+	if err != nil {
+		// the cluster agent forgot about the node, register again on the
+		// next poll
+		c.registered = false
+		return []check.Config{}, err
+	}
+	c.lastChange = response.LastChange
+	return response.Configs, nil
+}
+
+func (c *ClusterChecksConfigProvider) postStatus() (clusterchecks.StatusResponse, error) {
+	if err := c.initClient(); err != nil {
+		return clusterchecks.StatusResponse{}, err
+	}
+	status, err := c.client.PostClusterCheckStatus(c.nodeName)
+	if err != nil {
+		return status, err
+	}
+	c.registered = true
+	return status, nil
+}
+
+func (c *ClusterChecksConfigProvider) initClient() error {
+	if c.client != nil {
+		return nil
+	}
+	client, err := clusteragent.GetClusterAgentClient()
+	if err != nil {
+		return err
+	}
+	c.client = client
+	return nil
+}
+
+func init() {
+	RegisterProvider("clusterchecks", NewClusterChecksConfigProvider)
+}
@@ -0,0 +1,100 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package providers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+)
+
+type fakeClusterChecksClient struct {
+	registered map[string]bool
+	configs    clusterchecks.ConfigResponse
+	err        error
+}
+
+func (f *fakeClusterChecksClient) PostClusterCheckStatus(nodeName string) (clusterchecks.StatusResponse, error) {
+	if f.err != nil {
+		return clusterchecks.StatusResponse{}, f.err
+	}
+	f.registered[nodeName] = true
+	return clusterchecks.StatusResponse{LastChange: f.configs.LastChange}, nil
+}
+
+func (f *fakeClusterChecksClient) GetClusterCheckConfigs(nodeName string) (clusterchecks.ConfigResponse, error) {
+	if f.err != nil {
+		return clusterchecks.ConfigResponse{}, f.err
+	}
+	if !f.registered[nodeName] {
+		return clusterchecks.ConfigResponse{}, errors.New("node is not registered")
+	}
+	return f.configs, nil
+}
+
+func TestClusterChecksProvider(t *testing.T) {
+	client := &fakeClusterChecksClient{
+		registered: make(map[string]bool),
+		configs: clusterchecks.ConfigResponse{
+			Configs:    []check.Config{{Name: "postgres", ClusterCheck: true}},
+			LastChange: 10,
+		},
+	}
+	provider := &ClusterChecksConfigProvider{client: client, nodeName: "node1"}
+
+	upToDate, err := provider.IsUpToDate()
+	require.NoError(t, err)
+	assert.False(t, upToDate)
+
+	configs, err := provider.Collect()
+	require.NoError(t, err)
+	require.Len(t, configs, 1)
+	assert.Equal(t, "postgres", configs[0].Name)
+	assert.True(t, client.registered["node1"])
+
+	upToDate, err = provider.IsUpToDate()
+	require.NoError(t, err)
+	assert.True(t, upToDate)
+
+	client.configs = clusterchecks.ConfigResponse{LastChange: 20}
+	upToDate, err = provider.IsUpToDate()
+	require.NoError(t, err)
+	assert.False(t, upToDate)
+	configs, err = provider.Collect()
+	require.NoError(t, err)
+	assert.Len(t, configs, 0)
+
+	client.err = errors.New("cluster agent unreachable")
+	_, err = provider.IsUpToDate()
+	assert.Error(t, err)
+	_, err = provider.Collect()
+	assert.Error(t, err)
+}
+
+func TestClusterChecksProviderRegisters(t *testing.T) {
+	client := &fakeClusterChecksClient{
+		registered: make(map[string]bool),
+		configs:    clusterchecks.ConfigResponse{LastChange: 10},
+	}
+	provider := &ClusterChecksConfigProvider{client: client, nodeName: "node1"}
+
+	// Collect registers the node on the first call
+	_, err := provider.Collect()
+	require.NoError(t, err)
+	assert.True(t, client.registered["node1"])
+
+	// the cluster agent expired the node
+	client.registered["node1"] = false
+	_, err = provider.Collect()
+	assert.Error(t, err)
+	_, err = provider.Collect()
+	assert.NoError(t, err)
+}
@@ -6,14 +6,18 @@
 package providers
 
 import (
+	"crypto/md5"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
 	log "github.com/cihub/seelog"
+	"github.com/fsnotify/fsnotify"
 
 	"gopkg.in/yaml.v2"
 )
@@ -24,6 +28,7 @@ type configFormat struct {
 	MetricConfig  interface{} `yaml:"jmx_metrics"`
 	LogsConfig    interface{} `yaml:"logs"`
 	Instances     []check.ConfigRawMap
+	ClusterCheck  bool `yaml:"cluster_check"`
 }
 
 type configPkg struct {
@@ -45,6 +50,11 @@ type configEntry struct {
 type FileConfigProvider struct {
 	paths  []string
 	Errors map[string]string
+
+	m           sync.Mutex
+	watcher     *fsnotify.Watcher
+	outdated    bool
+	fingerprint string // used when polling, if the paths can't be watched
 }
 
 // NewFileConfigProvider creates a new FileConfigProvider searching for
@@ -60,6 +70,14 @@ func NewFileConfigProvider(paths []string) *FileConfigProvider {
 // it parses the files and try to unmarshall Yaml contents into a CheckConfig
 // instance
 func (c *FileConfigProvider) Collect() ([]check.Config, error) {
+	c.m.Lock()
+	// changes happening from now on will trigger another collection
+	c.outdated = false
+	if c.watcher == nil {
+		c.fingerprint = c.computeFingerprint()
+	}
+	c.m.Unlock()
+
 	configs := []check.Config{}
 	configNames := make(map[string]struct{}) // use this map as a python set
 	defaultConfigs := []check.Config{}
@@ -127,9 +145,115 @@ func (c *FileConfigProvider) Collect() ([]check.Config, error) {
 	return configs, nil
 }
 
-// IsUpToDate is not implemented for the file Providers as the files are not meant to change very often.
+// IsUpToDate returns whether the configuration files changed since the last
+// Collect. Changes are notified by inotify when Watch succeeded, otherwise the
+// files are listed and their size and modification time are compared.
 func (c *FileConfigProvider) IsUpToDate() (bool, error) {
-	return false, nil
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if c.watcher != nil {
+		return !c.outdated, nil
+	}
+	return c.fingerprint != "" && c.fingerprint == c.computeFingerprint(), nil
+}
+
+// Watch starts watching the configuration paths and their `.d` subdirectories
+// for changes. If the paths can't be watched, IsUpToDate falls back to polling.
+func (c *FileConfigProvider) Watch() error {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if c.watcher != nil {
+		return nil
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("can't create a watcher, polling configuration files instead: %s", err)
+	}
+	for _, path := range c.paths {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+		for _, dir := range append([]string{path}, listConfigDirs(path)...) {
+			if err := watcher.Add(dir); err != nil {
+				watcher.Close()
+				return fmt.Errorf("can't watch %s, polling configuration files instead: %s", dir, err)
+			}
+		}
+	}
+
+	c.watcher = watcher
+	// files might have changed before the watcher was set up
+	c.outdated = true
+	go c.watch(watcher)
+	return nil
+}
+
+// watch marks the provider as outdated when a configuration file changes
+func (c *FileConfigProvider) watch(watcher *fsnotify.Watcher) {
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			log.Debugf("Configuration change detected: %s", event)
+			// watch the new check directories
+			if event.Op&fsnotify.Create == fsnotify.Create && filepath.Ext(event.Name) == ".d" {
+				if fi, err := os.Stat(event.Name); err == nil && fi.IsDir() {
+					if err := watcher.Add(event.Name); err != nil {
+						log.Warnf("Can't watch %s: %s", event.Name, err)
+					}
+				}
+			}
+			c.m.Lock()
+			c.outdated = true
+			c.m.Unlock()
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Warnf("Error watching configuration files: %s", err)
+		}
+	}
+}
+
+// computeFingerprint returns a hash of the names, sizes and modification
+// times of the files in the configuration paths and their `.d` subdirectories
+func (c *FileConfigProvider) computeFingerprint() string {
+	h := md5.New()
+	for _, path := range c.paths {
+		for _, dir := range append([]string{path}, listConfigDirs(path)...) {
+			entries, err := ioutil.ReadDir(dir)
+			if err != nil {
+				continue
+			}
+			for _, entry := range entries {
+				if entry.IsDir() {
+					continue
+				}
+				fmt.Fprintf(h, "%s:%d:%d\n", filepath.Join(dir, entry.Name()), entry.Size(), entry.ModTime().UnixNano())
+			}
+		}
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// listConfigDirs returns the `checkname.d` directories of a configuration path
+func listConfigDirs(path string) []string {
+	dirs := []string{}
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return dirs
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && filepath.Ext(entry.Name()) == ".d" {
+			dirs = append(dirs, filepath.Join(path, entry.Name()))
+		}
+	}
+	return dirs
 }
 
 // String returns a string representation of the FileConfigProvider
@@ -289,6 +413,9 @@ func GetCheckConfigFromFile(name, fpath string) (check.Config, error) {
 	// Copy auto discovery identifiers
 	config.ADIdentifiers = cf.ADIdentifiers
 
+	// Cluster checks are dispatched to the node agents by the cluster agent
+	config.ClusterCheck = cf.ClusterCheck
+
 	// If logs was found, add it to the config
 	if cf.LogsConfig != nil {
 		rawLogsConfig, _ := yaml.Marshal(cf.LogsConfig)
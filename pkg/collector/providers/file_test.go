@@ -6,7 +6,11 @@
 package providers
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
 	"github.com/stretchr/testify/assert"
@@ -114,3 +118,79 @@ func TestCollect(t *testing.T) {
 	// incorrect configs get saved in the Errors map (invalid.yaml & notaconfig.yaml)
 	assert.Equal(t, 2, len(provider.Errors))
 }
+
+func TestFileProviderIsUpToDatePolling(t *testing.T) {
+	dir, err := ioutil.TempDir("", "confd")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "redisdb.d"), 0755))
+	confPath := filepath.Join(dir, "redisdb.d", "conf.yaml")
+	require.NoError(t, ioutil.WriteFile(confPath, []byte("instances:\n  - host: localhost\n"), 0644))
+
+	provider := NewFileConfigProvider([]string{dir})
+
+	// never collected
+	upToDate, err := provider.IsUpToDate()
+	require.NoError(t, err)
+	assert.False(t, upToDate)
+
+	configs, err := provider.Collect()
+	require.NoError(t, err)
+	require.Len(t, configs, 1)
+	upToDate, err = provider.IsUpToDate()
+	require.NoError(t, err)
+	assert.True(t, upToDate)
+
+	// an instance is added
+	require.NoError(t, ioutil.WriteFile(confPath, []byte("instances:\n  - host: localhost\n  - host: remote\n"), 0644))
+	upToDate, err = provider.IsUpToDate()
+	require.NoError(t, err)
+	assert.False(t, upToDate)
+
+	configs, err = provider.Collect()
+	require.NoError(t, err)
+	require.Len(t, configs, 1)
+	assert.Len(t, configs[0].Instances, 2)
+	upToDate, err = provider.IsUpToDate()
+	require.NoError(t, err)
+	assert.True(t, upToDate)
+}
+
+func TestFileProviderWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "confd")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	provider := NewFileConfigProvider([]string{dir})
+	require.NoError(t, provider.Watch())
+	_, err = provider.Collect()
+	require.NoError(t, err)
+	upToDate, err := provider.IsUpToDate()
+	require.NoError(t, err)
+	assert.True(t, upToDate)
+
+	// a new check directory is created, then a file is written in it
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "redisdb.d"), 0755))
+	assert.True(t, waitOutdated(provider))
+	_, err = provider.Collect()
+	require.NoError(t, err)
+
+	// wait for the new directory to be watched
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "redisdb.d", "conf.yaml"), []byte("instances:\n  - host: localhost\n"), 0644))
+	assert.True(t, waitOutdated(provider))
+
+	configs, err := provider.Collect()
+	require.NoError(t, err)
+	assert.Len(t, configs, 1)
+}
+
+func waitOutdated(provider *FileConfigProvider) bool {
+	for i := 0; i < 50; i++ {
+		if upToDate, _ := provider.IsUpToDate(); !upToDate {
+			return true
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return false
+}
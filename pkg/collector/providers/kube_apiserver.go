@@ -0,0 +1,232 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package providers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+
+	log "github.com/cihub/seelog"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+)
+
+const (
+	serviceAnnotationPrefix = "ad.datadoghq.com/service."
+	adIdentifiersPath       = "ad_identifiers"
+	clusterCheckPath        = "cluster_check"
+	kubeConfigMapKind       = "configmap"
+	kubeServiceKind         = "service"
+	hostTemplateVar         = "%%host%%"
+)
+
+// kubeTemplateSource is a ConfigMap or a Service that might hold check
+// configurations, reduced to the fields the provider cares about
+type kubeTemplateSource struct {
+	kind            string
+	namespace       string
+	name            string
+	resourceVersion string
+	// data holds the ConfigMap data or the Service annotations
+	data map[string]string
+	// clusterIP is only set for services
+	clusterIP string
+}
+
+func (s kubeTemplateSource) key() string {
+	return fmt.Sprintf("%s/%s/%s", s.kind, s.namespace, s.name)
+}
+
+// kubeTemplateLister lists the resources holding check configurations,
+// it abstracts the apiserver client for testing purposes
+type kubeTemplateLister interface {
+	listConfigMaps() ([]kubeTemplateSource, error)
+	listServices() ([]kubeTemplateSource, error)
+}
+
+// KubeAPIServerConfigProvider implements the ConfigProvider interface for
+// cluster-level configurations stored in the Kubernetes apiserver:
+//   - ConfigMaps matching a label selector, holding `check_names`, `init_configs`,
+//     `instances` and optionally `ad_identifiers` data keys
+//   - Services annotated with `ad.datadoghq.com/service.check_names`, etc.
+type KubeAPIServerConfigProvider struct {
+	sync.Mutex
+	lister kubeTemplateLister
+	// resourceVersions of the sources used by the last Collect
+	versions map[string]string
+	// sources fetched by IsUpToDate, reused by the next Collect
+	pending []kubeTemplateSource
+}
+
+func newKubeAPIServerConfigProvider(lister kubeTemplateLister) *KubeAPIServerConfigProvider {
+	return &KubeAPIServerConfigProvider{
+		lister:   lister,
+		versions: make(map[string]string),
+	}
+}
+
+// String returns a string representation of the KubeAPIServerConfigProvider
+func (k *KubeAPIServerConfigProvider) String() string {
+	return "Kubernetes apiserver"
+}
+
+// Collect retrieves the ConfigMaps and Services from the apiserver and
+// extracts the check configurations they hold
+func (k *KubeAPIServerConfigProvider) Collect() ([]check.Config, error) {
+	k.Lock()
+	defer k.Unlock()
+
+	sources := k.pending
+	k.pending = nil
+	if sources == nil {
+		var err error
+		sources, err = k.listSources()
+		if err != nil {
+			return []check.Config{}, err
+		}
+	}
+
+	configs := make([]check.Config, 0)
+	versions := make(map[string]string, len(sources))
+	for _, source := range sources {
+		versions[source.key()] = source.resourceVersion
+
+		var c []check.Config
+		var err error
+		switch source.kind {
+		case kubeConfigMapKind:
+			c, err = parseConfigMapSource(source)
+		case kubeServiceKind:
+			c, err = parseServiceSource(source)
+		}
+		if err != nil {
+			log.Errorf("Can't parse template for %s %s/%s: %s", source.kind, source.namespace, source.name, err)
+			continue
+		}
+		configs = append(configs, c...)
+	}
+	k.versions = versions
+
+	return configs, nil
+}
+
+// IsUpToDate lists the ConfigMaps and Services and compares their
+// resourceVersion with the ones seen by the last Collect
+func (k *KubeAPIServerConfigProvider) IsUpToDate() (bool, error) {
+	k.Lock()
+	defer k.Unlock()
+
+	sources, err := k.listSources()
+	if err != nil {
+		return false, err
+	}
+
+	upToDate := len(sources) == len(k.versions)
+	for _, source := range sources {
+		if !upToDate {
+			break
+		}
+		version, found := k.versions[source.key()]
+		upToDate = found && version == source.resourceVersion
+	}
+	if upToDate {
+		k.pending = nil
+	} else {
+		k.pending = sources
+	}
+	return upToDate, nil
+}
+
+func (k *KubeAPIServerConfigProvider) listSources() ([]kubeTemplateSource, error) {
+	configMaps, err := k.lister.listConfigMaps()
+	if err != nil {
+		return nil, fmt.Errorf("can't list ConfigMaps: %s", err)
+	}
+	services, err := k.lister.listServices()
+	if err != nil {
+		return nil, fmt.Errorf("can't list Services: %s", err)
+	}
+	return append(configMaps, services...), nil
+}
+
+// parseConfigMapSource extracts the configurations of a ConfigMap. If the
+// ConfigMap has an `ad_identifiers` key the configurations are templates,
+// otherwise they are scheduled as is.
+func parseConfigMapSource(source kubeTemplateSource) ([]check.Config, error) {
+	configs, err := extractTemplatesFromMap("", source.data, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var adIdentifiers []string
+	if value, found := source.data[adIdentifiersPath]; found {
+		if err := json.Unmarshal([]byte(value), &adIdentifiers); err != nil {
+			return nil, fmt.Errorf("in %s: %s", adIdentifiersPath, err)
+		}
+	}
+	clusterCheck, err := parseClusterCheck(source.data, "")
+	if err != nil {
+		return nil, err
+	}
+	for i := range configs {
+		configs[i].ADIdentifiers = adIdentifiers
+		configs[i].ClusterCheck = clusterCheck
+	}
+	return configs, nil
+}
+
+// parseServiceSource extracts the configurations of a Service's annotations,
+// resolving the %%host%% template variable to the service's cluster IP
+func parseServiceSource(source kubeTemplateSource) ([]check.Config, error) {
+	configs, err := extractTemplatesFromMap("", source.data, serviceAnnotationPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := []string{
+		fmt.Sprintf("kube_service:%s", source.name),
+		fmt.Sprintf("kube_namespace:%s", source.namespace),
+	}
+	clusterCheck, err := parseClusterCheck(source.data, serviceAnnotationPrefix)
+	if err != nil {
+		return nil, err
+	}
+	hasClusterIP := source.clusterIP != "" && source.clusterIP != "None"
+	for i := range configs {
+		configs[i].ADIdentifiers = nil
+		configs[i].ClusterCheck = clusterCheck
+		for j, instance := range configs[i].Instances {
+			if bytes.Contains(instance, []byte(hostTemplateVar)) {
+				if !hasClusterIP {
+					return nil, fmt.Errorf("%s can't be resolved for a service without cluster IP", hostTemplateVar)
+				}
+				instance = bytes.Replace(instance, []byte(hostTemplateVar), []byte(source.clusterIP), -1)
+			}
+			if err := instance.MergeAdditionalTags(tags); err != nil {
+				return nil, err
+			}
+			configs[i].Instances[j] = instance
+		}
+	}
+	return configs, nil
+}
+
+// parseClusterCheck returns whether the configurations are cluster checks,
+// per the optional `cluster_check` key
+func parseClusterCheck(data map[string]string, prefix string) (bool, error) {
+	value, found := data[prefix+clusterCheckPath]
+	if !found {
+		return false, nil
+	}
+	clusterCheck, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("in %s: %s", clusterCheckPath, err)
+	}
+	return clusterCheck, nil
+}
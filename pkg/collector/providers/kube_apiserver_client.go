@@ -0,0 +1,87 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build kubeapiserver
+
+package providers
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver"
+)
+
+// apiserverLister lists ConfigMaps and Services through the apiserver client
+type apiserverLister struct {
+	namespace string
+	selector  string
+}
+
+func (l *apiserverLister) listConfigMaps() ([]kubeTemplateSource, error) {
+	client, err := apiserver.GetAPIClient()
+	if err != nil {
+		return nil, err
+	}
+	configMaps, err := client.ListConfigMaps(l.namespace, l.selector)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make([]kubeTemplateSource, 0, len(configMaps.Items))
+	for _, cm := range configMaps.Items {
+		meta := cm.GetMetadata()
+		sources = append(sources, kubeTemplateSource{
+			kind:            kubeConfigMapKind,
+			namespace:       meta.GetNamespace(),
+			name:            meta.GetName(),
+			resourceVersion: meta.GetResourceVersion(),
+			data:            cm.GetData(),
+		})
+	}
+	return sources, nil
+}
+
+func (l *apiserverLister) listServices() ([]kubeTemplateSource, error) {
+	client, err := apiserver.GetAPIClient()
+	if err != nil {
+		return nil, err
+	}
+	services, err := client.ListServices(l.namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make([]kubeTemplateSource, 0)
+	for _, svc := range services.Items {
+		meta := svc.GetMetadata()
+		// only keep annotated services
+		if _, found := meta.GetAnnotations()[serviceAnnotationPrefix+checkNamePath]; !found {
+			continue
+		}
+		sources = append(sources, kubeTemplateSource{
+			kind:            kubeServiceKind,
+			namespace:       meta.GetNamespace(),
+			name:            meta.GetName(),
+			resourceVersion: meta.GetResourceVersion(),
+			data:            meta.GetAnnotations(),
+			clusterIP:       svc.GetSpec().GetClusterIP(),
+		})
+	}
+	return sources, nil
+}
+
+// NewKubeAPIServerConfigProvider returns a new ConfigProvider reading ConfigMaps
+// and Service annotations from the apiserver. Connectivity is not checked at
+// this stage to allow for retries, Collect will do it.
+func NewKubeAPIServerConfigProvider(cfg config.ConfigurationProviders) (ConfigProvider, error) {
+	lister := &apiserverLister{
+		namespace: config.Datadog.GetString("kubernetes_ad_namespace"),
+		selector:  config.Datadog.GetString("kubernetes_ad_configmap_selector"),
+	}
+	return newKubeAPIServerConfigProvider(lister), nil
+}
+
+func init() {
+	RegisterProvider("kube_apiserver", NewKubeAPIServerConfigProvider)
+}
@@ -0,0 +1,157 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build kubeapiserver
+
+package providers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ericchiang/k8s/api/v1"
+	metav1 "github.com/ericchiang/k8s/apis/meta/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver/testutil"
+)
+
+const testKubeConfig = `{
+  "apiVersion": "v1",
+  "kind": "Config",
+  "clusters": [{"name": "test", "cluster": {"server": "%s"}}],
+  "contexts": [{"name": "test", "context": {"cluster": "test", "user": "test"}}],
+  "current-context": "test",
+  "users": [{"name": "test", "user": {}}]
+}`
+
+func strPtr(s string) *string {
+	return &s
+}
+
+// newFakeAPIServer serves the endpoints needed by the apiserver client to
+// connect, and the ConfigMaps and Services listed by apiserverLister
+func newFakeAPIServer(t *testing.T) (*httptest.Server, *[]string) {
+	selectors := []string{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"major": "1", "minor": "9", "gitVersion": "v1.9.0"}`)
+	})
+	mux.HandleFunc("/api/v1/events", func(w http.ResponseWriter, r *http.Request) {
+		testutil.WriteProto(t, w, http.StatusOK, &v1.EventList{})
+	})
+	mux.HandleFunc("/api/v1/namespaces/default/configmaps", func(w http.ResponseWriter, r *http.Request) {
+		selectors = append(selectors, r.URL.Query().Get("labelSelector"))
+		testutil.WriteProto(t, w, http.StatusOK, &v1.ConfigMapList{
+			Items: []*v1.ConfigMap{
+				{
+					Metadata: &metav1.ObjectMeta{
+						Name:            strPtr("redis-checks"),
+						Namespace:       strPtr("default"),
+						ResourceVersion: strPtr("42"),
+					},
+					Data: map[string]string{
+						"redisdb.check_names": `["redisdb"]`,
+						"redisdb.instances":   `[{"host": "redis"}]`,
+					},
+				},
+			},
+		})
+	})
+	mux.HandleFunc("/api/v1/namespaces/default/services", func(w http.ResponseWriter, r *http.Request) {
+		testutil.WriteProto(t, w, http.StatusOK, &v1.ServiceList{
+			Items: []*v1.Service{
+				{
+					Metadata: &metav1.ObjectMeta{
+						Name:            strPtr("nginx"),
+						Namespace:       strPtr("default"),
+						ResourceVersion: strPtr("43"),
+						Annotations: map[string]string{
+							"ad.datadoghq.com/service.check_names": `["http_check"]`,
+							"ad.datadoghq.com/service.instances":   `[{"url": "http://%%host%%"}]`,
+						},
+					},
+					Spec: &v1.ServiceSpec{ClusterIP: strPtr("10.0.0.12")},
+				},
+				{
+					Metadata: &metav1.ObjectMeta{
+						Name:            strPtr("not-annotated"),
+						Namespace:       strPtr("default"),
+						ResourceVersion: strPtr("44"),
+					},
+					Spec: &v1.ServiceSpec{ClusterIP: strPtr("10.0.0.13")},
+				},
+			},
+		})
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to %s", r.URL.Path)
+		http.NotFound(w, r)
+	})
+	return httptest.NewServer(mux), &selectors
+}
+
+func TestAPIServerLister(t *testing.T) {
+	ts, selectors := newFakeAPIServer(t)
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "kube_apiserver_client_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	kubeConfigPath := filepath.Join(dir, "kubeconfig.json")
+	err = ioutil.WriteFile(kubeConfigPath, []byte(fmt.Sprintf(testKubeConfig, ts.URL)), 0600)
+	require.NoError(t, err)
+
+	config.Datadog.Set("kubernetes_kubeconfig_path", kubeConfigPath)
+	config.Datadog.Set("use_service_mapper", false)
+	defer config.Datadog.Set("kubernetes_kubeconfig_path", "")
+	defer config.Datadog.Set("use_service_mapper", true)
+
+	lister := &apiserverLister{
+		namespace: "default",
+		selector:  "datadoghq.com/ad=true",
+	}
+
+	configMaps, err := lister.listConfigMaps()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"datadoghq.com/ad=true"}, *selectors)
+	assert.Equal(t, []kubeTemplateSource{
+		{
+			kind:            kubeConfigMapKind,
+			namespace:       "default",
+			name:            "redis-checks",
+			resourceVersion: "42",
+			data: map[string]string{
+				"redisdb.check_names": `["redisdb"]`,
+				"redisdb.instances":   `[{"host": "redis"}]`,
+			},
+		},
+	}, configMaps)
+
+	// services without AD annotations are skipped
+	services, err := lister.listServices()
+	require.NoError(t, err)
+	assert.Equal(t, []kubeTemplateSource{
+		{
+			kind:            kubeServiceKind,
+			namespace:       "default",
+			name:            "nginx",
+			resourceVersion: "43",
+			data: map[string]string{
+				"ad.datadoghq.com/service.check_names": `["http_check"]`,
+				"ad.datadoghq.com/service.instances":   `[{"url": "http://%%host%%"}]`,
+			},
+			clusterIP: "10.0.0.12",
+		},
+	}, services)
+}
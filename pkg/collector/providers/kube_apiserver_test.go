@@ -0,0 +1,163 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package providers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+)
+
+// fakeKubeLister stores ConfigMaps and Services in memory, like a fake clientset
+type fakeKubeLister struct {
+	configMaps []kubeTemplateSource
+	services   []kubeTemplateSource
+	err        error
+	calls      int
+}
+
+func (f *fakeKubeLister) listConfigMaps() ([]kubeTemplateSource, error) {
+	f.calls++
+	return f.configMaps, f.err
+}
+
+func (f *fakeKubeLister) listServices() ([]kubeTemplateSource, error) {
+	return f.services, f.err
+}
+
+func newFakeKubeLister() *fakeKubeLister {
+	return &fakeKubeLister{
+		configMaps: []kubeTemplateSource{
+			{
+				kind:            kubeConfigMapKind,
+				namespace:       "default",
+				name:            "rds",
+				resourceVersion: "100",
+				data: map[string]string{
+					"check_names":   `["postgres"]`,
+					"init_configs":  `[{}]`,
+					"instances":     `[{"host": "rds.example.com", "port": 5432}]`,
+					"cluster_check": "true",
+				},
+			},
+			{
+				kind:            kubeConfigMapKind,
+				namespace:       "default",
+				name:            "redis-template",
+				resourceVersion: "101",
+				data: map[string]string{
+					"ad_identifiers": `["redis", "custom-redis"]`,
+					"check_names":    `["redisdb"]`,
+					"init_configs":   `[{}]`,
+					"instances":      `[{"host": "%%host%%", "port": "6379"}]`,
+				},
+			},
+		},
+		services: []kubeTemplateSource{
+			{
+				kind:            kubeServiceKind,
+				namespace:       "web",
+				name:            "nginx",
+				resourceVersion: "200",
+				clusterIP:       "10.0.0.12",
+				data: map[string]string{
+					"ad.datadoghq.com/service.check_names":  `["http_check"]`,
+					"ad.datadoghq.com/service.init_configs": `[{}]`,
+					"ad.datadoghq.com/service.instances":    `[{"name": "nginx", "url": "http://%%host%%/status"}]`,
+				},
+			},
+		},
+	}
+}
+
+func TestKubeAPIServerCollect(t *testing.T) {
+	provider := newKubeAPIServerConfigProvider(newFakeKubeLister())
+
+	configs, err := provider.Collect()
+	require.NoError(t, err)
+	require.Len(t, configs, 3)
+
+	rds := configs[0]
+	assert.Equal(t, "postgres", rds.Name)
+	assert.False(t, rds.IsTemplate())
+	assert.True(t, rds.ClusterCheck)
+	assert.Equal(t, check.ConfigData(`{"host":"rds.example.com","port":5432}`), rds.Instances[0])
+
+	redis := configs[1]
+	assert.Equal(t, "redisdb", redis.Name)
+	assert.Equal(t, []string{"redis", "custom-redis"}, redis.ADIdentifiers)
+	assert.True(t, redis.IsTemplate())
+	assert.False(t, redis.ClusterCheck)
+
+	nginx := configs[2]
+	assert.Equal(t, "http_check", nginx.Name)
+	assert.False(t, nginx.IsTemplate())
+	assert.Contains(t, string(nginx.Instances[0]), "url: http://10.0.0.12/status")
+	assert.Contains(t, string(nginx.Instances[0]), "- kube_service:nginx")
+	assert.Contains(t, string(nginx.Instances[0]), "- kube_namespace:web")
+}
+
+func TestKubeAPIServerInvalidSources(t *testing.T) {
+	lister := newFakeKubeLister()
+	// headless service, %%host%% can't be resolved
+	lister.services[0].clusterIP = "None"
+	lister.configMaps[1].data["ad_identifiers"] = "redis"
+	provider := newKubeAPIServerConfigProvider(lister)
+
+	configs, err := provider.Collect()
+	require.NoError(t, err)
+	require.Len(t, configs, 1)
+	assert.Equal(t, "postgres", configs[0].Name)
+
+	lister.err = errors.New("forbidden")
+	_, err = provider.Collect()
+	assert.Error(t, err)
+	_, err = provider.IsUpToDate()
+	assert.Error(t, err)
+}
+
+func TestKubeAPIServerIsUpToDate(t *testing.T) {
+	lister := newFakeKubeLister()
+	provider := newKubeAPIServerConfigProvider(lister)
+
+	// nothing collected yet
+	upToDate, err := provider.IsUpToDate()
+	require.NoError(t, err)
+	assert.False(t, upToDate)
+
+	// Collect reuses the sources listed by IsUpToDate
+	_, err = provider.Collect()
+	require.NoError(t, err)
+	assert.Equal(t, 1, lister.calls)
+
+	upToDate, err = provider.IsUpToDate()
+	require.NoError(t, err)
+	assert.True(t, upToDate)
+
+	// a ConfigMap is updated
+	lister.configMaps[0].resourceVersion = "102"
+	upToDate, err = provider.IsUpToDate()
+	require.NoError(t, err)
+	assert.False(t, upToDate)
+	_, err = provider.Collect()
+	require.NoError(t, err)
+	upToDate, err = provider.IsUpToDate()
+	require.NoError(t, err)
+	assert.True(t, upToDate)
+
+	// a Service is deleted
+	lister.services = nil
+	upToDate, err = provider.IsUpToDate()
+	require.NoError(t, err)
+	assert.False(t, upToDate)
+	configs, err := provider.Collect()
+	require.NoError(t, err)
+	assert.Len(t, configs, 2)
+}
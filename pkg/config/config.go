@@ -48,6 +48,12 @@ type Listeners struct {
 	Name string `mapstructure:"name"`
 }
 
+// ProcessADPattern helps unmarshalling `ad_process_patterns` config param
+type ProcessADPattern struct {
+	ADIdentifier string `mapstructure:"ad_identifier"`
+	CommandRegex string `mapstructure:"command_regex"`
+}
+
 // Proxy represents the configuration for proxies in the agent
 type Proxy struct {
 	HTTP    string   `mapstructure:"http"`
@@ -72,6 +78,7 @@ func init() {
 	Datadog.SetDefault("conf_path", ".")
 	Datadog.SetDefault("confd_path", defaultConfdPath)
 	Datadog.SetDefault("confd_dca_path", defaultDCAConfdPath)
+	BindEnvAndSetDefault("confd_watch", true)
 	Datadog.SetDefault("use_service_mapper", true)
 	Datadog.SetDefault("additional_checksd", defaultAdditionalChecksPath)
 	Datadog.SetDefault("log_level", "info")
@@ -90,6 +97,12 @@ func init() {
 	Datadog.SetDefault("check_runners", int64(1))
 	Datadog.SetDefault("expvar_port", "5000")
 
+	// Secrets
+	BindEnvAndSetDefault("secret_backend_command", "")
+	BindEnvAndSetDefault("secret_backend_arguments", []string{})
+	BindEnvAndSetDefault("secret_backend_output_max_size", 1024*1024)
+	BindEnvAndSetDefault("secret_backend_timeout", 5)
+
 	// Use to output logs in JSON format
 	BindEnvAndSetDefault("log_format_json", false)
 
@@ -138,15 +151,29 @@ func init() {
 	Datadog.SetDefault("dogstatsd_stats_buffer", 10)
 	Datadog.SetDefault("dogstatsd_expiry_seconds", 300)
 	Datadog.SetDefault("dogstatsd_origin_detection", false) // Only supported for socket traffic
+	// Honor the origin declared by UDP clients sending from the trusted networks
+	Datadog.SetDefault("dogstatsd_client_origin_detection", false)
+	BindEnvAndSetDefault("dogstatsd_client_origin_trusted_networks", []string{"127.0.0.0/8", "::1/128"})
+	Datadog.SetDefault("dogstatsd_tag_cardinality", "low")
+	Datadog.SetDefault("checks_tag_cardinality", "high")
 	Datadog.SetDefault("statsd_forward_host", "")
 	Datadog.SetDefault("statsd_forward_port", 0)
 	// Autoconfig
 	Datadog.SetDefault("autoconf_template_dir", "/datadog/check_configs")
 	Datadog.SetDefault("exclude_pause_container", true)
+	Datadog.SetDefault("ad_process_patterns", []ProcessADPattern{})
+	BindEnvAndSetDefault("ad_process_poll_interval", 10)
+	// Container runtimes
+	BindEnvAndSetDefault("cri_socket_path", "")
+	BindEnvAndSetDefault("cri_connection_timeout", 1)
+	BindEnvAndSetDefault("cri_query_timeout", 5)
 	// Docker
 	Datadog.SetDefault("docker_labels_as_tags", map[string]string{})
 	Datadog.SetDefault("docker_env_as_tags", map[string]string{})
 	Datadog.SetDefault("kubernetes_pod_labels_as_tags", map[string]string{})
+	Datadog.SetDefault("kubernetes_pod_annotations_as_tags", map[string]string{})
+	Datadog.SetDefault("kubernetes_node_labels_as_tags", map[string]string{})
+	Datadog.SetDefault("kubernetes_namespace_labels_as_tags", map[string]string{})
 
 	// Kubernetes
 	Datadog.SetDefault("kubernetes_http_kubelet_port", 10255)
@@ -161,11 +188,22 @@ func init() {
 
 	// Kube ApiServer
 	Datadog.SetDefault("kubernetes_kubeconfig_path", "")
+	BindEnvAndSetDefault("kubernetes_ad_namespace", "")
+	BindEnvAndSetDefault("kubernetes_ad_configmap_selector", "ad.datadoghq.com/checks=true")
+	BindEnvAndSetDefault("leader_election", false)
+	BindEnvAndSetDefault("leader_lease_duration", 60)
 
 	// Datadog cluster agent
+	Datadog.SetDefault("cluster_agent.enabled", false)
 	Datadog.SetDefault("cluster_agent.auth_token", "")
 	Datadog.SetDefault("cluster_agent.url", "")
 	Datadog.SetDefault("cluster_agent.kubernetes_service_name", "dca")
+	Datadog.SetDefault("cluster_checks.enabled", false)
+	Datadog.SetDefault("cluster_checks.node_expiration_timeout", 30) // value in seconds
+	Datadog.SetDefault("external_metrics_provider.enabled", false)
+	Datadog.SetDefault("external_metrics_provider.refresh_period", 30) // value in seconds
+	Datadog.SetDefault("external_metrics_provider.max_age", 300)       // value in seconds
+	Datadog.SetDefault("external_metrics_provider.api_url", "https://api.datadoghq.com")
 
 	// ECS
 	Datadog.SetDefault("ecs_agent_url", "") // Will be autodetected
@@ -207,6 +245,9 @@ func init() {
 	Datadog.BindEnv("dogstatsd_stats_port")
 	Datadog.BindEnv("dogstatsd_non_local_traffic")
 	Datadog.BindEnv("dogstatsd_origin_detection")
+	Datadog.BindEnv("dogstatsd_client_origin_detection")
+	Datadog.BindEnv("dogstatsd_tag_cardinality")
+	Datadog.BindEnv("checks_tag_cardinality")
 	Datadog.BindEnv("log_file")
 	Datadog.BindEnv("log_level")
 	Datadog.BindEnv("log_to_console")
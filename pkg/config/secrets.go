@@ -0,0 +1,60 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package config
+
+import (
+	"fmt"
+
+	"github.com/DataDog/datadog-agent/pkg/secrets"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// ResolveSecrets initializes the secrets package from the configuration and
+// replaces the ENC[] handles found in the configuration by their decrypted
+// value. It must be called right after the configuration file is read.
+func ResolveSecrets() error {
+	secrets.Init(
+		Datadog.GetString("secret_backend_command"),
+		Datadog.GetStringSlice("secret_backend_arguments"),
+		Datadog.GetInt("secret_backend_timeout"),
+		Datadog.GetInt("secret_backend_output_max_size"),
+	)
+	return resolveSecrets(Datadog.AllKeys(), Datadog.Get, Datadog.Set)
+}
+
+// resolveSecrets decrypts the values of the given keys containing ENC[]
+// handles, in a single call to the secret backend
+func resolveSecrets(keys []string, get func(string) interface{}, set func(string, interface{})) error {
+	encrypted := map[string]interface{}{}
+	for _, key := range keys {
+		value := get(key)
+		raw, err := yaml.Marshal(value)
+		if err != nil || !secrets.ContainsHandle(raw) {
+			continue
+		}
+		encrypted[key] = value
+	}
+	if len(encrypted) == 0 {
+		return nil
+	}
+
+	raw, err := yaml.Marshal(encrypted)
+	if err != nil {
+		return fmt.Errorf("unable to marshal configuration to YAML to decrypt secrets: %s", err)
+	}
+	decryptedRaw, err := secrets.Decrypt(raw, "datadog.yaml")
+	if err != nil {
+		return fmt.Errorf("unable to decrypt secrets from datadog.yaml: %s", err)
+	}
+	decrypted := map[string]interface{}{}
+	if err := yaml.Unmarshal(decryptedRaw, &decrypted); err != nil {
+		return fmt.Errorf("unable to unmarshal decrypted configuration: %s", err)
+	}
+	for key, value := range decrypted {
+		set(key, value)
+	}
+	return nil
+}
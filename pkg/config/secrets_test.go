@@ -0,0 +1,38 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveSecretsNoHandle(t *testing.T) {
+	values := map[string]interface{}{
+		"api_key":  "abcdef",
+		"tags":     []string{"env:prod"},
+		"cmd_port": 5001,
+	}
+	keys := []string{"api_key", "tags", "cmd_port"}
+	get := func(key string) interface{} { return values[key] }
+	set := func(key string, value interface{}) { t.Fatalf("unexpected set of %s", key) }
+
+	assert.NoError(t, resolveSecrets(keys, get, set))
+}
+
+func TestResolveSecretsNoBackend(t *testing.T) {
+	values := map[string]interface{}{
+		"api_key": "ENC[api_key]",
+		"tags":    []string{"env:prod"},
+	}
+	keys := []string{"api_key", "tags"}
+	get := func(key string) interface{} { return values[key] }
+	set := func(key string, value interface{}) { t.Fatalf("unexpected set of %s", key) }
+
+	// no secret_backend_command set
+	assert.Error(t, resolveSecrets(keys, get, set))
+}
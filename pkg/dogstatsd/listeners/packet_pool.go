@@ -47,5 +47,6 @@ func (p *PacketPool) Put(packet *Packet) {
 	if packet.Origin != NoOrigin {
 		packet.Origin = NoOrigin
 	}
+	packet.TrustClientOrigin = false
 	p.pool.Put(packet)
 }
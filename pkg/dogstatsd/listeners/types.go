@@ -15,6 +15,9 @@ type Packet struct {
 	Contents []byte // Contents, might contain several messages
 	buffer   []byte // Underlying buffer for data read
 	Origin   string // Origin container if identified
+	// TrustClientOrigin is true if the origin declared by the client in the
+	// messages can be used, because the packet comes from a trusted source
+	TrustClientOrigin bool
 }
 
 // StatsdListener opens a communication channel to get statsd packets in.
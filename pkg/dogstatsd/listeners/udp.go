@@ -23,11 +23,14 @@ var (
 // UDPListener implements the StatsdListener interface for UDP protocol.
 // It listens to a given UDP address and sends back packets ready to be
 // processed.
-// Origin detection is not implemented for UDP.
+// Origin detection is not possible for UDP, but clients can declare their
+// origin in the messages, honored if they send from a trusted network.
 type UDPListener struct {
 	conn       net.PacketConn
 	packetPool *PacketPool
 	packetOut  chan *Packet
+	// trustedNetworks is nil if client origin detection is disabled
+	trustedNetworks []*net.IPNet
 }
 
 // NewUDPListener returns an idle UDP Statsd listener
@@ -54,6 +57,13 @@ func NewUDPListener(packetOut chan *Packet, packetPool *PacketPool) (*UDPListene
 		packetPool: packetPool,
 		conn:       conn,
 	}
+	if config.Datadog.GetBool("dogstatsd_client_origin_detection") {
+		listener.trustedNetworks, err = parseNetworks(config.Datadog.GetStringSlice("dogstatsd_client_origin_trusted_networks"))
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("invalid dogstatsd_client_origin_trusted_networks: %s", err)
+		}
+	}
 	log.Debugf("dogstatsd-udp: %s successfully initialized", conn.LocalAddr())
 	return listener, nil
 }
@@ -63,7 +73,7 @@ func (l *UDPListener) Listen() {
 	log.Infof("dogstatsd-udp: starting to listen on %s", l.conn.LocalAddr())
 	for {
 		packet := l.packetPool.Get()
-		n, _, err := l.conn.ReadFrom(packet.buffer)
+		n, addr, err := l.conn.ReadFrom(packet.buffer)
 		if err != nil {
 			// connection has been closed
 			if strings.HasSuffix(err.Error(), " use of closed network connection") {
@@ -76,10 +86,52 @@ func (l *UDPListener) Listen() {
 		}
 
 		packet.Contents = packet.buffer[:n]
+		packet.TrustClientOrigin = l.isTrusted(addr)
 		l.packetOut <- packet
 	}
 }
 
+// isTrusted returns whether the origin declared by the clients can be used
+// for packets sent from addr
+func (l *UDPListener) isTrusted(addr net.Addr) bool {
+	udpAddr, ok := addr.(*net.UDPAddr)
+	if !ok {
+		return false
+	}
+	for _, network := range l.trustedNetworks {
+		if network.Contains(udpAddr.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseNetworks parses a list of networks in the CIDR notation, single IP
+// addresses being accepted as well
+func parseNetworks(cidrs []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address %q", cidr)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
 // Stop closes the UDP connection and stops listening
 func (l *UDPListener) Stop() {
 	l.conn.Close()
@@ -175,3 +175,59 @@ func getLocalIP() string {
 	}
 	return ""
 }
+
+func TestUDPClientOriginTrust(t *testing.T) {
+	config.Datadog.SetDefault("dogstatsd_non_local_traffic", false)
+	config.Datadog.SetDefault("dogstatsd_client_origin_detection", true)
+	defer config.Datadog.SetDefault("dogstatsd_client_origin_detection", false)
+	defer config.Datadog.SetDefault("dogstatsd_client_origin_trusted_networks", []string{"127.0.0.0/8", "::1/128"})
+
+	for _, tc := range []struct {
+		trustedNetworks []string
+		trusted         bool
+	}{
+		{[]string{"127.0.0.0/8", "::1/128"}, true},
+		// only the loopback is trusted
+		{[]string{"10.0.0.0/8"}, false},
+	} {
+		port, err := getAvailableUDPPort()
+		require.Nil(t, err)
+		config.Datadog.SetDefault("dogstatsd_port", port)
+		config.Datadog.SetDefault("dogstatsd_client_origin_trusted_networks", tc.trustedNetworks)
+
+		packetChannel := make(chan *Packet)
+		s, err := NewUDPListener(packetChannel, packetPoolUDP)
+		require.NoError(t, err)
+		go s.Listen()
+
+		conn, err := net.Dial("udp", fmt.Sprintf("127.0.0.1:%d", port))
+		require.NoError(t, err)
+		conn.Write([]byte("daemon:666|g|c:1234"))
+
+		select {
+		case packet := <-packetChannel:
+			assert.Equal(t, tc.trusted, packet.TrustClientOrigin, "trusted networks: %v", tc.trustedNetworks)
+			packetPoolUDP.Put(packet)
+		case <-time.After(2 * time.Second):
+			assert.FailNow(t, "Timeout on receive channel")
+		}
+		conn.Close()
+		s.Stop()
+	}
+}
+
+func TestParseNetworks(t *testing.T) {
+	networks, err := parseNetworks([]string{"172.16.0.0/12", "10.1.2.3", "::1"})
+	require.NoError(t, err)
+	require.Len(t, networks, 3)
+	assert.True(t, networks[0].Contains(net.ParseIP("172.17.0.2")))
+	assert.False(t, networks[0].Contains(net.ParseIP("172.32.0.1")))
+	assert.True(t, networks[1].Contains(net.ParseIP("10.1.2.3")))
+	assert.False(t, networks[1].Contains(net.ParseIP("10.1.2.4")))
+	assert.True(t, networks[2].Contains(net.ParseIP("::1")))
+
+	_, err = parseNetworks([]string{"10.0.0.0/33"})
+	assert.Error(t, err)
+	_, err = parseNetworks([]string{"not-an-ip"})
+	assert.Error(t, err)
+}
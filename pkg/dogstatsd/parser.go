@@ -10,10 +10,12 @@ import (
 	"bytes"
 	"fmt"
 	"strconv"
+	"strings"
 
 	log "github.com/cihub/seelog"
 
 	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/docker"
 )
 
 // Schema of a dogstatsd packet: see http://docs.datadoghq.com
@@ -32,6 +34,10 @@ var tagSeparator = []byte(",")
 var fieldSeparator = []byte("|")
 var valueSeparator = []byte(":")
 
+// entityIDTagPrefix is the prefix of the tag a client can use to declare the
+// tagger entity it sends from, eg. kubernetes_pod://<uid>
+const entityIDTagPrefix = "dd.internal.entity_id:"
+
 func nextMessage(packet *[]byte) (message []byte) {
 	if len(*packet) == 0 {
 		return nil
@@ -82,8 +88,30 @@ func parseTags(rawTags []byte, extractHost bool) ([]string, string) {
 	return tagsList, host
 }
 
+// extractEntityID removes the entity ID tag from the tags and returns the
+// origin it declares
+func extractEntityID(tags []string) ([]string, string) {
+	for i, tag := range tags {
+		if strings.HasPrefix(tag, entityIDTagPrefix) {
+			origin := clientOrigin(tag[len(entityIDTagPrefix):])
+			return append(tags[:i], tags[i+1:]...), origin
+		}
+	}
+	return tags, ""
+}
+
+// clientOrigin returns the tagger entity of an origin declared by a client,
+// a container ID without runtime prefix being considered a docker container
+func clientOrigin(origin string) string {
+	if strings.Contains(origin, "://") {
+		return origin
+	}
+	return docker.ContainerIDToEntityName(origin)
+}
+
 func parseServiceCheckMessage(message []byte) (*metrics.ServiceCheck, error) {
 	// _sc|name|status|[metadata|...]
+	// metadata fields: d:timestamp, h:hostname, #tags, m:message, c:container-id
 
 	separatorCount := bytes.Count(message, fieldSeparator)
 	if separatorCount < 2 {
@@ -129,11 +157,20 @@ func parseServiceCheckMessage(message []byte) (*metrics.ServiceCheck, error) {
 			service.Tags, _ = parseTags(rawMetadataField[1:], false)
 		} else if bytes.HasPrefix(rawMetadataField, []byte("m:")) {
 			service.Message = string(rawMetadataField[2:])
+		} else if bytes.HasPrefix(rawMetadataField, []byte("c:")) {
+			service.OriginID = clientOrigin(string(rawMetadataField[2:]))
 		} else {
 			log.Warnf("unknown metadata type: '%s'", rawMetadataField)
 		}
 	}
 
+	if tags, origin := extractEntityID(service.Tags); origin != "" {
+		service.Tags = tags
+		if service.OriginID == "" {
+			service.OriginID = origin
+		}
+	}
+
 	return &service, nil
 }
 
@@ -146,6 +183,7 @@ func parseEventMessage(message []byte) (*metrics.Event, error) {
 	//   |t:alert_type
 	//   |s:source_type_nam
 	//   |#tag1,tag2
+	//   |c:container_id
 	//  ]
 
 	messageRaw := bytes.SplitN(message, []byte(":"), 2)
@@ -222,21 +260,31 @@ func parseEventMessage(message []byte) (*metrics.Event, error) {
 				event.SourceTypeName = string(rawMetadataFields[i][2:])
 			} else if bytes.HasPrefix(rawMetadataFields[i], []byte("#")) {
 				event.Tags, _ = parseTags(rawMetadataFields[i][1:], false)
+			} else if bytes.HasPrefix(rawMetadataFields[i], []byte("c:")) {
+				event.OriginID = clientOrigin(string(rawMetadataFields[i][2:]))
 			} else {
 				log.Warnf("unknown metadata type: '%s'", rawMetadataFields[i])
 			}
 		}
 	}
 
+	if tags, origin := extractEntityID(event.Tags); origin != "" {
+		event.Tags = tags
+		if event.OriginID == "" {
+			event.OriginID = origin
+		}
+	}
+
 	return &event, nil
 }
 
 func parseMetricMessage(message []byte) (*metrics.MetricSample, error) {
 	// daemon:666|g|#sometag1:somevalue1,sometag2:somevalue2
 	// daemon:666|g|@0.1|#sometag:somevalue"
+	// daemon:666|g|#sometag:somevalue|c:container_id
 
 	separatorCount := bytes.Count(message, fieldSeparator)
-	if separatorCount < 1 || separatorCount > 3 {
+	if separatorCount < 1 || separatorCount > 4 {
 		return nil, fmt.Errorf("invalid field number for %q", message)
 	}
 
@@ -256,6 +304,7 @@ func parseMetricMessage(message []byte) (*metrics.MetricSample, error) {
 	// Metadata
 	var metricTags []string
 	var host string
+	var origin string
 	var rawMetadataField []byte
 	sampleRate := 1.0
 
@@ -271,6 +320,8 @@ func parseMetricMessage(message []byte) (*metrics.MetricSample, error) {
 			if err != nil {
 				return nil, fmt.Errorf("invalid sample value for %q", message)
 			}
+		} else if bytes.HasPrefix(rawMetadataField, []byte("c:")) {
+			origin = clientOrigin(string(rawMetadataField[2:]))
 		}
 
 		if remainder == nil {
@@ -278,6 +329,13 @@ func parseMetricMessage(message []byte) (*metrics.MetricSample, error) {
 		}
 	}
 
+	if tags, entityOrigin := extractEntityID(metricTags); entityOrigin != "" {
+		metricTags = tags
+		if origin == "" {
+			origin = entityOrigin
+		}
+	}
+
 	metricName := string(rawName)
 
 	metricType, ok := metricTypes[string(rawType)]
@@ -292,6 +350,7 @@ func parseMetricMessage(message []byte) (*metrics.MetricSample, error) {
 		Host:       host,
 		SampleRate: sampleRate,
 		Timestamp:  0,
+		OriginID:   origin,
 	}
 
 	if metricType == metrics.SetType {
@@ -631,3 +631,46 @@ func TestEventMetadataMultiple(t *testing.T) {
 	assert.Equal(t, "source test", e.SourceTypeName)
 	assert.Equal(t, "", e.EventType)
 }
+
+func TestParseMetricClientOrigin(t *testing.T) {
+	parsed, err := parseMetricMessage([]byte("daemon:666|g|@0.5|#sometag:somevalue|c:1234abcd"))
+	require.NoError(t, err)
+	assert.Equal(t, "docker://1234abcd", parsed.OriginID)
+	assert.Equal(t, []string{"sometag:somevalue"}, parsed.Tags)
+	assert.InEpsilon(t, 0.5, parsed.SampleRate, epsilon)
+
+	parsed, err = parseMetricMessage([]byte("daemon:666|g|c:containerd://1234abcd"))
+	require.NoError(t, err)
+	assert.Equal(t, "containerd://1234abcd", parsed.OriginID)
+
+	// entity ID tag, the c: field taking precedence
+	parsed, err = parseMetricMessage([]byte("daemon:666|g|#sometag:somevalue,dd.internal.entity_id:kubernetes_pod://my-uid"))
+	require.NoError(t, err)
+	assert.Equal(t, "kubernetes_pod://my-uid", parsed.OriginID)
+	assert.Equal(t, []string{"sometag:somevalue"}, parsed.Tags)
+
+	parsed, err = parseMetricMessage([]byte("daemon:666|g|#dd.internal.entity_id:5678|c:1234"))
+	require.NoError(t, err)
+	assert.Equal(t, "docker://1234", parsed.OriginID)
+	assert.Len(t, parsed.Tags, 0)
+
+	parsed, err = parseMetricMessage([]byte("daemon:666|g|#sometag:somevalue"))
+	require.NoError(t, err)
+	assert.Equal(t, "", parsed.OriginID)
+}
+
+func TestParseEventAndServiceCheckClientOrigin(t *testing.T) {
+	e, err := parseEventMessage([]byte("_e{10,9}:test title|test text|#tag1,dd.internal.entity_id:1234"))
+	require.NoError(t, err)
+	assert.Equal(t, "docker://1234", e.OriginID)
+	assert.Equal(t, []string{"tag1"}, e.Tags)
+
+	e, err = parseEventMessage([]byte("_e{10,9}:test title|test text|c:5678"))
+	require.NoError(t, err)
+	assert.Equal(t, "docker://5678", e.OriginID)
+
+	sc, err := parseServiceCheckMessage([]byte("_sc|agent.up|0|#tag1|c:1234"))
+	require.NoError(t, err)
+	assert.Equal(t, "docker://1234", sc.OriginID)
+	assert.Equal(t, []string{"tag1"}, sc.Tags)
+}
@@ -19,6 +19,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/dogstatsd/listeners"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/DataDog/datadog-agent/pkg/tagger"
+	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
 	"github.com/DataDog/datadog-agent/pkg/util"
 )
 
@@ -34,6 +35,9 @@ type Server struct {
 	Statistics *util.Stats
 	Started    bool
 	packetPool *listeners.PacketPool
+	// originTagCardinality is the cardinality of the origin tags, per
+	// dogstatsd_tag_cardinality
+	originTagCardinality collectors.TagCardinality
 }
 
 // NewServer returns a running Dogstatsd server
@@ -75,11 +79,12 @@ func NewServer(metricOut chan<- *metrics.MetricSample, eventOut chan<- metrics.E
 	}
 
 	s := &Server{
-		Started:    true,
-		Statistics: stats,
-		packetIn:   packetChannel,
-		listeners:  tmpListeners,
-		packetPool: packetPool,
+		Started:              true,
+		Statistics:           stats,
+		packetIn:             packetChannel,
+		listeners:            tmpListeners,
+		packetPool:           packetPool,
+		originTagCardinality: originTagCardinality(),
 	}
 
 	forwardHost := config.Datadog.GetString("statsd_forward_host")
@@ -158,13 +163,8 @@ func (s *Server) worker(metricOut chan<- *metrics.MetricSample, eventOut chan<-
 		var originTags []string
 
 		if packet.Origin != listeners.NoOrigin {
-			var err error
 			log.Tracef("dogstatsd receive from %s: %s", packet.Origin, packet.Contents)
-			originTags, err = tagger.Tag(packet.Origin, false)
-			if err != nil {
-				log.Errorf(err.Error())
-			}
-			log.Tracef("tags for %s: %s", packet.Origin, originTags)
+			originTags = s.originTags(packet.Origin)
 		} else {
 			log.Tracef("dogstatsd receive: %s", packet.Contents)
 		}
@@ -186,9 +186,7 @@ func (s *Server) worker(metricOut chan<- *metrics.MetricSample, eventOut chan<-
 					dogstatsdExpvar.Add("ServiceCheckParseErrors", 1)
 					continue
 				}
-				if len(originTags) > 0 {
-					serviceCheck.Tags = append(serviceCheck.Tags, originTags...)
-				}
+				serviceCheck.Tags = append(serviceCheck.Tags, s.messageOriginTags(packet, originTags, serviceCheck.OriginID)...)
 				dogstatsdExpvar.Add("ServiceCheckPackets", 1)
 				serviceCheckOut <- *serviceCheck
 			} else if bytes.HasPrefix(message, []byte("_e")) {
@@ -198,9 +196,7 @@ func (s *Server) worker(metricOut chan<- *metrics.MetricSample, eventOut chan<-
 					dogstatsdExpvar.Add("EventParseErrors", 1)
 					continue
 				}
-				if len(originTags) > 0 {
-					event.Tags = append(event.Tags, originTags...)
-				}
+				event.Tags = append(event.Tags, s.messageOriginTags(packet, originTags, event.OriginID)...)
 				dogstatsdExpvar.Add("EventPackets", 1)
 				eventOut <- *event
 			} else {
@@ -210,9 +206,7 @@ func (s *Server) worker(metricOut chan<- *metrics.MetricSample, eventOut chan<-
 					dogstatsdExpvar.Add("MetricParseErrors", 1)
 					continue
 				}
-				if len(originTags) > 0 {
-					sample.Tags = append(sample.Tags, originTags...)
-				}
+				sample.Tags = append(sample.Tags, s.messageOriginTags(packet, originTags, sample.OriginID)...)
 				dogstatsdExpvar.Add("MetricPackets", 1)
 				metricOut <- sample
 			}
@@ -222,6 +216,41 @@ func (s *Server) worker(metricOut chan<- *metrics.MetricSample, eventOut chan<-
 	}
 }
 
+// originTags returns the tags of an origin entity
+func (s *Server) originTags(origin string) []string {
+	tags, err := tagger.Tag(origin, s.originTagCardinality)
+	if err != nil {
+		log.Errorf(err.Error())
+	}
+	log.Tracef("tags for %s: %s", origin, tags)
+	return tags
+}
+
+// messageOriginTags returns the origin tags of a message: the ones of the
+// origin detected for the packet, or else the ones of the origin declared
+// by the client if the packet comes from a trusted source.
+func (s *Server) messageOriginTags(packet *listeners.Packet, packetOriginTags []string, clientOrigin string) []string {
+	if packet.Origin != listeners.NoOrigin || clientOrigin == "" {
+		return packetOriginTags
+	}
+	if !packet.TrustClientOrigin {
+		dogstatsdExpvar.Add("UntrustedClientOrigins", 1)
+		return nil
+	}
+	return s.originTags(clientOrigin)
+}
+
+// originTagCardinality returns the cardinality of the origin tags, per
+// dogstatsd_tag_cardinality
+func originTagCardinality() collectors.TagCardinality {
+	cardinality, err := collectors.StringToTagCardinality(config.Datadog.GetString("dogstatsd_tag_cardinality"))
+	if err != nil {
+		log.Warnf("dogstatsd: invalid dogstatsd_tag_cardinality, using low: %s", err)
+		return collectors.LowCardinality
+	}
+	return cardinality
+}
+
 // Stop stops a running Dogstatsd server
 func (s *Server) Stop() {
 	for _, l := range s.listeners {
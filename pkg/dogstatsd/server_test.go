@@ -6,6 +6,7 @@
 package dogstatsd
 
 import (
+	"expvar"
 	"fmt"
 	"net"
 	"strconv"
@@ -16,7 +17,9 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/listeners"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
 )
 
 // getAvailableUDPPort requests a random port number and makes sure it is available
@@ -244,3 +247,34 @@ func TestUDPForward(t *testing.T) {
 
 	assert.Equal(t, message, buffer)
 }
+
+func TestMessageOriginTags(t *testing.T) {
+	s := &Server{originTagCardinality: collectors.LowCardinality}
+	untrusted := func() int64 {
+		if v, ok := dogstatsdExpvar.Get("UntrustedClientOrigins").(*expvar.Int); ok {
+			return v.Value()
+		}
+		return 0
+	}
+	count := untrusted()
+
+	// the origin detected for the packet wins over the declared one
+	packet := &listeners.Packet{Origin: "docker://abc"}
+	assert.Equal(t, []string{"image_name:redis"},
+		s.messageOriginTags(packet, []string{"image_name:redis"}, "docker://def"))
+
+	// no origin declared by the client
+	packet = &listeners.Packet{}
+	assert.Nil(t, s.messageOriginTags(packet, nil, ""))
+	assert.Equal(t, count, untrusted())
+
+	// the origin declared from an untrusted source is dropped
+	packet = &listeners.Packet{TrustClientOrigin: false}
+	assert.Nil(t, s.messageOriginTags(packet, nil, "docker://def"))
+	assert.Equal(t, count+1, untrusted())
+
+	// the origin declared from a trusted source is looked up in the tagger
+	packet = &listeners.Packet{TrustClientOrigin: true}
+	s.messageOriginTags(packet, nil, "docker://def")
+	assert.Equal(t, count+1, untrusted())
+}
@@ -0,0 +1,19 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// Package errors holds the errors shared by packages that can't import each
+// other, like the tagger collectors and the apiserver client they query
+// through the cluster agent. It must not import any package of the agent.
+package errors
+
+import "errors"
+
+// ErrNotFound is returned if no error occurred but the entity is not found
+// in the source
+var ErrNotFound = errors.New("entity not found")
+
+// ErrOutdated is returned when an entity is found but
+// it's value is outdated compared to the one locally saved
+var ErrOutdated = errors.New("entity is outdated")
@@ -11,6 +11,8 @@ import (
 	"io"
 	"os"
 	"regexp"
+
+	"github.com/DataDog/datadog-agent/pkg/secrets"
 )
 
 type replacer struct {
@@ -90,6 +92,8 @@ func credentialsCleaner(file io.Reader) ([]byte, error) {
 					b = repl.regex.ReplaceAll(b, repl.repl)
 				}
 			}
+			// remove the decrypted secrets that could be found in the runtime config
+			b = secrets.Scrub(b)
 			finalFile += string(b) + "\n"
 		}
 	}
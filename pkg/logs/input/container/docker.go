@@ -16,6 +16,7 @@ import (
 	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/tagger"
+	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
 	dockerutil "github.com/DataDog/datadog-agent/pkg/util/docker"
 	log "github.com/cihub/seelog"
 
@@ -213,7 +214,7 @@ func (dt *DockerTailer) keepDockerTagsUpdated() {
 }
 
 func (dt *DockerTailer) checkForNewDockerTags() {
-	tags, err := tagger.Tag(dockerutil.ContainerIDToEntityName(dt.ContainerID), true)
+	tags, err := tagger.Tag(dockerutil.ContainerIDToEntityName(dt.ContainerID), collectors.HighCardinality)
 	if err != nil {
 		log.Warn(err)
 	} else {
@@ -23,6 +23,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/util/cloudfoundry"
 	"github.com/DataDog/datadog-agent/pkg/util/ec2"
 	"github.com/DataDog/datadog-agent/pkg/util/gce"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/hostinfo"
 	log "github.com/cihub/seelog"
 )
 
@@ -84,6 +85,12 @@ func getHostTags() *tags {
 	}
 	hostTags = append(hostTags, ec2Tags...)
 
+	k8sTags, err := hostinfo.GetTags()
+	if err != nil {
+		log.Debugf("No Kubernetes host tags %v", err)
+	}
+	hostTags = append(hostTags, k8sTags...)
+
 	gceTags, err = gce.GetTags()
 	if err != nil {
 		log.Debugf("No GCE host tags %v", err)
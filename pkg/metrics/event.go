@@ -80,6 +80,9 @@ type Event struct {
 	AggregationKey string         `json:"aggregation_key,omitempty"`
 	SourceTypeName string         `json:"source_type_name,omitempty"`
 	EventType      string         `json:"event_type,omitempty"`
+	// OriginID is the tagger entity the event was sent from, as declared
+	// by a DogStatsD client
+	OriginID string `json:"-"`
 }
 
 // Return a JSON string or "" in case of error during the Marshaling
@@ -63,4 +63,7 @@ type MetricSample struct {
 	Host       string
 	SampleRate float64
 	Timestamp  float64
+	// OriginID is the tagger entity the sample was sent from, as declared
+	// by a DogStatsD client
+	OriginID string
 }
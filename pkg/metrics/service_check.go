@@ -70,6 +70,9 @@ type ServiceCheck struct {
 	Status    ServiceCheckStatus `json:"status"`
 	Message   string             `json:"message"`
 	Tags      []string           `json:"tags"`
+	// OriginID is the tagger entity the service check was sent from, as
+	// declared by a DogStatsD client
+	OriginID string `json:"-"`
 }
 
 // ServiceChecks represents a list of service checks ready to be serialize
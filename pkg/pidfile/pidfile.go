@@ -18,14 +18,10 @@ import (
 // doesn't exist or doesn't contain a PID for a running process.
 func WritePID(pidFilePath string) error {
 	// check whether the pidfile exists and contains the PID for a running proc...
-	if byteContent, err := ioutil.ReadFile(pidFilePath); err == nil {
-		pidStr := strings.TrimSpace(string(byteContent))
-		pid, err := strconv.Atoi(pidStr)
-		if err == nil && isProcess(pid) {
-			// ...and return an error in case
-			return fmt.Errorf("Pidfile already exists, please check %s isn't running or remove %s",
-				os.Args[0], pidFilePath)
-		}
+	if pid, err := ReadPID(pidFilePath); err == nil && isProcess(pid) {
+		// ...and return an error in case
+		return fmt.Errorf("Pidfile already exists, please check %s isn't running or remove %s",
+			os.Args[0], pidFilePath)
 	}
 
 	// create the full path to the pidfile
@@ -42,3 +38,16 @@ func WritePID(pidFilePath string) error {
 	// all good
 	return nil
 }
+
+// ReadPID returns the PID stored in a pidfile
+func ReadPID(pidFilePath string) (int, error) {
+	byteContent, err := ioutil.ReadFile(pidFilePath)
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(byteContent)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid pidfile %s: %s", pidFilePath, err)
+	}
+	return pid, nil
+}
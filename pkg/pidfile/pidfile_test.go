@@ -32,3 +32,21 @@ func TestWritePID(t *testing.T) {
 func TestIsProcess(t *testing.T) {
 	assert.True(t, isProcess(os.Getpid()))
 }
+
+func TestReadPID(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "agent_test")
+	defer os.RemoveAll(dir)
+
+	pidFilePath := filepath.Join(dir, "agent.pid")
+	_, err := ReadPID(pidFilePath)
+	assert.NotNil(t, err)
+
+	ioutil.WriteFile(pidFilePath, []byte("1234\n"), 0644)
+	pid, err := ReadPID(pidFilePath)
+	assert.Nil(t, err)
+	assert.Equal(t, 1234, pid)
+
+	ioutil.WriteFile(pidFilePath, []byte("not a pid"), 0644)
+	_, err = ReadPID(pidFilePath)
+	assert.NotNil(t, err)
+}
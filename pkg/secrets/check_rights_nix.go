@@ -0,0 +1,44 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build !windows
+
+package secrets
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// checkRights validates that the secret backend command is a regular file,
+// owned by the user running the agent and that only this user can read,
+// write or execute it. Anybody able to modify it could read every secret.
+func checkRights(path string) error {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("invalid executable '%s': can't stat it: %s", path, err)
+	}
+	if !stat.Mode().IsRegular() {
+		return fmt.Errorf("invalid executable '%s': not a regular file", path)
+	}
+
+	// checking that the owner has exec rights and that group and others have no rights
+	if stat.Mode().Perm()&0100 == 0 {
+		return fmt.Errorf("invalid executable '%s': owner can't execute it", path)
+	}
+	if stat.Mode().Perm()&0077 != 0 {
+		return fmt.Errorf("invalid executable '%s': 'others' or 'group' have rights on it", path)
+	}
+
+	sysStat, ok := stat.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fmt.Errorf("invalid executable '%s': can't get its owner", path)
+	}
+	if int(sysStat.Uid) != os.Geteuid() {
+		return fmt.Errorf("invalid executable '%s': it isn't owned by the user running the agent", path)
+	}
+	return nil
+}
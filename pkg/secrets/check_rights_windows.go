@@ -0,0 +1,18 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build windows
+
+package secrets
+
+import (
+	"fmt"
+)
+
+// checkRights is not implemented on Windows yet: refuse to run the secret
+// backend command rather than running a binary anybody could have replaced
+func checkRights(path string) error {
+	return fmt.Errorf("secrets are not supported on windows yet, can't use '%s'", path)
+}
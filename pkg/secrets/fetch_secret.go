@@ -0,0 +1,128 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// PayloadVersion defines the current payload version sent to the secret
+// backend command
+const PayloadVersion = "1.0"
+
+// secret is the decrypted value of a handle, or the error the backend
+// ran into while decrypting it
+type secret struct {
+	Value    string `json:"value,omitempty"`
+	ErrorMsg string `json:"error,omitempty"`
+}
+
+// request is the payload sent on the stdin of the secret backend command
+type request struct {
+	Version string   `json:"version"`
+	Secrets []string `json:"secrets"`
+}
+
+// secretFetcher is a var so it can be mocked in tests
+var secretFetcher = fetchSecret
+
+// limitBuffer is a buffer returning an error when its size limit is reached
+type limitBuffer struct {
+	max int
+	buf *bytes.Buffer
+}
+
+func (b *limitBuffer) Write(p []byte) (n int, err error) {
+	if len(p)+b.buf.Len() > b.max {
+		return 0, fmt.Errorf("command output was too long: exceeded %d bytes", b.max)
+	}
+	return b.buf.Write(p)
+}
+
+// execCommand runs the secret backend command with inputPayload on its
+// stdin, and returns its stdout
+func execCommand(inputPayload string) ([]byte, error) {
+	if err := checkRights(secretBackendCommand); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(),
+		time.Duration(secretBackendTimeout)*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, secretBackendCommand, secretBackendArguments...)
+	cmd.Stdin = strings.NewReader(inputPayload)
+
+	stdout := limitBuffer{
+		buf: &bytes.Buffer{},
+		max: secretBackendOutputMaxSize,
+	}
+	stderr := limitBuffer{
+		buf: &bytes.Buffer{},
+		max: secretBackendOutputMaxSize,
+	}
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("error while running '%s': command timeout", secretBackendCommand)
+		}
+		return nil, fmt.Errorf("error while running '%s': %s (stderr: %s)",
+			secretBackendCommand, err, strings.TrimSpace(stderr.buf.String()))
+	}
+	return stdout.buf.Bytes(), nil
+}
+
+// fetchSecret receives a list of handles to decrypt and executes the secret
+// backend command with them. The command receives a JSON payload on its stdin:
+//
+//   {"version": "1.0", "secrets": ["handle1", "handle2"]}
+//
+// and must write the decrypted values on its stdout:
+//
+//   {"handle1": {"value": "password1", "error": null}, "handle2": {"value": null, "error": "unknown handle"}}
+func fetchSecret(secretsHandle []string, origin string) (map[string]string, error) {
+	payload := request{
+		Version: PayloadVersion,
+		Secrets: secretsHandle,
+	}
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("could not serialize secrets to fetch for %s: %s", origin, err)
+	}
+	output, err := execCommand(string(jsonPayload))
+	if err != nil {
+		return nil, err
+	}
+
+	secrets := map[string]secret{}
+	if err = json.Unmarshal(output, &secrets); err != nil {
+		return nil, fmt.Errorf("could not unmarshal 'secret_backend_command' output: %s", err)
+	}
+
+	res := map[string]string{}
+	for _, handle := range secretsHandle {
+		v, ok := secrets[handle]
+		if !ok {
+			return nil, fmt.Errorf("secret handle '%s' was not decrypted by the secret_backend_command", handle)
+		}
+		if v.ErrorMsg != "" {
+			return nil, fmt.Errorf("an error occurred while decrypting '%s': %s", handle, v.ErrorMsg)
+		}
+		if v.Value == "" {
+			return nil, fmt.Errorf("decrypted secret for '%s' is empty", handle)
+		}
+		res[handle] = v.Value
+	}
+	return res, nil
+}
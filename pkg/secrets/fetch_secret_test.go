@@ -0,0 +1,129 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build !windows
+
+package secrets
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeBackend writes a fake secret backend command in a temporary directory
+func writeBackend(t *testing.T, script string, mode os.FileMode) (string, func()) {
+	dir, err := ioutil.TempDir("", "secrets")
+	require.NoError(t, err)
+	path := filepath.Join(dir, "backend")
+	require.NoError(t, ioutil.WriteFile(path, []byte("#!/bin/sh\n"+script), mode))
+	// ioutil.WriteFile is subject to the umask
+	require.NoError(t, os.Chmod(path, mode))
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func TestCheckRights(t *testing.T) {
+	path, cleanup := writeBackend(t, "", 0700)
+	defer cleanup()
+	assert.NoError(t, checkRights(path))
+
+	require.NoError(t, os.Chmod(path, 0600))
+	assert.Error(t, checkRights(path))
+
+	require.NoError(t, os.Chmod(path, 0750))
+	assert.Error(t, checkRights(path))
+
+	require.NoError(t, os.Chmod(path, 0701))
+	assert.Error(t, checkRights(path))
+
+	assert.Error(t, checkRights(filepath.Dir(path)))
+	assert.Error(t, checkRights("/does/not/exist"))
+}
+
+func TestFetchSecret(t *testing.T) {
+	defer resetPackageVars()
+
+	path, cleanup := writeBackend(t, `
+read input
+case "$input" in
+  *'"version":"1.0"'*) ;;
+  *) echo "bad payload: $input" >&2; exit 1 ;;
+esac
+echo '{"pass1": {"value": "password1"}, "pass2": {"value": "password2"}}'
+`, 0700)
+	defer cleanup()
+	secretBackendCommand = path
+
+	res, err := fetchSecret([]string{"pass1", "pass2"}, "test")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"pass1": "password1", "pass2": "password2"}, res)
+
+	// handle missing from the output
+	_, err = fetchSecret([]string{"pass1", "pass3"}, "test")
+	assert.Error(t, err)
+}
+
+func TestFetchSecretBackendError(t *testing.T) {
+	defer resetPackageVars()
+
+	path, cleanup := writeBackend(t, `echo '{"pass1": {"value": null, "error": "unknown handle"}}'`, 0700)
+	defer cleanup()
+	secretBackendCommand = path
+
+	_, err := fetchSecret([]string{"pass1"}, "test")
+	assert.EqualError(t, err, "an error occurred while decrypting 'pass1': unknown handle")
+}
+
+func TestFetchSecretInvalidOutput(t *testing.T) {
+	defer resetPackageVars()
+
+	path, cleanup := writeBackend(t, `echo 'not json'`, 0700)
+	defer cleanup()
+	secretBackendCommand = path
+
+	_, err := fetchSecret([]string{"pass1"}, "test")
+	assert.Error(t, err)
+}
+
+func TestFetchSecretTimeout(t *testing.T) {
+	defer resetPackageVars()
+	defer func(timeout int) { secretBackendTimeout = timeout }(secretBackendTimeout)
+
+	path, cleanup := writeBackend(t, `exec sleep 5`, 0700)
+	defer cleanup()
+	secretBackendCommand = path
+	secretBackendTimeout = 1
+
+	_, err := fetchSecret([]string{"pass1"}, "test")
+	assert.Contains(t, err.Error(), "command timeout")
+}
+
+func TestFetchSecretOutputTooLong(t *testing.T) {
+	defer resetPackageVars()
+	defer func(size int) { secretBackendOutputMaxSize = size }(secretBackendOutputMaxSize)
+
+	path, cleanup := writeBackend(t, `echo '{"pass1": {"value": "password1"}}'`, 0700)
+	defer cleanup()
+	secretBackendCommand = path
+	secretBackendOutputMaxSize = 10
+
+	_, err := fetchSecret([]string{"pass1"}, "test")
+	assert.Error(t, err)
+}
+
+func TestFetchSecretWrongRights(t *testing.T) {
+	defer resetPackageVars()
+
+	path, cleanup := writeBackend(t, `echo '{"pass1": {"value": "password1"}}'`, 0755)
+	defer cleanup()
+	secretBackendCommand = path
+
+	_, err := fetchSecret([]string{"pass1"}, "test")
+	assert.Error(t, err)
+}
@@ -0,0 +1,269 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package secrets
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	log "github.com/cihub/seelog"
+	yaml "gopkg.in/yaml.v2"
+)
+
+var (
+	secretCache map[string]string
+	// list of handles and where they were found
+	secretOrigin map[string]map[string]struct{}
+	cacheMutex   sync.Mutex
+
+	secretBackendCommand          string
+	secretBackendArguments        []string
+	secretBackendTimeout          = 5
+	secretBackendOutputMaxSize    = 1024 * 1024
+	encryptedHandleRegex          = regexp.MustCompile(`^\s*ENC\[(.+)\]\s*$`)
+	scrubbedValue                 = "********"
+	minScrubbedSecretLength       = 3
+	errSecretBackendCommandNotSet = fmt.Errorf("found encrypted secrets but 'secret_backend_command' is not set")
+)
+
+func init() {
+	secretCache = make(map[string]string)
+	secretOrigin = make(map[string]map[string]struct{})
+}
+
+// Init initializes the command and other options of the secrets package. Since
+// this package is used by the 'config' package to decrypt datadog.yaml, it
+// can't use the config package itself.
+func Init(command string, arguments []string, timeout int, maxSize int) {
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+
+	secretBackendCommand = command
+	secretBackendArguments = arguments
+	if timeout > 0 {
+		secretBackendTimeout = timeout
+	}
+	if maxSize > 0 {
+		secretBackendOutputMaxSize = maxSize
+	}
+}
+
+// IsEnabled returns true if a secret backend command is configured
+func IsEnabled() bool {
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+	return secretBackendCommand != ""
+}
+
+// ContainsHandle returns true if data may contain an ENC[] handle, it's
+// a cheap check to avoid parsing YAML data that doesn't need to be decrypted
+func ContainsHandle(data []byte) bool {
+	return bytes.Contains(data, []byte("ENC["))
+}
+
+// isEnc returns true and the handle if str respects the ENC[handle] format,
+// spaces around the handle are not part of it
+func isEnc(str string) (bool, string) {
+	match := encryptedHandleRegex.FindStringSubmatch(str)
+	if match == nil {
+		return false, ""
+	}
+	handle := strings.TrimSpace(match[1])
+	if handle == "" {
+		return false, ""
+	}
+	return true, handle
+}
+
+// walk recursively goes through a YAML tree and calls callback on every string value
+func walk(data *interface{}, callback func(string) (string, error)) error {
+	switch v := (*data).(type) {
+	case string:
+		newValue, err := callback(v)
+		if err != nil {
+			return err
+		}
+		*data = newValue
+	case map[interface{}]interface{}:
+		for k := range v {
+			value := v[k]
+			if err := walk(&value, callback); err != nil {
+				return err
+			}
+			v[k] = value
+		}
+	case map[string]interface{}:
+		for k := range v {
+			value := v[k]
+			if err := walk(&value, callback); err != nil {
+				return err
+			}
+			v[k] = value
+		}
+	case []interface{}:
+		for i := range v {
+			if err := walk(&v[i], callback); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Decrypt replaces all the ENC[handle] values found in a YAML document by
+// their decrypted value. The secret backend command is executed once, only
+// for the handles missing from the cache. The origin is used for debugging
+// purposes, to know where a handle is used.
+func Decrypt(data []byte, origin string) ([]byte, error) {
+	if !ContainsHandle(data) {
+		return data, nil
+	}
+
+	var config interface{}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("could not parse data to decrypt: %s", err)
+	}
+
+	// First we collect all the handles in the config
+	handles := []string{}
+	haveSecret := false
+	err := walk(&config, func(str string) (string, error) {
+		if ok, handle := isEnc(str); ok {
+			haveSecret = true
+			handles = append(handles, handle)
+		}
+		return str, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// the string was matching "ENC[" but not the handle format
+	if !haveSecret {
+		return data, nil
+	}
+
+	secrets, err := fetchSecrets(handles, origin)
+	if err != nil {
+		return nil, err
+	}
+
+	// Then we replace the handles by their decrypted value
+	err = walk(&config, func(str string) (string, error) {
+		if ok, handle := isEnc(str); ok {
+			if secret, ok := secrets[handle]; ok {
+				log.Debugf("Secret '%s' was successfully decrypted", handle)
+				return secret, nil
+			}
+			// this should never happen since fetchSecrets returns an error
+			// if a handle couldn't be decrypted
+			return "", fmt.Errorf("unknown secret '%s'", handle)
+		}
+		return str, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(config)
+}
+
+// fetchSecrets returns the decrypted value of the given handles, using the
+// cache first and the secret backend command for the missing ones
+func fetchSecrets(handles []string, origin string) (map[string]string, error) {
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+
+	secrets := map[string]string{}
+	missing := []string{}
+	for _, handle := range handles {
+		if _, ok := secretOrigin[handle]; !ok {
+			secretOrigin[handle] = map[string]struct{}{}
+		}
+		secretOrigin[handle][origin] = struct{}{}
+
+		if value, ok := secretCache[handle]; ok {
+			secrets[handle] = value
+		} else {
+			missing = append(missing, handle)
+		}
+	}
+	if len(missing) == 0 {
+		return secrets, nil
+	}
+
+	if secretBackendCommand == "" {
+		return nil, errSecretBackendCommandNotSet
+	}
+
+	decrypted, err := secretFetcher(dedupe(missing), origin)
+	if err != nil {
+		return nil, err
+	}
+	for handle, value := range decrypted {
+		secretCache[handle] = value
+		secrets[handle] = value
+	}
+	return secrets, nil
+}
+
+// Scrub replaces the decrypted secrets values found in data, so that they
+// can't leak through the flare, config-check or GUI outputs
+func Scrub(data []byte) []byte {
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+
+	if len(secretCache) == 0 {
+		return data
+	}
+
+	// replace the longest values first in case a secret contains another one
+	values := make([]string, 0, len(secretCache))
+	for _, value := range secretCache {
+		// scrubbing very short values would make the output unreadable
+		if len(value) >= minScrubbedSecretLength {
+			values = append(values, value)
+		}
+	}
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+
+	for _, value := range values {
+		data = bytes.Replace(data, []byte(value), []byte(scrubbedValue), -1)
+	}
+	return data
+}
+
+// GetDebugInfo returns the handles decrypted so far and where they're used
+func GetDebugInfo() map[string][]string {
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+
+	info := make(map[string][]string, len(secretOrigin))
+	for handle, origins := range secretOrigin {
+		for origin := range origins {
+			info[handle] = append(info[handle], origin)
+		}
+		sort.Strings(info[handle])
+	}
+	return info
+}
+
+// dedupe returns the sorted list of unique handles
+func dedupe(handles []string) []string {
+	seen := make(map[string]struct{}, len(handles))
+	res := make([]string, 0, len(handles))
+	for _, h := range handles {
+		if _, found := seen[h]; !found {
+			seen[h] = struct{}{}
+			res = append(res, h)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
@@ -0,0 +1,178 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package secrets
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testConf = []byte(`---
+instances:
+- password: ENC[pass1]
+  user: test
+  tags:
+  - ENC[tag1]
+  - plain_tag
+- password: ENC[pass2]
+  user: test2
+`)
+	testConfDecrypted = `instances:
+- password: password1
+  tags:
+  - decrypted_tag
+  - plain_tag
+  user: test
+- password: password2
+  user: test2
+`
+)
+
+func resetPackageVars() {
+	secretCache = make(map[string]string)
+	secretOrigin = make(map[string]map[string]struct{})
+	secretBackendCommand = ""
+	secretBackendArguments = nil
+	secretFetcher = fetchSecret
+}
+
+func TestIsEnc(t *testing.T) {
+	enc, handle := isEnc("ENC[test]")
+	assert.True(t, enc)
+	assert.Equal(t, "test", handle)
+
+	enc, handle = isEnc("  ENC[test with spaces]  ")
+	assert.True(t, enc)
+	assert.Equal(t, "test with spaces", handle)
+
+	enc, handle = isEnc("ENC[ padded ]")
+	assert.True(t, enc)
+	assert.Equal(t, "padded", handle)
+
+	enc, _ = isEnc("ENC[]")
+	assert.False(t, enc)
+	enc, _ = isEnc("ENC[  ]")
+	assert.False(t, enc)
+	enc, _ = isEnc("test")
+	assert.False(t, enc)
+	enc, _ = isEnc("prefix ENC[test]")
+	assert.False(t, enc)
+}
+
+func TestDecryptNoSecret(t *testing.T) {
+	defer resetPackageVars()
+	secretFetcher = func(handles []string, origin string) (map[string]string, error) {
+		return nil, fmt.Errorf("should not be called")
+	}
+
+	data := []byte("instances:\n- host: localhost\n")
+	res, err := Decrypt(data, "test")
+	require.NoError(t, err)
+	assert.Equal(t, data, res)
+
+	// looks like a handle but isn't one
+	data = []byte("instances:\n- host: not ENC[a handle]\n")
+	res, err = Decrypt(data, "test")
+	require.NoError(t, err)
+	assert.Equal(t, data, res)
+}
+
+func TestDecryptNoCommand(t *testing.T) {
+	defer resetPackageVars()
+	_, err := Decrypt(testConf, "test")
+	assert.Equal(t, errSecretBackendCommandNotSet, err)
+}
+
+func TestDecrypt(t *testing.T) {
+	defer resetPackageVars()
+	secretBackendCommand = "some_command"
+
+	calls := 0
+	secretFetcher = func(handles []string, origin string) (map[string]string, error) {
+		calls++
+		assert.Equal(t, "test", origin)
+		assert.Equal(t, []string{"pass1", "pass2", "tag1"}, handles)
+		return map[string]string{
+			"pass1": "password1",
+			"pass2": "password2",
+			"tag1":  "decrypted_tag",
+		}, nil
+	}
+
+	res, err := Decrypt(testConf, "test")
+	require.NoError(t, err)
+	assert.Equal(t, testConfDecrypted, string(res))
+	assert.Equal(t, 1, calls)
+
+	// every handle is cached now, the backend isn't called again
+	res, err = Decrypt(testConf, "test")
+	require.NoError(t, err)
+	assert.Equal(t, testConfDecrypted, string(res))
+	assert.Equal(t, 1, calls)
+
+	assert.Equal(t, map[string][]string{
+		"pass1": {"test"},
+		"pass2": {"test"},
+		"tag1":  {"test"},
+	}, GetDebugInfo())
+}
+
+func TestDecryptPaddedHandle(t *testing.T) {
+	defer resetPackageVars()
+	secretBackendCommand = "some_command"
+	secretFetcher = func(handles []string, origin string) (map[string]string, error) {
+		assert.Equal(t, []string{"pass1"}, handles)
+		return map[string]string{"pass1": "password1"}, nil
+	}
+
+	res, err := Decrypt([]byte("password: ENC[ pass1 ]\nother: ENC[pass1]\n"), "test")
+	require.NoError(t, err)
+	assert.Equal(t, "other: password1\npassword: password1\n", string(res))
+}
+
+func TestDecryptPartialCache(t *testing.T) {
+	defer resetPackageVars()
+	secretBackendCommand = "some_command"
+	secretCache["pass1"] = "password1"
+	secretCache["tag1"] = "decrypted_tag"
+
+	secretFetcher = func(handles []string, origin string) (map[string]string, error) {
+		assert.Equal(t, []string{"pass2"}, handles)
+		return map[string]string{"pass2": "password2"}, nil
+	}
+
+	res, err := Decrypt(testConf, "test")
+	require.NoError(t, err)
+	assert.Equal(t, testConfDecrypted, string(res))
+}
+
+func TestDecryptFetchError(t *testing.T) {
+	defer resetPackageVars()
+	secretBackendCommand = "some_command"
+	secretFetcher = func(handles []string, origin string) (map[string]string, error) {
+		return nil, fmt.Errorf("some error")
+	}
+
+	_, err := Decrypt(testConf, "test")
+	assert.EqualError(t, err, "some error")
+	assert.Len(t, secretCache, 0)
+}
+
+func TestScrub(t *testing.T) {
+	defer resetPackageVars()
+
+	data := []byte("password: password1\nuser: ab\n")
+	assert.Equal(t, data, Scrub(data))
+
+	secretCache["pass1"] = "password1"
+	secretCache["pass"] = "password"
+	secretCache["user"] = "ab"
+	assert.Equal(t, "********: ********\nuser: ab\n", string(Scrub(data)))
+}
@@ -39,6 +39,7 @@ func FormatStatus(data []byte) (string, error) {
 	aggregatorStats := stats["aggregatorStats"]
 	jmxStats := stats["JMXStatus"]
 	logsStats := stats["logsStats"]
+	leaderElectionStats := stats["leaderElectionStats"]
 	title := fmt.Sprintf("Agent (v%s)", stats["version"])
 	stats["title"] = title
 	renderHeader(b, stats)
@@ -47,6 +48,9 @@ func FormatStatus(data []byte) (string, error) {
 	renderForwarderStatus(b, forwarderStats)
 	renderLogsStatus(b, logsStats)
 	renderDogstatsdStatus(b, aggregatorStats)
+	if leaderElectionStats != nil {
+		renderLeaderElectionStatus(b, leaderElectionStats)
+	}
 
 	return b.String(), nil
 }
@@ -111,6 +115,14 @@ func renderJMXFetchStatus(w io.Writer, jmxStats interface{}) {
 	}
 }
 
+func renderLeaderElectionStatus(w io.Writer, leaderElectionStats interface{}) {
+	t := template.Must(template.New("leaderelection.tmpl").Funcs(fmap).ParseFiles(filepath.Join(templateFolder, "leaderelection.tmpl")))
+	err := t.Execute(w, leaderElectionStats)
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
 func renderLogsStatus(w io.Writer, logsStats interface{}) {
 	t := template.Must(template.New("logsagent.tmpl").Funcs(fmap).ParseFiles(filepath.Join(templateFolder, "logsagent.tmpl")))
 	err := t.Execute(w, logsStats)
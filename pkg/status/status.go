@@ -135,6 +135,13 @@ func expvarStats(stats map[string]interface{}) (map[string]interface{}, error) {
 	json.Unmarshal(aggregatorStatsJSON, &aggregatorStats)
 	stats["aggregatorStats"] = aggregatorStats
 
+	// only published by the cluster agent
+	if leaderElection := expvar.Get("leaderelection"); leaderElection != nil {
+		leaderElectionStats := make(map[string]interface{})
+		json.Unmarshal([]byte(leaderElection.String()), &leaderElectionStats)
+		stats["leaderElectionStats"] = leaderElectionStats
+	}
+
 	if expvar.Get("ntpOffset").String() != "" {
 		stats["ntpOffset"], err = strconv.ParseFloat(expvar.Get("ntpOffset").String(), 64)
 	}
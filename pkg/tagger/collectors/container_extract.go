@@ -3,39 +3,33 @@
 // This product includes software developed at Datadog (https://www.datadoghq.com/).
 // Copyright 2018 Datadog, Inc.
 
-// +build docker
-
 package collectors
 
 import (
 	"strings"
 
 	log "github.com/cihub/seelog"
-	"github.com/docker/docker/api/types"
 
 	"github.com/DataDog/datadog-agent/pkg/tagger/utils"
+	"github.com/DataDog/datadog-agent/pkg/util/container"
 	"github.com/DataDog/datadog-agent/pkg/util/docker"
 )
 
-// extractFromInspect extract tags for a container inspect JSON
-func (c *DockerCollector) extractFromInspect(co types.ContainerJSON) ([]string, []string, error) {
+// extractFromInspect extract tags for an inspected container
+func (c *ContainerCollector) extractFromInspect(co *container.Details) ([]string, []string, []string, error) {
 	tags := utils.NewTagList()
 
-	//TODO: remove when Inspect returns resolved image names
-	dockerImage, err := c.dockerUtil.ResolveImageName(co.Image)
-	if err != nil {
-		log.Debugf("error resolving image %s: %s", co.Image, err)
-	} else {
-		dockerExtractImage(tags, dockerImage)
+	if co.Image != "" {
+		dockerExtractImage(tags, co.Image)
 	}
-	dockerExtractLabels(tags, co.Config.Labels, c.labelsAsTags)
-	dockerExtractEnvironmentVariables(tags, co.Config.Env, c.envAsTags)
+	dockerExtractLabels(tags, co.Labels, c.labelsAsTags)
+	dockerExtractEnvironmentVariables(tags, co.Env, c.envAsTags)
 
-	tags.AddHigh("container_name", strings.TrimPrefix(co.Name, "/"))
+	tags.AddHigh("container_name", co.Name)
 	tags.AddHigh("container_id", co.ID)
 
-	low, high := tags.Compute()
-	return low, high, nil
+	low, orchestrator, high := tags.Compute()
+	return low, orchestrator, high, nil
 }
 
 func dockerExtractImage(tags *utils.TagList, dockerImage string) {
@@ -97,7 +91,7 @@ func dockerExtractEnvironmentVariables(tags *utils.TagList, containerEnvVariable
 		case "CHRONOS_JOB_OWNER":
 			tags.AddLow("chronos_job_owner", envValue)
 		case "MESOS_TASK_ID":
-			tags.AddHigh("mesos_task", envValue)
+			tags.AddOrchestrator("mesos_task", envValue)
 
 		default:
 			if tagName, found := envAsTags[strings.ToLower(envSplit[0])]; found {
@@ -25,6 +25,7 @@ func TestDockerRecordsFromInspect(t *testing.T) {
 		toRecordEnvAsTags    map[string]string
 		toRecordLabelsAsTags map[string]string
 		expectedLow          []string
+		expectedOrchestrator []string
 		expectedHigh         []string
 	}{
 		{
@@ -99,7 +100,8 @@ func TestDockerRecordsFromInspect(t *testing.T) {
 				"chronos_job:app1_process-orders",
 				"chronos_job_owner:qa",
 			},
-			expectedHigh: []string{"mesos_task:system_dd-agent.dcc75b42-4b87-11e7-9a62-70b3d5800001"},
+			expectedOrchestrator: []string{"mesos_task:system_dd-agent.dcc75b42-4b87-11e7-9a62-70b3d5800001"},
+			expectedHigh:         []string{},
 		},
 		{
 			testName: "NoValue",
@@ -196,7 +198,7 @@ func TestDockerRecordsFromInspect(t *testing.T) {
 		},
 	}
 
-	dc := &DockerCollector{}
+	dc := &ContainerCollector{}
 	for i, test := range testCases {
 		t.Run(fmt.Sprintf("case %d: %s", i, test.testName), func(t *testing.T) {
 			dc.envAsTags = test.toRecordEnvAsTags
@@ -204,7 +206,7 @@ func TestDockerRecordsFromInspect(t *testing.T) {
 			tags := utils.NewTagList()
 			dockerExtractEnvironmentVariables(tags, test.co.Config.Env, test.toRecordEnvAsTags)
 			dockerExtractLabels(tags, test.co.Config.Labels, test.toRecordLabelsAsTags)
-			low, high := tags.Compute()
+			low, orchestrator, high := tags.Compute()
 
 			// Low card tags
 			assert.Equal(t, len(test.expectedLow), len(low), "test case %d", i)
@@ -212,6 +214,12 @@ func TestDockerRecordsFromInspect(t *testing.T) {
 				assert.Contains(t, low, lt, "test case %d", i)
 			}
 
+			// Orchestrator card tags
+			assert.Equal(t, len(test.expectedOrchestrator), len(orchestrator), "test case %d", i)
+			for _, ot := range test.expectedOrchestrator {
+				assert.Contains(t, orchestrator, ot, "test case %d", i)
+			}
+
 			// High card tags
 			assert.True(t, len(test.expectedHigh) == len(high))
 			for _, ht := range test.expectedHigh {
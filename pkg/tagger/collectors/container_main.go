@@ -0,0 +1,119 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package collectors
+
+import (
+	"io"
+	"strings"
+
+	log "github.com/cihub/seelog"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/container"
+)
+
+// ContainerCollector listens to the events of a container runtime to get
+// new/dead containers and feed a stram of TagInfo. It requires access to the
+// runtime socket. It is registered as the docker and cri collectors.
+type ContainerCollector struct {
+	name         string
+	runtimeName  string
+	runtime      container.Runtime
+	stop         chan bool
+	infoOut      chan<- []*TagInfo
+	labelsAsTags map[string]string
+	envAsTags    map[string]string
+}
+
+// Detect tries to connect to the runtime socket and returns success
+func (c *ContainerCollector) Detect(out chan<- []*TagInfo) (CollectionMode, error) {
+	rt, err := container.GetRuntime(c.runtimeName)
+	if err != nil {
+		return NoCollection, err
+	}
+
+	c.runtime = rt
+	c.stop = make(chan bool)
+	c.infoOut = out
+
+	// viper lower-cases map keys, so extractor must lowercase before matching
+	c.labelsAsTags = config.Datadog.GetStringMapString("docker_labels_as_tags")
+	c.envAsTags = config.Datadog.GetStringMapString("docker_env_as_tags")
+
+	// TODO: list and inspect existing containers once docker utils are merged
+
+	return StreamCollection, nil
+}
+
+// Stream runs the continuous event watching loop and sends new info
+// to the channel. But be called in a goroutine.
+func (c *ContainerCollector) Stream() error {
+	messages, errs, err := c.runtime.SubscribeToEvents("ContainerCollector")
+	if err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-c.stop:
+			return c.runtime.UnsubscribeFromEvents("ContainerCollector")
+		case msg := <-messages:
+			c.processEvent(msg)
+		case err := <-errs:
+			if err != nil && err != io.EOF {
+				log.Errorf("stopping collection: %s", err)
+				return err
+			}
+			return nil
+		}
+	}
+}
+
+// Stop queues a shutdown of ContainerCollector
+func (c *ContainerCollector) Stop() error {
+	c.stop <- true
+	return nil
+}
+
+// Fetch inspect a given container to get its tags on-demand (cache miss)
+func (c *ContainerCollector) Fetch(entity string) ([]string, []string, []string, error) {
+	// entity names are prefixed with the runtime name, eg. docker://
+	prefix := c.runtime.ContainerIDToEntityName("")
+	cid := strings.TrimPrefix(entity, prefix)
+	if cid == entity || len(cid) == 0 {
+		return nil, nil, nil, ErrNotFound
+	}
+	return c.fetchForContainerID(cid)
+}
+
+func (c *ContainerCollector) processEvent(e *container.Event) {
+	if e == nil {
+		return
+	}
+	out := make([]*TagInfo, 1)
+	entity := c.runtime.ContainerIDToEntityName(e.ContainerID)
+
+	switch e.Action {
+	case container.EventDie:
+		out[0] = &TagInfo{Entity: entity, Source: c.name, DeleteEntity: true}
+	case container.EventStart:
+		low, orchestrator, high, _ := c.fetchForContainerID(e.ContainerID)
+		out[0] = &TagInfo{Entity: entity, Source: c.name, LowCardTags: low, OrchestratorCardTags: orchestrator, HighCardTags: high}
+	default:
+		return
+	}
+	c.infoOut <- out
+}
+
+func (c *ContainerCollector) fetchForContainerID(cID string) ([]string, []string, []string, error) {
+	co, err := c.runtime.Inspect(cID)
+	if err != nil {
+		// TODO separate "not found" and inspect error
+		log.Errorf("Failed to inspect container %s - %s", cID, err)
+		return nil, nil, nil, err
+	}
+	return c.extractFromInspect(co)
+}
@@ -0,0 +1,27 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build cri
+
+package collectors
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/util/container"
+)
+
+const (
+	criCollectorName = "cri"
+)
+
+func criFactory() Collector {
+	return &ContainerCollector{
+		name:        criCollectorName,
+		runtimeName: container.CRIRuntimeName,
+	}
+}
+
+func init() {
+	registerCollector(criCollectorName, criFactory)
+}
@@ -29,14 +29,16 @@ func (c *ECSCollector) parseTasks(tasks_list ecsutil.TasksV1Response) ([]*TagInf
 				tags := utils.NewTagList()
 				tags.AddLow("task_version", task.Version)
 				tags.AddLow("task_name", task.Family)
+				tags.AddOrchestrator("task_arn", task.Arn)
 
-				low, high := tags.Compute()
+				low, orchestrator, high := tags.Compute()
 
 				info := &TagInfo{
-					Source:       ecsCollectorName,
-					Entity:       docker.ContainerIDToEntityName(container.DockerID),
-					HighCardTags: high,
-					LowCardTags:  low,
+					Source:               ecsCollectorName,
+					Entity:               docker.ContainerIDToEntityName(container.DockerID),
+					HighCardTags:         high,
+					OrchestratorCardTags: orchestrator,
+					LowCardTags:          low,
 				}
 				output = append(output, info)
 			}
@@ -46,6 +46,7 @@ func (c *ECSFargateCollector) pullMetadata(meta ecs.TaskMetadata) ([]*TagInfo, [
 			// task
 			tags.AddLow("task_family", meta.Family)
 			tags.AddLow("task_version", meta.Version)
+			tags.AddOrchestrator("task_arn", meta.TaskARN)
 
 			// container
 			tags.AddLow("ecs_container_name", ctr.Name)
@@ -64,17 +65,20 @@ func (c *ECSFargateCollector) pullMetadata(meta ecs.TaskMetadata) ([]*TagInfo, [
 
 			// container labels
 			for k, v := range ctr.Labels {
-				if isBlacklisted[k] {
+				if k == "com.amazonaws.ecs.task-arn" {
+					tags.AddOrchestrator(k, v)
+				} else if isBlacklisted[k] {
 					tags.AddHigh(k, v)
 				}
 			}
 
-			low, high := tags.Compute()
+			low, orchestrator, high := tags.Compute()
 			info := &TagInfo{
-				Source:       ecsFargateCollectorName,
-				Entity:       docker.ContainerIDToEntityName(string(ctr.DockerID)),
-				HighCardTags: high,
-				LowCardTags:  low,
+				Source:               ecsFargateCollectorName,
+				Entity:               docker.ContainerIDToEntityName(string(ctr.DockerID)),
+				HighCardTags:         high,
+				OrchestratorCardTags: orchestrator,
+				LowCardTags:          low,
 			}
 			output = append(output, info)
 		}
@@ -92,7 +96,7 @@ func (c *ECSFargateCollector) pullMetadata(meta ecs.TaskMetadata) ([]*TagInfo, [
 }
 
 // fetchMetadata looks for a given container in a TaskMetadata object and returns its tags if found.
-func (c *ECSFargateCollector) fetchMetadata(meta ecs.TaskMetadata, container string) ([]string, []string, error) {
+func (c *ECSFargateCollector) fetchMetadata(meta ecs.TaskMetadata, container string) ([]string, []string, []string, error) {
 	for _, ctr := range meta.Containers {
 		entity := docker.ContainerIDToEntityName(string(ctr.DockerID))
 		if entity != container {
@@ -106,6 +110,7 @@ func (c *ECSFargateCollector) fetchMetadata(meta ecs.TaskMetadata, container str
 		// task
 		tags.AddLow("task_family", meta.Family)
 		tags.AddLow("task_version", meta.Version)
+		tags.AddOrchestrator("task_arn", meta.TaskARN)
 
 		// container
 		tags.AddLow("ecs_container_name", ctr.Name)
@@ -124,19 +129,22 @@ func (c *ECSFargateCollector) fetchMetadata(meta ecs.TaskMetadata, container str
 
 		// container labels
 		for k, v := range ctr.Labels {
-			if isBlacklisted[k] {
+			if k == "com.amazonaws.ecs.task-arn" {
+				tags.AddOrchestrator(k, v)
+			} else if isBlacklisted[k] {
 				tags.AddHigh(k, v)
 			}
 		}
 
-		low, high := tags.Compute()
+		low, orchestrator, high := tags.Compute()
 		info := &TagInfo{
-			Source:       ecsFargateCollectorName,
-			Entity:       docker.ContainerIDToEntityName(string(ctr.DockerID)),
-			HighCardTags: high,
-			LowCardTags:  low,
+			Source:               ecsFargateCollectorName,
+			Entity:               docker.ContainerIDToEntityName(string(ctr.DockerID)),
+			HighCardTags:         high,
+			OrchestratorCardTags: orchestrator,
+			LowCardTags:          low,
 		}
-		return info.LowCardTags, info.HighCardTags, nil
+		return info.LowCardTags, info.OrchestratorCardTags, info.HighCardTags, nil
 	}
-	return nil, nil, ErrNotFound
+	return nil, nil, nil, ErrNotFound
 }
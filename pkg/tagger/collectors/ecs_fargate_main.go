@@ -79,23 +79,23 @@ func (c *ECSFargateCollector) Pull() error {
 }
 
 // Fetch fetches ECS tags for a container on demand
-func (c *ECSFargateCollector) Fetch(container string) ([]string, []string, error) {
+func (c *ECSFargateCollector) Fetch(container string) ([]string, []string, []string, error) {
 	meta, err := ecsutil.GetTaskMetadata()
 	if err != nil {
-		return []string{}, []string{}, err
+		return []string{}, []string{}, []string{}, err
 	}
 
 	// since we download the metadata anyway might as well do a Pull refresh
 	updates, deadCo, err := c.pullMetadata(meta)
 	if err != nil {
-		return []string{}, []string{}, err
+		return []string{}, []string{}, []string{}, err
 	}
 
 	c.infoOut <- updates
 
 	expiries, err := c.parseExpires(deadCo)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	c.infoOut <- expiries
 	c.lastExpire = time.Now()
@@ -48,15 +48,15 @@ func (c *ECSCollector) Detect(out chan<- []*TagInfo) (CollectionMode, error) {
 }
 
 // Fetch fetches ECS tags
-func (c *ECSCollector) Fetch(container string) ([]string, []string, error) {
+func (c *ECSCollector) Fetch(container string) ([]string, []string, []string, error) {
 
 	tasks_list, err := ecsutil.GetTasks()
 	if err != nil {
-		return []string{}, []string{}, err
+		return []string{}, []string{}, []string{}, err
 	}
 	updates, err := c.parseTasks(tasks_list)
 	if err != nil {
-		return []string{}, []string{}, err
+		return []string{}, []string{}, []string{}, err
 	}
 	c.infoOut <- updates
 
@@ -70,11 +70,11 @@ func (c *ECSCollector) Fetch(container string) ([]string, []string, error) {
 
 	for _, info := range updates {
 		if info.Entity == container {
-			return info.LowCardTags, info.HighCardTags, nil
+			return info.LowCardTags, info.OrchestratorCardTags, info.HighCardTags, nil
 		}
 	}
 	// container not found in updates
-	return []string{}, []string{}, ErrNotFound
+	return []string{}, []string{}, []string{}, ErrNotFound
 }
 
 func ecsFactory() Collector {
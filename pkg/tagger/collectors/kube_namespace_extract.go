@@ -0,0 +1,48 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build kubelet
+
+package collectors
+
+import (
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/tagger/utils"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/kubelet"
+)
+
+const kubeNamespaceCollectorName = "kube-namespace"
+
+// parseNamespaceLabels converts the labels of the namespaces of the pods to
+// TagInfo objects for their containers. Containers of namespaces missing in
+// namespaceLabels are skipped.
+func parseNamespaceLabels(pods []*kubelet.Pod, namespaceLabels map[string]map[string]string, labelsAsTags map[string]string) []*TagInfo {
+	var output []*TagInfo
+	for _, pod := range pods {
+		labels, found := namespaceLabels[pod.Metadata.Namespace]
+		if !found {
+			continue
+		}
+		tags := utils.NewTagList()
+		for labelName, labelValue := range labels {
+			if tagName, found := labelsAsTags[strings.ToLower(labelName)]; found {
+				tags.AddAuto(tagName, labelValue)
+			}
+		}
+		low, orchestrator, high := tags.Compute()
+
+		for _, container := range pod.Status.Containers {
+			output = append(output, &TagInfo{
+				Source:               kubeNamespaceCollectorName,
+				Entity:               container.ID,
+				HighCardTags:         high,
+				OrchestratorCardTags: orchestrator,
+				LowCardTags:          low,
+			})
+		}
+	}
+	return output
+}
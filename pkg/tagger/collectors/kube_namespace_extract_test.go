@@ -0,0 +1,79 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build kubelet
+
+package collectors
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/kubelet"
+)
+
+func TestParseNamespaceLabels(t *testing.T) {
+	pods := []*kubelet.Pod{
+		{
+			Metadata: kubelet.PodMetadata{Name: "web-1", Namespace: "shop"},
+			Status: kubelet.Status{
+				Containers: []kubelet.ContainerStatus{
+					{ID: "docker://web"},
+					{ID: "docker://sidecar"},
+				},
+			},
+		},
+		{
+			Metadata: kubelet.PodMetadata{Name: "kube-dns-1", Namespace: "kube-system"},
+			Status: kubelet.Status{
+				Containers: []kubelet.ContainerStatus{{ID: "docker://dns"}},
+			},
+		},
+		{
+			Metadata: kubelet.PodMetadata{Name: "job-1", Namespace: "unknown"},
+			Status: kubelet.Status{
+				Containers: []kubelet.ContainerStatus{{ID: "docker://job"}},
+			},
+		},
+	}
+	namespaceLabels := map[string]map[string]string{
+		"shop": {
+			"Team":        "payments",
+			"cost-center": "42",
+			"ignored":     "value",
+		},
+		"kube-system": {},
+	}
+	labelsAsTags := map[string]string{
+		"team":        "team",
+		"cost-center": "+cost_center",
+	}
+
+	infos := parseNamespaceLabels(pods, namespaceLabels, labelsAsTags)
+	assert.Equal(t, []*TagInfo{
+		{
+			Source:               kubeNamespaceCollectorName,
+			Entity:               "docker://web",
+			LowCardTags:          []string{"team:payments"},
+			OrchestratorCardTags: []string{},
+			HighCardTags:         []string{"cost_center:42"},
+		},
+		{
+			Source:               kubeNamespaceCollectorName,
+			Entity:               "docker://sidecar",
+			LowCardTags:          []string{"team:payments"},
+			OrchestratorCardTags: []string{},
+			HighCardTags:         []string{"cost_center:42"},
+		},
+		{
+			Source:               kubeNamespaceCollectorName,
+			Entity:               "docker://dns",
+			LowCardTags:          []string{},
+			OrchestratorCardTags: []string{},
+			HighCardTags:         []string{},
+		},
+	}, infos)
+}
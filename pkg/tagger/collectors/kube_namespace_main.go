@@ -0,0 +1,176 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build kubelet,kubeapiserver
+
+package collectors
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/cihub/seelog"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/kubelet"
+)
+
+const namespaceLabelsExpire = 5 * time.Minute
+
+// KubeNamespaceCollector tags the containers of the local pods with the
+// labels of their namespace, fetched from the apiserver. It relies on the
+// KubeletCollector to trigger deletions.
+type KubeNamespaceCollector struct {
+	kubeUtil     *kubelet.KubeUtil
+	apiClient    *apiserver.APIClient
+	infoOut      chan<- []*TagInfo
+	labelsAsTags map[string]string
+
+	// m protects the fields below, Pull and Fetch can run concurrently
+	m               sync.Mutex
+	namespaceLabels map[string]map[string]string
+	lastRefresh     time.Time
+	// sent holds the containers whose tags were sent by the last Pull
+	sent map[string]struct{}
+}
+
+// Detect checks that namespace labels are to be collected and connects to
+// the kubelet and to the apiserver
+func (c *KubeNamespaceCollector) Detect(out chan<- []*TagInfo) (CollectionMode, error) {
+	if config.Datadog.GetBool("cluster_agent.enabled") {
+		return NoCollection, errors.New("namespace labels are collected by the kubelet collector from the cluster agent")
+	}
+
+	// viper lower-cases map keys, so extractor must lowercase before matching
+	c.labelsAsTags = config.Datadog.GetStringMapString("kubernetes_namespace_labels_as_tags")
+	if len(c.labelsAsTags) == 0 {
+		return NoCollection, errors.New("no namespace label to collect")
+	}
+
+	ku, err := kubelet.GetKubeUtil()
+	if err != nil {
+		return NoCollection, err
+	}
+	client, err := apiserver.GetAPIClient()
+	if err != nil {
+		return NoCollection, err
+	}
+	c.kubeUtil = ku
+	c.apiClient = client
+	c.infoOut = out
+	c.namespaceLabels = make(map[string]map[string]string)
+	c.sent = make(map[string]struct{})
+
+	return PullCollection, nil
+}
+
+// Pull sends the tags of the new containers, or of all of them if the
+// labels of a namespace changed. The labels are refreshed every
+// namespaceLabelsExpire.
+func (c *KubeNamespaceCollector) Pull() error {
+	pods, err := c.kubeUtil.GetLocalPodList()
+	if err != nil {
+		return err
+	}
+
+	c.m.Lock()
+	expired := time.Now().Sub(c.lastRefresh) > namespaceLabelsExpire
+	if expired {
+		c.lastRefresh = time.Now()
+	}
+	changed := c.updateLabels(pods, expired)
+	infos := parseNamespaceLabels(pods, c.namespaceLabels, c.labelsAsTags)
+	sent := make(map[string]struct{}, len(infos))
+	var updates []*TagInfo
+	for _, info := range infos {
+		if _, found := c.sent[info.Entity]; changed || !found {
+			updates = append(updates, info)
+		}
+		sent[info.Entity] = struct{}{}
+	}
+	c.sent = sent
+	c.m.Unlock()
+
+	if len(updates) > 0 {
+		c.infoOut <- updates
+	}
+	return nil
+}
+
+// Fetch gets the namespace tags of a container
+func (c *KubeNamespaceCollector) Fetch(container string) ([]string, []string, []string, error) {
+	if strings.HasPrefix(container, kubelet.KubePodPrefix) {
+		// only the containers get the namespace labels
+		return []string{}, []string{}, []string{}, ErrNotFound
+	}
+	pod, err := c.kubeUtil.GetPodForContainerID(container)
+	if err != nil {
+		return []string{}, []string{}, []string{}, err
+	}
+
+	c.m.Lock()
+	c.updateLabels([]*kubelet.Pod{pod}, false)
+	infos := parseNamespaceLabels([]*kubelet.Pod{pod}, c.namespaceLabels, c.labelsAsTags)
+	c.m.Unlock()
+
+	for _, info := range infos {
+		if info.Entity == container {
+			return info.LowCardTags, info.OrchestratorCardTags, info.HighCardTags, nil
+		}
+	}
+	return []string{}, []string{}, []string{}, ErrNotFound
+}
+
+// updateLabels fetches the labels of the namespaces of the pods that are not
+// cached yet, or of all of them if the cache expired, in which case pods must
+// be the full pod list. It returns whether the labels of a cached namespace
+// changed. Must be called with c.m held.
+func (c *KubeNamespaceCollector) updateLabels(pods []*kubelet.Pod, expired bool) bool {
+	changed := false
+	seen := make(map[string]struct{})
+	for _, pod := range pods {
+		namespace := pod.Metadata.Namespace
+		if _, found := seen[namespace]; found {
+			continue
+		}
+		seen[namespace] = struct{}{}
+
+		cached, found := c.namespaceLabels[namespace]
+		if found && !expired {
+			continue
+		}
+		labels, err := c.apiClient.NamespaceLabels(namespace)
+		if err != nil {
+			log.Debugf("cannot get the labels of namespace %s: %s", namespace, err)
+			continue
+		}
+		if found && !reflect.DeepEqual(cached, labels) {
+			changed = true
+		}
+		c.namespaceLabels[namespace] = labels
+	}
+
+	if expired {
+		// forget the namespaces without pods on the node anymore
+		for namespace := range c.namespaceLabels {
+			if _, found := seen[namespace]; !found {
+				delete(c.namespaceLabels, namespace)
+			}
+		}
+	}
+	return changed
+}
+
+func kubeNamespaceFactory() Collector {
+	return &KubeNamespaceCollector{}
+}
+
+func init() {
+	registerCollector(kubeNamespaceCollectorName, kubeNamespaceFactory)
+}
@@ -8,11 +8,14 @@
 package collectors
 
 import (
+	"encoding/json"
+	"fmt"
 	"strings"
 
 	log "github.com/cihub/seelog"
 
 	"github.com/DataDog/datadog-agent/pkg/tagger/utils"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver"
 	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/kubelet"
 )
 
@@ -21,7 +24,8 @@ import (
      - labels are collected when whitelisted, like docker ones, see
      kubernetes_pod_labels_as_tags option
    Moved to cluster agent:
-     - kube_service
+     - kube_service and the owners of the replicasets and jobs, added
+     when cluster_agent.enabled is set
 	 - node tags
    To deprecate:
      - kube_replicate_controller added everytime, just keep if real owner
@@ -38,59 +42,51 @@ const KubeAllowedEncodeStringAlphaNums = "bcdfghjklmnpqrstvwxz2456789"
 // Digits holds the digits used for naming replicasets in kubenetes < 1.8
 const Digits = "1234567890"
 
-// parsePods convert Pods from the PodWatcher to TagInfo objects
+const (
+	// podTagsAnnotation holds custom tags for all the containers of a pod,
+	// as a JSON object like {"tag_name": "value"} or {"tag_name": ["value1", "value2"]}
+	podTagsAnnotation = "ad.datadoghq.com/tags"
+	// podContainerTagsAnnotationFormat holds custom tags for a single
+	// container, in the same format as podTagsAnnotation
+	podContainerTagsAnnotationFormat = "ad.datadoghq.com/%s.tags"
+)
+
+// parsePods convert Pods from the PodWatcher to TagInfo objects, one per
+// container, and one for the pod itself if it has a UID
 func (c *KubeletCollector) parsePods(pods []*kubelet.Pod) ([]*TagInfo, error) {
 	var output []*TagInfo
 	for _, pod := range pods {
+		metadata, namespaceLabels := c.getClusterMetadata(pod)
+
+		// Pod entity, for the pod level metrics like the volume usage
+		if entity := kubelet.PodUIDToEntityName(pod.Metadata.UID); entity != "" {
+			tags := utils.NewTagList()
+			c.addPodTags(tags, pod, metadata, namespaceLabels)
+			low, orchestrator, high := tags.Compute()
+			output = append(output, &TagInfo{
+				Source:               kubeletCollectorName,
+				Entity:               entity,
+				HighCardTags:         high,
+				OrchestratorCardTags: orchestrator,
+				LowCardTags:          low,
+			})
+		}
+
 		for _, container := range pod.Status.Containers {
 			tags := utils.NewTagList()
+			c.addPodTags(tags, pod, metadata, namespaceLabels)
 
-			// Pod name
-			tags.AddHigh("pod_name", pod.Metadata.Name)
-			tags.AddLow("kube_namespace", pod.Metadata.Namespace)
 			tags.AddLow("kube_container_name", container.Name)
+			// Custom tags from the container annotations
+			parseTagsAnnotation(tags, pod, fmt.Sprintf(podContainerTagsAnnotationFormat, container.Name))
 
-			// Pod labels
-			for labelName, labelValue := range pod.Metadata.Labels {
-				if tagName, found := c.labelsAsTags[strings.ToLower(labelName)]; found {
-					tags.AddAuto(tagName, labelValue)
-				}
-			}
-
-			// Creator
-			for _, owner := range pod.Metadata.Owners {
-				switch owner.Kind {
-				case "":
-					continue
-				case "Deployment":
-					tags.AddLow("kube_deployment", owner.Name)
-				case "DaemonSet":
-					tags.AddLow("kube_daemon_set", owner.Name)
-				case "ReplicationController":
-					tags.AddLow("kube_replication_controller", owner.Name)
-				case "StatefulSet":
-					tags.AddLow("kube_stateful_set", owner.Name)
-				case "Job":
-					tags.AddHigh("kube_job", owner.Name) // TODO detect if no from cronjob, then low card
-				case "ReplicaSet":
-					deployment := c.parseDeploymentForReplicaset(owner.Name)
-					if len(deployment) > 0 {
-						tags.AddHigh("kube_replica_set", owner.Name)
-						tags.AddLow("kube_deployment", deployment)
-					} else {
-						tags.AddLow("kube_replica_set", owner.Name)
-					}
-				default:
-					log.Debugf("unknown owner kind %s for pod %s", owner.Kind, pod.Metadata.Name)
-				}
-			}
-
-			low, high := tags.Compute()
+			low, orchestrator, high := tags.Compute()
 			info := &TagInfo{
-				Source:       kubeletCollectorName,
-				Entity:       container.ID,
-				HighCardTags: high,
-				LowCardTags:  low,
+				Source:               kubeletCollectorName,
+				Entity:               container.ID,
+				HighCardTags:         high,
+				OrchestratorCardTags: orchestrator,
+				LowCardTags:          low,
 			}
 			output = append(output, info)
 		}
@@ -98,6 +94,170 @@ func (c *KubeletCollector) parsePods(pods []*kubelet.Pod) ([]*TagInfo, error) {
 	return output, nil
 }
 
+// addPodTags adds the tags shared by a pod and its containers
+func (c *KubeletCollector) addPodTags(tags *utils.TagList, pod *kubelet.Pod, metadata *apiserver.PodMetadata, namespaceLabels map[string]string) {
+	// Pod name
+	tags.AddOrchestrator("pod_name", pod.Metadata.Name)
+	tags.AddLow("kube_namespace", pod.Metadata.Namespace)
+
+	// Pod labels
+	for labelName, labelValue := range pod.Metadata.Labels {
+		if tagName, found := c.labelsAsTags[strings.ToLower(labelName)]; found {
+			tags.AddAuto(tagName, labelValue)
+		}
+	}
+
+	// Pod annotations
+	for annotationName, annotationValue := range pod.Metadata.Annotations {
+		if tagName, found := c.annotationsAsTags[strings.ToLower(annotationName)]; found {
+			tags.AddAuto(tagName, annotationValue)
+		}
+	}
+
+	// Custom tags from the pod annotations
+	parseTagsAnnotation(tags, pod, podTagsAnnotation)
+
+	// Namespace labels and services, from the cluster agent
+	for labelName, labelValue := range namespaceLabels {
+		if tagName, found := c.namespaceLabelsAsTags[strings.ToLower(labelName)]; found {
+			tags.AddAuto(tagName, labelValue)
+		}
+	}
+	if metadata != nil {
+		for _, svc := range metadata.Services {
+			tags.AddLow("kube_service", svc)
+		}
+	}
+
+	// Creator, from the owner chain known by the cluster agent if any
+	if metadata != nil && len(metadata.Owners) > 0 {
+		addOwnerChainTags(tags, metadata.Owners)
+	} else {
+		for _, owner := range pod.Metadata.Owners {
+			c.addOwnerTags(tags, pod, owner)
+		}
+	}
+}
+
+// addOwnerTags adds the tags of an owner reported by the kubelet, guessing
+// the deployment of the replicasets from their name
+func (c *KubeletCollector) addOwnerTags(tags *utils.TagList, pod *kubelet.Pod, owner kubelet.PodOwner) {
+	switch owner.Kind {
+	case "":
+		return
+	case "Deployment":
+		tags.AddLow("kube_deployment", owner.Name)
+	case "DaemonSet":
+		tags.AddLow("kube_daemon_set", owner.Name)
+	case "ReplicationController":
+		tags.AddLow("kube_replication_controller", owner.Name)
+	case "StatefulSet":
+		tags.AddLow("kube_stateful_set", owner.Name)
+	case "Job":
+		tags.AddOrchestrator("kube_job", owner.Name) // TODO detect if no from cronjob, then low card
+	case "ReplicaSet":
+		deployment := c.parseDeploymentForReplicaset(owner.Name)
+		if len(deployment) > 0 {
+			tags.AddOrchestrator("kube_replica_set", owner.Name)
+			tags.AddLow("kube_deployment", deployment)
+		} else {
+			tags.AddLow("kube_replica_set", owner.Name)
+		}
+	default:
+		log.Debugf("unknown owner kind %s for pod %s", owner.Kind, pod.Metadata.Name)
+	}
+}
+
+// addOwnerChainTags adds the tags of the owner chain of a pod, as known by
+// the cluster agent: the controller of the pod, then its own controller. The
+// replicasets of deployments and the jobs of cronjobs are orchestrator
+// cardinality, as they change on every rollout or schedule.
+func addOwnerChainTags(tags *utils.TagList, owners []apiserver.OwnerReference) {
+	controller := owners[0]
+	parent := apiserver.OwnerReference{}
+	if len(owners) > 1 {
+		parent = owners[1]
+	}
+
+	switch {
+	case controller.Kind == "ReplicaSet" && parent.Kind == "Deployment":
+		tags.AddOrchestrator("kube_replica_set", controller.Name)
+		tags.AddLow("kube_deployment", parent.Name)
+	case controller.Kind == "Job" && parent.Kind == "CronJob":
+		tags.AddOrchestrator("kube_job", controller.Name)
+		tags.AddLow("kube_cronjob", parent.Name)
+	case controller.Kind == "ReplicaSet":
+		tags.AddLow("kube_replica_set", controller.Name)
+	case controller.Kind == "Job":
+		tags.AddLow("kube_job", controller.Name)
+	case controller.Kind == "Deployment":
+		tags.AddLow("kube_deployment", controller.Name)
+	case controller.Kind == "DaemonSet":
+		tags.AddLow("kube_daemon_set", controller.Name)
+	case controller.Kind == "ReplicationController":
+		tags.AddLow("kube_replication_controller", controller.Name)
+	case controller.Kind == "StatefulSet":
+		tags.AddLow("kube_stateful_set", controller.Name)
+	default:
+		log.Debugf("unknown owner kind %s", controller.Kind)
+	}
+}
+
+// getClusterMetadata gets the metadata and the namespace labels of a pod
+// from the cluster agent, if it is enabled. Errors are logged, the kubelet
+// tags being sent anyway.
+func (c *KubeletCollector) getClusterMetadata(pod *kubelet.Pod) (*apiserver.PodMetadata, map[string]string) {
+	client := c.getClusterClient()
+	if client == nil {
+		return nil, nil
+	}
+
+	metadata, err := client.GetPodMetadata(pod.Spec.NodeName, pod.Metadata.Name)
+	if err != nil {
+		log.Debugf("Cannot get the cluster metadata of pod %s: %s", pod.Metadata.Name, err)
+		metadata = nil
+	}
+
+	var namespaceLabels map[string]string
+	if len(c.namespaceLabelsAsTags) > 0 {
+		namespaceLabels, err = client.GetNamespaceLabels(pod.Metadata.Namespace)
+		if err != nil {
+			log.Debugf("Cannot get the labels of namespace %s: %s", pod.Metadata.Namespace, err)
+		}
+	}
+	return metadata, namespaceLabels
+}
+
+// parseTagsAnnotation adds the custom tags of a JSON annotation of the pod to
+// the list. Tag names prefixed with + are high cardinality, as for labels.
+func parseTagsAnnotation(tags *utils.TagList, pod *kubelet.Pod, annotation string) {
+	value, found := pod.Metadata.Annotations[annotation]
+	if !found {
+		return
+	}
+	customTags := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(value), &customTags); err != nil {
+		log.Debugf("cannot parse the %s annotation of pod %s: %s", annotation, pod.Metadata.Name, err)
+		return
+	}
+	for tagName, tagValue := range customTags {
+		switch v := tagValue.(type) {
+		case string:
+			tags.AddAuto(tagName, v)
+		case []interface{}:
+			for _, item := range v {
+				if s, ok := item.(string); ok {
+					tags.AddAuto(tagName, s)
+				} else {
+					log.Debugf("ignoring the non-string value %v of tag %s in the %s annotation of pod %s", item, tagName, annotation, pod.Metadata.Name)
+				}
+			}
+		default:
+			log.Debugf("ignoring the non-string value %v of tag %s in the %s annotation of pod %s", v, tagName, annotation, pod.Metadata.Name)
+		}
+	}
+}
+
 // parseDeploymentForReplicaset gets the deployment name from a replicaset,
 // or returns an empty string if no parent deployment is found.
 func (c *KubeletCollector) parseDeploymentForReplicaset(name string) string {
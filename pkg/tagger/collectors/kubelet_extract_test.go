@@ -8,11 +8,13 @@
 package collectors
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver"
 	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/kubelet"
 )
 
@@ -29,10 +31,11 @@ func TestParsePods(t *testing.T) {
 	}
 
 	for nb, tc := range []struct {
-		desc         string
-		pod          *kubelet.Pod
-		labelsAsTags map[string]string
-		expectedInfo *TagInfo
+		desc              string
+		pod               *kubelet.Pod
+		labelsAsTags      map[string]string
+		annotationsAsTags map[string]string
+		expectedInfo      *TagInfo
 	}{
 		{
 			desc:         "empty pod",
@@ -58,10 +61,11 @@ func TestParsePods(t *testing.T) {
 			},
 			labelsAsTags: map[string]string{},
 			expectedInfo: &TagInfo{
-				Source:       "kubelet",
-				Entity:       entityID,
-				LowCardTags:  []string{"kube_namespace:default", "kube_container_name:dd-agent", "kube_daemon_set:dd-agent-rc"},
-				HighCardTags: []string{"pod_name:dd-agent-rc-qd876"},
+				Source:               "kubelet",
+				Entity:               entityID,
+				LowCardTags:          []string{"kube_namespace:default", "kube_container_name:dd-agent", "kube_daemon_set:dd-agent-rc"},
+				OrchestratorCardTags: []string{"pod_name:dd-agent-rc-qd876"},
+				HighCardTags:         []string{},
 			},
 		},
 		{
@@ -79,10 +83,11 @@ func TestParsePods(t *testing.T) {
 			},
 			labelsAsTags: map[string]string{},
 			expectedInfo: &TagInfo{
-				Source:       "kubelet",
-				Entity:       entityID,
-				LowCardTags:  []string{"kube_container_name:dd-agent", "kube_replica_set:kubernetes-dashboard"},
-				HighCardTags: []string{},
+				Source:               "kubelet",
+				Entity:               entityID,
+				LowCardTags:          []string{"kube_container_name:dd-agent", "kube_replica_set:kubernetes-dashboard"},
+				OrchestratorCardTags: []string{},
+				HighCardTags:         []string{},
 			},
 		},
 		{
@@ -100,10 +105,11 @@ func TestParsePods(t *testing.T) {
 			},
 			labelsAsTags: map[string]string{},
 			expectedInfo: &TagInfo{
-				Source:       "kubelet",
-				Entity:       entityID,
-				LowCardTags:  []string{"kube_container_name:dd-agent", "kube_deployment:frontend"},
-				HighCardTags: []string{"kube_replica_set:frontend-2891696001"},
+				Source:               "kubelet",
+				Entity:               entityID,
+				LowCardTags:          []string{"kube_container_name:dd-agent", "kube_deployment:frontend"},
+				OrchestratorCardTags: []string{"kube_replica_set:frontend-2891696001"},
+				HighCardTags:         []string{},
 			},
 		},
 		{
@@ -121,10 +127,11 @@ func TestParsePods(t *testing.T) {
 			},
 			labelsAsTags: map[string]string{},
 			expectedInfo: &TagInfo{
-				Source:       "kubelet",
-				Entity:       entityID,
-				LowCardTags:  []string{"kube_container_name:dd-agent", "kube_deployment:front-end"},
-				HighCardTags: []string{"kube_replica_set:front-end-768dd754b7"},
+				Source:               "kubelet",
+				Entity:               entityID,
+				LowCardTags:          []string{"kube_container_name:dd-agent", "kube_deployment:front-end"},
+				OrchestratorCardTags: []string{"kube_replica_set:front-end-768dd754b7"},
+				HighCardTags:         []string{},
 			},
 		},
 		{
@@ -157,13 +164,83 @@ func TestParsePods(t *testing.T) {
 					"component:kube-proxy",
 					"tier:node",
 				},
-				HighCardTags: []string{"GitCommit:ea38b55f07e40b68177111a2bff1e918132fd5fb"},
+				OrchestratorCardTags: []string{},
+				HighCardTags:         []string{"GitCommit:ea38b55f07e40b68177111a2bff1e918132fd5fb"},
+			},
+		},
+		{
+			desc: "pod annotations",
+			pod: &kubelet.Pod{
+				Metadata: kubelet.PodMetadata{
+					Annotations: map[string]string{
+						"app.example.com/Owner":   "payments",
+						"app.example.com/version": "1.2.3",
+						"ignored":                 "value",
+					},
+				},
+				Status: oneContainer,
+			},
+			annotationsAsTags: map[string]string{
+				"app.example.com/owner":   "owner",
+				"app.example.com/version": "+version",
+			},
+			expectedInfo: &TagInfo{
+				Source:               "kubelet",
+				Entity:               entityID,
+				LowCardTags:          []string{"kube_container_name:dd-agent", "owner:payments"},
+				OrchestratorCardTags: []string{},
+				HighCardTags:         []string{"version:1.2.3"},
+			},
+		},
+		{
+			desc: "custom tags annotations",
+			pod: &kubelet.Pod{
+				Metadata: kubelet.PodMetadata{
+					Annotations: map[string]string{
+						"ad.datadoghq.com/tags":          `{"team": "payments", "+build": "1234", "role": ["web", "public"], "replicas": 3}`,
+						"ad.datadoghq.com/dd-agent.tags": `{"component": "agent"}`,
+						"ad.datadoghq.com/other.tags":    `{"component": "other"}`,
+					},
+				},
+				Status: oneContainer,
+			},
+			expectedInfo: &TagInfo{
+				Source: "kubelet",
+				Entity: entityID,
+				LowCardTags: []string{
+					"kube_container_name:dd-agent",
+					"team:payments",
+					"role:web",
+					"role:public",
+					"component:agent",
+				},
+				OrchestratorCardTags: []string{},
+				HighCardTags:         []string{"build:1234"},
+			},
+		},
+		{
+			desc: "invalid custom tags annotation",
+			pod: &kubelet.Pod{
+				Metadata: kubelet.PodMetadata{
+					Annotations: map[string]string{
+						"ad.datadoghq.com/tags": `["team:payments"]`,
+					},
+				},
+				Status: oneContainer,
+			},
+			expectedInfo: &TagInfo{
+				Source:               "kubelet",
+				Entity:               entityID,
+				LowCardTags:          []string{"kube_container_name:dd-agent"},
+				OrchestratorCardTags: []string{},
+				HighCardTags:         []string{},
 			},
 		},
 	} {
 		t.Run(fmt.Sprintf("case %d: %s", nb, tc.desc), func(t *testing.T) {
 			collector := &KubeletCollector{
-				labelsAsTags: tc.labelsAsTags,
+				labelsAsTags:      tc.labelsAsTags,
+				annotationsAsTags: tc.annotationsAsTags,
 			}
 			infos, err := collector.parsePods([]*kubelet.Pod{tc.pod})
 			assert.Nil(t, err)
@@ -178,6 +255,166 @@ func TestParsePods(t *testing.T) {
 	}
 }
 
+// fakeClusterMetadataClient serves the cluster metadata of pods by name and
+// the labels of namespaces
+type fakeClusterMetadataClient struct {
+	pods       map[string]*apiserver.PodMetadata
+	namespaces map[string]map[string]string
+}
+
+func (f *fakeClusterMetadataClient) GetPodMetadata(nodeName, podName string) (*apiserver.PodMetadata, error) {
+	if metadata, found := f.pods[nodeName+"/"+podName]; found {
+		return metadata, nil
+	}
+	return nil, errors.New("unexpected status code from cluster agent: 404")
+}
+
+func (f *fakeClusterMetadataClient) GetNamespaceLabels(namespace string) (map[string]string, error) {
+	if labels, found := f.namespaces[namespace]; found {
+		return labels, nil
+	}
+	return nil, errors.New("unexpected status code from cluster agent: 404")
+}
+
+func TestParsePodsClusterMetadata(t *testing.T) {
+	entityID := "docker://d0242fc32d53137526dc365e7c86ef43b5f50b6f72dfd53dcb948eff4560376f"
+	newPod := func(name string, owner kubelet.PodOwner) *kubelet.Pod {
+		return &kubelet.Pod{
+			Metadata: kubelet.PodMetadata{
+				Name:      name,
+				Namespace: "prod",
+				Owners:    []kubelet.PodOwner{owner},
+			},
+			Spec: kubelet.Spec{NodeName: "node1"},
+			Status: kubelet.Status{
+				Containers: []kubelet.ContainerStatus{{ID: entityID, Name: "app"}},
+			},
+		}
+	}
+	client := &fakeClusterMetadataClient{
+		pods: map[string]*apiserver.PodMetadata{
+			"node1/web-1": {
+				Services: []string{"web", "web-internal"},
+				Owners: []apiserver.OwnerReference{
+					{Kind: "ReplicaSet", Name: "web-manual"},
+					{Kind: "Deployment", Name: "web"},
+				},
+			},
+			"node1/backup-1528700400-x2x9s": {
+				Owners: []apiserver.OwnerReference{
+					{Kind: "Job", Name: "backup-1528700400"},
+					{Kind: "CronJob", Name: "backup"},
+				},
+			},
+			"node1/migrate-x2x9s": {
+				Owners: []apiserver.OwnerReference{{Kind: "Job", Name: "migrate"}},
+			},
+		},
+		namespaces: map[string]map[string]string{
+			"prod": {"Team": "infra", "other": "ignored"},
+		},
+	}
+
+	for nb, tc := range []struct {
+		desc         string
+		pod          *kubelet.Pod
+		expectedInfo *TagInfo
+	}{
+		{
+			desc: "deployment known by the cluster agent, whatever the replicaset name",
+			pod:  newPod("web-1", kubelet.PodOwner{Kind: "ReplicaSet", Name: "web-manual"}),
+			expectedInfo: &TagInfo{
+				Source: "kubelet",
+				Entity: entityID,
+				LowCardTags: []string{"kube_namespace:prod", "kube_container_name:app", "team:infra",
+					"kube_service:web", "kube_service:web-internal", "kube_deployment:web"},
+				OrchestratorCardTags: []string{"pod_name:web-1", "kube_replica_set:web-manual"},
+				HighCardTags:         []string{},
+			},
+		},
+		{
+			desc: "job of a cronjob",
+			pod:  newPod("backup-1528700400-x2x9s", kubelet.PodOwner{Kind: "Job", Name: "backup-1528700400"}),
+			expectedInfo: &TagInfo{
+				Source:               "kubelet",
+				Entity:               entityID,
+				LowCardTags:          []string{"kube_namespace:prod", "kube_container_name:app", "team:infra", "kube_cronjob:backup"},
+				OrchestratorCardTags: []string{"pod_name:backup-1528700400-x2x9s", "kube_job:backup-1528700400"},
+				HighCardTags:         []string{},
+			},
+		},
+		{
+			desc: "standalone job",
+			pod:  newPod("migrate-x2x9s", kubelet.PodOwner{Kind: "Job", Name: "migrate"}),
+			expectedInfo: &TagInfo{
+				Source:               "kubelet",
+				Entity:               entityID,
+				LowCardTags:          []string{"kube_namespace:prod", "kube_container_name:app", "team:infra", "kube_job:migrate"},
+				OrchestratorCardTags: []string{"pod_name:migrate-x2x9s"},
+				HighCardTags:         []string{},
+			},
+		},
+		{
+			desc: "pod unknown by the cluster agent falls back to the kubelet owners",
+			pod:  newPod("front-end-768dd754b7-8xkzl", kubelet.PodOwner{Kind: "ReplicaSet", Name: "front-end-768dd754b7"}),
+			expectedInfo: &TagInfo{
+				Source:               "kubelet",
+				Entity:               entityID,
+				LowCardTags:          []string{"kube_namespace:prod", "kube_container_name:app", "team:infra", "kube_deployment:front-end"},
+				OrchestratorCardTags: []string{"pod_name:front-end-768dd754b7-8xkzl", "kube_replica_set:front-end-768dd754b7"},
+				HighCardTags:         []string{},
+			},
+		},
+	} {
+		t.Run(fmt.Sprintf("case %d: %s", nb, tc.desc), func(t *testing.T) {
+			collector := &KubeletCollector{
+				clusterAgentEnabled:   true,
+				clusterClient:         client,
+				namespaceLabelsAsTags: map[string]string{"team": "team"},
+			}
+			infos, err := collector.parsePods([]*kubelet.Pod{tc.pod})
+			assert.Nil(t, err)
+			assert.Len(t, infos, 1)
+			assertTagInfoEqual(t, tc.expectedInfo, infos[0])
+		})
+	}
+}
+
+func TestParsePodsPodEntity(t *testing.T) {
+	pod := &kubelet.Pod{
+		Metadata: kubelet.PodMetadata{
+			Name:        "web-1",
+			Namespace:   "prod",
+			UID:         "e82c9361-7e13-11e7-bbe9-42010a8401cc",
+			Annotations: map[string]string{"ad.datadoghq.com/app.tags": `{"team": "web"}`},
+			Owners:      []kubelet.PodOwner{{Kind: "StatefulSet", Name: "web"}},
+		},
+		Status: kubelet.Status{
+			Containers: []kubelet.ContainerStatus{{ID: "docker://abcdef", Name: "app"}},
+		},
+	}
+	collector := &KubeletCollector{}
+	infos, err := collector.parsePods([]*kubelet.Pod{pod})
+	assert.Nil(t, err)
+	assert.Len(t, infos, 2)
+
+	// the pod has the tags shared by its containers
+	assertTagInfoEqual(t, &TagInfo{
+		Source:               "kubelet",
+		Entity:               "kubernetes_pod://e82c9361-7e13-11e7-bbe9-42010a8401cc",
+		LowCardTags:          []string{"kube_namespace:prod", "kube_stateful_set:web"},
+		OrchestratorCardTags: []string{"pod_name:web-1"},
+		HighCardTags:         []string{},
+	}, infos[0])
+	assertTagInfoEqual(t, &TagInfo{
+		Source:               "kubelet",
+		Entity:               "docker://abcdef",
+		LowCardTags:          []string{"kube_namespace:prod", "kube_stateful_set:web", "kube_container_name:app", "team:web"},
+		OrchestratorCardTags: []string{"pod_name:web-1"},
+		HighCardTags:         []string{},
+	}, infos[1])
+}
+
 func TestParseDeploymentForReplicaset(t *testing.T) {
 	for in, out := range map[string]string{
 		// Nominal 1.6 cases
@@ -8,9 +8,14 @@
 package collectors
 
 import (
+	"strings"
 	"time"
 
+	log "github.com/cihub/seelog"
+
 	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/clusteragent"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver"
 	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/kubelet"
 )
 
@@ -19,15 +24,29 @@ const (
 	kubeletExpireFreq    = 5 * time.Minute
 )
 
+// clusterMetadataClient gets the cluster-level metadata of the pods, it is
+// implemented by the cluster agent client
+type clusterMetadataClient interface {
+	GetPodMetadata(nodeName, podName string) (*apiserver.PodMetadata, error)
+	GetNamespaceLabels(namespace string) (map[string]string, error)
+}
+
 // KubeletCollector connects to the local kubelet to get kubernetes container
-// tags. It is to be supplemented by the cluster agent collector for tags from
-// the apiserver.
+// tags. When the cluster agent is enabled, it adds the services, the owners
+// and the namespace labels of the pods it knows from the apiserver.
 type KubeletCollector struct {
-	watcher      *kubelet.PodWatcher
-	infoOut      chan<- []*TagInfo
-	lastExpire   time.Time
-	expireFreq   time.Duration
-	labelsAsTags map[string]string
+	watcher           *kubelet.PodWatcher
+	infoOut           chan<- []*TagInfo
+	lastExpire        time.Time
+	expireFreq        time.Duration
+	labelsAsTags      map[string]string
+	annotationsAsTags map[string]string
+
+	// clusterAgentEnabled is set by cluster_agent.enabled, clusterClient
+	// is nil until the client is successfully initialized
+	clusterAgentEnabled   bool
+	clusterClient         clusterMetadataClient
+	namespaceLabelsAsTags map[string]string
 }
 
 // Detect tries to connect to the kubelet
@@ -43,10 +62,33 @@ func (c *KubeletCollector) Detect(out chan<- []*TagInfo) (CollectionMode, error)
 
 	// viper lower-cases map keys, so extractor must lowercase before matching
 	c.labelsAsTags = config.Datadog.GetStringMapString("kubernetes_pod_labels_as_tags")
+	c.annotationsAsTags = config.Datadog.GetStringMapString("kubernetes_pod_annotations_as_tags")
+
+	c.clusterAgentEnabled = config.Datadog.GetBool("cluster_agent.enabled")
+	if c.clusterAgentEnabled {
+		c.namespaceLabelsAsTags = config.Datadog.GetStringMapString("kubernetes_namespace_labels_as_tags")
+		c.getClusterClient()
+	}
 
 	return PullCollection, nil
 }
 
+// getClusterClient returns the cluster agent client, initializing it if
+// needed. It returns nil if the cluster agent is disabled or unreachable,
+// the tags from the kubelet are then sent without the cluster metadata.
+func (c *KubeletCollector) getClusterClient() clusterMetadataClient {
+	if c.clusterClient != nil || !c.clusterAgentEnabled {
+		return c.clusterClient
+	}
+	client, err := clusteragent.GetClusterAgentClient()
+	if err != nil {
+		log.Debugf("Cannot get the cluster agent client, the cluster metadata is not collected: %s", err)
+		return nil
+	}
+	c.clusterClient = client
+	return c.clusterClient
+}
+
 // Pull triggers a podlist refresh and sends new info. It also triggers
 // container deletion computation every 'expireFreq'
 func (c *KubeletCollector) Pull() error {
@@ -80,26 +122,32 @@ func (c *KubeletCollector) Pull() error {
 	return nil
 }
 
-// Fetch fetches tags for a given container by iterating on the whole podlist
+// Fetch fetches tags for a given container or pod by iterating on the whole podlist
 // TODO: optimize if called too often on production
-func (c *KubeletCollector) Fetch(container string) ([]string, []string, error) {
-	pod, err := c.watcher.GetPodForContainerID(container)
+func (c *KubeletCollector) Fetch(container string) ([]string, []string, []string, error) {
+	var pod *kubelet.Pod
+	var err error
+	if strings.HasPrefix(container, kubelet.KubePodPrefix) {
+		pod, err = c.watcher.GetPodFromUID(strings.TrimPrefix(container, kubelet.KubePodPrefix))
+	} else {
+		pod, err = c.watcher.GetPodForContainerID(container)
+	}
 	if err != nil {
-		return []string{}, []string{}, err
+		return []string{}, []string{}, []string{}, err
 	}
 	updates, err := c.parsePods([]*kubelet.Pod{pod})
 	if err != nil {
-		return []string{}, []string{}, err
+		return []string{}, []string{}, []string{}, err
 	}
 	c.infoOut <- updates
 
 	for _, info := range updates {
 		if info.Entity == container {
-			return info.LowCardTags, info.HighCardTags, nil
+			return info.LowCardTags, info.OrchestratorCardTags, info.HighCardTags, nil
 		}
 	}
 	// container not found in updates
-	return []string{}, []string{}, ErrNotFound
+	return []string{}, []string{}, []string{}, ErrNotFound
 }
 
 // parseExpires transforms event from the PodWatcher to TagInfo objects
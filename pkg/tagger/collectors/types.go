@@ -5,16 +5,62 @@
 
 package collectors
 
-import "errors"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/errors"
+)
 
 // TagInfo holds the tag information for a given entity and source. It's meant
 // to be created from collectors and read by the store.
 type TagInfo struct {
-	Source       string   // source collector's name
-	Entity       string   // entity name ready for lookup
-	HighCardTags []string // high cardinality tags that can create a lot of contexts
-	LowCardTags  []string // low cardinality tags safe for every pipeline
-	DeleteEntity bool     // true if the entity is to be deleted from the store
+	Source               string   `json:"source"`                  // source collector's name
+	Entity               string   `json:"entity"`                  // entity name ready for lookup
+	HighCardTags         []string `json:"high_card_tags"`          // high cardinality tags that can create a lot of contexts
+	OrchestratorCardTags []string `json:"orchestrator_card_tags"`  // orchestrator cardinality tags that change value for each pod, task, etc.
+	LowCardTags          []string `json:"low_card_tags"`           // low cardinality tags safe for every pipeline
+	DeleteEntity         bool     `json:"delete_entity,omitempty"` // true if the entity is to be deleted from the store
+}
+
+// TagCardinality indicates the cardinality level of the tags returned by the
+// tagger. Each level includes the tags of the lower ones.
+type TagCardinality int
+
+// Tag cardinality levels
+const (
+	LowCardinality          TagCardinality = iota // in the order of magnitude of the host count
+	OrchestratorCardinality                       // change value for each pod, task, etc.
+	HighCardinality                               // change value for each container
+)
+
+// StringToTagCardinality parses a cardinality level from the configuration:
+// low, orchestrator or high
+func StringToTagCardinality(c string) (TagCardinality, error) {
+	switch strings.ToLower(c) {
+	case "low":
+		return LowCardinality, nil
+	case "orchestrator":
+		return OrchestratorCardinality, nil
+	case "high":
+		return HighCardinality, nil
+	default:
+		return LowCardinality, fmt.Errorf("unknown tag cardinality %q, expected low, orchestrator or high", c)
+	}
+}
+
+// String returns the name of the cardinality level
+func (c TagCardinality) String() string {
+	switch c {
+	case LowCardinality:
+		return "low"
+	case OrchestratorCardinality:
+		return "orchestrator"
+	case HighCardinality:
+		return "high"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(c))
+	}
 }
 
 // CollectionMode informs the Tagger of how to schedule a Collector
@@ -22,11 +68,11 @@ type CollectionMode int
 
 // ErrNotFound is returned by Fetch if no collection error occurred but
 // the entity is not found in the source
-var ErrNotFound = errors.New("entity not found")
+var ErrNotFound = errors.ErrNotFound
 
 // ErrOutdated is returned when an entity is found but
 // it's value is outdated compared to the one locally saved
-var ErrOutdated = errors.New("entity is outdated")
+var ErrOutdated = errors.ErrOutdated
 
 // Return values for Collector.Init to inform the Tagger of the scheduling needed
 const (
@@ -43,9 +89,10 @@ type Collector interface {
 	Detect(chan<- []*TagInfo) (CollectionMode, error)
 }
 
-// Fetcher allows to fetch tags on-demand in case of cache miss
+// Fetcher allows to fetch tags on-demand in case of cache miss.
+// Fetch returns the low, orchestrator and high cardinality tags of an entity.
 type Fetcher interface {
-	Fetch(string) ([]string, []string, error)
+	Fetch(string) ([]string, []string, []string, error)
 }
 
 // Streamer feeds back TagInfo when detecting changes
@@ -0,0 +1,28 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package collectors
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStringToTagCardinality(t *testing.T) {
+	for in, out := range map[string]TagCardinality{
+		"low":          LowCardinality,
+		"Orchestrator": OrchestratorCardinality,
+		"HIGH":         HighCardinality,
+	} {
+		cardinality, err := StringToTagCardinality(in)
+		assert.NoError(t, err)
+		assert.Equal(t, out, cardinality)
+	}
+
+	_, err := StringToTagCardinality("medium")
+	assert.Error(t, err)
+	assert.Equal(t, "orchestrator", OrchestratorCardinality.String())
+}
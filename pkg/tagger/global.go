@@ -8,20 +8,46 @@ package tagger
 import (
 	log "github.com/cihub/seelog"
 
+	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
 )
 
 // defaultTagger is the shared tagger instance backing the global Tag and Init functions
 var defaultTagger *Tagger
 
+// ChecksCardinality is the cardinality of the tags the checks add to the
+// metrics of the containers, per checks_tag_cardinality
+var ChecksCardinality = collectors.HighCardinality
+
 // Init must be called once config is available, call it in your cmd
 func Init() error {
+	cardinality, err := collectors.StringToTagCardinality(config.Datadog.GetString("checks_tag_cardinality"))
+	if err != nil {
+		log.Warnf("invalid checks_tag_cardinality, using high: %s", err)
+		cardinality = collectors.HighCardinality
+	}
+	ChecksCardinality = cardinality
 	return defaultTagger.Init(collectors.DefaultCatalog)
 }
 
 // Tag queries the defaulttagger to get entity tags from cache or sources
-func Tag(entity string, highCard bool) ([]string, error) {
-	return defaultTagger.Tag(entity, highCard)
+func Tag(entity string, cardinality collectors.TagCardinality) ([]string, error) {
+	return defaultTagger.Tag(entity, cardinality)
+}
+
+// List returns the content of the defaulttagger store
+func List() []*collectors.TagInfo {
+	return defaultTagger.List()
+}
+
+// Subscribe returns a channel receiving the updates of the defaulttagger store
+func Subscribe() chan []*collectors.TagInfo {
+	return defaultTagger.Subscribe()
+}
+
+// Unsubscribe stops sending the updates of the defaulttagger store to ch
+func Unsubscribe(ch chan []*collectors.TagInfo) {
+	defaultTagger.Unsubscribe(ch)
 }
 
 // Stop queues a stop signal to the defaulttagger
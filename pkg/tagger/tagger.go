@@ -32,8 +32,15 @@ type Tagger struct {
 	pruneTicker *time.Ticker
 	retryTicker *time.Ticker
 	stop        chan bool
+
+	subscribersMutex sync.Mutex
+	subscribers      map[chan []*collectors.TagInfo]struct{}
 }
 
+// subscriberBufferSize is the number of updates a subscriber can lag behind
+// before being unsubscribed
+const subscriberBufferSize = 100
+
 type collectorReply struct {
 	name     string
 	mode     collectors.CollectionMode
@@ -60,6 +67,7 @@ func newTagger() (*Tagger, error) {
 		pruneTicker: time.NewTicker(5 * time.Minute),
 		retryTicker: time.NewTicker(30 * time.Second),
 		stop:        make(chan bool),
+		subscribers: make(map[chan []*collectors.TagInfo]struct{}),
 	}
 
 	return t, nil
@@ -103,9 +111,7 @@ func (t *Tagger) run() error {
 			t.retryTicker.Stop()
 			return nil
 		case msg := <-t.infoIn:
-			for _, info := range msg {
-				t.tagStore.processTagInfo(info)
-			}
+			t.processTagInfo(msg)
 		case <-t.retryTicker.C:
 			go t.startCollectors()
 		case <-t.pullTicker.C:
@@ -214,13 +220,13 @@ func (t *Tagger) Stop() error {
 	return nil
 }
 
-// Tag returns tags for a given entity. If highCard is false, high
-// cardinality tags are left out.
-func (t *Tagger) Tag(entity string, highCard bool) ([]string, error) {
+// Tag returns tags for a given entity, up to the given cardinality level:
+// the orchestrator and high cardinality tags are left out at low cardinality.
+func (t *Tagger) Tag(entity string, cardinality collectors.TagCardinality) ([]string, error) {
 	if entity == "" {
 		return nil, errors.New("empty entity ID")
 	}
-	cachedTags, sources := t.tagStore.lookup(entity, highCard)
+	cachedTags, sources := t.tagStore.lookup(entity, cardinality)
 
 	if len(sources) == len(t.fetchers) {
 		// All sources sent data to cache
@@ -239,7 +245,7 @@ ITER_COLLECTORS:
 			}
 		}
 		log.Debugf("cache miss for %s, collecting tags for %s", name, entity)
-		low, high, err := collector.Fetch(entity)
+		low, orchestrator, high, err := collector.Fetch(entity)
 		switch {
 		case err == collectors.ErrNotFound:
 			log.Debugf("entity %s not found in %s, skipping", entity, name)
@@ -248,18 +254,71 @@ ITER_COLLECTORS:
 			continue // don't store empty tags, retry next time
 		}
 		tagArrays = append(tagArrays, low)
-		if highCard {
+		if cardinality >= collectors.OrchestratorCardinality {
+			tagArrays = append(tagArrays, orchestrator)
+		}
+		if cardinality == collectors.HighCardinality {
 			tagArrays = append(tagArrays, high)
 		}
 		// Submit to cache for next lookup
-		t.tagStore.processTagInfo(&collectors.TagInfo{
-			Entity:       entity,
-			Source:       name,
-			LowCardTags:  low,
-			HighCardTags: high,
-		})
+		t.processTagInfo([]*collectors.TagInfo{{
+			Entity:               entity,
+			Source:               name,
+			LowCardTags:          low,
+			OrchestratorCardTags: orchestrator,
+			HighCardTags:         high,
+		}})
 	}
 	t.RUnlock()
 
 	return utils.ConcatenateTags(tagArrays), nil
 }
+
+// List returns the content of the store as a TagInfo per entity and source
+func (t *Tagger) List() []*collectors.TagInfo {
+	return t.tagStore.list()
+}
+
+// Subscribe returns a channel receiving the updates of the store. A
+// subscriber lagging behind is unsubscribed and its channel closed, it
+// should then subscribe again and List the store to resync.
+func (t *Tagger) Subscribe() chan []*collectors.TagInfo {
+	ch := make(chan []*collectors.TagInfo, subscriberBufferSize)
+	t.subscribersMutex.Lock()
+	t.subscribers[ch] = struct{}{}
+	t.subscribersMutex.Unlock()
+	return ch
+}
+
+// Unsubscribe stops sending updates to a channel returned by Subscribe,
+// and closes it
+func (t *Tagger) Unsubscribe(ch chan []*collectors.TagInfo) {
+	t.subscribersMutex.Lock()
+	if _, found := t.subscribers[ch]; found {
+		delete(t.subscribers, ch)
+		close(ch)
+	}
+	t.subscribersMutex.Unlock()
+}
+
+// processTagInfo stores the updates and sends them to the subscribers
+func (t *Tagger) processTagInfo(infos []*collectors.TagInfo) {
+	for _, info := range infos {
+		err := t.tagStore.processTagInfo(info)
+		if err != nil {
+			log.Debugf("skipping tag update: %s", err)
+		}
+	}
+
+	t.subscribersMutex.Lock()
+	for ch := range t.subscribers {
+		select {
+		case ch <- infos:
+		default:
+			log.Warnf("tagger subscriber lagging behind, unsubscribing it")
+			delete(t.subscribers, ch)
+			close(ch)
+		}
+	}
+	t.subscribersMutex.Unlock()
+}
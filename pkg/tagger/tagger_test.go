@@ -25,9 +25,9 @@ func (c *DummyCollector) Detect(out chan<- []*collectors.TagInfo) (collectors.Co
 	args := c.Called(out)
 	return args.Get(0).(collectors.CollectionMode), args.Error(1)
 }
-func (c *DummyCollector) Fetch(entity string) ([]string, []string, error) {
+func (c *DummyCollector) Fetch(entity string) ([]string, []string, []string, error) {
 	args := c.Called(entity)
-	return args.Get(0).([]string), args.Get(1).([]string), args.Error(2)
+	return args.Get(0).([]string), args.Get(1).([]string), args.Get(2).([]string), args.Error(3)
 }
 
 func (c *DummyCollector) Stream() error {
@@ -107,13 +107,13 @@ func TestFetchAllMiss(t *testing.T) {
 
 	streamer := tagger.streamers["stream"].(*DummyCollector)
 	assert.NotNil(t, streamer)
-	streamer.On("Fetch", "entity_name").Return([]string{"low1"}, []string{}, nil)
+	streamer.On("Fetch", "entity_name").Return([]string{"low1"}, []string{}, []string{}, nil)
 
 	puller := tagger.pullers["pull"].(*DummyCollector)
 	assert.NotNil(t, puller)
-	puller.On("Fetch", "entity_name").Return([]string{"low2"}, []string{}, nil)
+	puller.On("Fetch", "entity_name").Return([]string{"low2"}, []string{}, []string{}, nil)
 
-	tags, err := tagger.Tag("entity_name", false)
+	tags, err := tagger.Tag("entity_name", collectors.LowCardinality)
 	assert.Nil(t, err)
 	sort.Strings(tags)
 	assert.Equal(t, []string{"low1", "low2"}, tags)
@@ -141,13 +141,13 @@ func TestFetchAllCached(t *testing.T) {
 
 	streamer := tagger.streamers["stream"].(*DummyCollector)
 	assert.NotNil(t, streamer)
-	streamer.On("Fetch", "entity_name").Return([]string{"low1"}, []string{}, nil)
+	streamer.On("Fetch", "entity_name").Return([]string{"low1"}, []string{}, []string{}, nil)
 
 	puller := tagger.pullers["pull"].(*DummyCollector)
 	assert.NotNil(t, puller)
-	puller.On("Fetch", "entity_name").Return([]string{"low2"}, []string{}, nil)
+	puller.On("Fetch", "entity_name").Return([]string{"low2"}, []string{}, []string{}, nil)
 
-	tags, err := tagger.Tag("entity_name", true)
+	tags, err := tagger.Tag("entity_name", collectors.HighCardinality)
 	assert.Nil(t, err)
 	sort.Strings(tags)
 	assert.Equal(t, []string{"high", "low1", "low2"}, tags)
@@ -173,17 +173,17 @@ func TestFetchOneCached(t *testing.T) {
 
 	streamer := tagger.streamers["stream"].(*DummyCollector)
 	assert.NotNil(t, streamer)
-	streamer.On("Fetch", "entity_name").Return([]string{"low1"}, []string{}, nil)
+	streamer.On("Fetch", "entity_name").Return([]string{"low1"}, []string{}, []string{}, nil)
 
 	puller := tagger.pullers["pull"].(*DummyCollector)
 	assert.NotNil(t, puller)
-	puller.On("Fetch", "entity_name").Return([]string{"low2"}, []string{}, nil)
+	puller.On("Fetch", "entity_name").Return([]string{"low2"}, []string{}, []string{}, nil)
 
 	fetcher := tagger.fetchers["fetcher"].(*DummyCollector)
 	assert.NotNil(t, fetcher)
-	fetcher.On("Fetch", "entity_name").Return([]string{"low3"}, []string{}, nil)
+	fetcher.On("Fetch", "entity_name").Return([]string{"low3"}, []string{}, []string{}, nil)
 
-	tags, err := tagger.Tag("entity_name", true)
+	tags, err := tagger.Tag("entity_name", collectors.HighCardinality)
 	assert.Nil(t, err)
 	sort.Strings(tags)
 	assert.Equal(t, []string{"low1", "low2", "low3"}, tags)
@@ -206,7 +206,7 @@ func TestEmptyEntity(t *testing.T) {
 		LowCardTags: []string{"low1"},
 	})
 
-	tags, err := tagger.Tag("", true)
+	tags, err := tagger.Tag("", collectors.HighCardinality)
 	assert.Nil(t, tags)
 	assert.NotNil(t, err)
 	assert.Contains(t, err.Error(), "empty entity ID")
@@ -264,20 +264,57 @@ func TestErrNotFound(t *testing.T) {
 	tagger.Init(catalog)
 
 	// Result should not be cached
-	c.On("Fetch", mock.Anything).Return([]string{}, []string{}, badErr).Once()
-	_, err := tagger.Tag("invalid", true)
+	c.On("Fetch", mock.Anything).Return([]string{}, []string{}, []string{}, badErr).Once()
+	_, err := tagger.Tag("invalid", collectors.HighCardinality)
 	assert.Nil(t, err)
 	c.AssertNumberOfCalls(t, "Fetch", 1)
 
 	// Nil result should be cached now
-	c.On("Fetch", mock.Anything).Return([]string{}, []string{}, collectors.ErrNotFound).Once()
-	_, err = tagger.Tag("invalid", true)
+	c.On("Fetch", mock.Anything).Return([]string{}, []string{}, []string{}, collectors.ErrNotFound).Once()
+	_, err = tagger.Tag("invalid", collectors.HighCardinality)
 	assert.Nil(t, err)
 	c.AssertNumberOfCalls(t, "Fetch", 2)
 
 	// Fetch will not be called again
-	c.On("Fetch", mock.Anything).Return([]string{}, []string{}, collectors.ErrNotFound).Once()
-	_, err = tagger.Tag("invalid", true)
+	c.On("Fetch", mock.Anything).Return([]string{}, []string{}, []string{}, collectors.ErrNotFound).Once()
+	_, err = tagger.Tag("invalid", collectors.HighCardinality)
 	assert.Nil(t, err)
 	c.AssertNumberOfCalls(t, "Fetch", 2)
 }
+
+func TestSubscribe(t *testing.T) {
+	tagger, _ := newTagger()
+	ch := tagger.Subscribe()
+
+	update := []*collectors.TagInfo{{
+		Source:      "source",
+		Entity:      "entity",
+		LowCardTags: []string{"low"},
+	}}
+	tagger.processTagInfo(update)
+	assert.Equal(t, update, <-ch)
+	assert.Equal(t, update, tagger.List())
+
+	tagger.Unsubscribe(ch)
+	_, open := <-ch
+	assert.False(t, open)
+	// Unsubscribing twice is a no-op
+	tagger.Unsubscribe(ch)
+	tagger.processTagInfo(update)
+}
+
+func TestSubscribeLagging(t *testing.T) {
+	tagger, _ := newTagger()
+	ch := tagger.Subscribe()
+
+	update := []*collectors.TagInfo{{Source: "source", Entity: "entity"}}
+	for i := 0; i <= subscriberBufferSize; i++ {
+		tagger.processTagInfo(update)
+	}
+	for i := 0; i < subscriberBufferSize; i++ {
+		<-ch
+	}
+	_, open := <-ch
+	assert.False(t, open)
+	assert.Len(t, tagger.subscribers, 0)
+}
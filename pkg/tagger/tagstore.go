@@ -2,6 +2,7 @@ package tagger
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 
 	log "github.com/cihub/seelog"
@@ -13,12 +14,14 @@ import (
 // entityTags holds the tag information for a given entity
 type entityTags struct {
 	sync.RWMutex
-	lowCardTags  map[string][]string
-	highCardTags map[string][]string
-	cacheValid   bool
-	cachedSource []string
-	cachedAll    []string // Low + high
-	cachedLow    []string // Sub-slice of cachedAll
+	lowCardTags          map[string][]string
+	orchestratorCardTags map[string][]string
+	highCardTags         map[string][]string
+	cacheValid           bool
+	cachedSource         []string
+	cachedAll            []string // Low + orchestrator + high
+	cachedOrchestrator   []string // Low + orchestrator, sub-slice of cachedAll
+	cachedLow            []string // Sub-slice of cachedAll
 }
 
 // tagStore stores entity tags in memory and handles search and collation.
@@ -58,13 +61,15 @@ func (s *tagStore) processTagInfo(info *collectors.TagInfo) error {
 	s.storeMutex.RUnlock()
 	if exist == false {
 		storedTags = &entityTags{
-			lowCardTags:  make(map[string][]string),
-			highCardTags: make(map[string][]string),
+			lowCardTags:          make(map[string][]string),
+			orchestratorCardTags: make(map[string][]string),
+			highCardTags:         make(map[string][]string),
 		}
 	}
 
 	storedTags.Lock()
 	storedTags.lowCardTags[info.Source] = info.LowCardTags
+	storedTags.orchestratorCardTags[info.Source] = info.OrchestratorCardTags
 	storedTags.highCardTags[info.Source] = info.HighCardTags
 	storedTags.cacheValid = false
 	storedTags.Unlock()
@@ -105,7 +110,7 @@ func (s *tagStore) prune() error {
 // lookup gets tags from the store and returns them concatenated in a []string
 // array. It returns the source names in the second []string to allow the
 // client to trigger manual lookups on missing sources.
-func (s *tagStore) lookup(entity string, highCard bool) ([]string, []string) {
+func (s *tagStore) lookup(entity string, cardinality collectors.TagCardinality) ([]string, []string) {
 	s.storeMutex.RLock()
 	storedTags, present := s.store[entity]
 	s.storeMutex.RUnlock()
@@ -113,31 +118,62 @@ func (s *tagStore) lookup(entity string, highCard bool) ([]string, []string) {
 	if present == false {
 		return nil, nil
 	}
-	return storedTags.get(highCard)
+	return storedTags.get(cardinality)
 }
 
-func (e *entityTags) get(highCard bool) ([]string, []string) {
+// list returns the content of the store as a TagInfo per entity and source,
+// sorted by entity then source
+func (s *tagStore) list() []*collectors.TagInfo {
+	var infos []*collectors.TagInfo
+	s.storeMutex.RLock()
+	for entity, storedTags := range s.store {
+		storedTags.RLock()
+		for source, low := range storedTags.lowCardTags {
+			infos = append(infos, &collectors.TagInfo{
+				Source:               source,
+				Entity:               entity,
+				LowCardTags:          low,
+				OrchestratorCardTags: storedTags.orchestratorCardTags[source],
+				HighCardTags:         storedTags.highCardTags[source],
+			})
+		}
+		storedTags.RUnlock()
+	}
+	s.storeMutex.RUnlock()
+
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Entity != infos[j].Entity {
+			return infos[i].Entity < infos[j].Entity
+		}
+		return infos[i].Source < infos[j].Source
+	})
+	return infos
+}
+
+func (e *entityTags) get(cardinality collectors.TagCardinality) ([]string, []string) {
 	e.RLock()
 
 	// Cache hit
 	if e.cacheValid {
 		defer e.RUnlock()
-		if highCard {
-			return e.cachedAll, e.cachedSource
-		}
-		return e.cachedLow, e.cachedSource
+		return e.cachedTags(cardinality), e.cachedSource
 	}
 
 	// Cache miss
 	var arrays [][]string
 	var sources []string
 	lowCardCount := 0
+	orchestratorCardCount := 0
 
 	for source, tags := range e.lowCardTags {
 		arrays = append(arrays, tags)
 		lowCardCount += len(tags)
 		sources = append(sources, source)
 	}
+	for _, tags := range e.orchestratorCardTags {
+		arrays = append(arrays, tags)
+		orchestratorCardCount += len(tags)
+	}
 	for _, tags := range e.highCardTags {
 		arrays = append(arrays, tags)
 	}
@@ -149,11 +185,23 @@ func (e *entityTags) get(highCard bool) ([]string, []string) {
 	e.cacheValid = true
 	e.cachedSource = sources
 	e.cachedAll = tags
+	e.cachedOrchestrator = e.cachedAll[:lowCardCount+orchestratorCardCount]
 	e.cachedLow = e.cachedAll[:lowCardCount]
+	cached := e.cachedTags(cardinality)
 	e.Unlock()
 
-	if highCard {
-		return tags, sources
+	return cached, sources
+}
+
+// cachedTags returns the cached tags of a cardinality level, the caller
+// must hold the lock
+func (e *entityTags) cachedTags(cardinality collectors.TagCardinality) []string {
+	switch cardinality {
+	case collectors.HighCardinality:
+		return e.cachedAll
+	case collectors.OrchestratorCardinality:
+		return e.cachedOrchestrator
+	default:
+		return e.cachedLow
 	}
-	return tags[:lowCardCount], sources
 }
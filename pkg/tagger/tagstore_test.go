@@ -59,23 +59,33 @@ func (s *StoreTestSuite) TestLookup() {
 		LowCardTags: []string{"tag"},
 	})
 
-	tagsHigh, sourcesHigh := s.store.lookup("test", true)
-	tagsLow, sourcesLow := s.store.lookup("test", false)
+	s.store.processTagInfo(&collectors.TagInfo{
+		Source:               "source3",
+		Entity:               "test",
+		OrchestratorCardTags: []string{"tag"},
+	})
 
-	assert.Len(s.T(), tagsHigh, 3)
+	tagsHigh, sourcesHigh := s.store.lookup("test", collectors.HighCardinality)
+	tagsOrchestrator, sourcesOrchestrator := s.store.lookup("test", collectors.OrchestratorCardinality)
+	tagsLow, sourcesLow := s.store.lookup("test", collectors.LowCardinality)
+
+	assert.Len(s.T(), tagsHigh, 4)
+	assert.Len(s.T(), tagsOrchestrator, 3)
 	assert.Len(s.T(), tagsLow, 2)
 
-	assert.Len(s.T(), sourcesHigh, 2)
+	assert.Len(s.T(), sourcesHigh, 3)
 	assert.Contains(s.T(), sourcesHigh, "source1")
 	assert.Contains(s.T(), sourcesHigh, "source2")
+	assert.Contains(s.T(), sourcesHigh, "source3")
 
-	assert.Len(s.T(), sourcesLow, 2)
+	assert.Len(s.T(), sourcesOrchestrator, 3)
+	assert.Len(s.T(), sourcesLow, 3)
 	assert.Contains(s.T(), sourcesLow, "source1")
 	assert.Contains(s.T(), sourcesHigh, "source2")
 }
 
 func (s *StoreTestSuite) TestLookupNotPresent() {
-	tags, sources := s.store.lookup("test", false)
+	tags, sources := s.store.lookup("test", collectors.LowCardinality)
 	assert.Nil(s.T(), tags)
 	assert.Nil(s.T(), sources)
 }
@@ -116,10 +126,10 @@ func (s *StoreTestSuite) TestPrune() {
 	s.store.toDeleteMutex.RUnlock()
 
 	// Data should still be in the store
-	tagsHigh, sourcesHigh := s.store.lookup("test1", true)
+	tagsHigh, sourcesHigh := s.store.lookup("test1", collectors.HighCardinality)
 	assert.Len(s.T(), tagsHigh, 3)
 	assert.Len(s.T(), sourcesHigh, 2)
-	tagsHigh, sourcesHigh = s.store.lookup("test2", true)
+	tagsHigh, sourcesHigh = s.store.lookup("test2", collectors.HighCardinality)
 	assert.Len(s.T(), tagsHigh, 2)
 	assert.Len(s.T(), sourcesHigh, 1)
 
@@ -131,10 +141,10 @@ func (s *StoreTestSuite) TestPrune() {
 	s.store.toDeleteMutex.RUnlock()
 
 	// test1 should be removed, test2 still present
-	tagsHigh, sourcesHigh = s.store.lookup("test1", true)
+	tagsHigh, sourcesHigh = s.store.lookup("test1", collectors.HighCardinality)
 	assert.Nil(s.T(), tagsHigh)
 	assert.Nil(s.T(), sourcesHigh)
-	tagsHigh, sourcesHigh = s.store.lookup("test2", true)
+	tagsHigh, sourcesHigh = s.store.lookup("test2", collectors.HighCardinality)
 	assert.Len(s.T(), tagsHigh, 2)
 	assert.Len(s.T(), sourcesHigh, 1)
 
@@ -142,49 +152,80 @@ func (s *StoreTestSuite) TestPrune() {
 	assert.Nil(s.T(), err)
 
 	// No impact if nothing is queued
-	tagsHigh, sourcesHigh = s.store.lookup("test1", true)
+	tagsHigh, sourcesHigh = s.store.lookup("test1", collectors.HighCardinality)
 	assert.Nil(s.T(), tagsHigh)
 	assert.Nil(s.T(), sourcesHigh)
-	tagsHigh, sourcesHigh = s.store.lookup("test2", true)
+	tagsHigh, sourcesHigh = s.store.lookup("test2", collectors.HighCardinality)
 	assert.Len(s.T(), tagsHigh, 2)
 	assert.Len(s.T(), sourcesHigh, 1)
 
 }
 
+func (s *StoreTestSuite) TestList() {
+	s.store.processTagInfo(&collectors.TagInfo{
+		Source:       "source2",
+		Entity:       "test1",
+		LowCardTags:  []string{"low"},
+		HighCardTags: []string{"high"},
+	})
+	s.store.processTagInfo(&collectors.TagInfo{
+		Source:               "source1",
+		Entity:               "test1",
+		OrchestratorCardTags: []string{"orchestrator"},
+	})
+	s.store.processTagInfo(&collectors.TagInfo{
+		Source:      "source1",
+		Entity:      "test0",
+		LowCardTags: []string{"low"},
+	})
+
+	assert.Equal(s.T(), []*collectors.TagInfo{
+		{Source: "source1", Entity: "test0", LowCardTags: []string{"low"}},
+		{Source: "source1", Entity: "test1", OrchestratorCardTags: []string{"orchestrator"}},
+		{Source: "source2", Entity: "test1", LowCardTags: []string{"low"}, HighCardTags: []string{"high"}},
+	}, s.store.list())
+}
+
 func TestStoreSuite(t *testing.T) {
 	suite.Run(t, &StoreTestSuite{})
 }
 
 func TestGetEntityTags(t *testing.T) {
 	etags := entityTags{
-		lowCardTags:  make(map[string][]string),
-		highCardTags: make(map[string][]string),
-		cacheValid:   false,
+		lowCardTags:          make(map[string][]string),
+		orchestratorCardTags: make(map[string][]string),
+		highCardTags:         make(map[string][]string),
+		cacheValid:           false,
 	}
 	assert.False(t, etags.cacheValid)
 
 	// Get empty tags and make sure cache is now set to valid
-	tags, sources := etags.get(true)
+	tags, sources := etags.get(collectors.HighCardinality)
 	assert.Len(t, tags, 0)
 	assert.Len(t, sources, 0)
 	assert.True(t, etags.cacheValid)
 
 	// Add tags but don't invalidate the cache, we should return empty arrays
 	etags.lowCardTags["source"] = []string{"low1", "low2"}
+	etags.orchestratorCardTags["source"] = []string{"orchestrator1"}
 	etags.highCardTags["source"] = []string{"high1", "high2"}
-	tags, sources = etags.get(true)
+	tags, sources = etags.get(collectors.HighCardinality)
 	assert.Len(t, tags, 0)
 	assert.Len(t, sources, 0)
 	assert.True(t, etags.cacheValid)
 
 	// Invalidate the cache, we should now get the tags
 	etags.cacheValid = false
-	tags, sources = etags.get(true)
-	assert.Len(t, tags, 4)
-	assert.Contains(t, tags, "low1", "low2", "high1", "high2")
+	tags, sources = etags.get(collectors.HighCardinality)
+	assert.Len(t, tags, 5)
+	assert.Contains(t, tags, "low1", "low2", "orchestrator1", "high1", "high2")
 	assert.Len(t, sources, 1)
 	assert.True(t, etags.cacheValid)
-	tags, sources = etags.get(false)
+	tags, sources = etags.get(collectors.OrchestratorCardinality)
+	assert.Len(t, tags, 3)
+	assert.Contains(t, tags, "low1", "low2", "orchestrator1")
+	assert.Len(t, sources, 1)
+	tags, sources = etags.get(collectors.LowCardinality)
 	assert.Len(t, tags, 2)
 	assert.Contains(t, tags, "low1", "low2")
 	assert.Len(t, sources, 1)
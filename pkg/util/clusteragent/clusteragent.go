@@ -19,7 +19,10 @@ import (
 	log "github.com/cihub/seelog"
 
 	"github.com/DataDog/datadog-agent/pkg/api/util"
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks"
 	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/cache"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver"
 	"github.com/DataDog/datadog-agent/pkg/util/retry"
 )
 
@@ -31,6 +34,13 @@ const (
 	authorizationHeaderKey        = "Authorization"
 	clusterAgentAuthTokenMinLen   = 32
 	clusterAgentAuthTokenFilename = "dca_auth_token"
+
+	podMetadataCachePrefix     = "dca_pod_metadata"
+	nodeLabelsCachePrefix      = "dca_node_labels"
+	namespaceLabelsCachePrefix = "dca_namespace_labels"
+	// metadataCacheExpire keeps the metadata long enough to not query the
+	// cluster agent for every container, short enough to follow the changes
+	metadataCacheExpire = 30 * time.Second
 )
 
 var globalClusterAgentClient *DCAClient
@@ -187,37 +197,104 @@ func getClusterAgentEndpoint() (string, error) {
 // GetKubernetesServiceNames queries the datadog cluster agent to get nodeName/podName registered
 // Kubernetes services.
 func (c *DCAClient) GetKubernetesServiceNames(nodeName, podName string) ([]string, error) {
-	const dcaMetadataPath = "api/v1/metadata"
 	var serviceNames serviceNames
+	// https://host:port /api/v1/metadata/ {nodeName}/ {pod-[0-9a-z]+}
+	err := c.get(fmt.Sprintf("api/v1/metadata/%s/%s", nodeName, podName), &serviceNames)
+	return serviceNames, err
+}
+
+// GetPodMetadata queries the datadog cluster agent to get the services and the
+// owners of a pod. The responses are cached for metadataCacheExpire.
+func (c *DCAClient) GetPodMetadata(nodeName, podName string) (*apiserver.PodMetadata, error) {
+	cacheKey := cache.BuildAgentKey(podMetadataCachePrefix, nodeName, podName)
+	if cached, found := cache.Cache.Get(cacheKey); found {
+		return cached.(*apiserver.PodMetadata), nil
+	}
+
+	metadata := &apiserver.PodMetadata{}
+	err := c.get(fmt.Sprintf("api/v1/pods/%s/%s/metadata", nodeName, podName), metadata)
+	if err != nil {
+		return nil, err
+	}
+	cache.Cache.Set(cacheKey, metadata, metadataCacheExpire)
+	return metadata, nil
+}
+
+// GetNodeLabels queries the datadog cluster agent to get the labels of a node.
+// The responses are cached for metadataCacheExpire.
+func (c *DCAClient) GetNodeLabels(nodeName string) (map[string]string, error) {
+	return c.getLabels(nodeLabelsCachePrefix, fmt.Sprintf("api/v1/nodes/%s/labels", nodeName), nodeName)
+}
+
+// GetNamespaceLabels queries the datadog cluster agent to get the labels of a
+// namespace. The responses are cached for metadataCacheExpire.
+func (c *DCAClient) GetNamespaceLabels(namespace string) (map[string]string, error) {
+	return c.getLabels(namespaceLabelsCachePrefix, fmt.Sprintf("api/v1/namespaces/%s/labels", namespace), namespace)
+}
+
+func (c *DCAClient) getLabels(cachePrefix, path, name string) (map[string]string, error) {
+	cacheKey := cache.BuildAgentKey(cachePrefix, name)
+	if cached, found := cache.Cache.Get(cacheKey); found {
+		return cached.(map[string]string), nil
+	}
+
+	var labels map[string]string
+	err := c.get(path, &labels)
+	if err != nil {
+		return nil, err
+	}
+	cache.Cache.Set(cacheKey, labels, metadataCacheExpire)
+	return labels, nil
+}
+
+// PostClusterCheckStatus registers the node agent to the cluster checks
+// dispatcher, or refreshes its heartbeat
+func (c *DCAClient) PostClusterCheckStatus(nodeName string) (clusterchecks.StatusResponse, error) {
+	var response clusterchecks.StatusResponse
+	err := c.do("POST", fmt.Sprintf("api/v1/clusterchecks/status/%s", nodeName), &response)
+	return response, err
+}
+
+// GetClusterCheckConfigs queries the cluster checks dispatched to the node agent
+func (c *DCAClient) GetClusterCheckConfigs(nodeName string) (clusterchecks.ConfigResponse, error) {
+	var response clusterchecks.ConfigResponse
+	err := c.get(fmt.Sprintf("api/v1/clusterchecks/configs/%s", nodeName), &response)
+	return response, err
+}
+
+// get queries a path of the cluster agent API and decodes the JSON response in v
+func (c *DCAClient) get(path string, v interface{}) error {
+	return c.do("GET", path, v)
+}
+
+// do sends a bodyless request to a path of the cluster agent API and
+// decodes the JSON response in v
+func (c *DCAClient) do(method, path string, v interface{}) error {
 	var err error
 
 	req := &http.Request{
+		Method: method,
 		Header: *c.clusterAgentAPIRequestHeaders,
 	}
-	// https://host:port /api/v1/metadata/ {nodeName}/ {pod-[0-9a-z]+}
-	rawURL := fmt.Sprintf("%s/%s/%s/%s", c.clusterAgentAPIEndpoint, dcaMetadataPath, nodeName, podName)
+	rawURL := fmt.Sprintf("%s/%s", c.clusterAgentAPIEndpoint, path)
 	req.URL, err = url.Parse(rawURL)
 	if err != nil {
-		return serviceNames, err
+		return err
 	}
 
 	resp, err := c.clusterAgentAPIClient.Do(req)
 	if err != nil {
-		return serviceNames, err
+		return err
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return serviceNames, fmt.Errorf("unexpected status code from cluster agent: %d", resp.StatusCode)
+		return fmt.Errorf("unexpected status code from cluster agent: %d", resp.StatusCode)
 	}
 
 	b, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return serviceNames, err
-	}
-	err = json.Unmarshal(b, &serviceNames)
-	if err != nil {
-		return serviceNames, err
+		return err
 	}
-
-	return serviceNames, nil
+	return json.Unmarshal(b, v)
 }
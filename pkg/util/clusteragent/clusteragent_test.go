@@ -24,11 +24,18 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
 	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver"
 )
 
 type dummyClusterAgent struct {
-	responses map[string][]string
+	responses   map[string][]string
+	podMetadata map[string]*apiserver.PodMetadata
+	labels      map[string]map[string]string
+	// clusterChecks holds the cluster checks of each node
+	clusterChecks map[string]clusterchecks.ConfigResponse
 	sync.RWMutex
 	token string
 }
@@ -43,6 +50,33 @@ func newDummyClusterAgent() (*dummyClusterAgent, error) {
 			"node2/pod-00005": {"svc3"},
 			"node2/pod-00006": {},
 		},
+		podMetadata: map[string]*apiserver.PodMetadata{
+			"node1/pod-00001": {
+				Services: []string{"svc1"},
+				Owners: []apiserver.OwnerReference{
+					{Kind: "ReplicaSet", Name: "web-5d6b8"},
+					{Kind: "Deployment", Name: "web"},
+				},
+			},
+			"node1/pod-00002": {Services: []string{"svc1", "svc2"}},
+		},
+		labels: map[string]map[string]string{
+			"nodes/node1":      {"kubernetes.io/role": "master"},
+			"namespaces/prod":  {"team": "infra"},
+			"namespaces/empty": {},
+		},
+		clusterChecks: map[string]clusterchecks.ConfigResponse{
+			"node1": {
+				Configs: []check.Config{
+					{
+						Name:         "postgres",
+						Instances:    []check.ConfigData{check.ConfigData("host: rds.example.com")},
+						ClusterCheck: true,
+					},
+				},
+				LastChange: 42,
+			},
+		},
 		token: config.Datadog.GetString("cluster_agent.auth_token"),
 	}
 	return dca, nil
@@ -56,29 +90,50 @@ func (d *dummyClusterAgent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(403)
 		return
 	}
-	// path should be like: /api/v1/metadata/{nodeName}/{pod-[0-9a-z]+}
 	s := strings.Split(r.URL.Path, "/")
-	if len(s) != 6 {
+	if len(s) < 6 {
 		w.WriteHeader(http.StatusInternalServerError)
-		log.Errorf("unexpected len 6 != %d", len(s))
+		log.Errorf("unexpected len 6 > %d", len(s))
 		return
 	}
-	nodeName, podName := s[4], s[5]
-	key := fmt.Sprintf("%s/%s", nodeName, podName)
 
 	d.RLock()
 	defer d.RUnlock()
-	svcs, found := d.responses[key]
-	if found {
-		b, err := json.Marshal(svcs)
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			return
+	var response interface{}
+	var found bool
+	switch s[3] {
+	case "metadata":
+		// path should be like: /api/v1/metadata/{nodeName}/{pod-[0-9a-z]+}
+		response, found = d.responses[fmt.Sprintf("%s/%s", s[4], s[5])]
+	case "pods":
+		// path should be like: /api/v1/pods/{nodeName}/{pod-[0-9a-z]+}/metadata
+		response, found = d.podMetadata[fmt.Sprintf("%s/%s", s[4], s[5])]
+	case "nodes", "namespaces":
+		// path should be like: /api/v1/nodes/{nodeName}/labels
+		response, found = d.labels[fmt.Sprintf("%s/%s", s[3], s[4])]
+	case "clusterchecks":
+		// path should be like: /api/v1/clusterchecks/status/{nodeName}
+		var nodeChecks clusterchecks.ConfigResponse
+		nodeChecks, found = d.clusterChecks[s[5]]
+		switch {
+		case s[4] == "status" && r.Method == "POST":
+			response = clusterchecks.StatusResponse{LastChange: nodeChecks.LastChange}
+		case s[4] == "configs" && r.Method == "GET":
+			response = nodeChecks
+		default:
+			found = false
 		}
-		w.Write(b)
+	}
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
 		return
 	}
-	w.WriteHeader(http.StatusNotFound)
+	b, err := json.Marshal(response)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Write(b)
 }
 
 func (d *dummyClusterAgent) parsePort(ts *httptest.Server) (*httptest.Server, int, error) {
@@ -304,6 +359,81 @@ func (suite *clusterAgentSuite) TestGetKubernetesServiceNames() {
 	}
 }
 
+func (suite *clusterAgentSuite) TestGetClusterMetadata() {
+	dca, err := newDummyClusterAgent()
+	require.Nil(suite.T(), err, fmt.Sprintf("%v", err))
+
+	ts, p, err := dca.StartTLS()
+	defer ts.Close()
+	require.Nil(suite.T(), err, fmt.Sprintf("%v", err))
+
+	config.Datadog.Set("cluster_agent.url", fmt.Sprintf("https://127.0.0.1:%d", p))
+
+	// not using the global client, already initialized with another endpoint
+	ca := &DCAClient{}
+	require.Nil(suite.T(), ca.init())
+
+	metadata, err := ca.GetPodMetadata("node1", "pod-00001")
+	require.Nil(suite.T(), err, fmt.Sprintf("%v", err))
+	assert.Equal(suite.T(), dca.podMetadata["node1/pod-00001"], metadata)
+
+	metadata, err = ca.GetPodMetadata("node1", "pod-00002")
+	require.Nil(suite.T(), err, fmt.Sprintf("%v", err))
+	assert.Equal(suite.T(), []string{"svc1", "svc2"}, metadata.Services)
+	assert.Len(suite.T(), metadata.Owners, 0)
+
+	_, err = ca.GetPodMetadata("node2", "pod-00004")
+	assert.NotNil(suite.T(), err)
+
+	labels, err := ca.GetNodeLabels("node1")
+	require.Nil(suite.T(), err, fmt.Sprintf("%v", err))
+	assert.Equal(suite.T(), map[string]string{"kubernetes.io/role": "master"}, labels)
+
+	labels, err = ca.GetNamespaceLabels("prod")
+	require.Nil(suite.T(), err, fmt.Sprintf("%v", err))
+	assert.Equal(suite.T(), map[string]string{"team": "infra"}, labels)
+
+	labels, err = ca.GetNamespaceLabels("empty")
+	require.Nil(suite.T(), err, fmt.Sprintf("%v", err))
+	assert.Len(suite.T(), labels, 0)
+
+	_, err = ca.GetNamespaceLabels("unknown")
+	assert.NotNil(suite.T(), err)
+
+	// the responses are cached
+	dca.Lock()
+	dca.labels["namespaces/prod"] = map[string]string{"team": "web"}
+	dca.Unlock()
+	labels, err = ca.GetNamespaceLabels("prod")
+	require.Nil(suite.T(), err, fmt.Sprintf("%v", err))
+	assert.Equal(suite.T(), map[string]string{"team": "infra"}, labels)
+}
+
+func (suite *clusterAgentSuite) TestGetClusterChecks() {
+	dca, err := newDummyClusterAgent()
+	require.Nil(suite.T(), err, fmt.Sprintf("%v", err))
+
+	ts, p, err := dca.StartTLS()
+	defer ts.Close()
+	require.Nil(suite.T(), err, fmt.Sprintf("%v", err))
+
+	config.Datadog.Set("cluster_agent.url", fmt.Sprintf("https://127.0.0.1:%d", p))
+
+	ca := &DCAClient{}
+	require.Nil(suite.T(), ca.init())
+
+	status, err := ca.PostClusterCheckStatus("node1")
+	require.Nil(suite.T(), err, fmt.Sprintf("%v", err))
+	assert.Equal(suite.T(), int64(42), status.LastChange)
+
+	configs, err := ca.GetClusterCheckConfigs("node1")
+	require.Nil(suite.T(), err, fmt.Sprintf("%v", err))
+	assert.Equal(suite.T(), dca.clusterChecks["node1"], configs)
+
+	_, err = ca.GetClusterCheckConfigs("node2")
+	assert.NotNil(suite.T(), err)
+}
+
 func TestClusterAgentSuite(t *testing.T) {
 	fakeDir, err := ioutil.TempDir("", "fake-datadog-etc")
 	require.Nil(t, err, fmt.Sprintf("%v", err))
@@ -0,0 +1,157 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package container
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	log "github.com/cihub/seelog"
+)
+
+const eventPollerBufferSize = 50
+
+// Errors subscribers might receive
+var (
+	ErrAlreadySubscribed = errors.New("already subscribed")
+	ErrNotSubscribed     = errors.New("not subscribed")
+)
+
+type pollSubscriber struct {
+	events chan *Event
+	errors chan error
+}
+
+// eventPoller emulates an event stream for the runtimes that don't expose
+// one: it lists the running containers periodically and sends a start or
+// die event for every container that appeared or disappeared.
+type eventPoller struct {
+	sync.Mutex
+	list        func() ([]*Details, error)
+	interval    time.Duration
+	subscribers map[string]*pollSubscriber
+	// running holds the containers seen by the last poll, nil before the first one
+	running map[string]*Details
+	stop    chan struct{}
+}
+
+func newEventPoller(list func() ([]*Details, error), interval time.Duration) *eventPoller {
+	return &eventPoller{
+		list:        list,
+		interval:    interval,
+		subscribers: make(map[string]*pollSubscriber),
+	}
+}
+
+func (p *eventPoller) subscribe(name string) (<-chan *Event, <-chan error, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	if _, found := p.subscribers[name]; found {
+		return nil, nil, ErrAlreadySubscribed
+	}
+	sub := &pollSubscriber{
+		events: make(chan *Event, eventPollerBufferSize),
+		errors: make(chan error, 1),
+	}
+	p.subscribers[name] = sub
+
+	if len(p.subscribers) == 1 {
+		// containers already running don't trigger events
+		p.running = nil
+		if err := p.pollLocked(); err != nil {
+			log.Warnf("can't list containers: %s", err)
+		}
+		p.stop = make(chan struct{})
+		go p.run(p.stop)
+	}
+	return sub.events, sub.errors, nil
+}
+
+func (p *eventPoller) unsubscribe(name string) error {
+	p.Lock()
+	defer p.Unlock()
+
+	sub, found := p.subscribers[name]
+	if !found {
+		return ErrNotSubscribed
+	}
+	delete(p.subscribers, name)
+	close(sub.events)
+	close(sub.errors)
+
+	if len(p.subscribers) == 0 {
+		close(p.stop)
+	}
+	return nil
+}
+
+func (p *eventPoller) run(stop chan struct{}) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := p.poll(); err != nil {
+				// runtime errors are usually transient, keep polling
+				log.Warnf("can't list containers: %s", err)
+			}
+		}
+	}
+}
+
+func (p *eventPoller) poll() error {
+	p.Lock()
+	defer p.Unlock()
+	return p.pollLocked()
+}
+
+func (p *eventPoller) pollLocked() error {
+	containers, err := p.list()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	running := make(map[string]*Details, len(containers))
+	var events []*Event
+	for _, c := range containers {
+		running[c.ID] = c
+		if _, found := p.running[c.ID]; !found && p.running != nil {
+			events = append(events, newPolledEvent(c, EventStart, now))
+		}
+	}
+	for id, c := range p.running {
+		if _, found := running[id]; !found {
+			events = append(events, newPolledEvent(c, EventDie, now))
+		}
+	}
+	p.running = running
+
+	for _, ev := range events {
+		for name, sub := range p.subscribers {
+			select {
+			case sub.events <- ev:
+			default:
+				log.Warnf("event buffer of %s is full, dropping %s event for container %s", name, ev.Action, ev.ContainerID)
+			}
+		}
+	}
+	return nil
+}
+
+func newPolledEvent(c *Details, action string, timestamp time.Time) *Event {
+	return &Event{
+		ContainerID:   c.ID,
+		ContainerName: c.Name,
+		ImageName:     c.Image,
+		Action:        action,
+		Timestamp:     timestamp,
+	}
+}
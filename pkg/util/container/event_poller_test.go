@@ -0,0 +1,68 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package container
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeLister struct {
+	containers []*Details
+	err        error
+}
+
+func (f *fakeLister) list() ([]*Details, error) {
+	return f.containers, f.err
+}
+
+func TestEventPoller(t *testing.T) {
+	lister := &fakeLister{
+		containers: []*Details{{ID: "already-running", Name: "redis", Image: "redis:latest"}},
+	}
+	// polls are triggered manually
+	poller := newEventPoller(lister.list, time.Hour)
+
+	events, errs, err := poller.subscribe("test")
+	require.NoError(t, err)
+	require.NotNil(t, errs)
+	_, _, err = poller.subscribe("test")
+	assert.Equal(t, ErrAlreadySubscribed, err)
+
+	// containers running at subscription don't trigger events
+	require.NoError(t, poller.poll())
+	assert.Len(t, events, 0)
+
+	lister.containers = []*Details{{ID: "new", Name: "nginx", Image: "nginx:latest"}}
+	require.NoError(t, poller.poll())
+	require.Len(t, events, 2)
+	received := map[string]*Event{}
+	for i := 0; i < 2; i++ {
+		ev := <-events
+		received[ev.ContainerID] = ev
+	}
+	assert.Equal(t, EventStart, received["new"].Action)
+	assert.Equal(t, "nginx", received["new"].ContainerName)
+	assert.Equal(t, "nginx:latest", received["new"].ImageName)
+	assert.Equal(t, EventDie, received["already-running"].Action)
+	assert.Equal(t, "redis", received["already-running"].ContainerName)
+
+	// errors are returned, the state is kept
+	lister.err = errors.New("runtime unavailable")
+	assert.Error(t, poller.poll())
+	lister.err = nil
+	require.NoError(t, poller.poll())
+	assert.Len(t, events, 0)
+
+	require.NoError(t, poller.unsubscribe("test"))
+	_, open := <-events
+	assert.False(t, open)
+	assert.Equal(t, ErrNotSubscribed, poller.unsubscribe("test"))
+}
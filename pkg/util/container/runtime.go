@@ -0,0 +1,164 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package container
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Names of the supported container runtimes
+const (
+	DockerRuntimeName = "docker"
+	CRIRuntimeName    = "cri"
+)
+
+// Container event actions
+const (
+	EventStart = "start"
+	EventDie   = "die"
+)
+
+// ErrRuntimeNotCompiled is returned when the runtime support was not built in the agent
+var ErrRuntimeNotCompiled = errors.New("container runtime not compiled in this build")
+
+// Runtime is the interface container runtimes implement, so that discovery,
+// tagging and metrics don't depend on the API of a given runtime.
+type Runtime interface {
+	// Name returns the runtime name
+	Name() string
+	// ContainerIDToEntityName returns the tagger entity name of a container, eg. docker://<id>
+	ContainerIDToEntityName(id string) string
+	// List returns the running containers, and the exited ones if includeExited is true.
+	// Fields requiring an inspection (Pid, Env, Hostname) might not be set.
+	List(includeExited bool) ([]*Details, error)
+	// Inspect returns all the known details of a container
+	Inspect(id string) (*Details, error)
+	// SubscribeToEvents streams the start and die events of containers
+	SubscribeToEvents(name string) (<-chan *Event, <-chan error, error)
+	// UnsubscribeFromEvents stops a stream opened with SubscribeToEvents
+	UnsubscribeFromEvents(name string) error
+	// Stats returns the resource usage of running containers, indexed by
+	// container ID. Containers the runtime can't find are not in the result.
+	Stats(ids []string) (map[string]*Stats, error)
+}
+
+// Details holds the runtime-neutral description of a container
+type Details struct {
+	ID        string
+	EntityID  string
+	Name      string
+	Image     string
+	ImageID   string
+	State     string
+	Created   int64
+	StartedAt int64
+	Pid       int
+	Hostname  string
+	Labels    map[string]string
+	// Env holds the environment variables in the KEY=value format
+	Env []string
+	// Networks maps the network names to the container's IP address
+	Networks map[string]string
+	// Ports are the container ports, nil if unknown
+	Ports []int
+}
+
+// Event is a container lifecycle event, Action is EventStart or EventDie
+type Event struct {
+	ContainerID   string
+	ContainerName string
+	ImageName     string
+	Action        string
+	Timestamp     time.Time
+}
+
+// CPUStats holds cumulative CPU times, in nanoseconds
+type CPUStats struct {
+	Total       uint64
+	User        uint64
+	System      uint64
+	NrThrottled uint64
+}
+
+// MemoryStats holds memory usage and limit, in bytes. A zero limit means no limit.
+type MemoryStats struct {
+	Usage      uint64
+	WorkingSet uint64
+	RSS        uint64
+	Cache      uint64
+	Limit      uint64
+}
+
+// IOStats holds cumulative disk I/O, in bytes
+type IOStats struct {
+	ReadBytes  uint64
+	WriteBytes uint64
+}
+
+// Stats holds the resource usage of a container. Runtimes leave the
+// sections they don't report nil.
+type Stats struct {
+	ContainerID string
+	Timestamp   time.Time
+	// CPULimit is the CPU limit in percent of a core, 100 if unlimited and 0 if unknown
+	CPULimit float64
+	CPU      *CPUStats
+	Memory   *MemoryStats
+	IO       *IOStats
+}
+
+type runtimeFactory func() (Runtime, error)
+
+var (
+	runtimeCatalog   = make(map[string]runtimeFactory)
+	runtimeCatalogMu sync.RWMutex
+)
+
+// registerRuntime adds a runtime to the catalog, it is called by the
+// runtime implementations on init
+func registerRuntime(name string, factory runtimeFactory) {
+	runtimeCatalogMu.Lock()
+	defer runtimeCatalogMu.Unlock()
+	runtimeCatalog[name] = factory
+}
+
+// GetRuntime returns a connected runtime by name
+func GetRuntime(name string) (Runtime, error) {
+	runtimeCatalogMu.RLock()
+	factory, found := runtimeCatalog[name]
+	runtimeCatalogMu.RUnlock()
+	if !found {
+		return nil, ErrRuntimeNotCompiled
+	}
+	rt, err := factory()
+	if err != nil {
+		return nil, fmt.Errorf("can't connect to the %s runtime: %s", name, err)
+	}
+	return rt, nil
+}
+
+// GetRuntimes returns the runtimes that are available on the host
+func GetRuntimes() []Runtime {
+	runtimeCatalogMu.RLock()
+	names := make([]string, 0, len(runtimeCatalog))
+	for name := range runtimeCatalog {
+		names = append(names, name)
+	}
+	runtimeCatalogMu.RUnlock()
+	sort.Strings(names)
+
+	var runtimes []Runtime
+	for _, name := range names {
+		if rt, err := GetRuntime(name); err == nil {
+			runtimes = append(runtimes, rt)
+		}
+	}
+	return runtimes
+}
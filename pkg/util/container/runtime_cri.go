@@ -0,0 +1,191 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build cri
+
+package container
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/cri"
+	"github.com/DataDog/datadog-agent/pkg/util/retry"
+)
+
+// The CRI doesn't stream events, the container list is polled instead
+const criEventsPollInterval = 5 * time.Second
+
+// criRuntime implements the Runtime interface on top of a CRI runtime
+// service, for hosts running containerd or cri-o without docker
+type criRuntime struct {
+	initRetry retry.Retrier
+	util      *cri.CRIUtil
+	poller    *eventPoller
+}
+
+var (
+	globalCRIRuntime   *criRuntime
+	globalCRIRuntimeMu sync.Mutex
+)
+
+func init() {
+	registerRuntime(CRIRuntimeName, getCRIRuntime)
+}
+
+func getCRIRuntime() (Runtime, error) {
+	globalCRIRuntimeMu.Lock()
+	if globalCRIRuntime == nil {
+		globalCRIRuntime = &criRuntime{}
+		globalCRIRuntime.poller = newEventPoller(globalCRIRuntime.listRunning, criEventsPollInterval)
+		globalCRIRuntime.initRetry.SetupRetrier(&retry.Config{
+			Name:          "criutil",
+			AttemptMethod: globalCRIRuntime.init,
+			Strategy:      retry.RetryCount,
+			RetryCount:    10,
+			RetryDelay:    30 * time.Second,
+		})
+	}
+	rt := globalCRIRuntime
+	globalCRIRuntimeMu.Unlock()
+
+	if err := rt.initRetry.TriggerRetry(); err != nil {
+		return nil, err
+	}
+	return rt, nil
+}
+
+func (r *criRuntime) init() error {
+	socketPath := config.Datadog.GetString("cri_socket_path")
+	if socketPath == "" {
+		return errors.New("cri_socket_path is not set")
+	}
+	connectionTimeout := time.Duration(config.Datadog.GetInt("cri_connection_timeout")) * time.Second
+	queryTimeout := time.Duration(config.Datadog.GetInt("cri_query_timeout")) * time.Second
+
+	util, err := cri.NewCRIUtil(socketPath, connectionTimeout, queryTimeout)
+	if err != nil {
+		return err
+	}
+	r.util = util
+	return nil
+}
+
+// Name returns the runtime name
+func (r *criRuntime) Name() string {
+	return CRIRuntimeName
+}
+
+// ContainerIDToEntityName uses the CRI runtime name as prefix, like the
+// kubelet does in the pod status, eg. containerd://<id>
+func (r *criRuntime) ContainerIDToEntityName(id string) string {
+	return fmt.Sprintf("%s://%s", r.util.RuntimeName, id)
+}
+
+// List returns the containers known by the runtime
+func (r *criRuntime) List(includeExited bool) ([]*Details, error) {
+	var filter *cri.ContainerFilter
+	if !includeExited {
+		filter = &cri.ContainerFilter{State: &cri.ContainerStateValue{State: cri.ContainerStateRunning}}
+	}
+	containers, err := r.util.ListContainers(filter)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*Details, 0, len(containers))
+	for _, co := range containers {
+		details := &Details{
+			ID:       co.Id,
+			EntityID: r.ContainerIDToEntityName(co.Id),
+			ImageID:  co.ImageRef,
+			State:    co.State.String(),
+			Created:  co.CreatedAt / int64(time.Second),
+			Labels:   co.Labels,
+		}
+		if co.Metadata != nil {
+			details.Name = co.Metadata.Name
+		}
+		if co.Image != nil {
+			details.Image = co.Image.Image
+		}
+		ret = append(ret, details)
+	}
+	return ret, nil
+}
+
+func (r *criRuntime) listRunning() ([]*Details, error) {
+	return r.List(false)
+}
+
+// Inspect returns the status of a container. The CRI doesn't report the
+// container networks, environment and ports: they are defined at the pod level.
+func (r *criRuntime) Inspect(id string) (*Details, error) {
+	status, pid, err := r.util.ContainerStatus(id)
+	if err != nil {
+		return nil, err
+	}
+	details := &Details{
+		ID:        status.Id,
+		EntityID:  r.ContainerIDToEntityName(status.Id),
+		ImageID:   status.ImageRef,
+		State:     status.State.String(),
+		Created:   status.CreatedAt / int64(time.Second),
+		StartedAt: status.StartedAt / int64(time.Second),
+		Pid:       pid,
+		Labels:    status.Labels,
+		Networks:  make(map[string]string),
+		Ports:     []int{},
+	}
+	if status.Metadata != nil {
+		details.Name = status.Metadata.Name
+	}
+	if status.Image != nil {
+		details.Image = status.Image.Image
+	}
+	return details, nil
+}
+
+// SubscribeToEvents streams the start and die events detected by polling
+func (r *criRuntime) SubscribeToEvents(name string) (<-chan *Event, <-chan error, error) {
+	return r.poller.subscribe(name)
+}
+
+// UnsubscribeFromEvents stops an event stream
+func (r *criRuntime) UnsubscribeFromEvents(name string) error {
+	return r.poller.unsubscribe(name)
+}
+
+// Stats returns the CPU and memory usage reported by the runtime
+func (r *criRuntime) Stats(ids []string) (map[string]*Stats, error) {
+	criStats, err := r.util.ListContainerStats(nil)
+	if err != nil {
+		return nil, err
+	}
+	stats := make(map[string]*Stats, len(ids))
+	for _, id := range ids {
+		s, found := criStats[id]
+		if !found {
+			continue
+		}
+		stats[id] = convertCRIStats(id, s)
+	}
+	return stats, nil
+}
+
+func convertCRIStats(id string, s *cri.ContainerStats) *Stats {
+	ret := &Stats{ContainerID: id}
+	if s.Cpu != nil && s.Cpu.UsageCoreNanoSeconds != nil {
+		ret.Timestamp = time.Unix(0, s.Cpu.Timestamp)
+		ret.CPU = &CPUStats{Total: s.Cpu.UsageCoreNanoSeconds.Value}
+	}
+	if s.Memory != nil && s.Memory.WorkingSetBytes != nil {
+		ret.Timestamp = time.Unix(0, s.Memory.Timestamp)
+		ret.Memory = &MemoryStats{WorkingSet: s.Memory.WorkingSetBytes.Value}
+	}
+	return ret
+}
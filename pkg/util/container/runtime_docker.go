@@ -0,0 +1,348 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build docker
+
+package container
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/cihub/seelog"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/go-connections/nat"
+
+	"github.com/DataDog/datadog-agent/pkg/util/docker"
+)
+
+// dockerRuntime implements the Runtime interface on top of DockerUtil
+type dockerRuntime struct {
+	du *docker.DockerUtil
+	m  sync.Mutex
+	// stop channels of the event forwarders, by subscriber name
+	forwarders map[string]chan struct{}
+}
+
+var (
+	globalDockerRuntime   *dockerRuntime
+	globalDockerRuntimeMu sync.Mutex
+)
+
+func init() {
+	registerRuntime(DockerRuntimeName, getDockerRuntime)
+}
+
+func getDockerRuntime() (Runtime, error) {
+	du, err := docker.GetDockerUtil()
+	if err != nil {
+		return nil, err
+	}
+
+	globalDockerRuntimeMu.Lock()
+	defer globalDockerRuntimeMu.Unlock()
+	if globalDockerRuntime == nil || globalDockerRuntime.du != du {
+		globalDockerRuntime = &dockerRuntime{
+			du:         du,
+			forwarders: make(map[string]chan struct{}),
+		}
+	}
+	return globalDockerRuntime, nil
+}
+
+// Name returns the runtime name
+func (r *dockerRuntime) Name() string {
+	return DockerRuntimeName
+}
+
+// ContainerIDToEntityName returns the tagger entity name of a container
+func (r *dockerRuntime) ContainerIDToEntityName(id string) string {
+	return docker.ContainerIDToEntityName(id)
+}
+
+// List returns the containers listed by the docker daemon
+func (r *dockerRuntime) List(includeExited bool) ([]*Details, error) {
+	containers, err := r.du.ContainerList(context.Background(), types.ContainerListOptions{All: includeExited})
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*Details, 0, len(containers))
+	for _, co := range containers {
+		details := dockerContainerToDetails(co)
+		if image, err := r.du.ResolveImageName(co.Image); err == nil {
+			details.Image = image
+		} else {
+			log.Warnf("error while resolving image name: %s", err)
+		}
+		ret = append(ret, details)
+	}
+	return ret, nil
+}
+
+// Inspect inspects a container, hitting the DockerUtil inspect cache first
+func (r *dockerRuntime) Inspect(id string) (*Details, error) {
+	cj, err := r.du.Inspect(id, false)
+	if err != nil {
+		return nil, err
+	}
+	details := dockerInspectToDetails(cj)
+	if image, err := r.du.ResolveImageName(cj.Image); err == nil {
+		details.Image = image
+	} else {
+		log.Warnf("error while resolving image name: %s", err)
+	}
+	return details, nil
+}
+
+// SubscribeToEvents forwards the start and die events of the docker event stream
+func (r *dockerRuntime) SubscribeToEvents(name string) (<-chan *Event, <-chan error, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	if _, found := r.forwarders[name]; found {
+		return nil, nil, ErrAlreadySubscribed
+	}
+
+	dockerEvents, dockerErrs, err := r.du.SubscribeToContainerEvents(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	events := make(chan *Event)
+	errs := make(chan error, 1)
+	stop := make(chan struct{})
+	r.forwarders[name] = stop
+
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case ev, ok := <-dockerEvents:
+				if !ok {
+					return
+				}
+				if ev.Action != EventStart && ev.Action != EventDie {
+					continue
+				}
+				select {
+				case events <- dockerEventToEvent(ev):
+				case <-stop:
+					return
+				}
+			case err := <-dockerErrs:
+				errs <- err
+				return
+			}
+		}
+	}()
+	return events, errs, nil
+}
+
+// UnsubscribeFromEvents stops the forwarding of the docker events
+func (r *dockerRuntime) UnsubscribeFromEvents(name string) error {
+	r.m.Lock()
+	stop, found := r.forwarders[name]
+	delete(r.forwarders, name)
+	r.m.Unlock()
+
+	if !found {
+		return ErrNotSubscribed
+	}
+	close(stop)
+	return r.du.UnsubscribeFromContainerEvents(name)
+}
+
+// Stats reads the resource usage of the containers from their cgroups
+func (r *dockerRuntime) Stats(ids []string) (map[string]*Stats, error) {
+	cgroups, err := docker.ScrapeAllCgroups()
+	if err != nil {
+		return nil, fmt.Errorf("could not get cgroups: %s", err)
+	}
+	now := time.Now()
+	stats := make(map[string]*Stats, len(ids))
+	for _, id := range ids {
+		cgroup, found := cgroups[id]
+		if !found {
+			continue
+		}
+		s, err := cgroupStats(cgroup)
+		if err != nil {
+			log.Debugf("Could not read cgroup stats for container %s: %s", id, err)
+			continue
+		}
+		s.Timestamp = now
+		stats[id] = s
+	}
+	return stats, nil
+}
+
+// cgroupStats converts the statistics of a container cgroup
+func cgroupStats(cgroup *docker.ContainerCgroup) (*Stats, error) {
+	mem, err := cgroup.Mem()
+	if err != nil {
+		return nil, err
+	}
+	cpu, err := cgroup.CPU()
+	if err != nil {
+		return nil, err
+	}
+	io, err := cgroup.IO()
+	if err != nil {
+		return nil, err
+	}
+	s := &Stats{
+		ContainerID: cgroup.ContainerID,
+		CPU: &CPUStats{
+			Total:  uint64(cpu.UsageTotal * docker.NanoToUserHZDivisor),
+			User:   uint64(float64(cpu.User) * docker.NanoToUserHZDivisor),
+			System: uint64(float64(cpu.System) * docker.NanoToUserHZDivisor),
+		},
+		Memory: &MemoryStats{
+			Usage: mem.MemUsageInBytes,
+			RSS:   mem.RSS,
+			Cache: mem.Cache,
+		},
+		IO: &IOStats{
+			ReadBytes:  io.ReadBytes,
+			WriteBytes: io.WriteBytes,
+		},
+	}
+	// the working set excludes the page cache that can be reclaimed
+	if mem.MemUsageInBytes > mem.TotalInactiveFile {
+		s.Memory.WorkingSet = mem.MemUsageInBytes - mem.TotalInactiveFile
+	}
+	if s.CPU.NrThrottled, err = cgroup.CPUNrThrottled(); err != nil {
+		log.Debugf("Cgroup cpuNrThrottled: %s", err)
+	}
+	if s.CPULimit, err = cgroup.CPULimit(); err != nil {
+		log.Debugf("Cgroup cpu limit: %s", err)
+	}
+	if s.Memory.Limit, err = cgroup.MemLimit(); err != nil {
+		log.Debugf("Cgroup memory limit: %s", err)
+	}
+	return s, nil
+}
+
+// dockerContainerToDetails converts a container from the docker ps output
+func dockerContainerToDetails(co types.Container) *Details {
+	details := &Details{
+		ID:       co.ID,
+		EntityID: docker.ContainerIDToEntityName(co.ID),
+		Image:    co.Image,
+		ImageID:  co.ImageID,
+		State:    co.State,
+		Created:  co.Created,
+		Labels:   co.Labels,
+		Networks: make(map[string]string),
+	}
+	if len(co.Names) > 0 {
+		details.Name = strings.TrimPrefix(co.Names[0], "/")
+	}
+	if co.NetworkSettings != nil {
+		for net, settings := range co.NetworkSettings.Networks {
+			if len(settings.IPAddress) > 0 {
+				details.Networks[net] = settings.IPAddress
+			}
+		}
+	}
+	// Nil if there are no ports, the container needs to be inspected
+	for _, p := range co.Ports {
+		details.Ports = append(details.Ports, int(p.PrivatePort))
+	}
+	return details
+}
+
+// dockerInspectToDetails converts the output of docker inspect
+func dockerInspectToDetails(cj types.ContainerJSON) *Details {
+	details := &Details{
+		ID:       cj.ID,
+		EntityID: docker.ContainerIDToEntityName(cj.ID),
+		Name:     strings.TrimPrefix(cj.Name, "/"),
+		Image:    cj.Image,
+		ImageID:  cj.Image,
+		Networks: make(map[string]string),
+		// Make a non-nil array to avoid re-inspecting if we find zero port
+		Ports: []int{},
+	}
+	if cj.State != nil {
+		details.State = cj.State.Status
+		details.Pid = cj.State.Pid
+		if started, err := time.Parse(time.RFC3339Nano, cj.State.StartedAt); err == nil {
+			details.StartedAt = started.Unix()
+		}
+	}
+	if created, err := time.Parse(time.RFC3339Nano, cj.Created); err == nil {
+		details.Created = created.Unix()
+	}
+	if cj.Config != nil {
+		details.Hostname = cj.Config.Hostname
+		details.Labels = cj.Config.Labels
+		details.Env = cj.Config.Env
+	}
+	if cj.NetworkSettings != nil {
+		for net, settings := range cj.NetworkSettings.Networks {
+			if len(settings.IPAddress) > 0 {
+				details.Networks[net] = settings.IPAddress
+			}
+		}
+	}
+
+	switch {
+	case cj.NetworkSettings != nil && len(cj.NetworkSettings.Ports) > 0:
+		for p := range cj.NetworkSettings.Ports {
+			out, err := parseDockerPort(p)
+			if err != nil {
+				log.Warn(err.Error())
+				continue
+			}
+			details.Ports = append(details.Ports, out...)
+		}
+	case cj.Config != nil && len(cj.Config.ExposedPorts) > 0:
+		log.Infof("using ExposedPorts for container %s as no port bindings are listed", cj.ID)
+		for p := range cj.Config.ExposedPorts {
+			out, err := parseDockerPort(p)
+			if err != nil {
+				log.Warn(err.Error())
+				continue
+			}
+			details.Ports = append(details.Ports, out...)
+		}
+	}
+	return details
+}
+
+func parseDockerPort(port nat.Port) ([]int, error) {
+	var output []int
+
+	// Try to parse a port range, eg. 22-25
+	first, last, err := port.Range()
+	if err == nil && last > first {
+		for p := first; p <= last; p++ {
+			output = append(output, p)
+		}
+		return output, nil
+	}
+
+	// Try to parse a single port (most common case)
+	p := port.Int()
+	if p > 0 {
+		output = append(output, p)
+		return output, nil
+	}
+
+	return output, fmt.Errorf("failed to extract port from: %v", port)
+}
+
+func dockerEventToEvent(ev *docker.ContainerEvent) *Event {
+	return &Event{
+		ContainerID:   ev.ContainerID,
+		ContainerName: ev.ContainerName,
+		ImageName:     ev.ImageName,
+		Action:        ev.Action,
+		Timestamp:     ev.Timestamp,
+	}
+}
@@ -0,0 +1,151 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build docker
+
+package container
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDockerContainerToDetails(t *testing.T) {
+	co := types.Container{
+		ID:    "foo",
+		Names: []string{"/redis"},
+		Image: "test",
+	}
+	details := dockerContainerToDetails(co)
+	assert.Equal(t, "redis", details.Name)
+	assert.Equal(t, "docker://foo", details.EntityID)
+	assert.Empty(t, details.Networks)
+	assert.Nil(t, details.Ports) // must be nil to trigger an inspection
+
+	nets := make(map[string]*network.EndpointSettings)
+	nets["bridge"] = &network.EndpointSettings{IPAddress: "172.17.0.2"}
+	nets["foo"] = &network.EndpointSettings{IPAddress: "172.17.0.3"}
+	nets["none"] = &network.EndpointSettings{}
+	co = types.Container{
+		ID:              "deadbeef",
+		Image:           "test",
+		NetworkSettings: &types.SummaryNetworkSettings{Networks: nets},
+		Ports:           []types.Port{{PrivatePort: 1337}, {PrivatePort: 42}},
+	}
+	details = dockerContainerToDetails(co)
+	assert.Equal(t, map[string]string{"bridge": "172.17.0.2", "foo": "172.17.0.3"}, details.Networks)
+	assert.Equal(t, []int{1337, 42}, details.Ports)
+}
+
+func TestDockerInspectToDetails(t *testing.T) {
+	exposedPorts := make(map[nat.Port]struct{})
+	ep, _ := nat.NewPort("tcp", "42-45")
+	exposedPorts[ep] = struct{}{}
+
+	cj := types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			ID:      "deadbeef",
+			Name:    "/redis",
+			Image:   "sha256:7c2a",
+			Created: "2018-02-05T10:48:21.021829431Z",
+			State: &types.ContainerState{
+				Status:    "running",
+				Pid:       1337,
+				StartedAt: "2018-02-05T10:48:22.5Z",
+			},
+		},
+		Config: &container.Config{
+			Hostname:     "redis-1",
+			Env:          []string{"PATH=/usr/bin"},
+			Labels:       map[string]string{"app": "redis"},
+			ExposedPorts: exposedPorts,
+		},
+		NetworkSettings: &types.NetworkSettings{
+			Networks: map[string]*network.EndpointSettings{"bridge": {IPAddress: "172.17.0.2"}},
+		},
+	}
+	details := dockerInspectToDetails(cj)
+	assert.Equal(t, "redis", details.Name)
+	assert.Equal(t, "running", details.State)
+	assert.Equal(t, 1337, details.Pid)
+	assert.Equal(t, int64(1517827701), details.Created)
+	assert.Equal(t, int64(1517827702), details.StartedAt)
+	assert.Equal(t, "redis-1", details.Hostname)
+	assert.Equal(t, []string{"PATH=/usr/bin"}, details.Env)
+	assert.Equal(t, map[string]string{"app": "redis"}, details.Labels)
+	assert.Equal(t, map[string]string{"bridge": "172.17.0.2"}, details.Networks)
+	// exposed ports are used as there are no bindings
+	assert.Len(t, details.Ports, 4)
+	assert.Contains(t, details.Ports, 42)
+	assert.Contains(t, details.Ports, 45)
+
+	// bindings take precedence
+	ports := make(nat.PortMap)
+	p, _ := nat.NewPort("tcp", "1234")
+	ports[p] = nil
+	cj.NetworkSettings.Ports = ports
+	details = dockerInspectToDetails(cj)
+	assert.Equal(t, []int{1234}, details.Ports)
+
+	// non-nil even without any port
+	cj.NetworkSettings.Ports = nil
+	cj.Config.ExposedPorts = nil
+	details = dockerInspectToDetails(cj)
+	assert.NotNil(t, details.Ports)
+	assert.Empty(t, details.Ports)
+}
+
+func TestParseDockerPort(t *testing.T) {
+	testCases := []struct {
+		proto         string
+		port          string
+		expectedPorts []int
+		expectedError error
+	}{
+		{
+			proto:         "tcp",
+			port:          "42",
+			expectedPorts: []int{42},
+			expectedError: nil,
+		},
+		{
+			proto:         "udp",
+			port:          "500-503",
+			expectedPorts: []int{500, 501, 502, 503},
+			expectedError: nil,
+		},
+		{
+			proto:         "tcp",
+			port:          "0",
+			expectedPorts: nil,
+			expectedError: errors.New("failed to extract port from: 0/tcp"),
+		},
+	}
+
+	for i, test := range testCases {
+		t.Run(fmt.Sprintf("case %d: %s/%s", i, test.port, test.proto), func(t *testing.T) {
+			p, err := nat.NewPort(test.proto, test.port)
+			assert.Nil(t, err)
+
+			ports, err := parseDockerPort(p)
+			if test.expectedError == nil {
+				assert.Nil(t, err)
+			} else {
+				require.NotNil(t, err)
+				assert.Equal(t, test.expectedError.Error(), err.Error())
+			}
+
+			assert.Equal(t, test.expectedPorts, ports)
+		})
+	}
+}
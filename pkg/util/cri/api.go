@@ -0,0 +1,273 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build cri
+
+package cri
+
+// This file holds the subset of the CRI runtime.v1alpha2 protobuf messages
+// used by the agent, see k8s.io/kubernetes/pkg/kubelet/apis/cri/runtime/v1alpha2/api.proto
+// Field numbers must match the upstream definitions, unknown fields are skipped.
+
+import (
+	"github.com/golang/protobuf/proto"
+)
+
+// gRPC methods of the RuntimeService
+const (
+	runtimeService           = "/runtime.v1alpha2.RuntimeService/"
+	versionMethod            = runtimeService + "Version"
+	listContainersMethod     = runtimeService + "ListContainers"
+	containerStatusMethod    = runtimeService + "ContainerStatus"
+	listContainerStatsMethod = runtimeService + "ListContainerStats"
+	runtimeAPIVersion        = "v1alpha2"
+	// key of the JSON runtime-specific details in verbose ContainerStatus responses
+	verboseInfoKey = "info"
+)
+
+// ContainerState is the state of a container
+type ContainerState int32
+
+// Container states
+const (
+	ContainerStateCreated ContainerState = 0
+	ContainerStateRunning ContainerState = 1
+	ContainerStateExited  ContainerState = 2
+	ContainerStateUnknown ContainerState = 3
+)
+
+var containerStateNames = map[ContainerState]string{
+	ContainerStateCreated: "created",
+	ContainerStateRunning: "running",
+	ContainerStateExited:  "exited",
+	ContainerStateUnknown: "unknown",
+}
+
+// String returns the state as reported by docker
+func (s ContainerState) String() string {
+	if name, found := containerStateNames[s]; found {
+		return name
+	}
+	return "unknown"
+}
+
+// VersionRequest is the request of the Version method
+type VersionRequest struct {
+	Version string `protobuf:"bytes,1,opt,name=version,proto3"`
+}
+
+func (m *VersionRequest) Reset()         { *m = VersionRequest{} }
+func (m *VersionRequest) String() string { return proto.CompactTextString(m) }
+func (*VersionRequest) ProtoMessage()    {}
+
+// VersionResponse is the response of the Version method
+type VersionResponse struct {
+	Version           string `protobuf:"bytes,1,opt,name=version,proto3"`
+	RuntimeName       string `protobuf:"bytes,2,opt,name=runtime_name,json=runtimeName,proto3"`
+	RuntimeVersion    string `protobuf:"bytes,3,opt,name=runtime_version,json=runtimeVersion,proto3"`
+	RuntimeApiVersion string `protobuf:"bytes,4,opt,name=runtime_api_version,json=runtimeApiVersion,proto3"`
+}
+
+func (m *VersionResponse) Reset()         { *m = VersionResponse{} }
+func (m *VersionResponse) String() string { return proto.CompactTextString(m) }
+func (*VersionResponse) ProtoMessage()    {}
+
+// ContainerMetadata holds the container name given by the kubelet
+type ContainerMetadata struct {
+	Name    string `protobuf:"bytes,1,opt,name=name,proto3"`
+	Attempt uint32 `protobuf:"varint,2,opt,name=attempt,proto3"`
+}
+
+func (m *ContainerMetadata) Reset()         { *m = ContainerMetadata{} }
+func (m *ContainerMetadata) String() string { return proto.CompactTextString(m) }
+func (*ContainerMetadata) ProtoMessage()    {}
+
+// ImageSpec is an image reference
+type ImageSpec struct {
+	Image string `protobuf:"bytes,1,opt,name=image,proto3"`
+}
+
+func (m *ImageSpec) Reset()         { *m = ImageSpec{} }
+func (m *ImageSpec) String() string { return proto.CompactTextString(m) }
+func (*ImageSpec) ProtoMessage()    {}
+
+// ContainerStateValue wraps a state in filters
+type ContainerStateValue struct {
+	State ContainerState `protobuf:"varint,1,opt,name=state,proto3,enum=runtime.v1alpha2.ContainerState"`
+}
+
+func (m *ContainerStateValue) Reset()         { *m = ContainerStateValue{} }
+func (m *ContainerStateValue) String() string { return proto.CompactTextString(m) }
+func (*ContainerStateValue) ProtoMessage()    {}
+
+// ContainerFilter filters the containers of ListContainers
+type ContainerFilter struct {
+	Id            string               `protobuf:"bytes,1,opt,name=id,proto3"`
+	State         *ContainerStateValue `protobuf:"bytes,2,opt,name=state"`
+	PodSandboxId  string               `protobuf:"bytes,3,opt,name=pod_sandbox_id,json=podSandboxId,proto3"`
+	LabelSelector map[string]string    `protobuf:"bytes,4,rep,name=label_selector,json=labelSelector" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (m *ContainerFilter) Reset()         { *m = ContainerFilter{} }
+func (m *ContainerFilter) String() string { return proto.CompactTextString(m) }
+func (*ContainerFilter) ProtoMessage()    {}
+
+// Container is an item of the ListContainers response
+type Container struct {
+	Id           string             `protobuf:"bytes,1,opt,name=id,proto3"`
+	PodSandboxId string             `protobuf:"bytes,2,opt,name=pod_sandbox_id,json=podSandboxId,proto3"`
+	Metadata     *ContainerMetadata `protobuf:"bytes,3,opt,name=metadata"`
+	Image        *ImageSpec         `protobuf:"bytes,4,opt,name=image"`
+	ImageRef     string             `protobuf:"bytes,5,opt,name=image_ref,json=imageRef,proto3"`
+	State        ContainerState     `protobuf:"varint,6,opt,name=state,proto3,enum=runtime.v1alpha2.ContainerState"`
+	CreatedAt    int64              `protobuf:"varint,7,opt,name=created_at,json=createdAt,proto3"`
+	Labels       map[string]string  `protobuf:"bytes,8,rep,name=labels" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Annotations  map[string]string  `protobuf:"bytes,9,rep,name=annotations" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (m *Container) Reset()         { *m = Container{} }
+func (m *Container) String() string { return proto.CompactTextString(m) }
+func (*Container) ProtoMessage()    {}
+
+// ListContainersRequest is the request of the ListContainers method
+type ListContainersRequest struct {
+	Filter *ContainerFilter `protobuf:"bytes,1,opt,name=filter"`
+}
+
+func (m *ListContainersRequest) Reset()         { *m = ListContainersRequest{} }
+func (m *ListContainersRequest) String() string { return proto.CompactTextString(m) }
+func (*ListContainersRequest) ProtoMessage()    {}
+
+// ListContainersResponse is the response of the ListContainers method
+type ListContainersResponse struct {
+	Containers []*Container `protobuf:"bytes,1,rep,name=containers"`
+}
+
+func (m *ListContainersResponse) Reset()         { *m = ListContainersResponse{} }
+func (m *ListContainersResponse) String() string { return proto.CompactTextString(m) }
+func (*ListContainersResponse) ProtoMessage()    {}
+
+// ContainerStatusRequest is the request of the ContainerStatus method
+type ContainerStatusRequest struct {
+	ContainerId string `protobuf:"bytes,1,opt,name=container_id,json=containerId,proto3"`
+	Verbose     bool   `protobuf:"varint,2,opt,name=verbose,proto3"`
+}
+
+func (m *ContainerStatusRequest) Reset()         { *m = ContainerStatusRequest{} }
+func (m *ContainerStatusRequest) String() string { return proto.CompactTextString(m) }
+func (*ContainerStatusRequest) ProtoMessage()    {}
+
+// ContainerStatus is the detailed status of a container
+type ContainerStatus struct {
+	Id          string             `protobuf:"bytes,1,opt,name=id,proto3"`
+	Metadata    *ContainerMetadata `protobuf:"bytes,2,opt,name=metadata"`
+	State       ContainerState     `protobuf:"varint,3,opt,name=state,proto3,enum=runtime.v1alpha2.ContainerState"`
+	CreatedAt   int64              `protobuf:"varint,4,opt,name=created_at,json=createdAt,proto3"`
+	StartedAt   int64              `protobuf:"varint,5,opt,name=started_at,json=startedAt,proto3"`
+	FinishedAt  int64              `protobuf:"varint,6,opt,name=finished_at,json=finishedAt,proto3"`
+	ExitCode    int32              `protobuf:"varint,7,opt,name=exit_code,json=exitCode,proto3"`
+	Image       *ImageSpec         `protobuf:"bytes,8,opt,name=image"`
+	ImageRef    string             `protobuf:"bytes,9,opt,name=image_ref,json=imageRef,proto3"`
+	Reason      string             `protobuf:"bytes,10,opt,name=reason,proto3"`
+	Message     string             `protobuf:"bytes,11,opt,name=message,proto3"`
+	Labels      map[string]string  `protobuf:"bytes,12,rep,name=labels" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Annotations map[string]string  `protobuf:"bytes,13,rep,name=annotations" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (m *ContainerStatus) Reset()         { *m = ContainerStatus{} }
+func (m *ContainerStatus) String() string { return proto.CompactTextString(m) }
+func (*ContainerStatus) ProtoMessage()    {}
+
+// ContainerStatusResponse is the response of the ContainerStatus method.
+// Info is only filled for verbose requests.
+type ContainerStatusResponse struct {
+	Status *ContainerStatus  `protobuf:"bytes,1,opt,name=status"`
+	Info   map[string]string `protobuf:"bytes,2,rep,name=info" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (m *ContainerStatusResponse) Reset()         { *m = ContainerStatusResponse{} }
+func (m *ContainerStatusResponse) String() string { return proto.CompactTextString(m) }
+func (*ContainerStatusResponse) ProtoMessage()    {}
+
+// ContainerStatsFilter filters the containers of ListContainerStats
+type ContainerStatsFilter struct {
+	Id            string            `protobuf:"bytes,1,opt,name=id,proto3"`
+	PodSandboxId  string            `protobuf:"bytes,2,opt,name=pod_sandbox_id,json=podSandboxId,proto3"`
+	LabelSelector map[string]string `protobuf:"bytes,3,rep,name=label_selector,json=labelSelector" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (m *ContainerStatsFilter) Reset()         { *m = ContainerStatsFilter{} }
+func (m *ContainerStatsFilter) String() string { return proto.CompactTextString(m) }
+func (*ContainerStatsFilter) ProtoMessage()    {}
+
+// ListContainerStatsRequest is the request of the ListContainerStats method
+type ListContainerStatsRequest struct {
+	Filter *ContainerStatsFilter `protobuf:"bytes,1,opt,name=filter"`
+}
+
+func (m *ListContainerStatsRequest) Reset()         { *m = ListContainerStatsRequest{} }
+func (m *ListContainerStatsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListContainerStatsRequest) ProtoMessage()    {}
+
+// ListContainerStatsResponse is the response of the ListContainerStats method
+type ListContainerStatsResponse struct {
+	Stats []*ContainerStats `protobuf:"bytes,1,rep,name=stats"`
+}
+
+func (m *ListContainerStatsResponse) Reset()         { *m = ListContainerStatsResponse{} }
+func (m *ListContainerStatsResponse) String() string { return proto.CompactTextString(m) }
+func (*ListContainerStatsResponse) ProtoMessage()    {}
+
+// ContainerAttributes identifies the container of a ContainerStats
+type ContainerAttributes struct {
+	Id          string             `protobuf:"bytes,1,opt,name=id,proto3"`
+	Metadata    *ContainerMetadata `protobuf:"bytes,2,opt,name=metadata"`
+	Labels      map[string]string  `protobuf:"bytes,3,rep,name=labels" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Annotations map[string]string  `protobuf:"bytes,4,rep,name=annotations" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (m *ContainerAttributes) Reset()         { *m = ContainerAttributes{} }
+func (m *ContainerAttributes) String() string { return proto.CompactTextString(m) }
+func (*ContainerAttributes) ProtoMessage()    {}
+
+// UInt64Value wraps an optional value
+type UInt64Value struct {
+	Value uint64 `protobuf:"varint,1,opt,name=value,proto3"`
+}
+
+func (m *UInt64Value) Reset()         { *m = UInt64Value{} }
+func (m *UInt64Value) String() string { return proto.CompactTextString(m) }
+func (*UInt64Value) ProtoMessage()    {}
+
+// CpuUsage holds the cumulative CPU usage of a container
+type CpuUsage struct {
+	Timestamp            int64        `protobuf:"varint,1,opt,name=timestamp,proto3"`
+	UsageCoreNanoSeconds *UInt64Value `protobuf:"bytes,2,opt,name=usage_core_nano_seconds,json=usageCoreNanoSeconds"`
+}
+
+func (m *CpuUsage) Reset()         { *m = CpuUsage{} }
+func (m *CpuUsage) String() string { return proto.CompactTextString(m) }
+func (*CpuUsage) ProtoMessage()    {}
+
+// MemoryUsage holds the memory usage of a container
+type MemoryUsage struct {
+	Timestamp       int64        `protobuf:"varint,1,opt,name=timestamp,proto3"`
+	WorkingSetBytes *UInt64Value `protobuf:"bytes,2,opt,name=working_set_bytes,json=workingSetBytes"`
+}
+
+func (m *MemoryUsage) Reset()         { *m = MemoryUsage{} }
+func (m *MemoryUsage) String() string { return proto.CompactTextString(m) }
+func (*MemoryUsage) ProtoMessage()    {}
+
+// ContainerStats holds the resource usage of a container
+type ContainerStats struct {
+	Attributes *ContainerAttributes `protobuf:"bytes,1,opt,name=attributes"`
+	Cpu        *CpuUsage            `protobuf:"bytes,2,opt,name=cpu"`
+	Memory     *MemoryUsage         `protobuf:"bytes,3,opt,name=memory"`
+}
+
+func (m *ContainerStats) Reset()         { *m = ContainerStats{} }
+func (m *ContainerStats) String() string { return proto.CompactTextString(m) }
+func (*ContainerStats) ProtoMessage()    {}
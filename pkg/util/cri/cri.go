@@ -0,0 +1,136 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build cri
+
+package cri
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+
+	log "github.com/cihub/seelog"
+	"google.golang.org/grpc"
+)
+
+// CRIUtil wraps a gRPC connection to the runtime service of a
+// Container Runtime Interface implementation, eg. containerd or cri-o
+type CRIUtil struct {
+	conn         *grpc.ClientConn
+	queryTimeout time.Duration
+	// RuntimeName is the runtime name reported by the CRI, eg. containerd
+	RuntimeName string
+	// RuntimeVersion is the runtime version reported by the CRI
+	RuntimeVersion string
+}
+
+// NewCRIUtil connects to the CRI unix socket and queries the runtime version
+func NewCRIUtil(socketPath string, connectionTimeout, queryTimeout time.Duration) (*CRIUtil, error) {
+	dialer := func(addr string, timeout time.Duration) (net.Conn, error) {
+		return net.DialTimeout("unix", addr, timeout)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), connectionTimeout)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, socketPath, grpc.WithInsecure(), grpc.WithBlock(), grpc.WithDialer(dialer))
+	if err != nil {
+		return nil, fmt.Errorf("can't connect to %s: %s", socketPath, err)
+	}
+
+	c := &CRIUtil{
+		conn:         conn,
+		queryTimeout: queryTimeout,
+	}
+	version, err := c.Version()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if version.RuntimeApiVersion != runtimeAPIVersion {
+		log.Warnf("CRI runtime %s implements the %s API, the agent expects %s", version.RuntimeName, version.RuntimeApiVersion, runtimeAPIVersion)
+	}
+	c.RuntimeName = version.RuntimeName
+	c.RuntimeVersion = version.RuntimeVersion
+	return c, nil
+}
+
+// Close closes the connection to the runtime
+func (c *CRIUtil) Close() error {
+	return c.conn.Close()
+}
+
+func (c *CRIUtil) invoke(method string, req, resp interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.queryTimeout)
+	defer cancel()
+	return c.conn.Invoke(ctx, method, req, resp)
+}
+
+// Version returns the runtime name and version
+func (c *CRIUtil) Version() (*VersionResponse, error) {
+	resp := &VersionResponse{}
+	if err := c.invoke(versionMethod, &VersionRequest{Version: runtimeAPIVersion}, resp); err != nil {
+		return nil, fmt.Errorf("can't get the CRI version: %s", err)
+	}
+	return resp, nil
+}
+
+// ListContainers returns the containers matching the filter, all of them if it is nil
+func (c *CRIUtil) ListContainers(filter *ContainerFilter) ([]*Container, error) {
+	resp := &ListContainersResponse{}
+	if err := c.invoke(listContainersMethod, &ListContainersRequest{Filter: filter}, resp); err != nil {
+		return nil, fmt.Errorf("can't list containers: %s", err)
+	}
+	return resp.Containers, nil
+}
+
+// ContainerStatus returns the status of a container and the pid of its
+// main process, 0 if the runtime doesn't report it
+func (c *CRIUtil) ContainerStatus(containerID string) (*ContainerStatus, int, error) {
+	resp := &ContainerStatusResponse{}
+	req := &ContainerStatusRequest{ContainerId: containerID, Verbose: true}
+	if err := c.invoke(containerStatusMethod, req, resp); err != nil {
+		return nil, 0, fmt.Errorf("can't get the status of container %s: %s", containerID, err)
+	}
+	if resp.Status == nil {
+		return nil, 0, fmt.Errorf("empty status for container %s", containerID)
+	}
+	return resp.Status, parseVerbosePid(resp.Info), nil
+}
+
+// ListContainerStats returns the resource usage of the containers
+// matching the filter, indexed by container ID
+func (c *CRIUtil) ListContainerStats(filter *ContainerStatsFilter) (map[string]*ContainerStats, error) {
+	resp := &ListContainerStatsResponse{}
+	if err := c.invoke(listContainerStatsMethod, &ListContainerStatsRequest{Filter: filter}, resp); err != nil {
+		return nil, fmt.Errorf("can't list container stats: %s", err)
+	}
+	stats := make(map[string]*ContainerStats, len(resp.Stats))
+	for _, s := range resp.Stats {
+		if s.Attributes == nil {
+			continue
+		}
+		stats[s.Attributes.Id] = s
+	}
+	return stats, nil
+}
+
+// parseVerbosePid extracts the pid from the runtime-specific JSON details,
+// containerd and cri-o both report it as `{"pid": 1234, ...}`
+func parseVerbosePid(info map[string]string) int {
+	raw, found := info[verboseInfoKey]
+	if !found {
+		return 0
+	}
+	var details struct {
+		Pid int `json:"pid"`
+	}
+	if err := json.Unmarshal([]byte(raw), &details); err != nil {
+		log.Debugf("can't parse the container status details: %s", err)
+		return 0
+	}
+	return details.Pid
+}
@@ -0,0 +1,224 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build cri
+
+package cri
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// fakeRuntimeServer serves canned responses for the runtime service methods
+type fakeRuntimeServer struct {
+	containers []*Container
+	statuses   map[string]*ContainerStatusResponse
+	stats      []*ContainerStats
+	// last filter received by ListContainers
+	lastFilter *ContainerFilter
+}
+
+type runtimeServiceServer interface {
+	version(*VersionRequest) (*VersionResponse, error)
+	listContainers(*ListContainersRequest) (*ListContainersResponse, error)
+	containerStatus(*ContainerStatusRequest) (*ContainerStatusResponse, error)
+	listContainerStats(*ListContainerStatsRequest) (*ListContainerStatsResponse, error)
+}
+
+func (s *fakeRuntimeServer) version(*VersionRequest) (*VersionResponse, error) {
+	return &VersionResponse{
+		Version:           "0.1.0",
+		RuntimeName:       "containerd",
+		RuntimeVersion:    "v1.1.0",
+		RuntimeApiVersion: runtimeAPIVersion,
+	}, nil
+}
+
+func (s *fakeRuntimeServer) listContainers(req *ListContainersRequest) (*ListContainersResponse, error) {
+	s.lastFilter = req.Filter
+	return &ListContainersResponse{Containers: s.containers}, nil
+}
+
+func (s *fakeRuntimeServer) containerStatus(req *ContainerStatusRequest) (*ContainerStatusResponse, error) {
+	resp, found := s.statuses[req.ContainerId]
+	if !found {
+		return &ContainerStatusResponse{}, nil
+	}
+	if !req.Verbose {
+		return &ContainerStatusResponse{Status: resp.Status}, nil
+	}
+	return resp, nil
+}
+
+func (s *fakeRuntimeServer) listContainerStats(*ListContainerStatsRequest) (*ListContainerStatsResponse, error) {
+	return &ListContainerStatsResponse{Stats: s.stats}, nil
+}
+
+func unaryHandler(newReq func() interface{}, call func(runtimeServiceServer, interface{}) (interface{}, error)) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+		req := newReq()
+		if err := dec(req); err != nil {
+			return nil, err
+		}
+		return call(srv.(runtimeServiceServer), req)
+	}
+}
+
+var fakeRuntimeServiceDesc = grpc.ServiceDesc{
+	ServiceName: "runtime.v1alpha2.RuntimeService",
+	HandlerType: (*runtimeServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Version",
+			Handler: unaryHandler(func() interface{} { return &VersionRequest{} },
+				func(s runtimeServiceServer, req interface{}) (interface{}, error) {
+					return s.version(req.(*VersionRequest))
+				}),
+		},
+		{
+			MethodName: "ListContainers",
+			Handler: unaryHandler(func() interface{} { return &ListContainersRequest{} },
+				func(s runtimeServiceServer, req interface{}) (interface{}, error) {
+					return s.listContainers(req.(*ListContainersRequest))
+				}),
+		},
+		{
+			MethodName: "ContainerStatus",
+			Handler: unaryHandler(func() interface{} { return &ContainerStatusRequest{} },
+				func(s runtimeServiceServer, req interface{}) (interface{}, error) {
+					return s.containerStatus(req.(*ContainerStatusRequest))
+				}),
+		},
+		{
+			MethodName: "ListContainerStats",
+			Handler: unaryHandler(func() interface{} { return &ListContainerStatsRequest{} },
+				func(s runtimeServiceServer, req interface{}) (interface{}, error) {
+					return s.listContainerStats(req.(*ListContainerStatsRequest))
+				}),
+		},
+	},
+}
+
+// startFakeCRI serves the fake runtime on a unix socket in a temp directory
+func startFakeCRI(t *testing.T, fake *fakeRuntimeServer) (string, func()) {
+	dir, err := ioutil.TempDir("", "fake-cri")
+	require.NoError(t, err)
+	socketPath := filepath.Join(dir, "cri.sock")
+	listener, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+
+	server := grpc.NewServer()
+	server.RegisterService(&fakeRuntimeServiceDesc, fake)
+	go server.Serve(listener)
+
+	return socketPath, func() {
+		server.Stop()
+		os.RemoveAll(dir)
+	}
+}
+
+func newFakeRuntimeServer() *fakeRuntimeServer {
+	return &fakeRuntimeServer{
+		containers: []*Container{
+			{
+				Id:           "3e7b2a",
+				PodSandboxId: "9f1c",
+				Metadata:     &ContainerMetadata{Name: "redis"},
+				Image:        &ImageSpec{Image: "docker.io/library/redis:4.0"},
+				ImageRef:     "sha256:5958914",
+				State:        ContainerStateRunning,
+				CreatedAt:    1517827701000000000,
+				Labels:       map[string]string{"io.kubernetes.pod.name": "redis-0"},
+			},
+			{
+				Id:       "c0ffee",
+				Metadata: &ContainerMetadata{Name: "init"},
+				Image:    &ImageSpec{Image: "busybox"},
+				State:    ContainerStateExited,
+			},
+		},
+		statuses: map[string]*ContainerStatusResponse{
+			"3e7b2a": {
+				Status: &ContainerStatus{
+					Id:        "3e7b2a",
+					Metadata:  &ContainerMetadata{Name: "redis"},
+					State:     ContainerStateRunning,
+					StartedAt: 1517827702000000000,
+					Image:     &ImageSpec{Image: "docker.io/library/redis:4.0"},
+					Labels:    map[string]string{"io.kubernetes.pod.name": "redis-0"},
+				},
+				Info: map[string]string{"info": `{"sandboxID":"9f1c","pid":4242,"removing":false}`},
+			},
+		},
+		stats: []*ContainerStats{
+			{
+				Attributes: &ContainerAttributes{Id: "3e7b2a"},
+				Cpu:        &CpuUsage{Timestamp: 1517827710000000000, UsageCoreNanoSeconds: &UInt64Value{Value: 1200000000}},
+				Memory:     &MemoryUsage{Timestamp: 1517827710000000000, WorkingSetBytes: &UInt64Value{Value: 10485760}},
+			},
+		},
+	}
+}
+
+func TestCRIUtil(t *testing.T) {
+	fake := newFakeRuntimeServer()
+	socketPath, stop := startFakeCRI(t, fake)
+	defer stop()
+
+	util, err := NewCRIUtil(socketPath, time.Second, time.Second)
+	require.NoError(t, err)
+	defer util.Close()
+	assert.Equal(t, "containerd", util.RuntimeName)
+	assert.Equal(t, "v1.1.0", util.RuntimeVersion)
+
+	containers, err := util.ListContainers(&ContainerFilter{State: &ContainerStateValue{State: ContainerStateRunning}})
+	require.NoError(t, err)
+	require.Len(t, containers, 2)
+	assert.Equal(t, "3e7b2a", containers[0].Id)
+	assert.Equal(t, "redis", containers[0].Metadata.Name)
+	assert.Equal(t, "docker.io/library/redis:4.0", containers[0].Image.Image)
+	assert.Equal(t, ContainerStateRunning, containers[0].State)
+	assert.Equal(t, int64(1517827701000000000), containers[0].CreatedAt)
+	assert.Equal(t, map[string]string{"io.kubernetes.pod.name": "redis-0"}, containers[0].Labels)
+	assert.Equal(t, ContainerStateExited, containers[1].State)
+	require.NotNil(t, fake.lastFilter)
+	assert.Equal(t, ContainerStateRunning, fake.lastFilter.State.State)
+
+	status, pid, err := util.ContainerStatus("3e7b2a")
+	require.NoError(t, err)
+	assert.Equal(t, 4242, pid)
+	assert.Equal(t, int64(1517827702000000000), status.StartedAt)
+	assert.Equal(t, "running", status.State.String())
+
+	_, _, err = util.ContainerStatus("unknown")
+	assert.Error(t, err)
+
+	stats, err := util.ListContainerStats(nil)
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, uint64(1200000000), stats["3e7b2a"].Cpu.UsageCoreNanoSeconds.Value)
+	assert.Equal(t, uint64(10485760), stats["3e7b2a"].Memory.WorkingSetBytes.Value)
+}
+
+func TestCRIUtilUnavailable(t *testing.T) {
+	_, err := NewCRIUtil("/does/not/exist.sock", 100*time.Millisecond, time.Second)
+	assert.Error(t, err)
+}
+
+func TestParseVerbosePid(t *testing.T) {
+	assert.Equal(t, 0, parseVerbosePid(nil))
+	assert.Equal(t, 0, parseVerbosePid(map[string]string{"info": "{"}))
+	assert.Equal(t, 12, parseVerbosePid(map[string]string{"info": `{"pid": 12}`}))
+}
@@ -107,6 +107,9 @@ type ContainerCgroup struct {
 // Mem returns the memory statistics for a Cgroup. If the cgroup file is not
 // available then we return an empty stats file.
 func (c ContainerCgroup) Mem() (*CgroupMemStat, error) {
+	if c.isCgroupV2() {
+		return c.memV2()
+	}
 	ret := &CgroupMemStat{ContainerID: c.ContainerID}
 	statfile := c.cgroupFilePath("memory", "memory.stat")
 
@@ -196,6 +199,9 @@ func (c ContainerCgroup) Mem() (*CgroupMemStat, error) {
 // MemLimit returns the memory limit of the cgroup, if it exists. If the file does not
 // exist or there is no limit then this will default to 0.
 func (c ContainerCgroup) MemLimit() (uint64, error) {
+	if c.isCgroupV2() {
+		return c.memLimitV2()
+	}
 	v, err := c.ParseSingleStat("memory", "memory.limit_in_bytes")
 	if os.IsNotExist(err) {
 		log.Debugf("missing cgroup file: %s",
@@ -215,6 +221,9 @@ func (c ContainerCgroup) MemLimit() (uint64, error) {
 // CPU returns the CPU status for this cgroup instance
 // If the cgroup file does not exist then we just log debug return nothing.
 func (c ContainerCgroup) CPU() (*CgroupTimesStat, error) {
+	if c.isCgroupV2() {
+		return c.cpuV2()
+	}
 	ret := &CgroupTimesStat{ContainerID: c.ContainerID}
 	statfile := c.cgroupFilePath("cpuacct", "cpuacct.stat")
 	f, err := os.Open(statfile)
@@ -259,6 +268,23 @@ func (c ContainerCgroup) CPU() (*CgroupTimesStat, error) {
 // throttle/limited because of CPU quota / limit
 // If the cgroup file does not exist then we just log debug and return 0.
 func (c ContainerCgroup) CPUNrThrottled() (uint64, error) {
+	return c.cpuStatValue("nr_throttled")
+}
+
+// CPUThrottledTime returns the total time, in nanoseconds, the cgroup has
+// been throttled because of its CFS quota.
+// If the cgroup file does not exist then we just log debug and return 0.
+func (c ContainerCgroup) CPUThrottledTime() (uint64, error) {
+	if c.isCgroupV2() {
+		value, err := c.cpuStatValue("throttled_usec")
+		return value * 1000, err
+	}
+	return c.cpuStatValue("throttled_time")
+}
+
+// cpuStatValue reads a counter from cpu.stat, whose format is shared
+// by cgroup v1 and v2
+func (c ContainerCgroup) cpuStatValue(key string) (uint64, error) {
 	statfile := c.cgroupFilePath("cpu", "cpu.stat")
 	f, err := os.Open(statfile)
 	if os.IsNotExist(err) {
@@ -271,7 +297,7 @@ func (c ContainerCgroup) CPUNrThrottled() (uint64, error) {
 	scanner := bufio.NewScanner(f)
 	for scanner.Scan() {
 		fields := strings.Split(scanner.Text(), " ")
-		if fields[0] == "nr_throttled" {
+		if fields[0] == key && len(fields) > 1 {
 			value, err := strconv.ParseUint(fields[1], 10, 64)
 			if err != nil {
 				return 0, err
@@ -279,7 +305,7 @@ func (c ContainerCgroup) CPUNrThrottled() (uint64, error) {
 			return value, nil
 		}
 	}
-	log.Debugf("missing nr_throttled line in %s", statfile)
+	log.Debugf("missing %s line in %s", key, statfile)
 	return 0, nil
 }
 
@@ -293,6 +319,9 @@ func (c ContainerCgroup) CPUNrThrottled() (uint64, error) {
 // If the limits files aren't available (on older version) then
 // we'll return the default value of 100.
 func (c ContainerCgroup) CPULimit() (float64, error) {
+	if c.isCgroupV2() {
+		return c.cpuLimitV2()
+	}
 	periodFile := c.cgroupFilePath("cpu", "cpu.cfs_period_us")
 	quotaFile := c.cgroupFilePath("cpu", "cpu.cfs_quota_us")
 	plines, err := readLines(periodFile)
@@ -340,6 +369,9 @@ func (c ContainerCgroup) CPULimit() (float64, error) {
 // 252:0 Total 58945536
 //
 func (c ContainerCgroup) IO() (*CgroupIOStat, error) {
+	if c.isCgroupV2() {
+		return c.ioV2()
+	}
 	ret := &CgroupIOStat{ContainerID: c.ContainerID}
 	statfile := c.cgroupFilePath("blkio", "blkio.throttle.io_service_bytes")
 	f, err := os.Open(statfile)
@@ -404,7 +436,11 @@ func (c ContainerCgroup) ContainerStartTime() (int64, error) {
 }
 
 // cgroupFilePath constructs file path to get targeted stats file.
+// On cgroup v2, all the controllers share the unified hierarchy.
 func (c ContainerCgroup) cgroupFilePath(target, file string) string {
+	if c.isCgroupV2() {
+		target = cgroupV2Target
+	}
 	mount, ok := c.Mounts[target]
 	if !ok {
 		log.Errorf("missing target %s from mounts", target)
@@ -442,6 +478,9 @@ func (c ContainerCgroup) cgroupFilePath(target, file string) string {
 //	 cgroup /sys/fs/cgroup/perf_event cgroup rw,relatime,perf_event 0 0
 //	 cgroup /sys/fs/cgroup/hugetlb cgroup rw,relatime,hugetlb 0 0
 //
+// The cgroup v2 unified hierarchy is stored under the cgroup2 target:
+//	 cgroup2 /sys/fs/cgroup cgroup2 rw,nosuid,nodev,noexec,relatime,nsdelegate 0 0
+//
 // Returns a map for every target (cpuset, cpu, cpuacct) => path
 func cgroupMountPoints() (map[string]string, error) {
 	mountsFile := "/proc/mounts"
@@ -477,6 +516,12 @@ func parseCgroupMountPoints(r io.Reader) map[string]string {
 			for _, target := range tsp {
 				mountPoints[target] = cgroupPath
 			}
+		} else if len(tokens) >= 3 && tokens[2] == "cgroup2" {
+			// The unified hierarchy can be mounted at the cgroup root itself
+			if !strings.HasPrefix(tokens[1]+"/", cgroupRoot) {
+				continue
+			}
+			mountPoints[cgroupV2Target] = tokens[1]
 		}
 	}
 	if len(mountPoints) == 0 {
@@ -568,6 +613,10 @@ func readCgroupPaths(pidCgroupPath string) (string, map[string]string, error) {
 // 8:memory:/kubepods/besteffort/pod2baa3444-4d37-11e7-bd2f-080027d2bf10/47fc31db38b4fa0f4db44b99d0cad10e3cd4d5f142135a7721c1c95c1aadfb2e
 // 7:blkio:/kubepods/besteffort/pod2baa3444-4d37-11e7-bd2f-080027d2bf10/47fc31db38b4fa0f4db44b99d0cad10e3cd4d5f142135a7721c1c95c1aadfb2e
 //
+// On cgroup v2, the unified hierarchy has no controller list and is stored under the cgroup2 target:
+//
+// 0::/system.slice/docker-47fc31db38b4fa0f4db44b99d0cad10e3cd4d5f142135a7721c1c95c1aadfb2e.scope
+//
 // Returns the common containerID and a mapping of target => path
 // If the first line doesn't have a valid container ID we will return an empty string
 func parseCgroupPaths(r io.Reader) (string, map[string]string, error) {
@@ -590,6 +639,10 @@ func parseCgroupPaths(r io.Reader) (string, map[string]string, error) {
 		if len(sp) < 3 {
 			continue
 		}
+		if sp[0] == "0" && sp[1] == "" {
+			paths[cgroupV2Target] = sp[2]
+			continue
+		}
 		// Target can be comma-separate values like cpu,cpuacct
 		tsp := strings.Split(sp[1], ",")
 		for _, target := range tsp {
@@ -0,0 +1,216 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// This code is not tied to docker itself, hence no docker build flag.
+// It could be moved to its own package.
+
+package docker
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	log "github.com/cihub/seelog"
+)
+
+// PressureStat holds one line of a pressure stall information (PSI) file:
+// the share of time, in percent, some or all tasks were stalled over the
+// last 10, 60 and 300 seconds, and the total stall time in microseconds.
+type PressureStat struct {
+	Avg10  float64
+	Avg60  float64
+	Avg300 float64
+	Total  uint64
+}
+
+// Pressure holds the content of a PSI file. Full is nil for the
+// cpu resource on kernels that don't report it.
+type Pressure struct {
+	Some *PressureStat
+	Full *PressureStat
+}
+
+// CgroupPressure holds the PSI of a cgroup for every resource.
+// Resources without a pressure file are nil.
+type CgroupPressure struct {
+	CPU    *Pressure
+	Memory *Pressure
+	IO     *Pressure
+}
+
+// CgroupMemEvents holds the memory events counters of a cgroup
+type CgroupMemEvents struct {
+	// OOM counts the times the cgroup hit its limit and the OOM killer was invoked
+	OOM uint64
+	// OOMKill counts the processes of the cgroup killed by the OOM killer
+	OOMKill uint64
+}
+
+// ReadPressureFile parses a PSI file such as /proc/pressure/memory
+func ReadPressureFile(path string) (*Pressure, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	p, err := parsePressure(f)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %s", path, err)
+	}
+	return p, nil
+}
+
+// PressureSender is the part of aggregator.Sender used by SendPressure,
+// the aggregator package can't be imported from here.
+type PressureSender interface {
+	Gauge(metric string, value float64, hostname string, tags []string)
+	Rate(metric string, value float64, hostname string, tags []string)
+}
+
+// SendPressure sends the pressure stall information of a resource under the
+// given metric prefix, e.g. system.mem.pressure.some.avg10. The total stall
+// time is sent as a rate, in microseconds per second.
+func SendPressure(sender PressureSender, prefix string, p *Pressure, tags []string) {
+	if p == nil {
+		return
+	}
+	for kind, stat := range map[string]*PressureStat{"some": p.Some, "full": p.Full} {
+		if stat == nil {
+			continue
+		}
+		sender.Gauge(fmt.Sprintf("%s.pressure.%s.avg10", prefix, kind), stat.Avg10, "", tags)
+		sender.Gauge(fmt.Sprintf("%s.pressure.%s.avg60", prefix, kind), stat.Avg60, "", tags)
+		sender.Gauge(fmt.Sprintf("%s.pressure.%s.avg300", prefix, kind), stat.Avg300, "", tags)
+		sender.Rate(fmt.Sprintf("%s.pressure.%s.total", prefix, kind), float64(stat.Total), "", tags)
+	}
+}
+
+// parsePressure parses the content of a PSI file. Format:
+//
+// some avg10=0.00 avg60=0.12 avg300=0.34 total=123456
+// full avg10=0.00 avg60=0.05 avg300=0.10 total=45678
+//
+func parsePressure(r io.Reader) (*Pressure, error) {
+	ret := &Pressure{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		stat := &PressureStat{}
+		for _, field := range fields[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			var err error
+			switch kv[0] {
+			case "avg10":
+				stat.Avg10, err = strconv.ParseFloat(kv[1], 64)
+			case "avg60":
+				stat.Avg60, err = strconv.ParseFloat(kv[1], 64)
+			case "avg300":
+				stat.Avg300, err = strconv.ParseFloat(kv[1], 64)
+			case "total":
+				stat.Total, err = strconv.ParseUint(kv[1], 10, 64)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		switch fields[0] {
+		case "some":
+			ret.Some = stat
+		case "full":
+			ret.Full = stat
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if ret.Some == nil {
+		return nil, fmt.Errorf("missing some line")
+	}
+	return ret, nil
+}
+
+// Pressure returns the PSI of the cgroup. Per-cgroup pressure files are
+// only available on the cgroup v2 hierarchy, nil is returned on cgroup v1.
+func (c ContainerCgroup) Pressure() (*CgroupPressure, error) {
+	if !c.isCgroupV2() {
+		return nil, nil
+	}
+	ret := &CgroupPressure{}
+	for _, res := range []struct {
+		file string
+		out  **Pressure
+	}{
+		{"cpu.pressure", &ret.CPU},
+		{"memory.pressure", &ret.Memory},
+		{"io.pressure", &ret.IO},
+	} {
+		p, err := ReadPressureFile(c.cgroupFilePath(cgroupV2Target, res.file))
+		if os.IsNotExist(err) {
+			log.Debugf("missing cgroup file: %s", c.cgroupFilePath(cgroupV2Target, res.file))
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		*res.out = p
+	}
+	return ret, nil
+}
+
+// MemEvents returns the OOM counters of the cgroup, read from memory.events
+// on cgroup v2 and memory.oom_control on cgroup v1. The oom_kill counter
+// of memory.oom_control requires kernel 4.13+, it is zero on older kernels.
+func (c ContainerCgroup) MemEvents() (*CgroupMemEvents, error) {
+	ret := &CgroupMemEvents{}
+	if c.isCgroupV2() {
+		events, err := c.parseFlatKeyedFile("memory.events")
+		if os.IsNotExist(err) {
+			log.Debugf("missing cgroup file: %s", c.cgroupFilePath(cgroupV2Target, "memory.events"))
+			return ret, nil
+		} else if err != nil {
+			return nil, err
+		}
+		ret.OOM = events["oom"]
+		ret.OOMKill = events["oom_kill"]
+		return ret, nil
+	}
+
+	statfile := c.cgroupFilePath("memory", "memory.oom_control")
+	f, err := os.Open(statfile)
+	if os.IsNotExist(err) {
+		log.Debugf("missing cgroup file: %s", statfile)
+		return ret, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || fields[0] != "oom_kill" {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		// cgroup v1 doesn't count the OOM killer invocations separately
+		ret.OOM = v
+		ret.OOMKill = v
+	}
+	if err := scanner.Err(); err != nil {
+		return ret, fmt.Errorf("error reading %s: %s", statfile, err)
+	}
+	return ret, nil
+}
@@ -0,0 +1,137 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package docker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePressure(t *testing.T) {
+	p, err := parsePressure(strings.NewReader(
+		"some avg10=0.22 avg60=0.17 avg300=1.11 total=58761459\n" +
+			"full avg10=0.10 avg60=0.05 avg300=0.57 total=35219802\n"))
+	require.NoError(t, err)
+	assert.Equal(t, &PressureStat{Avg10: 0.22, Avg60: 0.17, Avg300: 1.11, Total: 58761459}, p.Some)
+	assert.Equal(t, &PressureStat{Avg10: 0.10, Avg60: 0.05, Avg300: 0.57, Total: 35219802}, p.Full)
+
+	// the cpu resource has no full line before kernel 5.13
+	p, err = parsePressure(strings.NewReader("some avg10=0.00 avg60=0.00 avg300=0.00 total=42\n"))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), p.Some.Total)
+	assert.Nil(t, p.Full)
+
+	_, err = parsePressure(strings.NewReader("some avg10=abc avg60=0.00 avg300=0.00 total=42\n"))
+	assert.Error(t, err)
+	_, err = parsePressure(strings.NewReader(""))
+	assert.Error(t, err)
+}
+
+func TestCgroupV2Pressure(t *testing.T) {
+	tempFolder, err := newTempFolder("pressure-v2")
+	require.NoError(t, err)
+	defer tempFolder.removeAll()
+	tempFolder.add("cgroup2/cpu.pressure", "some avg10=12.50 avg60=8.25 avg300=2.00 total=5120000\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=0\n")
+	tempFolder.add("cgroup2/memory.pressure", "some avg10=1.20 avg60=0.80 avg300=0.30 total=340000\nfull avg10=0.90 avg60=0.60 avg300=0.20 total=270000\n")
+
+	pressure, err := newDummyContainerCgroup(tempFolder.RootPath, cgroupV2Target).Pressure()
+	require.NoError(t, err)
+	assert.Equal(t, 12.5, pressure.CPU.Some.Avg10)
+	assert.Equal(t, uint64(5120000), pressure.CPU.Some.Total)
+	assert.Equal(t, 0.9, pressure.Memory.Full.Avg10)
+	assert.Equal(t, uint64(340000), pressure.Memory.Some.Total)
+	// no io.pressure file
+	assert.Nil(t, pressure.IO)
+
+	// cgroup v1 has no per-cgroup pressure
+	pressure, err = newDummyContainerCgroup("/nonexistent", "cpu", "memory").Pressure()
+	require.NoError(t, err)
+	assert.Nil(t, pressure)
+}
+
+func TestMemEvents(t *testing.T) {
+	tempFolder, err := newTempFolder("mem-events")
+	require.NoError(t, err)
+	defer tempFolder.removeAll()
+
+	tempFolder.add("cgroup2/memory.events", "low 0\nhigh 0\nmax 4\noom 1\noom_kill 1\n")
+	events, err := newDummyContainerCgroup(tempFolder.RootPath, cgroupV2Target).MemEvents()
+	require.NoError(t, err)
+	assert.Equal(t, &CgroupMemEvents{OOM: 1, OOMKill: 1}, events)
+
+	cgroup := newDummyContainerCgroup(tempFolder.RootPath, "memory")
+
+	// No file
+	events, err = cgroup.MemEvents()
+	require.NoError(t, err)
+	assert.Equal(t, &CgroupMemEvents{}, events)
+
+	tempFolder.add("memory/memory.oom_control", "oom_kill_disable 0\nunder_oom 0\noom_kill 3\n")
+	events, err = cgroup.MemEvents()
+	require.NoError(t, err)
+	assert.Equal(t, &CgroupMemEvents{OOM: 3, OOMKill: 3}, events)
+}
+
+func TestCPUThrottledTime(t *testing.T) {
+	tempFolder, err := newTempFolder("cpu-throttled-time")
+	require.NoError(t, err)
+	defer tempFolder.removeAll()
+
+	tempFolder.add("cgroup2/cpu.stat", "nr_periods 1201\nnr_throttled 10\nthrottled_usec 18327\n")
+	value, err := newDummyContainerCgroup(tempFolder.RootPath, cgroupV2Target).CPUThrottledTime()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(18327000), value)
+
+	cgroup := newDummyContainerCgroup(tempFolder.RootPath, "cpu")
+
+	value, err = cgroup.CPUThrottledTime()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), value)
+
+	tempFolder.add("cpu/cpu.stat", "nr_periods 0\nnr_throttled 10\nthrottled_time 18327\n")
+	value, err = cgroup.CPUThrottledTime()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(18327), value)
+}
+
+// fakePressureSender records the metrics sent, mocksender can't be used
+// from this package
+type fakePressureSender struct {
+	gauges map[string]float64
+	rates  map[string]float64
+	tags   []string
+}
+
+func (s *fakePressureSender) Gauge(metric string, value float64, hostname string, tags []string) {
+	s.gauges[metric] = value
+	s.tags = tags
+}
+
+func (s *fakePressureSender) Rate(metric string, value float64, hostname string, tags []string) {
+	s.rates[metric] = value
+	s.tags = tags
+}
+
+func TestSendPressure(t *testing.T) {
+	sender := &fakePressureSender{gauges: map[string]float64{}, rates: map[string]float64{}}
+	tags := []string{"container_name:leaky"}
+
+	SendPressure(sender, "docker.mem", &Pressure{
+		Some: &PressureStat{Avg10: 1.5, Avg60: 1, Avg300: 0.5, Total: 1200},
+	}, tags)
+	SendPressure(sender, "docker.io", nil, tags)
+
+	assert.Equal(t, map[string]float64{
+		"docker.mem.pressure.some.avg10":  1.5,
+		"docker.mem.pressure.some.avg60":  1,
+		"docker.mem.pressure.some.avg300": 0.5,
+	}, sender.gauges)
+	assert.Equal(t, map[string]float64{"docker.mem.pressure.some.total": 1200}, sender.rates)
+	assert.Equal(t, tags, sender.tags)
+}
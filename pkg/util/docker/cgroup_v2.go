@@ -0,0 +1,247 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// This code is not tied to docker itself, hence no docker build flag.
+// It could be moved to its own package.
+
+package docker
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	log "github.com/cihub/seelog"
+)
+
+// cgroupV2Target is the key used in ContainerCgroup.Mounts and ContainerCgroup.Paths
+// for the cgroup v2 unified hierarchy, where all controllers share the same directory.
+const cgroupV2Target = "cgroup2"
+
+// isCgroupV2 returns whether the cgroup only lives in the unified hierarchy.
+// On hybrid systems the cgroup v2 hierarchy is mounted along the v1 controllers
+// but doesn't hold any controller: the v1 files are used.
+func (c ContainerCgroup) isCgroupV2() bool {
+	if _, found := c.Mounts[cgroupV2Target]; !found {
+		return false
+	}
+	_, hasV1Memory := c.Mounts["memory"]
+	return !hasV1Memory
+}
+
+// parseFlatKeyedFile parses cgroup v2 files made of `key value` lines,
+// such as memory.stat, memory.events or cpu.stat
+func (c ContainerCgroup) parseFlatKeyedFile(file string) (map[string]uint64, error) {
+	statfile := c.cgroupFilePath(cgroupV2Target, file)
+	f, err := os.Open(statfile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values := make(map[string]uint64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		values[fields[0]] = v
+	}
+	if err := scanner.Err(); err != nil {
+		return values, fmt.Errorf("error reading %s: %s", statfile, err)
+	}
+	return values, nil
+}
+
+// parseMaxValue reads single-value cgroup v2 files such as memory.max,
+// where `max` means no limit. Returns 0 if there is no limit.
+func (c ContainerCgroup) parseMaxValue(file string) (uint64, error) {
+	statfile := c.cgroupFilePath(cgroupV2Target, file)
+	lines, err := readLines(statfile)
+	if err != nil {
+		return 0, err
+	}
+	if len(lines) != 1 {
+		return 0, fmt.Errorf("wrong file format: %s", statfile)
+	}
+	if lines[0] == "max" {
+		return 0, nil
+	}
+	return strconv.ParseUint(lines[0], 10, 64)
+}
+
+// memV2 returns the memory statistics of a cgroup v2. Statistics are
+// hierarchical, they are reported both in the regular and total fields.
+func (c ContainerCgroup) memV2() (*CgroupMemStat, error) {
+	ret := &CgroupMemStat{ContainerID: c.ContainerID}
+	stats, err := c.parseFlatKeyedFile("memory.stat")
+	if os.IsNotExist(err) {
+		log.Debugf("missing cgroup file: %s", c.cgroupFilePath(cgroupV2Target, "memory.stat"))
+		return ret, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	ret.Cache = stats["file"]
+	ret.RSS = stats["anon"]
+	ret.RSSHuge = stats["anon_thp"]
+	ret.MappedFile = stats["file_mapped"]
+	ret.Pgfault = stats["pgfault"]
+	ret.Pgmajfault = stats["pgmajfault"]
+	ret.InactiveAnon = stats["inactive_anon"]
+	ret.ActiveAnon = stats["active_anon"]
+	ret.InactiveFile = stats["inactive_file"]
+	ret.ActiveFile = stats["active_file"]
+	ret.Unevictable = stats["unevictable"]
+
+	ret.TotalCache = ret.Cache
+	ret.TotalRSS = ret.RSS
+	ret.TotalRSSHuge = ret.RSSHuge
+	ret.TotalMappedFile = ret.MappedFile
+	ret.TotalPgFault = ret.Pgfault
+	ret.TotalPgMajFault = ret.Pgmajfault
+	ret.TotalInactiveAnon = ret.InactiveAnon
+	ret.TotalActiveAnon = ret.ActiveAnon
+	ret.TotalInactiveFile = ret.InactiveFile
+	ret.TotalActiveFile = ret.ActiveFile
+	ret.TotalUnevictable = ret.Unevictable
+
+	if usage, err := c.ParseSingleStat(cgroupV2Target, "memory.current"); err == nil {
+		ret.MemUsageInBytes = usage
+	} else {
+		log.Debugf("missing memory usage for %s: %s", c.ContainerID, err)
+	}
+	if limit, err := c.parseMaxValue("memory.max"); err == nil {
+		ret.HierarchicalMemoryLimit = limit
+	}
+	if swap, err := c.ParseSingleStat(cgroupV2Target, "memory.swap.current"); err == nil {
+		ret.Swap = swap
+		ret.SwapPresent = true
+	}
+	// the `max` event counts the times the usage hit the limit, like v1's failcnt
+	if events, err := c.parseFlatKeyedFile("memory.events"); err == nil {
+		ret.MemFailCnt = events["max"]
+	}
+
+	return ret, nil
+}
+
+// memLimitV2 returns the memory limit of a cgroup v2, 0 if there is none
+func (c ContainerCgroup) memLimitV2() (uint64, error) {
+	v, err := c.parseMaxValue("memory.max")
+	if os.IsNotExist(err) {
+		log.Debugf("missing cgroup file: %s", c.cgroupFilePath(cgroupV2Target, "memory.max"))
+		return 0, nil
+	}
+	return v, err
+}
+
+// cpuV2 returns the CPU times of a cgroup v2. cpu.stat reports microseconds,
+// converted to USER_HZ like cpuacct.stat.
+func (c ContainerCgroup) cpuV2() (*CgroupTimesStat, error) {
+	ret := &CgroupTimesStat{ContainerID: c.ContainerID}
+	stats, err := c.parseFlatKeyedFile("cpu.stat")
+	if os.IsNotExist(err) {
+		log.Debugf("missing cgroup file: %s", c.cgroupFilePath(cgroupV2Target, "cpu.stat"))
+		return ret, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	ret.User = stats["user_usec"] * 1000 / uint64(NanoToUserHZDivisor)
+	ret.System = stats["system_usec"] * 1000 / uint64(NanoToUserHZDivisor)
+	ret.UsageTotal = float64(stats["usage_usec"]*1000) / NanoToUserHZDivisor
+	return ret, nil
+}
+
+// cpuLimitV2 reads the `$MAX $PERIOD` content of cpu.max, where $MAX
+// is `max` if there is no limit
+func (c ContainerCgroup) cpuLimitV2() (float64, error) {
+	maxFile := c.cgroupFilePath(cgroupV2Target, "cpu.max")
+	lines, err := readLines(maxFile)
+	if os.IsNotExist(err) {
+		log.Debugf("missing cgroup file: %s", maxFile)
+		return 100, nil
+	} else if err != nil {
+		return 0, err
+	}
+	if len(lines) != 1 {
+		return 0, fmt.Errorf("wrong file format: %s", maxFile)
+	}
+	fields := strings.Fields(lines[0])
+	if len(fields) != 2 {
+		return 0, fmt.Errorf("wrong file format: %s", maxFile)
+	}
+	if fields[0] == "max" {
+		return 100, nil
+	}
+	quota, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, err
+	}
+	period, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return 0, err
+	}
+	limit := 100.0
+	if (period > 0) && (quota > 0) {
+		limit = (quota / period) * 100.0
+	}
+	return limit, nil
+}
+
+// ioV2 returns the disk read and write bytes of a cgroup v2, summed over
+// all devices. Format:
+//
+// 8:16 rbytes=1459200 wbytes=314773504 rios=192 wios=353 dbytes=0 dios=0
+// 8:0 rbytes=90430464 wbytes=299008000 rios=8950 wios=1252 dbytes=50331648 dios=3021
+//
+func (c ContainerCgroup) ioV2() (*CgroupIOStat, error) {
+	ret := &CgroupIOStat{ContainerID: c.ContainerID}
+	statfile := c.cgroupFilePath(cgroupV2Target, "io.stat")
+	f, err := os.Open(statfile)
+	if os.IsNotExist(err) {
+		log.Debugf("missing cgroup file: %s", statfile)
+		return ret, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		for _, field := range fields[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			v, err := strconv.ParseUint(kv[1], 10, 64)
+			if err != nil {
+				continue
+			}
+			switch kv[0] {
+			case "rbytes":
+				ret.ReadBytes += v
+			case "wbytes":
+				ret.WriteBytes += v
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return ret, fmt.Errorf("error reading %s: %s", statfile, err)
+	}
+	return ret, nil
+}
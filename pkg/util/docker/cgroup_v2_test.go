@@ -0,0 +1,165 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package docker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const limitedContainerID = "47fc31db38b4fa0f4db44b99d0cad10e3cd4d5f142135a7721c1c95c1aadfb2e"
+
+const memoryStatV2 = `anon 10633216
+file 4325376
+kernel_stack 73728
+file_mapped 2297856
+anon_thp 2097152
+inactive_anon 0
+active_anon 10579968
+inactive_file 2568192
+active_file 1757184
+pgfault 9834
+pgmajfault 33
+`
+
+func TestCgroupV2Mem(t *testing.T) {
+	tempFolder, err := newTempFolder("mem-v2")
+	require.NoError(t, err)
+	defer tempFolder.removeAll()
+	cgroup := newDummyContainerCgroup(tempFolder.RootPath, cgroupV2Target)
+	require.True(t, cgroup.isCgroupV2())
+
+	// no limit, no memory.stat
+	tempFolder.add("cgroup2/memory.max", "max")
+	limit, err := cgroup.MemLimit()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), limit)
+	mem, err := cgroup.Mem()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), mem.RSS)
+
+	tempFolder.add("cgroup2/memory.stat", memoryStatV2)
+	tempFolder.add("cgroup2/memory.current", "16449536")
+	tempFolder.add("cgroup2/memory.max", "268435456")
+	tempFolder.add("cgroup2/memory.events", "low 0\nhigh 0\nmax 4\noom 1\noom_kill 1\n")
+	tempFolder.add("cgroup2/memory.swap.current", "0")
+
+	mem, err = cgroup.Mem()
+	require.NoError(t, err)
+	assert.Equal(t, "dummy", mem.ContainerID)
+	assert.Equal(t, uint64(10633216), mem.RSS)
+	assert.Equal(t, uint64(10633216), mem.TotalRSS)
+	assert.Equal(t, uint64(4325376), mem.Cache)
+	assert.Equal(t, uint64(2097152), mem.RSSHuge)
+	assert.Equal(t, uint64(2297856), mem.MappedFile)
+	assert.Equal(t, uint64(9834), mem.Pgfault)
+	assert.Equal(t, uint64(33), mem.TotalPgMajFault)
+	assert.Equal(t, uint64(10579968), mem.ActiveAnon)
+	assert.Equal(t, uint64(2568192), mem.InactiveFile)
+	assert.Equal(t, uint64(16449536), mem.MemUsageInBytes)
+	assert.Equal(t, uint64(268435456), mem.HierarchicalMemoryLimit)
+	assert.Equal(t, uint64(4), mem.MemFailCnt)
+	assert.True(t, mem.SwapPresent)
+	assert.Equal(t, uint64(0), mem.Swap)
+
+	limit, err = cgroup.MemLimit()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(268435456), limit)
+}
+
+func TestCgroupV2CPU(t *testing.T) {
+	tempFolder, err := newTempFolder("cpu-v2")
+	require.NoError(t, err)
+	defer tempFolder.removeAll()
+	cgroup := newDummyContainerCgroup(tempFolder.RootPath, cgroupV2Target)
+
+	tempFolder.add("cgroup2/cpu.stat", "usage_usec 915266418\nuser_usec 641400000\nsystem_usec 183270000\nnr_periods 1201\nnr_throttled 10\nthrottled_usec 18327\n")
+	cpu, err := cgroup.CPU()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(64140), cpu.User)
+	assert.Equal(t, uint64(18327), cpu.System)
+	assert.InDelta(t, 91526.6418, cpu.UsageTotal, 0.0000001)
+
+	throttled, err := cgroup.CPUNrThrottled()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(10), throttled)
+
+	// no cpu.max file
+	limit, err := cgroup.CPULimit()
+	require.NoError(t, err)
+	assert.Equal(t, 100.0, limit)
+
+	tempFolder.add("cgroup2/cpu.max", "max 100000")
+	limit, err = cgroup.CPULimit()
+	require.NoError(t, err)
+	assert.Equal(t, 100.0, limit)
+
+	tempFolder.add("cgroup2/cpu.max", "50000 100000")
+	limit, err = cgroup.CPULimit()
+	require.NoError(t, err)
+	assert.Equal(t, 50.0, limit)
+
+	tempFolder.add("cgroup2/cpu.max", "")
+	_, err = cgroup.CPULimit()
+	assert.Error(t, err)
+}
+
+func TestCgroupV2IO(t *testing.T) {
+	tempFolder, err := newTempFolder("io-v2")
+	require.NoError(t, err)
+	defer tempFolder.removeAll()
+	cgroup := newDummyContainerCgroup(tempFolder.RootPath, cgroupV2Target)
+
+	// missing file
+	io, err := cgroup.IO()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), io.ReadBytes)
+
+	tempFolder.add("cgroup2/io.stat", strings.Join([]string{
+		"8:16 rbytes=1459200 wbytes=314773504 rios=192 wios=353 dbytes=0 dios=0",
+		"8:0 rbytes=90430464 wbytes=299008000 rios=8950 wios=1252 dbytes=50331648 dios=3021",
+	}, "\n"))
+	io, err = cgroup.IO()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1459200+90430464), io.ReadBytes)
+	assert.Equal(t, uint64(314773504+299008000), io.WriteBytes)
+}
+
+func TestCgroupV2Detection(t *testing.T) {
+	mounts := parseCgroupMountPoints(strings.NewReader(strings.Join([]string{
+		"sysfs /sys sysfs rw,nosuid,nodev,noexec,relatime 0 0",
+		"cgroup2 /sys/fs/cgroup cgroup2 rw,nosuid,nodev,noexec,relatime,nsdelegate 0 0",
+	}, "\n")))
+	assert.Equal(t, map[string]string{cgroupV2Target: "/sys/fs/cgroup"}, mounts)
+
+	containerID, paths, err := parseCgroupPaths(strings.NewReader(
+		"0::/system.slice/docker-" + limitedContainerID + ".scope"))
+	require.NoError(t, err)
+	assert.Equal(t, limitedContainerID, containerID)
+	assert.Equal(t, map[string]string{cgroupV2Target: "/system.slice/docker-" + limitedContainerID + ".scope"}, paths)
+
+	cgroup := ContainerCgroup{Mounts: mounts, Paths: paths}
+	assert.True(t, cgroup.isCgroupV2())
+	assert.Equal(t, "/sys/fs/cgroup/system.slice/docker-"+limitedContainerID+".scope/memory.stat",
+		cgroup.cgroupFilePath("memory", "memory.stat"))
+
+	// hybrid hierarchy: the v1 controllers are used
+	mounts = parseCgroupMountPoints(strings.NewReader(strings.Join([]string{
+		"cgroup2 /sys/fs/cgroup/unified cgroup2 rw,nosuid,nodev,noexec,relatime,nsdelegate 0 0",
+		"cgroup /sys/fs/cgroup/memory cgroup rw,nosuid,nodev,noexec,relatime,memory 0 0",
+	}, "\n")))
+	assert.Equal(t, map[string]string{
+		cgroupV2Target: "/sys/fs/cgroup/unified",
+		"memory":       "/sys/fs/cgroup/memory",
+	}, mounts)
+	cgroup = ContainerCgroup{Mounts: mounts, Paths: map[string]string{"memory": "/docker/" + limitedContainerID}}
+	assert.False(t, cgroup.isCgroupV2())
+	assert.Equal(t, "/sys/fs/cgroup/memory/docker/"+limitedContainerID+"/memory.stat",
+		cgroup.cgroupFilePath("memory", "memory.stat"))
+}
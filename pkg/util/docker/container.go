@@ -30,14 +30,17 @@ type Container struct {
 	Pids     []int32
 	Excluded bool
 
-	CPULimit       float64
-	MemLimit       uint64
-	CPUNrThrottled uint64
-	CPU            *CgroupTimesStat
-	Memory         *CgroupMemStat
-	IO             *CgroupIOStat
-	Network        ContainerNetStats
-	StartedAt      int64
+	CPULimit         float64
+	MemLimit         uint64
+	CPUNrThrottled   uint64
+	CPUThrottledTime uint64
+	CPU              *CgroupTimesStat
+	Memory           *CgroupMemStat
+	MemEvents        *CgroupMemEvents
+	Pressure         *CgroupPressure
+	IO               *CgroupIOStat
+	Network          ContainerNetStats
+	StartedAt        int64
 
 	// For internal use only
 	cgroup *ContainerCgroup
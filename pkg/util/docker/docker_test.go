@@ -54,6 +54,18 @@ func TestParseContainerHealth(t *testing.T) {
 			input:    "Up 1 minute (health: unhealthy)",
 			expected: "unhealthy",
 		},
+		{
+			input:    "Up 3 hours (healthy)",
+			expected: "healthy",
+		},
+		{
+			input:    "Up 5 minutes (unhealthy)",
+			expected: "unhealthy",
+		},
+		{
+			input:    "Up 2 days (Paused)",
+			expected: "",
+		},
 	} {
 		assert.Equal(tc.expected, parseContainerHealth(tc.input), "test %d failed", i)
 	}
@@ -329,6 +329,19 @@ func (d *DockerUtil) Containers(cfg *ContainerListConfig) ([]*Container, error)
 			log.Debugf("Cgroup cpuNrThrottled: %s", err)
 			continue
 		}
+		// Newer statistics, missing on some kernels: don't skip the container
+		container.CPUThrottledTime, err = cgroup.CPUThrottledTime()
+		if err != nil {
+			log.Debugf("Cgroup cpuThrottledTime: %s", err)
+		}
+		container.MemEvents, err = cgroup.MemEvents()
+		if err != nil {
+			log.Debugf("Cgroup memory events: %s", err)
+		}
+		container.Pressure, err = cgroup.Pressure()
+		if err != nil {
+			log.Debugf("Cgroup pressure: %s", err)
+		}
 		container.IO, err = cgroup.IO()
 		if err != nil {
 			log.Debugf("Cgroup i/o: %s", err)
@@ -478,10 +491,11 @@ func (d *DockerUtil) invalidateCaches(containers []types.Container) {
 	d.Unlock()
 }
 
-var healthRe = regexp.MustCompile(`\(health: (\w+)\)`)
+var healthRe = regexp.MustCompile(`\((?:health: )?(starting|healthy|unhealthy)\)`)
 
 // Parse the health out of a container status. The format is either:
 //  - 'Up 5 seconds (health: starting)'
+//  - 'Up 2 minutes (healthy)' or 'Up 2 minutes (unhealthy)'
 //  - 'Up about an hour'
 //
 func parseContainerHealth(status string) string {
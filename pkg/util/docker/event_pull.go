@@ -24,12 +24,14 @@ import (
 )
 
 // openEventChannel just wraps the client.Event call with saner argument types.
-func (d *DockerUtil) openEventChannel(since, until time.Time, filter map[string]string) (<-chan events.Message, <-chan error) {
+func (d *DockerUtil) openEventChannel(since, until time.Time, filter map[string][]string) (<-chan events.Message, <-chan error) {
 	// Event since/until string can be formatted or hold a timestamp,
 	// see https://github.com/moby/moby/blob/7cbbbb95097f065757d38bcccdb1bbef81d10ddb/api/types/time/timestamp.go#L95
 	queryFilter := filters.NewArgs()
-	for k, v := range filter {
-		queryFilter.Add(k, v)
+	for k, values := range filter {
+		for _, v := range values {
+			queryFilter.Add(k, v)
+		}
 	}
 	options := types.EventsOptions{
 		Since:   fmt.Sprintf("%d.%09d", since.Unix(), int64(since.Nanosecond())),
@@ -44,6 +46,9 @@ func (d *DockerUtil) openEventChannel(since, until time.Time, filter map[string]
 // It can return nil, nil if the event is filtered out, one should check for nil pointers before using the event.
 func (d *DockerUtil) processContainerEvent(msg events.Message) (*ContainerEvent, error) {
 	// Type filtering
+	if msg.Type == "image" && msg.Action == ImagePullAction {
+		return d.processImagePullEvent(msg), nil
+	}
 	if msg.Type != "container" {
 		return nil, nil
 	}
@@ -96,11 +101,32 @@ func (d *DockerUtil) processContainerEvent(msg events.Message) (*ContainerEvent,
 	return event, nil
 }
 
+// processImagePullEvent formats an image pull event, whose actor is the
+// pulled image. It returns nil if the image is excluded.
+func (d *DockerUtil) processImagePullEvent(msg events.Message) *ContainerEvent {
+	imageName := msg.Actor.ID
+	if d.cfg.filter.computeIsExcluded("", imageName) {
+		log.Tracef("pull event of %s is skipped as the image is excluded for the event collection", imageName)
+		return nil
+	}
+	ns := msg.TimeNano
+	if ns > 1e10 {
+		ns = ns - msg.Time*1e9
+	}
+	return &ContainerEvent{
+		ImageName:  imageName,
+		Action:     msg.Action,
+		Timestamp:  time.Unix(msg.Time, ns),
+		Attributes: msg.Actor.Attributes,
+	}
+}
+
 // LatestContainerEvents returns events matching the filter that occurred after the time passed.
+// Along with the container events, it returns the image pull events, that have no container.
 // It returns the latest event timestamp in the slice for the user to store and pass again in the next call.
 func (d *DockerUtil) LatestContainerEvents(since time.Time) ([]*ContainerEvent, time.Time, error) {
 	var events []*ContainerEvent
-	filters := map[string]string{"type": "container"}
+	filters := map[string][]string{"type": {"container", "image"}}
 
 	msgChan, errorChan := d.openEventChannel(since, time.Now(), filters)
 
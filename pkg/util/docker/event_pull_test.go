@@ -123,6 +123,50 @@ func TestProcessContainerEvent(t *testing.T) {
 			event: nil,
 			err:   nil,
 		},
+		{
+			// Image pull event
+			source: events.Message{
+				Type: "image",
+				Actor: events.Actor{
+					ID: "redis:latest",
+					Attributes: map[string]string{
+						"name": "redis",
+					},
+				},
+				Action:   "pull",
+				Time:     timestamp.Unix(),
+				TimeNano: timestamp.UnixNano(),
+			},
+			event: &ContainerEvent{
+				ImageName: "redis:latest",
+				Action:    "pull",
+				Timestamp: timestamp,
+				Attributes: map[string]string{
+					"name": "redis",
+				},
+			},
+			err: nil,
+		},
+		{
+			// Ignore other image events
+			source: events.Message{
+				Type:   "image",
+				Actor:  events.Actor{ID: "redis:latest"},
+				Action: "tag",
+			},
+			event: nil,
+			err:   nil,
+		},
+		{
+			// Ignore pull events of excluded images
+			source: events.Message{
+				Type:   "image",
+				Actor:  events.Actor{ID: "excluded_image"},
+				Action: "pull",
+			},
+			event: nil,
+			err:   nil,
+		},
 		{
 			// Ignore excluded image name
 			source: events.Message{
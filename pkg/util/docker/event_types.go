@@ -11,7 +11,12 @@ import (
 	"time"
 )
 
-// ContainerEvent describes an event from the docker daemon
+// ImagePullAction is the action of the image pull events, that are the only
+// image events reported along with the container events
+const ImagePullAction = "pull"
+
+// ContainerEvent describes an event from the docker daemon.
+// ContainerID and ContainerName are empty for image pull events.
 type ContainerEvent struct {
 	ContainerID   string
 	ContainerName string
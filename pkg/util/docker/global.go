@@ -26,10 +26,12 @@ var (
 	// If new sub-structs are added to Container this must
 	// be updated.
 	NullContainer = &Container{
-		CPU:     &CgroupTimesStat{},
-		Memory:  &CgroupMemStat{},
-		IO:      &CgroupIOStat{},
-		Network: ContainerNetStats{},
+		CPU:       &CgroupTimesStat{},
+		Memory:    &CgroupMemStat{},
+		MemEvents: &CgroupMemEvents{},
+		Pressure:  &CgroupPressure{},
+		IO:        &CgroupIOStat{},
+		Network:   ContainerNetStats{},
 	}
 )
 
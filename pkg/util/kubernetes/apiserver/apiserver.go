@@ -11,6 +11,7 @@ import (
 	"context"
 	"encoding/json"
 	"io/ioutil"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
@@ -19,11 +20,12 @@ import (
 	"github.com/ericchiang/k8s"
 
 	"github.com/ericchiang/k8s/api/v1"
+	autoscalingv1 "github.com/ericchiang/k8s/apis/autoscaling/v1"
 
 	"fmt"
 
 	"github.com/DataDog/datadog-agent/pkg/config"
-	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
+	"github.com/DataDog/datadog-agent/pkg/errors"
 	"github.com/DataDog/datadog-agent/pkg/util/cache"
 	"github.com/DataDog/datadog-agent/pkg/util/retry"
 )
@@ -36,6 +38,7 @@ const (
 	tokenTime         = "tokenTimestamp"
 	tokenKey          = "tokenKey"
 	servicesPollIntl  = 10 * time.Second
+	metadataPollIntl  = time.Minute
 	serviceMapExpire  = 5 * time.Minute
 )
 
@@ -47,6 +50,10 @@ type APIClient struct {
 
 	client  *k8s.Client
 	timeout time.Duration
+
+	// stateStores cache the ClusterState, created on the first GetClusterState
+	stateStores []*stateStore
+	stateOnce   sync.Once
 }
 
 // GetAPIClient returns the shared ApiClient instance.
@@ -120,24 +127,36 @@ func (c *APIClient) connect() error {
 	return nil
 }
 
-// ServiceMapperBundle maps the podNames to the serviceNames they are associated with.
-// It is updated by mapServices in services.go
-type ServiceMapperBundle struct {
+// MetadataMapperBundle maps the podNames to the serviceNames they are associated with,
+// and to their owner chain. It is updated by mapServices in services.go and mapOwners
+// in metadata.go
+type MetadataMapperBundle struct {
 	PodNameToServices map[string][]string
+	PodNameToOwners   map[string][]OwnerReference
 	m                 sync.RWMutex
 }
 
-func newServiceMapperBundle() *ServiceMapperBundle {
-	return &ServiceMapperBundle{
+// ServiceMapperBundle is the former name of MetadataMapperBundle.
+//
+// Deprecated: use MetadataMapperBundle instead.
+type ServiceMapperBundle = MetadataMapperBundle
+
+func newMetadataMapperBundle() *MetadataMapperBundle {
+	return &MetadataMapperBundle{
 		PodNameToServices: make(map[string][]string),
+		PodNameToOwners:   make(map[string][]OwnerReference),
 	}
 }
 
 // startServiceMapping is only called once, when we have confirmed we could correctly connect to the API server.
-// The logic here is solely to retrieve Nodes, Pods and Endpoints. The processing part is in mapServices.
+// The logic here is solely to retrieve Nodes, Namespaces, Pods, Endpoints and the owners of the pods.
+// The processing part is in mapServices and mapOwners.
+// Namespaces and owners change less often, they are only refreshed every metadataPollIntl.
 func (c *APIClient) startServiceMapping() {
 	tickerSvcProcess := time.NewTicker(servicesPollIntl)
 	go func() {
+		var parents map[string]OwnerReference
+		var lastMetadataPoll time.Time
 		for {
 			select {
 			case <-tickerSvcProcess.C:
@@ -167,18 +186,28 @@ func (c *APIClient) startServiceMapping() {
 					log.Errorf("Could not collect pods from the API Server: %q", err.Error())
 					continue
 				}
+				// The namespaces and the owners are optional, to not require more RBAC permissions
+				if time.Since(lastMetadataPoll) >= metadataPollIntl {
+					c.cacheNamespaceLabels(ctx)
+					parents = c.ownerParents(ctx, *pods)
+					lastMetadataPoll = time.Now()
+				}
 
 				for _, node := range nodes.Items {
-					smb, found := cache.Cache.Get(*node.Metadata.Name)
+					nodeName := node.Metadata.GetName()
+					cache.Cache.Set(nodeLabelsCacheKey(nodeName), node.Metadata.GetLabels(), serviceMapExpire)
+
+					mmb, found := cache.Cache.Get(nodeName)
 					if !found {
-						smb = newServiceMapperBundle()
+						mmb = newMetadataMapperBundle()
 					}
-					err := smb.(*ServiceMapperBundle).mapServices(*node.Metadata.Name, *pods, *endpointList)
+					err := mmb.(*MetadataMapperBundle).mapServices(nodeName, *pods, *endpointList)
 					if err != nil {
-						log.Errorf("Could not map the services: %s on node %s", err.Error(), *node.Metadata.Name)
+						log.Errorf("Could not map the services: %s on node %s", err.Error(), nodeName)
 						continue
 					}
-					cache.Cache.Set(*node.Metadata.Name, smb, serviceMapExpire)
+					mmb.(*MetadataMapperBundle).mapOwners(nodeName, *pods, parents)
+					cache.Cache.Set(nodeName, mmb, serviceMapExpire)
 				}
 			}
 		}
@@ -246,6 +275,131 @@ func (c *APIClient) ComponentStatuses() (*v1.ComponentStatusList, error) {
 	return c.client.CoreV1().ListComponentStatuses(ctx)
 }
 
+// ListConfigMaps returns the ConfigMaps of a namespace (all namespaces if empty)
+// matching the given label selector
+func (c *APIClient) ListConfigMaps(namespace, labelSelector string) (*v1.ConfigMapList, error) {
+	var options []k8s.Option
+	if labelSelector != "" {
+		selector, err := parseLabelSelector(labelSelector)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, selector.Selector())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	return c.client.CoreV1().ListConfigMaps(ctx, namespace, options...)
+}
+
+// labelRegexp matches the label keys and values accepted by k8s.LabelSelector
+var labelRegexp = regexp.MustCompile("^([A-Za-z0-9][-A-Za-z0-9_./]*)?[A-Za-z0-9]$")
+
+// parseLabelSelector parses a comma separated list of equality selectors
+// (key=value, key==value or key!=value). k8s.LabelSelector silently drops
+// the invalid labels, which would select more objects than asked for, so
+// they are rejected here.
+func parseLabelSelector(labelSelector string) (*k8s.LabelSelector, error) {
+	selector := new(k8s.LabelSelector)
+	for _, requirement := range strings.Split(labelSelector, ",") {
+		add := selector.Eq
+		parts := strings.SplitN(requirement, "!=", 2)
+		if len(parts) == 2 {
+			add = selector.NotEq
+		} else {
+			parts = strings.SplitN(strings.Replace(requirement, "==", "=", 1), "=", 2)
+		}
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("unsupported label selector %q, only equality selectors are supported", requirement)
+		}
+		key, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		if len(key) > 63 || len(value) > 63 || !labelRegexp.MatchString(key) || !labelRegexp.MatchString(value) {
+			return nil, fmt.Errorf("invalid label selector %q", requirement)
+		}
+		add(key, value)
+	}
+	return selector, nil
+}
+
+// GetPod returns a Pod
+func (c *APIClient) GetPod(namespace, name string) (*v1.Pod, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	return c.client.CoreV1().GetPod(ctx, name, namespace)
+}
+
+// GetConfigMap returns a ConfigMap
+func (c *APIClient) GetConfigMap(namespace, name string) (*v1.ConfigMap, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	return c.client.CoreV1().GetConfigMap(ctx, name, namespace)
+}
+
+// CreateConfigMap creates a ConfigMap, in the namespace of its metadata
+func (c *APIClient) CreateConfigMap(configMap *v1.ConfigMap) (*v1.ConfigMap, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	return c.client.CoreV1().CreateConfigMap(ctx, configMap)
+}
+
+// UpdateConfigMap updates a ConfigMap. The update fails with a conflict if
+// the resource version of its metadata is not the latest one.
+func (c *APIClient) UpdateConfigMap(configMap *v1.ConfigMap) (*v1.ConfigMap, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	return c.client.CoreV1().UpdateConfigMap(ctx, configMap)
+}
+
+// ListServices returns the Services of a namespace (all namespaces if empty)
+func (c *APIClient) ListServices(namespace string) (*v1.ServiceList, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	return c.client.CoreV1().ListServices(ctx, namespace)
+}
+
+// ListHorizontalPodAutoscalers returns the HorizontalPodAutoscalers of all
+// namespaces
+func (c *APIClient) ListHorizontalPodAutoscalers() (*autoscalingv1.HorizontalPodAutoscalerList, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	return c.client.AutoscalingV1().ListHorizontalPodAutoscalers(ctx, "")
+}
+
+// WatchHorizontalPodAutoscalers watches the changes of the
+// HorizontalPodAutoscalers of all namespaces since a resource version.
+// The watch has no timeout, it lasts until the apiserver ends it or the
+// watcher is closed.
+func (c *APIClient) WatchHorizontalPodAutoscalers(resourceVersion string) (*k8s.AutoscalingV1HorizontalPodAutoscalerWatcher, error) {
+	return c.client.AutoscalingV1().WatchHorizontalPodAutoscalers(context.Background(), "", k8s.ResourceVersion(resourceVersion))
+}
+
+// NodeLabels returns the labels of a node
+func (c *APIClient) NodeLabels(nodeName string) (map[string]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	node, err := c.client.CoreV1().GetNode(ctx, nodeName)
+	if err != nil {
+		return nil, err
+	}
+	if node.Metadata == nil {
+		return nil, nil
+	}
+	return node.Metadata.Labels, nil
+}
+
+// NamespaceLabels returns the labels of a namespace
+func (c *APIClient) NamespaceLabels(namespace string) (map[string]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	ns, err := c.client.CoreV1().GetNamespace(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if ns.Metadata == nil {
+		return nil, nil
+	}
+	return ns.Metadata.Labels, nil
+}
+
 // GetTokenFromConfigmap returns the value of the `tokenValue` from the `tokenKey` in the ConfigMap `configMapDCAToken` if its timestamp is less than tokenTimeout old.
 func (c *APIClient) GetTokenFromConfigmap(token string, tokenTimeout int64) (string, bool, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
@@ -253,14 +407,14 @@ func (c *APIClient) GetTokenFromConfigmap(token string, tokenTimeout int64) (str
 	tokenConfigMap, err := c.client.CoreV1().GetConfigMap(ctx, configMapDCAToken, defaultNamespace)
 	if err != nil {
 		log.Debugf("Could not find the ConfigMap %s: %s", configMapDCAToken, err.Error())
-		return "", false, collectors.ErrNotFound
+		return "", false, errors.ErrNotFound
 	}
 	log.Infof("Found the ConfigMap %s", configMapDCAToken)
 
 	tokenValue, found := tokenConfigMap.Data[fmt.Sprintf("%s.%s", token, tokenKey)]
 	if !found {
 		log.Errorf("%s was not found in the ConfigMap %s", token, configMapDCAToken)
-		return "", found, collectors.ErrNotFound
+		return "", found, errors.ErrNotFound
 	}
 	log.Tracef("%s is %s", token, tokenValue)
 
@@ -269,7 +423,7 @@ func (c *APIClient) GetTokenFromConfigmap(token string, tokenTimeout int64) (str
 	if !set {
 		log.Debugf("Could not find timestamp associated with %s in the ConfigMap %s. Refreshing.", token, configMapDCAToken)
 		// We return ErrOutdated to reset the tokenValue and its timestamp as token's timestamp was not found.
-		return tokenValue, found, collectors.ErrOutdated
+		return tokenValue, found, errors.ErrOutdated
 	}
 
 	tokenTime, err := time.Parse(time.RFC822, tokenTimeStr)
@@ -280,7 +434,7 @@ func (c *APIClient) GetTokenFromConfigmap(token string, tokenTimeout int64) (str
 
 	if tokenAge > tokenTimeout {
 		log.Debugf("The tokenValue %s is outdated, refreshing the state", token)
-		return tokenValue, found, collectors.ErrOutdated
+		return tokenValue, found, errors.ErrOutdated
 	}
 	log.Debugf("Token %s was updated recently, using value to collect newer events.", token)
 	return tokenValue, found, nil
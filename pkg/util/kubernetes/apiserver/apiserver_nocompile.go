@@ -24,3 +24,21 @@ func GetPodSvcs(nodeName string, podName string) []string {
 	log.Errorf("GetPodSvcs not implemented %s", ErrNotCompiled.Error())
 	return nil
 }
+
+// GetPodMetadata is used when the API endpoint of the DCA to get the metadata of a pod is hit.
+func GetPodMetadata(nodeName string, podName string) *PodMetadata {
+	log.Errorf("GetPodMetadata not implemented %s", ErrNotCompiled.Error())
+	return nil
+}
+
+// GetNodeLabels is used when the API endpoint of the DCA to get the labels of a node is hit.
+func GetNodeLabels(nodeName string) (map[string]string, bool) {
+	log.Errorf("GetNodeLabels not implemented %s", ErrNotCompiled.Error())
+	return nil, false
+}
+
+// GetNamespaceLabels is used when the API endpoint of the DCA to get the labels of a namespace is hit.
+func GetNamespaceLabels(namespace string) (map[string]string, bool) {
+	log.Errorf("GetNamespaceLabels not implemented %s", ErrNotCompiled.Error())
+	return nil, false
+}
@@ -0,0 +1,27 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build kubeapiserver
+
+package apiserver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLabelSelector(t *testing.T) {
+	for _, selector := range []string{"ad.datadoghq.com/checks=true", "app==web, tier!=db"} {
+		_, err := parseLabelSelector(selector)
+		assert.NoError(t, err, selector)
+	}
+
+	// k8s.LabelSelector would drop these and select every ConfigMap
+	for _, selector := range []string{"app", "app in (web)", "app=", "app=web/", ",app=web"} {
+		_, err := parseLabelSelector(selector)
+		assert.Error(t, err, selector)
+	}
+}
@@ -0,0 +1,193 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build kubeapiserver
+
+package leaderelection
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ericchiang/k8s"
+	"github.com/ericchiang/k8s/api/v1"
+	metav1 "github.com/ericchiang/k8s/apis/meta/v1"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/cache"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver"
+)
+
+const (
+	leaderElectionConfigMap = "datadog-leader-election"
+	defaultNamespace        = "default"
+	// podNamespaceEnv is set to the namespace of the pod with the downward API
+	podNamespaceEnv = "DD_POD_NAMESPACE"
+	// leaderAnnotation is the annotation of the client-go ConfigMap lock
+	leaderAnnotation = "control-plane.alpha.kubernetes.io/leader"
+	// leaderIPCachePrefix caches the IP of the leader pods, by pod name
+	leaderIPCachePrefix = "leaderIP"
+)
+
+// GetLeaderEngine returns the shared LeaderEngine instance, identified by the
+// hostname, which is the pod name in kubernetes
+func GetLeaderEngine() (*LeaderEngine, error) {
+	globalLeaderEngineMutex.Lock()
+	defer globalLeaderEngineMutex.Unlock()
+	if globalLeaderEngine != nil {
+		return globalLeaderEngine, nil
+	}
+
+	client, err := apiserver.GetAPIClient()
+	if err != nil {
+		return nil, err
+	}
+	holderIdentity, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	leaseDuration := time.Duration(config.Datadog.GetInt("leader_lease_duration")) * time.Second
+	if leaseDuration <= 0 {
+		return nil, fmt.Errorf("invalid leader_lease_duration: %s", leaseDuration)
+	}
+	lock := &configMapLock{
+		client:    client,
+		namespace: leaderElectionNamespace(),
+		name:      leaderElectionConfigMap,
+	}
+	globalLeaderEngine = newLeaderEngine(holderIdentity, leaseDuration, lock)
+	return globalLeaderEngine, nil
+}
+
+// GetLeaderIP returns the IP of the leader pod, for the other replicas to
+// forward it the requests only the leader answers. The identities being the
+// pod names, the pod of the leader is queried from the apiserver and its IP
+// cached for the lease duration.
+func (le *LeaderEngine) GetLeaderIP() (string, error) {
+	leader := le.GetLeader()
+	if leader == "" {
+		return "", errors.New("no leader elected yet")
+	}
+	cacheKey := cache.BuildAgentKey(leaderIPCachePrefix, leader)
+	if ip, found := cache.Cache.Get(cacheKey); found {
+		return ip.(string), nil
+	}
+
+	client, err := apiserver.GetAPIClient()
+	if err != nil {
+		return "", err
+	}
+	pod, err := client.GetPod(leaderElectionNamespace(), leader)
+	if err != nil {
+		return "", fmt.Errorf("could not get the leader pod %s: %s", leader, err)
+	}
+	ip := pod.GetStatus().GetPodIP()
+	if ip == "" {
+		return "", fmt.Errorf("the leader pod %s has no IP", leader)
+	}
+	cache.Cache.Set(cacheKey, ip, le.LeaseDuration)
+	return ip, nil
+}
+
+// For testing purpose
+var serviceAccountNamespacePath = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// leaderElectionNamespace returns the namespace of the pod, from the
+// DD_POD_NAMESPACE environment variable or from the service account, and
+// falls back to the default namespace
+func leaderElectionNamespace() string {
+	if namespace := os.Getenv(podNamespaceEnv); namespace != "" {
+		return namespace
+	}
+	if content, err := ioutil.ReadFile(serviceAccountNamespacePath); err == nil {
+		if namespace := strings.TrimSpace(string(content)); namespace != "" {
+			return namespace
+		}
+	}
+	return defaultNamespace
+}
+
+// configMapLock stores the record in an annotation of a ConfigMap
+type configMapLock struct {
+	client    *apiserver.APIClient
+	namespace string
+	name      string
+}
+
+func (l *configMapLock) get() (*LeaderElectionRecord, string, error) {
+	configMap, err := l.client.GetConfigMap(l.namespace, l.name)
+	if err != nil {
+		if isAPIError(err, http.StatusNotFound) {
+			return nil, "", errLockNotFound
+		}
+		return nil, "", err
+	}
+	record := &LeaderElectionRecord{}
+	if value, found := configMap.Metadata.GetAnnotations()[leaderAnnotation]; found {
+		if err := json.Unmarshal([]byte(value), record); err != nil {
+			return nil, "", err
+		}
+	}
+	return record, configMap.Metadata.GetResourceVersion(), nil
+}
+
+func (l *configMapLock) create(record LeaderElectionRecord) error {
+	configMap, err := l.configMap(record, "")
+	if err != nil {
+		return err
+	}
+	_, err = l.client.CreateConfigMap(configMap)
+	if isAPIError(err, http.StatusConflict) {
+		return errLockConflict
+	}
+	return err
+}
+
+func (l *configMapLock) update(record LeaderElectionRecord, version string) error {
+	configMap, err := l.configMap(record, version)
+	if err != nil {
+		return err
+	}
+	_, err = l.client.UpdateConfigMap(configMap)
+	if isAPIError(err, http.StatusConflict) {
+		return errLockConflict
+	}
+	return err
+}
+
+func (l *configMapLock) describe() string {
+	return fmt.Sprintf("ConfigMap %s/%s", l.namespace, l.name)
+}
+
+// configMap returns the ConfigMap holding the record, the resource version
+// making the apiserver reject concurrent updates
+func (l *configMapLock) configMap(record LeaderElectionRecord, version string) (*v1.ConfigMap, error) {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	configMap := &v1.ConfigMap{
+		Metadata: &metav1.ObjectMeta{
+			Name:        k8s.String(l.name),
+			Namespace:   k8s.String(l.namespace),
+			Annotations: map[string]string{leaderAnnotation: string(value)},
+		},
+	}
+	if version != "" {
+		configMap.Metadata.ResourceVersion = k8s.String(version)
+	}
+	return configMap, nil
+}
+
+func isAPIError(err error, code int) bool {
+	apiErr, ok := err.(*k8s.APIError)
+	return ok && apiErr.Code == code
+}
@@ -0,0 +1,213 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build kubeapiserver
+
+package leaderelection
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ericchiang/k8s/api/v1"
+	metav1 "github.com/ericchiang/k8s/apis/meta/v1"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver/testutil"
+)
+
+const testKubeConfig = `{
+  "apiVersion": "v1",
+  "kind": "Config",
+  "clusters": [{"name": "test", "cluster": {"server": "%s"}}],
+  "contexts": [{"name": "test", "context": {"cluster": "test", "user": "test"}}],
+  "current-context": "test",
+  "users": [{"name": "test", "user": {}}]
+}`
+
+const testConfigMapPath = "/api/v1/namespaces/datadog/configmaps/" + leaderElectionConfigMap
+
+// fakeAPIServer stores a single ConfigMap, rejecting the updates of outdated
+// resource versions like the apiserver, and serves the dca-1 pod
+type fakeAPIServer struct {
+	sync.Mutex
+	t         *testing.T
+	configMap *v1.ConfigMap
+	version   int
+	podGets   int
+}
+
+func (s *fakeAPIServer) writeStatus(w http.ResponseWriter, code int, reason string) {
+	testutil.WriteProto(s.t, w, code, &metav1.Status{
+		Status: proto.String("Failure"),
+		Reason: proto.String(reason),
+		Code:   proto.Int32(int32(code)),
+	})
+}
+
+// readConfigMap decodes the protobuf ConfigMap sent by the client
+func (s *fakeAPIServer) readConfigMap(r *http.Request) *v1.ConfigMap {
+	body, err := ioutil.ReadAll(r.Body)
+	require.NoError(s.t, err)
+	configMap := &v1.ConfigMap{}
+	testutil.DecodeProto(s.t, body, configMap)
+	return configMap
+}
+
+func (s *fakeAPIServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	switch {
+	case r.URL.Path == "/version":
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"major": "1", "minor": "9", "gitVersion": "v1.9.0"}`)
+	case r.URL.Path == "/api/v1/events":
+		testutil.WriteProto(s.t, w, http.StatusOK, &v1.EventList{})
+	case r.URL.Path == "/api/v1/namespaces/datadog/pods/dca-1":
+		s.podGets++
+		testutil.WriteProto(s.t, w, http.StatusOK, &v1.Pod{
+			Metadata: &metav1.ObjectMeta{Name: proto.String("dca-1")},
+			Status:   &v1.PodStatus{PodIP: proto.String("10.4.2.7")},
+		})
+	case r.URL.Path == testConfigMapPath && r.Method == "GET":
+		if s.configMap == nil {
+			s.writeStatus(w, http.StatusNotFound, "NotFound")
+			return
+		}
+		testutil.WriteProto(s.t, w, http.StatusOK, s.configMap)
+	case r.URL.Path == "/api/v1/namespaces/datadog/configmaps" && r.Method == "POST":
+		if s.configMap != nil {
+			s.writeStatus(w, http.StatusConflict, "AlreadyExists")
+			return
+		}
+		s.store(s.readConfigMap(r))
+		testutil.WriteProto(s.t, w, http.StatusCreated, s.configMap)
+	case r.URL.Path == testConfigMapPath && r.Method == "PUT":
+		configMap := s.readConfigMap(r)
+		if s.configMap == nil || configMap.Metadata.GetResourceVersion() != strconv.Itoa(s.version) {
+			s.writeStatus(w, http.StatusConflict, "Conflict")
+			return
+		}
+		s.store(configMap)
+		testutil.WriteProto(s.t, w, http.StatusOK, s.configMap)
+	default:
+		s.t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		http.NotFound(w, r)
+	}
+}
+
+func (s *fakeAPIServer) store(configMap *v1.ConfigMap) {
+	s.version++
+	configMap.Metadata.ResourceVersion = proto.String(strconv.Itoa(s.version))
+	s.configMap = configMap
+}
+
+func TestConfigMapLock(t *testing.T) {
+	apiServer := &fakeAPIServer{t: t}
+	ts := httptest.NewServer(apiServer)
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "configmap_lock_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	kubeConfigPath := filepath.Join(dir, "kubeconfig.json")
+	err = ioutil.WriteFile(kubeConfigPath, []byte(fmt.Sprintf(testKubeConfig, ts.URL)), 0600)
+	require.NoError(t, err)
+
+	config.Datadog.Set("kubernetes_kubeconfig_path", kubeConfigPath)
+	config.Datadog.Set("use_service_mapper", false)
+	defer config.Datadog.Set("kubernetes_kubeconfig_path", "")
+	defer config.Datadog.Set("use_service_mapper", true)
+
+	client, err := apiserver.GetAPIClient()
+	require.NoError(t, err)
+	lock := &configMapLock{
+		client:    client,
+		namespace: "datadog",
+		name:      leaderElectionConfigMap,
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	record := LeaderElectionRecord{
+		HolderIdentity:       "dca-1",
+		LeaseDurationSeconds: 60,
+		AcquireTime:          now,
+		RenewTime:            now,
+	}
+
+	// the ConfigMap does not exist yet
+	_, _, err = lock.get()
+	assert.Equal(t, errLockNotFound, err)
+
+	require.NoError(t, lock.create(record))
+	assert.Equal(t, errLockConflict, lock.create(record))
+
+	// the record is stored in the annotation of the ConfigMap
+	var stored LeaderElectionRecord
+	require.NoError(t, json.Unmarshal([]byte(apiServer.configMap.Metadata.GetAnnotations()[leaderAnnotation]), &stored))
+	assert.Equal(t, record, stored)
+
+	got, version, err := lock.get()
+	require.NoError(t, err)
+	assert.Equal(t, "1", version)
+	assert.Equal(t, record, *got)
+
+	// updates of outdated versions are rejected
+	record.RenewTime = now.Add(10 * time.Second)
+	require.NoError(t, lock.update(record, version))
+	assert.Equal(t, errLockConflict, lock.update(record, version))
+
+	got, version, err = lock.get()
+	require.NoError(t, err)
+	assert.Equal(t, "2", version)
+	assert.Equal(t, record, *got)
+
+	// the followers get the IP of the leader pod, queried once
+	os.Setenv(podNamespaceEnv, "datadog")
+	defer os.Unsetenv(podNamespaceEnv)
+	engine := newLeaderEngine("dca-2", time.Minute, lock)
+	_, err = engine.GetLeaderIP()
+	assert.Error(t, err)
+	engine.setObservedRecord(record, time.Time{})
+	for i := 0; i < 2; i++ {
+		ip, err := engine.GetLeaderIP()
+		require.NoError(t, err)
+		assert.Equal(t, "10.4.2.7", ip)
+	}
+	assert.Equal(t, 1, apiServer.podGets)
+}
+
+func TestLeaderElectionNamespace(t *testing.T) {
+	dir, err := ioutil.TempDir("", "configmap_lock_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	defer func(path string) { serviceAccountNamespacePath = path }(serviceAccountNamespacePath)
+	serviceAccountNamespacePath = filepath.Join(dir, "namespace")
+	defer os.Unsetenv(podNamespaceEnv)
+
+	// no service account
+	assert.Equal(t, "default", leaderElectionNamespace())
+
+	require.NoError(t, ioutil.WriteFile(serviceAccountNamespacePath, []byte("datadog\n"), 0600))
+	assert.Equal(t, "datadog", leaderElectionNamespace())
+
+	// the downward API takes precedence
+	os.Setenv(podNamespaceEnv, "monitoring")
+	assert.Equal(t, "monitoring", leaderElectionNamespace())
+}
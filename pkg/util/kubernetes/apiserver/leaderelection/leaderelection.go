@@ -0,0 +1,237 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// Package leaderelection elects a leader among the replicas of the cluster
+// agent, for the cluster checks and the event collection to run once per
+// cluster. The leader holds a lease in a ConfigMap annotation, in the format
+// of the client-go ConfigMap lock, and renews it before it expires. The other
+// replicas take the lease over when it expires.
+package leaderelection
+
+import (
+	"errors"
+	"expvar"
+	"sync"
+	"time"
+
+	log "github.com/cihub/seelog"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+)
+
+const (
+	// leaderMetric reports 1 on the leader replica and 0 on the others
+	leaderMetric = "datadog.cluster_agent.leader_election.is_leader"
+)
+
+var (
+	globalLeaderEngine      *LeaderEngine
+	globalLeaderEngineMutex sync.Mutex
+
+	errLockNotFound = errors.New("leader election lock not found")
+	errLockConflict = errors.New("leader election lock updated concurrently")
+)
+
+// LeaderElectionRecord is the lease stored in the lock
+type LeaderElectionRecord struct {
+	HolderIdentity       string    `json:"holderIdentity"`
+	LeaseDurationSeconds int       `json:"leaseDurationSeconds"`
+	AcquireTime          time.Time `json:"acquireTime"`
+	RenewTime            time.Time `json:"renewTime"`
+	LeaderTransitions    int       `json:"leaderTransitions"`
+}
+
+// resourceLock stores the leader election record. Updates are rejected with
+// errLockConflict if the record changed since it was read, like the
+// resource versions of the apiserver.
+type resourceLock interface {
+	// get returns the record and its version, or errLockNotFound
+	get() (*LeaderElectionRecord, string, error)
+	create(record LeaderElectionRecord) error
+	update(record LeaderElectionRecord, version string) error
+	describe() string
+}
+
+// LeaderEngine runs the election for a replica, use the shared instance via
+// GetLeaderEngine
+type LeaderEngine struct {
+	HolderIdentity string
+	LeaseDuration  time.Duration
+	// RenewDeadline is how long the leader keeps acting as such without
+	// renewing its lease, shorter than LeaseDuration for another replica
+	// to not take over while it still acts as the leader
+	RenewDeadline time.Duration
+	RetryPeriod   time.Duration
+
+	lock    resourceLock
+	runOnce sync.Once
+	stop    chan struct{}
+
+	m sync.RWMutex
+	// observedRecord is the last record read or written
+	observedRecord LeaderElectionRecord
+	// lastRenew is when the lease was last acquired or renewed by this
+	// replica
+	lastRenew time.Time
+}
+
+func newLeaderEngine(holderIdentity string, leaseDuration time.Duration, lock resourceLock) *LeaderEngine {
+	return &LeaderEngine{
+		HolderIdentity: holderIdentity,
+		LeaseDuration:  leaseDuration,
+		RenewDeadline:  leaseDuration * 2 / 3,
+		RetryPeriod:    leaseDuration / 6,
+		lock:           lock,
+		stop:           make(chan struct{}),
+	}
+}
+
+// EnsureLeaderElectionRuns starts the election loop if it is not running yet
+func (le *LeaderEngine) EnsureLeaderElectionRuns() {
+	le.runOnce.Do(func() {
+		log.Infof("Starting the leader election as %s on %s", le.HolderIdentity, le.lock.describe())
+		go le.run()
+	})
+}
+
+// StopLeaderElection stops the election loop. The lease is not released,
+// another replica takes it over when it expires.
+func (le *LeaderEngine) StopLeaderElection() {
+	close(le.stop)
+}
+
+func (le *LeaderEngine) run() {
+	ticker := time.NewTicker(le.RetryPeriod)
+	defer ticker.Stop()
+	for {
+		wasLeader := le.IsLeader()
+		le.tryAcquireOrRenew(time.Now())
+		isLeader := le.IsLeader()
+		if isLeader != wasLeader {
+			if isLeader {
+				log.Infof("%s is now the leader", le.HolderIdentity)
+			} else {
+				log.Infof("%s is no longer the leader, the leader is %s", le.HolderIdentity, le.GetLeader())
+			}
+		}
+		le.reportMetric(isLeader)
+
+		select {
+		case <-ticker.C:
+		case <-le.stop:
+			return
+		}
+	}
+}
+
+// tryAcquireOrRenew acquires the lease if it is free or expired, or renews
+// it if this replica holds it. It returns whether this replica holds the
+// lease.
+func (le *LeaderEngine) tryAcquireOrRenew(now time.Time) bool {
+	record := LeaderElectionRecord{
+		HolderIdentity:       le.HolderIdentity,
+		LeaseDurationSeconds: int(le.LeaseDuration / time.Second),
+		AcquireTime:          now,
+		RenewTime:            now,
+	}
+
+	current, version, err := le.lock.get()
+	if err == errLockNotFound {
+		if err = le.lock.create(record); err != nil {
+			log.Debugf("Cannot create the leader election lock %s: %s", le.lock.describe(), err)
+			return false
+		}
+		le.setObservedRecord(record, now)
+		return true
+	}
+	if err != nil {
+		log.Debugf("Cannot get the leader election lock %s: %s", le.lock.describe(), err)
+		return false
+	}
+
+	expiry := current.RenewTime.Add(time.Duration(current.LeaseDurationSeconds) * time.Second)
+	if current.HolderIdentity != le.HolderIdentity && current.HolderIdentity != "" && now.Before(expiry) {
+		le.setObservedRecord(*current, time.Time{})
+		return false
+	}
+
+	if current.HolderIdentity == le.HolderIdentity {
+		record.AcquireTime = current.AcquireTime
+		record.LeaderTransitions = current.LeaderTransitions
+	} else {
+		record.LeaderTransitions = current.LeaderTransitions + 1
+	}
+	if err = le.lock.update(record, version); err != nil {
+		// another replica acquired it first on conflicts
+		log.Debugf("Cannot update the leader election lock %s: %s", le.lock.describe(), err)
+		return false
+	}
+	le.setObservedRecord(record, now)
+	return true
+}
+
+// setObservedRecord stores the last known record, and the time this replica
+// acquired or renewed the lease, zero if another replica holds it
+func (le *LeaderEngine) setObservedRecord(record LeaderElectionRecord, renew time.Time) {
+	le.m.Lock()
+	defer le.m.Unlock()
+	le.observedRecord = record
+	le.lastRenew = renew
+}
+
+// IsLeader returns whether this replica is the leader: it holds the lease
+// and renewed it within the renew deadline
+func (le *LeaderEngine) IsLeader() bool {
+	le.m.RLock()
+	defer le.m.RUnlock()
+	return le.observedRecord.HolderIdentity == le.HolderIdentity &&
+		time.Since(le.lastRenew) < le.RenewDeadline
+}
+
+// GetLeader returns the identity of the last known leader
+func (le *LeaderEngine) GetLeader() string {
+	le.m.RLock()
+	defer le.m.RUnlock()
+	return le.observedRecord.HolderIdentity
+}
+
+// getStatus returns the state of the election, for the agent status
+func (le *LeaderEngine) getStatus() map[string]interface{} {
+	isLeader := le.IsLeader()
+	le.m.RLock()
+	defer le.m.RUnlock()
+	return map[string]interface{}{
+		"holderIdentity":    le.HolderIdentity,
+		"isLeader":          isLeader,
+		"leaderIdentity":    le.observedRecord.HolderIdentity,
+		"leaderTransitions": le.observedRecord.LeaderTransitions,
+		"acquireTime":       le.observedRecord.AcquireTime,
+		"renewTime":         le.observedRecord.RenewTime,
+	}
+}
+
+func (le *LeaderEngine) reportMetric(isLeader bool) {
+	sender, err := aggregator.GetDefaultSender()
+	if err != nil {
+		return
+	}
+	value := 0.0
+	if isLeader {
+		value = 1.0
+	}
+	sender.Gauge(leaderMetric, value, "", nil)
+	sender.Commit()
+}
+
+func init() {
+	expvar.Publish("leaderelection", expvar.Func(func() interface{} {
+		globalLeaderEngineMutex.Lock()
+		defer globalLeaderEngineMutex.Unlock()
+		if globalLeaderEngine == nil {
+			return nil
+		}
+		return globalLeaderEngine.getStatus()
+	}))
+}
@@ -0,0 +1,22 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build !kubeapiserver
+
+package leaderelection
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver"
+)
+
+// GetLeaderEngine returns the shared LeaderEngine instance
+func GetLeaderEngine() (*LeaderEngine, error) {
+	return nil, apiserver.ErrNotCompiled
+}
+
+// GetLeaderIP returns the IP of the leader pod
+func (le *LeaderEngine) GetLeaderIP() (string, error) {
+	return "", apiserver.ErrNotCompiled
+}
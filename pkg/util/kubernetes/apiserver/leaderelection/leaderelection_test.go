@@ -0,0 +1,145 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package leaderelection
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLock is an in-memory lock, rejecting the updates of outdated versions
+// like the apiserver
+type fakeLock struct {
+	sync.Mutex
+	record  *LeaderElectionRecord
+	version int
+	err     error
+}
+
+func (l *fakeLock) get() (*LeaderElectionRecord, string, error) {
+	l.Lock()
+	defer l.Unlock()
+	if l.err != nil {
+		return nil, "", l.err
+	}
+	if l.record == nil {
+		return nil, "", errLockNotFound
+	}
+	record := *l.record
+	return &record, strconv.Itoa(l.version), nil
+}
+
+func (l *fakeLock) create(record LeaderElectionRecord) error {
+	l.Lock()
+	defer l.Unlock()
+	if l.record != nil {
+		return errLockConflict
+	}
+	l.record = &record
+	l.version++
+	return nil
+}
+
+func (l *fakeLock) update(record LeaderElectionRecord, version string) error {
+	l.Lock()
+	defer l.Unlock()
+	if version != strconv.Itoa(l.version) {
+		return errLockConflict
+	}
+	l.record = &record
+	l.version++
+	return nil
+}
+
+func (l *fakeLock) describe() string {
+	return "fake lock"
+}
+
+func TestLeaderElection(t *testing.T) {
+	lock := &fakeLock{}
+	first := newLeaderEngine("dca-1", time.Minute, lock)
+	second := newLeaderEngine("dca-2", time.Minute, lock)
+	now := time.Now()
+
+	// the first replica creates the lock
+	assert.True(t, first.tryAcquireOrRenew(now))
+	assert.True(t, first.IsLeader())
+	assert.Equal(t, "dca-1", first.GetLeader())
+
+	// the second one follows while the lease is valid
+	assert.False(t, second.tryAcquireOrRenew(now.Add(10*time.Second)))
+	assert.False(t, second.IsLeader())
+	assert.Equal(t, "dca-1", second.GetLeader())
+
+	// the leader renews the lease
+	assert.True(t, first.tryAcquireOrRenew(now.Add(20*time.Second)))
+	assert.False(t, second.tryAcquireOrRenew(now.Add(70*time.Second)))
+	require.NotNil(t, lock.record)
+	assert.Equal(t, now, lock.record.AcquireTime)
+	assert.Equal(t, now.Add(20*time.Second), lock.record.RenewTime)
+	assert.Equal(t, 0, lock.record.LeaderTransitions)
+
+	// the second one takes over once the lease expired
+	assert.True(t, second.tryAcquireOrRenew(now.Add(81*time.Second)))
+	assert.True(t, second.IsLeader())
+	assert.Equal(t, 1, lock.record.LeaderTransitions)
+
+	// the former leader follows on its next attempt
+	assert.False(t, first.tryAcquireOrRenew(now.Add(90*time.Second)))
+	assert.False(t, first.IsLeader())
+	assert.Equal(t, "dca-2", first.GetLeader())
+}
+
+func TestLeaderElectionConflict(t *testing.T) {
+	lock := &fakeLock{}
+	first := newLeaderEngine("dca-1", time.Minute, lock)
+	second := newLeaderEngine("dca-2", time.Minute, lock)
+	now := time.Now()
+	require.True(t, first.tryAcquireOrRenew(now.Add(-2*time.Minute)))
+
+	// both replicas read the expired lease, only the first update succeeds
+	record, version, err := lock.get()
+	require.NoError(t, err)
+	require.NoError(t, lock.update(LeaderElectionRecord{
+		HolderIdentity:       "dca-3",
+		LeaseDurationSeconds: 60,
+		RenewTime:            now,
+	}, version))
+	assert.Error(t, lock.update(*record, version))
+
+	assert.False(t, second.tryAcquireOrRenew(now))
+	assert.Equal(t, "dca-3", second.GetLeader())
+}
+
+func TestLeaderElectionRenewDeadline(t *testing.T) {
+	lock := &fakeLock{}
+	engine := newLeaderEngine("dca-1", time.Minute, lock)
+	require.True(t, engine.tryAcquireOrRenew(time.Now()))
+	require.True(t, engine.IsLeader())
+
+	// the leader stops acting as such when it cannot renew its lease
+	lock.err = errors.New("apiserver unavailable")
+	engine.lastRenew = time.Now().Add(-engine.RenewDeadline)
+	assert.False(t, engine.tryAcquireOrRenew(time.Now()))
+	assert.False(t, engine.IsLeader())
+	assert.Equal(t, "dca-1", engine.GetLeader())
+}
+
+func TestLeaderElectionStatus(t *testing.T) {
+	engine := newLeaderEngine("dca-1", time.Minute, &fakeLock{})
+	require.True(t, engine.tryAcquireOrRenew(time.Now()))
+
+	status := engine.getStatus()
+	assert.Equal(t, true, status["isLeader"])
+	assert.Equal(t, "dca-1", status["leaderIdentity"])
+	assert.Equal(t, 0, status["leaderTransitions"])
+}
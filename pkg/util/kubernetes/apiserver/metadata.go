@@ -0,0 +1,182 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build kubeapiserver
+
+package apiserver
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/cihub/seelog"
+	"github.com/ericchiang/k8s/api/v1"
+	metav1 "github.com/ericchiang/k8s/apis/meta/v1"
+
+	"github.com/DataDog/datadog-agent/pkg/util/cache"
+)
+
+const (
+	nodeLabelsCachePrefix      = "kube_node_labels"
+	namespaceLabelsCachePrefix = "kube_namespace_labels"
+)
+
+func nodeLabelsCacheKey(nodeName string) string {
+	return cache.BuildAgentKey(nodeLabelsCachePrefix, nodeName)
+}
+
+func namespaceLabelsCacheKey(namespace string) string {
+	return cache.BuildAgentKey(namespaceLabelsCachePrefix, namespace)
+}
+
+// ownerKey identifies an object owning pods in the parents map
+func ownerKey(kind, namespace, name string) string {
+	return fmt.Sprintf("%s/%s/%s", kind, namespace, name)
+}
+
+// cacheNamespaceLabels stores the labels of the namespaces in the cache
+func (c *APIClient) cacheNamespaceLabels(ctx context.Context) {
+	namespaces, err := c.client.CoreV1().ListNamespaces(ctx)
+	if err != nil {
+		log.Debugf("Could not collect namespaces from the API Server: %q", err.Error())
+		return
+	}
+	for _, ns := range namespaces.Items {
+		cache.Cache.Set(namespaceLabelsCacheKey(ns.Metadata.GetName()), ns.Metadata.GetLabels(), serviceMapExpire)
+	}
+}
+
+// ownerNamespaces returns the namespaces of the pods controlled by a
+// ReplicaSet and of the pods controlled by a Job
+func ownerNamespaces(pods v1.PodList) (replicaSets, jobs map[string]bool) {
+	replicaSets = make(map[string]bool)
+	jobs = make(map[string]bool)
+	for _, pod := range pods.Items {
+		for _, owner := range pod.Metadata.GetOwnerReferences() {
+			if !owner.GetController() {
+				continue
+			}
+			switch owner.GetKind() {
+			case "ReplicaSet":
+				replicaSets[pod.Metadata.GetNamespace()] = true
+			case "Job":
+				jobs[pod.Metadata.GetNamespace()] = true
+			}
+			break
+		}
+	}
+	return replicaSets, jobs
+}
+
+// ownerParents returns the controllers of the ReplicaSets and Jobs owning
+// pods, keyed by ownerKey. Only the namespaces of these pods are listed.
+func (c *APIClient) ownerParents(ctx context.Context, pods v1.PodList) map[string]OwnerReference {
+	parents := make(map[string]OwnerReference)
+	rsNamespaces, jobNamespaces := ownerNamespaces(pods)
+
+	for namespace := range rsNamespaces {
+		replicaSets, err := c.client.ExtensionsV1Beta1().ListReplicaSets(ctx, namespace)
+		if err != nil {
+			log.Debugf("Could not collect replicasets of %s from the API Server: %q", namespace, err.Error())
+			continue
+		}
+		for _, rs := range replicaSets.Items {
+			addParent(parents, "ReplicaSet", rs.Metadata)
+		}
+	}
+
+	for namespace := range jobNamespaces {
+		jobs, err := c.client.BatchV1().ListJobs(ctx, namespace)
+		if err != nil {
+			log.Debugf("Could not collect jobs of %s from the API Server: %q", namespace, err.Error())
+			continue
+		}
+		for _, job := range jobs.Items {
+			addParent(parents, "Job", job.Metadata)
+		}
+	}
+	return parents
+}
+
+// addParent adds the controller of an object to the parents map
+func addParent(parents map[string]OwnerReference, kind string, meta *metav1.ObjectMeta) {
+	for _, owner := range meta.GetOwnerReferences() {
+		if owner.GetController() {
+			parents[ownerKey(kind, meta.GetNamespace(), meta.GetName())] = OwnerReference{
+				Kind: owner.GetKind(),
+				Name: owner.GetName(),
+			}
+			return
+		}
+	}
+}
+
+// mapOwners maps the pods of the node to their owner chain: their controller
+// and its own controller, found in parents
+func (smb *MetadataMapperBundle) mapOwners(nodeName string, pods v1.PodList, parents map[string]OwnerReference) {
+	smb.m.Lock()
+	defer smb.m.Unlock()
+
+	podNameToOwners := make(map[string][]OwnerReference)
+	for _, pod := range pods.Items {
+		if pod.Spec.GetNodeName() != nodeName {
+			continue
+		}
+		for _, owner := range pod.Metadata.GetOwnerReferences() {
+			if !owner.GetController() {
+				continue
+			}
+			ref := OwnerReference{Kind: owner.GetKind(), Name: owner.GetName()}
+			owners := []OwnerReference{ref}
+			if parent, found := parents[ownerKey(ref.Kind, pod.Metadata.GetNamespace(), ref.Name)]; found {
+				owners = append(owners, parent)
+			}
+			podNameToOwners[pod.Metadata.GetName()] = owners
+			break
+		}
+	}
+	smb.PodNameToOwners = podNameToOwners
+}
+
+// GetPodMetadata is used when the API endpoint of the DCA to get the metadata of a pod is hit.
+func GetPodMetadata(nodeName string, podName string) *PodMetadata {
+	mmb, found := cache.Cache.Get(nodeName)
+	if !found {
+		log.Debugf("No metadata was found for the pod %s on node %s", podName, nodeName)
+		return nil
+	}
+	bundle := mmb.(*MetadataMapperBundle)
+	bundle.m.RLock()
+	defer bundle.m.RUnlock()
+
+	services, servicesFound := bundle.PodNameToServices[podName]
+	owners, ownersFound := bundle.PodNameToOwners[podName]
+	if !servicesFound && !ownersFound {
+		log.Debugf("No cached metadata found for the pod %s on the node %s", podName, nodeName)
+		return nil
+	}
+	return &PodMetadata{
+		Services: services,
+		Owners:   owners,
+	}
+}
+
+// GetNodeLabels is used when the API endpoint of the DCA to get the labels of a node is hit.
+func GetNodeLabels(nodeName string) (map[string]string, bool) {
+	labels, found := cache.Cache.Get(nodeLabelsCacheKey(nodeName))
+	if !found {
+		return nil, false
+	}
+	return labels.(map[string]string), true
+}
+
+// GetNamespaceLabels is used when the API endpoint of the DCA to get the labels of a namespace is hit.
+func GetNamespaceLabels(namespace string) (map[string]string, bool) {
+	labels, found := cache.Cache.Get(namespaceLabelsCacheKey(namespace))
+	if !found {
+		return nil, false
+	}
+	return labels.(map[string]string), true
+}
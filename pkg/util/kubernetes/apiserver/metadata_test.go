@@ -0,0 +1,88 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build kubeapiserver
+
+package apiserver
+
+import (
+	"testing"
+
+	"github.com/ericchiang/k8s/api/v1"
+	metav1 "github.com/ericchiang/k8s/apis/meta/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func createOwnedPod(nodeName, name, ownerKind, ownerName string) *v1.Pod {
+	pod := &v1.Pod{
+		Metadata: &metav1.ObjectMeta{
+			Name:      toPtr(name),
+			Namespace: toPtr("default"),
+		},
+		Spec: &v1.PodSpec{NodeName: toPtr(nodeName)},
+	}
+	if ownerKind != "" {
+		pod.Metadata.OwnerReferences = []*metav1.OwnerReference{
+			{Kind: toPtr(ownerKind), Name: toPtr(ownerName), Controller: boolPtr(true)},
+		}
+	}
+	return pod
+}
+
+func TestMapOwners(t *testing.T) {
+	pods := v1.PodList{
+		Items: []*v1.Pod{
+			createOwnedPod("node1", "web-5d6b8-x2x9s", "ReplicaSet", "web-5d6b8"),
+			createOwnedPod("node1", "backup-1528700400-b7x2x", "Job", "backup-1528700400"),
+			createOwnedPod("node1", "agent-qd876", "DaemonSet", "agent"),
+			createOwnedPod("node1", "standalone", "", ""),
+			createOwnedPod("node2", "web-5d6b8-8xkzl", "ReplicaSet", "web-5d6b8"),
+		},
+	}
+	parents := map[string]OwnerReference{
+		ownerKey("ReplicaSet", "default", "web-5d6b8"):     {Kind: "Deployment", Name: "web"},
+		ownerKey("Job", "default", "backup-1528700400"):    {Kind: "CronJob", Name: "backup"},
+		ownerKey("ReplicaSet", "other", "unrelated-5d6b8"): {Kind: "Deployment", Name: "unrelated"},
+	}
+
+	mmb := newMetadataMapperBundle()
+	mmb.mapOwners("node1", pods, parents)
+
+	assert.Equal(t, map[string][]OwnerReference{
+		"web-5d6b8-x2x9s": {
+			{Kind: "ReplicaSet", Name: "web-5d6b8"},
+			{Kind: "Deployment", Name: "web"},
+		},
+		"backup-1528700400-b7x2x": {
+			{Kind: "Job", Name: "backup-1528700400"},
+			{Kind: "CronJob", Name: "backup"},
+		},
+		"agent-qd876": {
+			{Kind: "DaemonSet", Name: "agent"},
+		},
+	}, mmb.PodNameToOwners)
+}
+
+func TestOwnerNamespaces(t *testing.T) {
+	other := createOwnedPod("node1", "worker-7c9f4-k2x8s", "ReplicaSet", "worker-7c9f4")
+	other.Metadata.Namespace = toPtr("other")
+	pods := v1.PodList{
+		Items: []*v1.Pod{
+			createOwnedPod("node1", "web-5d6b8-x2x9s", "ReplicaSet", "web-5d6b8"),
+			createOwnedPod("node2", "backup-1528700400-b7x2x", "Job", "backup-1528700400"),
+			createOwnedPod("node1", "agent-qd876", "DaemonSet", "agent"),
+			createOwnedPod("node1", "standalone", "", ""),
+			other,
+		},
+	}
+
+	replicaSets, jobs := ownerNamespaces(pods)
+	assert.Equal(t, map[string]bool{"default": true, "other": true}, replicaSets)
+	assert.Equal(t, map[string]bool{"default": true}, jobs)
+}
@@ -18,7 +18,7 @@ import (
 
 // mapServices maps each pod (endpoint) to the services connected to it.
 // It is on a per node basis to avoid mixing up the services pods are actually connected to if all pods of different nodes share a similar subnet, therefore sharing a similar IP.
-func (smb *ServiceMapperBundle) mapServices(nodeName string, pods v1.PodList, endpointList v1.EndpointsList) error {
+func (smb *MetadataMapperBundle) mapServices(nodeName string, pods v1.PodList, endpointList v1.EndpointsList) error {
 	smb.m.Lock()
 	defer smb.m.Unlock()
 	ipToEndpoints := make(map[string][]string) // maps the IP address from an endpoint (pod) to associated services ex: "10.10.1.1" : ["service1","service2"]
@@ -71,7 +71,7 @@ func GetPodSvcs(nodeName string, podName string) []string {
 		return nil
 	}
 
-	serviceList, found := smb.(*ServiceMapperBundle).PodNameToServices[podName]
+	serviceList, found := smb.(*MetadataMapperBundle).PodNameToServices[podName]
 	if !found {
 		log.Debugf("No cached metadata found for the pod %s on the node %s", podName, nodeName)
 		return nil
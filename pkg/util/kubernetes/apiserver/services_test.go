@@ -154,11 +154,11 @@ func TestMapServices(t *testing.T) {
 		"pod3_name": {"svc4"},
 		"pod5_name": {"svc3"},
 	}
-	allCasesBundle := newServiceMapperBundle()
+	allCasesBundle := newMetadataMapperBundle()
 	allBundleMu := &sync.RWMutex{}
 	for i, testCase := range testCases {
 		t.Run(fmt.Sprintf("#%d %s", i, testCase.caseName), func(t *testing.T) {
-			testCaseBundle := newServiceMapperBundle()
+			testCaseBundle := newMetadataMapperBundle()
 			podList := createPodList(testCase.pods)
 			nodeName := *testCase.node.Metadata.Name
 			epList := createSvcList(nodeName, testCase.services)
@@ -0,0 +1,396 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build kubeapiserver
+
+package apiserver
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/cihub/seelog"
+	"github.com/ericchiang/k8s"
+	"github.com/ericchiang/k8s/api/v1"
+	autoscalingv1 "github.com/ericchiang/k8s/apis/autoscaling/v1"
+	batchv1 "github.com/ericchiang/k8s/apis/batch/v1"
+	"github.com/ericchiang/k8s/apis/extensions/v1beta1"
+	metav1 "github.com/ericchiang/k8s/apis/meta/v1"
+)
+
+// ClusterState holds the objects of all namespaces the state of which is
+// reported by the kubernetes_state_core check
+type ClusterState struct {
+	Pods                     []*v1.Pod
+	Nodes                    []*v1.Node
+	Deployments              []*v1beta1.Deployment
+	ReplicaSets              []*v1beta1.ReplicaSet
+	Jobs                     []*batchv1.Job
+	PersistentVolumeClaims   []*v1.PersistentVolumeClaim
+	HorizontalPodAutoscalers []*autoscalingv1.HorizontalPodAutoscaler
+}
+
+// stateObject is implemented by the objects of the ClusterState
+type stateObject interface {
+	GetMetadata() *metav1.ObjectMeta
+}
+
+// stateWatcher wraps the typed watcher of a resource of the ClusterState
+type stateWatcher interface {
+	next() (eventType string, obj stateObject, err error)
+	Close() error
+}
+
+// stateStore caches the objects of a resource of the ClusterState. They are
+// listed once, then kept up to date by watching their changes from the
+// version of the list. When the watch ends the objects are listed again on
+// the next sync.
+type stateStore struct {
+	m        sync.Mutex
+	resource string
+	list     func(ctx context.Context) ([]stateObject, string, error)
+	watch    func(resourceVersion string) (stateWatcher, error)
+	collect  func(state *ClusterState, obj stateObject)
+	objects  map[string]stateObject
+	watching bool
+}
+
+// stateKey identifies an object in a stateStore
+func stateKey(obj stateObject) string {
+	return obj.GetMetadata().GetNamespace() + "/" + obj.GetMetadata().GetName()
+}
+
+// sync lists the objects and starts watching them, unless they are
+// already being watched
+func (s *stateStore) sync(timeout time.Duration) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	if s.watching {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	objects, resourceVersion, err := s.list(ctx)
+	cancel()
+	if err != nil {
+		// not reported with stale objects
+		s.objects = nil
+		return err
+	}
+	s.objects = make(map[string]stateObject, len(objects))
+	for _, obj := range objects {
+		s.objects[stateKey(obj)] = obj
+	}
+
+	watcher, err := s.watch(resourceVersion)
+	if err != nil {
+		log.Debugf("Could not watch the %s, listing them again on the next run: %s", s.resource, err)
+		return nil
+	}
+	s.watching = true
+	go s.run(watcher)
+	return nil
+}
+
+// run applies the watch events to the objects until the watch ends
+func (s *stateStore) run(watcher stateWatcher) {
+	defer watcher.Close()
+	for {
+		eventType, obj, err := watcher.next()
+		if err != nil {
+			log.Debugf("The watch of the %s ended: %s", s.resource, err)
+			break
+		}
+		if eventType == "ERROR" {
+			// the resource version is too old, the objects must be listed again
+			log.Debugf("The watch of the %s failed, listing them again", s.resource)
+			break
+		}
+		s.m.Lock()
+		switch eventType {
+		case "ADDED", "MODIFIED":
+			s.objects[stateKey(obj)] = obj
+		case "DELETED":
+			delete(s.objects, stateKey(obj))
+		}
+		s.m.Unlock()
+	}
+	s.m.Lock()
+	s.watching = false
+	s.m.Unlock()
+}
+
+// fill adds the objects to the state, sorted by namespace and name
+func (s *stateStore) fill(state *ClusterState) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	keys := make([]string, 0, len(s.objects))
+	for key := range s.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s.collect(state, s.objects[key])
+	}
+}
+
+// GetClusterState returns the objects of the ClusterState, listed on the
+// first call then watched. The resources that cannot be listed, for lack of
+// RBAC permissions for example, are left empty and reported in the error,
+// the others are still returned.
+func (c *APIClient) GetClusterState() (*ClusterState, error) {
+	c.stateOnce.Do(func() {
+		c.stateStores = c.newStateStores()
+	})
+
+	state := &ClusterState{}
+	var failed []string
+	for _, store := range c.stateStores {
+		// each list gets the whole timeout, large clusters take a while
+		if err := store.sync(c.timeout); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %q", store.resource, err.Error()))
+		}
+		store.fill(state)
+	}
+
+	if len(failed) > 0 {
+		return state, fmt.Errorf("could not list %s", strings.Join(failed, ", "))
+	}
+	return state, nil
+}
+
+func (c *APIClient) newStateStores() []*stateStore {
+	return []*stateStore{
+		{
+			resource: "pods",
+			list: func(ctx context.Context) ([]stateObject, string, error) {
+				pods, err := c.client.CoreV1().ListPods(ctx, "")
+				if err != nil {
+					return nil, "", err
+				}
+				objects := make([]stateObject, 0, len(pods.Items))
+				for _, pod := range pods.Items {
+					objects = append(objects, pod)
+				}
+				return objects, pods.GetMetadata().GetResourceVersion(), nil
+			},
+			watch: func(resourceVersion string) (stateWatcher, error) {
+				watcher, err := c.client.CoreV1().WatchPods(context.Background(), "", k8s.ResourceVersion(resourceVersion))
+				if err != nil {
+					return nil, err
+				}
+				return podWatcher{watcher}, nil
+			},
+			collect: func(state *ClusterState, obj stateObject) {
+				state.Pods = append(state.Pods, obj.(*v1.Pod))
+			},
+		},
+		{
+			resource: "nodes",
+			list: func(ctx context.Context) ([]stateObject, string, error) {
+				nodes, err := c.client.CoreV1().ListNodes(ctx)
+				if err != nil {
+					return nil, "", err
+				}
+				objects := make([]stateObject, 0, len(nodes.Items))
+				for _, node := range nodes.Items {
+					objects = append(objects, node)
+				}
+				return objects, nodes.GetMetadata().GetResourceVersion(), nil
+			},
+			watch: func(resourceVersion string) (stateWatcher, error) {
+				watcher, err := c.client.CoreV1().WatchNodes(context.Background(), k8s.ResourceVersion(resourceVersion))
+				if err != nil {
+					return nil, err
+				}
+				return nodeWatcher{watcher}, nil
+			},
+			collect: func(state *ClusterState, obj stateObject) {
+				state.Nodes = append(state.Nodes, obj.(*v1.Node))
+			},
+		},
+		{
+			resource: "deployments",
+			list: func(ctx context.Context) ([]stateObject, string, error) {
+				deployments, err := c.client.ExtensionsV1Beta1().ListDeployments(ctx, "")
+				if err != nil {
+					return nil, "", err
+				}
+				objects := make([]stateObject, 0, len(deployments.Items))
+				for _, deployment := range deployments.Items {
+					objects = append(objects, deployment)
+				}
+				return objects, deployments.GetMetadata().GetResourceVersion(), nil
+			},
+			watch: func(resourceVersion string) (stateWatcher, error) {
+				watcher, err := c.client.ExtensionsV1Beta1().WatchDeployments(context.Background(), "", k8s.ResourceVersion(resourceVersion))
+				if err != nil {
+					return nil, err
+				}
+				return deploymentWatcher{watcher}, nil
+			},
+			collect: func(state *ClusterState, obj stateObject) {
+				state.Deployments = append(state.Deployments, obj.(*v1beta1.Deployment))
+			},
+		},
+		{
+			resource: "replicasets",
+			list: func(ctx context.Context) ([]stateObject, string, error) {
+				replicaSets, err := c.client.ExtensionsV1Beta1().ListReplicaSets(ctx, "")
+				if err != nil {
+					return nil, "", err
+				}
+				objects := make([]stateObject, 0, len(replicaSets.Items))
+				for _, rs := range replicaSets.Items {
+					objects = append(objects, rs)
+				}
+				return objects, replicaSets.GetMetadata().GetResourceVersion(), nil
+			},
+			watch: func(resourceVersion string) (stateWatcher, error) {
+				watcher, err := c.client.ExtensionsV1Beta1().WatchReplicaSets(context.Background(), "", k8s.ResourceVersion(resourceVersion))
+				if err != nil {
+					return nil, err
+				}
+				return replicaSetWatcher{watcher}, nil
+			},
+			collect: func(state *ClusterState, obj stateObject) {
+				state.ReplicaSets = append(state.ReplicaSets, obj.(*v1beta1.ReplicaSet))
+			},
+		},
+		{
+			resource: "jobs",
+			list: func(ctx context.Context) ([]stateObject, string, error) {
+				jobs, err := c.client.BatchV1().ListJobs(ctx, "")
+				if err != nil {
+					return nil, "", err
+				}
+				objects := make([]stateObject, 0, len(jobs.Items))
+				for _, job := range jobs.Items {
+					objects = append(objects, job)
+				}
+				return objects, jobs.GetMetadata().GetResourceVersion(), nil
+			},
+			watch: func(resourceVersion string) (stateWatcher, error) {
+				watcher, err := c.client.BatchV1().WatchJobs(context.Background(), "", k8s.ResourceVersion(resourceVersion))
+				if err != nil {
+					return nil, err
+				}
+				return jobWatcher{watcher}, nil
+			},
+			collect: func(state *ClusterState, obj stateObject) {
+				state.Jobs = append(state.Jobs, obj.(*batchv1.Job))
+			},
+		},
+		{
+			resource: "persistentvolumeclaims",
+			list: func(ctx context.Context) ([]stateObject, string, error) {
+				claims, err := c.client.CoreV1().ListPersistentVolumeClaims(ctx, "")
+				if err != nil {
+					return nil, "", err
+				}
+				objects := make([]stateObject, 0, len(claims.Items))
+				for _, claim := range claims.Items {
+					objects = append(objects, claim)
+				}
+				return objects, claims.GetMetadata().GetResourceVersion(), nil
+			},
+			watch: func(resourceVersion string) (stateWatcher, error) {
+				watcher, err := c.client.CoreV1().WatchPersistentVolumeClaims(context.Background(), "", k8s.ResourceVersion(resourceVersion))
+				if err != nil {
+					return nil, err
+				}
+				return claimWatcher{watcher}, nil
+			},
+			collect: func(state *ClusterState, obj stateObject) {
+				state.PersistentVolumeClaims = append(state.PersistentVolumeClaims, obj.(*v1.PersistentVolumeClaim))
+			},
+		},
+		{
+			resource: "horizontalpodautoscalers",
+			list: func(ctx context.Context) ([]stateObject, string, error) {
+				hpas, err := c.client.AutoscalingV1().ListHorizontalPodAutoscalers(ctx, "")
+				if err != nil {
+					return nil, "", err
+				}
+				objects := make([]stateObject, 0, len(hpas.Items))
+				for _, hpa := range hpas.Items {
+					objects = append(objects, hpa)
+				}
+				return objects, hpas.GetMetadata().GetResourceVersion(), nil
+			},
+			watch: func(resourceVersion string) (stateWatcher, error) {
+				watcher, err := c.WatchHorizontalPodAutoscalers(resourceVersion)
+				if err != nil {
+					return nil, err
+				}
+				return hpaWatcher{watcher}, nil
+			},
+			collect: func(state *ClusterState, obj stateObject) {
+				state.HorizontalPodAutoscalers = append(state.HorizontalPodAutoscalers, obj.(*autoscalingv1.HorizontalPodAutoscaler))
+			},
+		},
+	}
+}
+
+type podWatcher struct{ *k8s.CoreV1PodWatcher }
+
+func (w podWatcher) next() (string, stateObject, error) {
+	event, pod, err := w.Next()
+	return event.GetType(), pod, err
+}
+
+type nodeWatcher struct{ *k8s.CoreV1NodeWatcher }
+
+func (w nodeWatcher) next() (string, stateObject, error) {
+	event, node, err := w.Next()
+	return event.GetType(), node, err
+}
+
+type deploymentWatcher struct {
+	*k8s.ExtensionsV1Beta1DeploymentWatcher
+}
+
+func (w deploymentWatcher) next() (string, stateObject, error) {
+	event, deployment, err := w.Next()
+	return event.GetType(), deployment, err
+}
+
+type replicaSetWatcher struct {
+	*k8s.ExtensionsV1Beta1ReplicaSetWatcher
+}
+
+func (w replicaSetWatcher) next() (string, stateObject, error) {
+	event, rs, err := w.Next()
+	return event.GetType(), rs, err
+}
+
+type jobWatcher struct{ *k8s.BatchV1JobWatcher }
+
+func (w jobWatcher) next() (string, stateObject, error) {
+	event, job, err := w.Next()
+	return event.GetType(), job, err
+}
+
+type claimWatcher struct {
+	*k8s.CoreV1PersistentVolumeClaimWatcher
+}
+
+func (w claimWatcher) next() (string, stateObject, error) {
+	event, claim, err := w.Next()
+	return event.GetType(), claim, err
+}
+
+type hpaWatcher struct {
+	*k8s.AutoscalingV1HorizontalPodAutoscalerWatcher
+}
+
+func (w hpaWatcher) next() (string, stateObject, error) {
+	event, hpa, err := w.Next()
+	return event.GetType(), hpa, err
+}
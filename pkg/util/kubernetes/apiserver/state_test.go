@@ -0,0 +1,222 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build kubeapiserver
+
+package apiserver
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ericchiang/k8s"
+	"github.com/ericchiang/k8s/api/v1"
+	autoscalingv1 "github.com/ericchiang/k8s/apis/autoscaling/v1"
+	"github.com/ericchiang/k8s/apis/extensions/v1beta1"
+	metav1 "github.com/ericchiang/k8s/apis/meta/v1"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver/testutil"
+)
+
+const testKubeConfig = `{
+  "apiVersion": "v1",
+  "kind": "Config",
+  "clusters": [{"name": "test", "cluster": {"server": "%s"}}],
+  "contexts": [{"name": "test", "context": {"cluster": "test", "user": "test"}}],
+  "current-context": "test",
+  "users": [{"name": "test", "user": {}}]
+}`
+
+type podEvent struct {
+	eventType string
+	pod       *v1.Pod
+}
+
+// fakeStateAPIServer lists the resources of the ClusterState, forbidding
+// the jobs, and streams the pod events sent to its events channel until it
+// is closed. The other watches last until done is closed.
+type fakeStateAPIServer struct {
+	sync.Mutex
+	t        *testing.T
+	pods     []*v1.Pod
+	lists    map[string]int
+	versions []string
+	events   chan podEvent
+	done     chan struct{}
+}
+
+func (s *fakeStateAPIServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	watch := r.URL.Query().Get("watch") == "true"
+	s.Lock()
+	if !watch {
+		s.lists[r.URL.Path]++
+	}
+	s.Unlock()
+	listMeta := &metav1.ListMeta{ResourceVersion: proto.String("42")}
+
+	switch r.URL.Path {
+	case "/api/v1/pods":
+		if watch {
+			s.watchPods(w, r)
+			return
+		}
+		s.Lock()
+		list := &v1.PodList{Metadata: listMeta, Items: s.pods}
+		s.Unlock()
+		testutil.WriteProto(s.t, w, http.StatusOK, list)
+	case "/api/v1/nodes", "/apis/extensions/v1beta1/deployments", "/apis/extensions/v1beta1/replicasets",
+		"/api/v1/persistentvolumeclaims", "/apis/autoscaling/v1/horizontalpodautoscalers":
+		if watch {
+			testutil.WriteWatchHeader(w)
+			<-s.done
+			return
+		}
+		var list proto.Message
+		switch r.URL.Path {
+		case "/api/v1/nodes":
+			list = &v1.NodeList{Metadata: listMeta, Items: []*v1.Node{{Metadata: &metav1.ObjectMeta{Name: proto.String("node-1")}}}}
+		case "/apis/extensions/v1beta1/deployments":
+			list = &v1beta1.DeploymentList{Metadata: listMeta}
+		case "/apis/extensions/v1beta1/replicasets":
+			list = &v1beta1.ReplicaSetList{Metadata: listMeta}
+		case "/api/v1/persistentvolumeclaims":
+			list = &v1.PersistentVolumeClaimList{Metadata: listMeta}
+		default:
+			list = &autoscalingv1.HorizontalPodAutoscalerList{Metadata: listMeta}
+		}
+		testutil.WriteProto(s.t, w, http.StatusOK, list)
+	case "/apis/batch/v1/jobs":
+		testutil.WriteProto(s.t, w, http.StatusForbidden, &metav1.Status{
+			Status: proto.String("Failure"),
+			Reason: proto.String("Forbidden"),
+			Code:   proto.Int32(http.StatusForbidden),
+		})
+	default:
+		s.t.Errorf("unexpected request to %s", r.URL.Path)
+		http.NotFound(w, r)
+	}
+}
+
+func (s *fakeStateAPIServer) watchPods(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	s.versions = append(s.versions, r.URL.Query().Get("resourceVersion"))
+	events := s.events
+	s.Unlock()
+	testutil.WriteWatchHeader(w)
+	for event := range events {
+		testutil.WriteWatchEvent(s.t, w, event.eventType, event.pod)
+	}
+}
+
+func (s *fakeStateAPIServer) listCount(path string) int {
+	s.Lock()
+	defer s.Unlock()
+	return s.lists[path]
+}
+
+func newStatePod(name, phase string) *v1.Pod {
+	return &v1.Pod{
+		Metadata: &metav1.ObjectMeta{Namespace: proto.String("default"), Name: proto.String(name)},
+		Status:   &v1.PodStatus{Phase: proto.String(phase)},
+	}
+}
+
+func podPhases(t *testing.T, c *APIClient) map[string]string {
+	state, err := c.GetClusterState()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "jobs")
+	phases := make(map[string]string)
+	for _, pod := range state.Pods {
+		phases[pod.Metadata.GetName()] = pod.Status.GetPhase()
+	}
+	return phases
+}
+
+// waitFor polls the condition for up to 5 seconds
+func waitFor(t *testing.T, condition func() bool) {
+	for i := 0; i < 500; i++ {
+		if condition() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("timed out")
+}
+
+func TestGetClusterState(t *testing.T) {
+	apiServer := &fakeStateAPIServer{
+		t:      t,
+		pods:   []*v1.Pod{newStatePod("web", "Running")},
+		lists:  make(map[string]int),
+		events: make(chan podEvent),
+		done:   make(chan struct{}),
+	}
+	ts := httptest.NewServer(apiServer)
+	defer ts.Close()
+	defer close(apiServer.done)
+
+	dir, err := ioutil.TempDir("", "state_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	kubeConfigPath := filepath.Join(dir, "kubeconfig.json")
+	err = ioutil.WriteFile(kubeConfigPath, []byte(fmt.Sprintf(testKubeConfig, ts.URL)), 0600)
+	require.NoError(t, err)
+	kubeConfig, err := ParseKubeConfig(kubeConfigPath)
+	require.NoError(t, err)
+	client, err := k8s.NewClient(kubeConfig)
+	require.NoError(t, err)
+	c := &APIClient{client: client, timeout: 5 * time.Second}
+
+	// the forbidden jobs are reported, the other resources are returned
+	state, err := c.GetClusterState()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "jobs")
+	require.Len(t, state.Nodes, 1)
+	assert.Equal(t, "node-1", state.Nodes[0].Metadata.GetName())
+	assert.Empty(t, state.Jobs)
+	assert.Equal(t, map[string]string{"web": "Running"}, podPhases(t, c))
+
+	// the changes are watched from the version of the list
+	apiServer.events <- podEvent{"ADDED", newStatePod("worker", "Pending")}
+	apiServer.events <- podEvent{"DELETED", newStatePod("web", "Running")}
+	apiServer.events <- podEvent{"MODIFIED", newStatePod("worker", "Running")}
+	waitFor(t, func() bool {
+		phases := podPhases(t, c)
+		return len(phases) == 1 && phases["worker"] == "Running"
+	})
+
+	// the watched resources are listed once, the forbidden ones every run
+	assert.Equal(t, 1, apiServer.listCount("/api/v1/pods"))
+	assert.Equal(t, 1, apiServer.listCount("/api/v1/nodes"))
+	assert.True(t, apiServer.listCount("/apis/batch/v1/jobs") > 2)
+	apiServer.Lock()
+	assert.Equal(t, []string{"42"}, apiServer.versions)
+	apiServer.Unlock()
+
+	// the pods are listed again when the watch ends
+	apiServer.Lock()
+	close(apiServer.events)
+	apiServer.events = make(chan podEvent)
+	apiServer.Unlock()
+	pods := c.stateStores[0]
+	waitFor(t, func() bool {
+		pods.m.Lock()
+		defer pods.m.Unlock()
+		return !pods.watching
+	})
+	assert.Equal(t, map[string]string{"web": "Running"}, podPhases(t, c))
+	assert.Equal(t, 2, apiServer.listCount("/api/v1/pods"))
+	close(apiServer.events)
+}
@@ -0,0 +1,69 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build kubeapiserver
+
+// Package testutil holds the helpers shared by the tests faking the
+// apiserver the kubernetes client talks to.
+package testutil
+
+import (
+	"encoding/binary"
+	"net/http"
+	"testing"
+
+	"github.com/ericchiang/k8s/runtime"
+	"github.com/ericchiang/k8s/watch/versioned"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/require"
+)
+
+// magicBytes prefix the protobuf objects sent and received by the apiserver
+var magicBytes = []byte{0x6b, 0x38, 0x73, 0x00}
+
+// EncodeProto encodes msg the way the apiserver encodes protobuf objects
+func EncodeProto(t *testing.T, msg proto.Message) []byte {
+	raw, err := proto.Marshal(msg)
+	require.NoError(t, err)
+	body, err := proto.Marshal(&runtime.Unknown{Raw: raw})
+	require.NoError(t, err)
+	return append(append([]byte{}, magicBytes...), body...)
+}
+
+// DecodeProto decodes the protobuf object sent by the client in msg
+func DecodeProto(t *testing.T, body []byte, msg proto.Message) {
+	require.True(t, len(body) > len(magicBytes))
+	unknown := &runtime.Unknown{}
+	require.NoError(t, proto.Unmarshal(body[len(magicBytes):], unknown))
+	require.NoError(t, proto.Unmarshal(unknown.Raw, msg))
+}
+
+// WriteProto writes msg as a protobuf response with the given status code
+func WriteProto(t *testing.T, w http.ResponseWriter, code int, msg proto.Message) {
+	w.Header().Set("Content-Type", "application/vnd.kubernetes.protobuf")
+	w.WriteHeader(code)
+	w.Write(EncodeProto(t, msg))
+}
+
+// WriteWatchHeader starts a protobuf watch response
+func WriteWatchHeader(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/vnd.kubernetes.protobuf;stream=watch")
+	w.WriteHeader(http.StatusOK)
+	w.(http.Flusher).Flush()
+}
+
+// WriteWatchEvent writes a watch event of msg with the protobuf streaming
+// wire format, each frame being prefixed by its length
+func WriteWatchEvent(t *testing.T, w http.ResponseWriter, eventType string, msg proto.Message) {
+	frame, err := proto.Marshal(&versioned.Event{
+		Type:   proto.String(eventType),
+		Object: &runtime.RawExtension{Raw: EncodeProto(t, msg)},
+	})
+	require.NoError(t, err)
+	length := make([]byte, 4)
+	binary.BigEndian.PutUint32(length, uint32(len(frame)))
+	w.Write(append(length, frame...))
+	w.(http.Flusher).Flush()
+}
@@ -0,0 +1,21 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package apiserver
+
+// PodMetadata holds the cluster-level metadata of a pod, served by the
+// cluster agent to the node agents
+type PodMetadata struct {
+	Services []string `json:"services,omitempty"`
+	// Owners is the owner chain of the pod, from its controller up, like
+	// a ReplicaSet then its Deployment, or a Job then its CronJob
+	Owners []OwnerReference `json:"owners,omitempty"`
+}
+
+// OwnerReference identifies the owner of a kubernetes object
+type OwnerReference struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
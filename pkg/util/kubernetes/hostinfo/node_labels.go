@@ -0,0 +1,50 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build kubelet
+
+package hostinfo
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/clusteragent"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/kubelet"
+)
+
+// GetTags gets the host tags from the labels of the node the agent runs on,
+// per kubernetes_node_labels_as_tags
+func GetTags() ([]string, error) {
+	labelsToTags := config.Datadog.GetStringMapString("kubernetes_node_labels_as_tags")
+	if len(labelsToTags) == 0 {
+		return []string{}, nil
+	}
+
+	ku, err := kubelet.GetKubeUtil()
+	if err != nil {
+		return []string{}, err
+	}
+	nodeName, err := ku.GetHostname()
+	if err != nil {
+		return []string{}, err
+	}
+	nodeLabels, err := getNodeLabels(nodeName)
+	if err != nil {
+		return []string{}, err
+	}
+	return extractTags(nodeLabels, labelsToTags), nil
+}
+
+// getNodeLabels gets the labels of the node from the cluster agent if it is
+// enabled, or else from the apiserver
+func getNodeLabels(nodeName string) (map[string]string, error) {
+	if config.Datadog.GetBool("cluster_agent.enabled") {
+		client, err := clusteragent.GetClusterAgentClient()
+		if err != nil {
+			return nil, err
+		}
+		return client.GetNodeLabels(nodeName)
+	}
+	return apiserverNodeLabels(nodeName)
+}
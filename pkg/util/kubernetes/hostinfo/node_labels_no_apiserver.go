@@ -0,0 +1,18 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build kubelet,!kubeapiserver
+
+package hostinfo
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver"
+)
+
+// apiserverNodeLabels fails as the apiserver support is not compiled in, the
+// node labels can be collected through the cluster agent instead
+func apiserverNodeLabels(nodeName string) (map[string]string, error) {
+	return nil, apiserver.ErrNotCompiled
+}
@@ -0,0 +1,27 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// Package hostinfo gathers the host tags of a kubernetes node
+package hostinfo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// extractTags maps the node labels found in labelsToTags to host tags.
+// Viper lower-cases the map keys, so the label names are lower-cased
+// before matching.
+func extractTags(nodeLabels, labelsToTags map[string]string) []string {
+	tags := []string{}
+	for labelName, labelValue := range nodeLabels {
+		if tagName, found := labelsToTags[strings.ToLower(labelName)]; found {
+			tags = append(tags, fmt.Sprintf("%s:%s", tagName, labelValue))
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
@@ -0,0 +1,38 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package hostinfo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractTags(t *testing.T) {
+	nodeLabels := map[string]string{
+		"failure-domain.beta.kubernetes.io/zone": "us-east1-b",
+		"beta.kubernetes.io/instance-type":       "n1-standard-4",
+		"cloud.google.com/gke-nodepool":          "default-pool",
+		"kubernetes.io/hostname":                 "gke-cluster-default-pool-1",
+		"Team":                                   "infra",
+	}
+	labelsToTags := map[string]string{
+		"failure-domain.beta.kubernetes.io/zone": "zone",
+		"beta.kubernetes.io/instance-type":       "instance-type",
+		"cloud.google.com/gke-nodepool":          "node_pool",
+		"team":                                   "team",
+		"unknown":                                "unknown",
+	}
+
+	assert.Equal(t, []string{
+		"instance-type:n1-standard-4",
+		"node_pool:default-pool",
+		"team:infra",
+		"zone:us-east1-b",
+	}, extractTags(nodeLabels, labelsToTags))
+	assert.Empty(t, extractTags(nodeLabels, map[string]string{}))
+	assert.Empty(t, extractTags(nil, labelsToTags))
+}
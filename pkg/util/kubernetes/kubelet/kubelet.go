@@ -28,6 +28,7 @@ import (
 
 const (
 	kubeletPodPath         = "/pods"
+	kubeletSummaryPath     = "/stats/summary"
 	authorizationHeaderKey = "Authorization"
 	podListCacheKey        = "KubeletPodListCacheKey"
 )
@@ -177,6 +178,42 @@ func (ku *KubeUtil) GetPodForContainerID(containerID string) (*Pod, error) {
 	return ku.searchPodForContainerID(pods, containerID)
 }
 
+// GetPodFromUID fetches the podlist and returns the pod with the given UID,
+// used for the pod entities of the tagger
+func (ku *KubeUtil) GetPodFromUID(podUID string) (*Pod, error) {
+	if podUID == "" {
+		return nil, errors.New("pod UID is empty")
+	}
+	pods, err := ku.GetLocalPodList()
+	if err != nil {
+		return nil, err
+	}
+	for _, pod := range pods {
+		if pod.Metadata.UID == podUID {
+			return pod, nil
+		}
+	}
+	return nil, fmt.Errorf("pod %s not found in podlist", podUID)
+}
+
+// GetStatsSummary returns the resource usage of the node, pods and containers
+// reported by the kubelet on /stats/summary
+func (ku *KubeUtil) GetStatsSummary() (*Summary, error) {
+	data, code, err := ku.QueryKubelet(kubeletSummaryPath)
+	if err != nil {
+		return nil, fmt.Errorf("error performing kubelet query %s%s: %s", ku.kubeletApiEndpoint, kubeletSummaryPath, err)
+	}
+	if code != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d on %s%s: %s", code, ku.kubeletApiEndpoint, kubeletSummaryPath, string(data))
+	}
+
+	summary := &Summary{}
+	if err := json.Unmarshal(data, summary); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
 func (ku *KubeUtil) searchPodForContainerID(podlist []*Pod, containerID string) (*Pod, error) {
 	if containerID == "" {
 		return nil, errors.New("containerID is empty")
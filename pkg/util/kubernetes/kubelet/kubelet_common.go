@@ -9,8 +9,20 @@ import (
 	"errors"
 )
 
+// KubePodPrefix is the entity prefix of the pods in the tagger, the pod UID
+// following it
+const KubePodPrefix = "kubernetes_pod://"
+
 var (
 	// ErrNotCompiled is returned if kubelet support is not compiled in.
 	// User classes should handle that case as gracefully as possible.
 	ErrNotCompiled = errors.New("kubelet support not compiled in")
 )
+
+// PodUIDToEntityName returns the tagger entity of a pod from its UID
+func PodUIDToEntityName(uid string) string {
+	if uid == "" {
+		return ""
+	}
+	return KubePodPrefix + uid
+}
@@ -21,6 +21,7 @@ type PodWatcher struct {
 	kubeUtil       *KubeUtil
 	expiryDuration time.Duration
 	lastSeen       map[string]time.Time
+	lastSeenPod    map[string]time.Time
 }
 
 // NewPodWatcher creates a new watcher. User call must then trigger PullChanges
@@ -33,6 +34,7 @@ func NewPodWatcher(expiryDuration time.Duration) (*PodWatcher, error) {
 	watcher := &PodWatcher{
 		kubeUtil:       kubeutil,
 		lastSeen:       make(map[string]time.Time),
+		lastSeenPod:    make(map[string]time.Time),
 		expiryDuration: expiryDuration,
 	}
 	return watcher, nil
@@ -58,6 +60,9 @@ func (w *PodWatcher) computeChanges(podlist []*Pod) ([]*Pod, error) {
 	w.Lock()
 	defer w.Unlock()
 	for _, pod := range podlist {
+		if entity := PodUIDToEntityName(pod.Metadata.UID); entity != "" {
+			w.lastSeenPod[entity] = now
+		}
 		// Detect new containers
 		newContainer := false
 		for _, container := range pod.Status.Containers {
@@ -75,7 +80,8 @@ func (w *PodWatcher) computeChanges(podlist []*Pod) ([]*Pod, error) {
 }
 
 // ExpireContainers returns a list of container id for containers
-// that are not listed in the podlist anymore. It must be called
+// that are not listed in the podlist anymore, followed by the entities of
+// the pods not listed anymore, see PodUIDToEntityName. It must be called
 // immediately after a PullChanges.
 func (w *PodWatcher) ExpireContainers() ([]string, error) {
 	now := time.Now()
@@ -93,6 +99,13 @@ func (w *PodWatcher) ExpireContainers() ([]string, error) {
 			delete(w.lastSeen, id)
 		}
 	}
+
+	for entity, lastSeen := range w.lastSeenPod {
+		if now.Sub(lastSeen) > w.expiryDuration {
+			expiredContainers = append(expiredContainers, entity)
+			delete(w.lastSeenPod, entity)
+		}
+	}
 	return expiredContainers, nil
 }
 
@@ -102,3 +115,9 @@ func (w *PodWatcher) ExpireContainers() ([]string, error) {
 func (w *PodWatcher) GetPodForContainerID(containerID string) (*Pod, error) {
 	return w.kubeUtil.GetPodForContainerID(containerID)
 }
+
+// GetPodFromUID fetches the podlist and returns the pod with the given UID.
+// It just proxies the call to its kubeutil.
+func (w *PodWatcher) GetPodFromUID(podUID string) (*Pod, error) {
+	return w.kubeUtil.GetPodFromUID(podUID)
+}
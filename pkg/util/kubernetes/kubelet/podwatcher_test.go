@@ -40,6 +40,7 @@ func (suite *PodwatcherTestSuite) TestPodWatcherComputeChanges() {
 
 	watcher := &PodWatcher{
 		lastSeen:       make(map[string]time.Time),
+		lastSeenPod:    make(map[string]time.Time),
 		expiryDuration: 5 * time.Minute,
 	}
 
@@ -82,6 +83,7 @@ func (suite *PodwatcherTestSuite) TestPodWatcherExpireContainers() {
 
 	watcher := &PodWatcher{
 		lastSeen:       make(map[string]time.Time),
+		lastSeenPod:    make(map[string]time.Time),
 		expiryDuration: 5 * time.Minute,
 	}
 
@@ -108,6 +110,15 @@ func (suite *PodwatcherTestSuite) TestPodWatcherExpireContainers() {
 	require.Len(suite.T(), expire, 1)
 	require.Equal(suite.T(), testContainerID, expire[0])
 	require.Len(suite.T(), watcher.lastSeen, 4)
+
+	// Pods are expired after their containers
+	require.Len(suite.T(), watcher.lastSeenPod, 4)
+	testPodEntity := PodUIDToEntityName(sourcePods[0].Metadata.UID)
+	watcher.lastSeenPod[testPodEntity] = watcher.lastSeenPod[testPodEntity].Add(-6 * time.Minute)
+	expire, err = watcher.ExpireContainers()
+	require.Nil(suite.T(), err)
+	require.Equal(suite.T(), []string{testPodEntity}, expire)
+	require.Len(suite.T(), watcher.lastSeenPod, 3)
 }
 
 func (suite *PodwatcherTestSuite) TestPullChanges() {
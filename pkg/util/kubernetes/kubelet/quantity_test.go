@@ -0,0 +1,42 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package kubelet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseQuantity(t *testing.T) {
+	for _, tc := range []struct {
+		quantity string
+		value    float64
+		err      bool
+	}{
+		{"2", 2, false},
+		{"0.5", 0.5, false},
+		{"250m", 0.25, false},
+		{"128Mi", 128 * 1024 * 1024, false},
+		{"1Gi", 1024 * 1024 * 1024, false},
+		{"1G", 1e9, false},
+		{"10k", 1e4, false},
+		{"1e3", 1000, false},
+		{"", 0, true},
+		{"Mi", 0, true},
+		{"12Xi", 0, true},
+	} {
+		t.Run(tc.quantity, func(t *testing.T) {
+			value, err := ParseQuantity(tc.quantity)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.InDelta(t, tc.value, value, 1e-9)
+		})
+	}
+}
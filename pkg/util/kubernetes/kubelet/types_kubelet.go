@@ -40,15 +40,26 @@ type PodOwner struct {
 // Spec contains fields for unmarshalling a Pod.Spec
 type Spec struct {
 	HostNetwork bool            `json:"hostNetwork,omitempty"`
+	Hostname    string          `json:"hostname,omitempty"`
 	NodeName    string          `json:"nodeName,omitempty"`
 	Containers  []ContainerSpec `json:"containers,omitempty"`
 }
 
 // ContainerSpec contains fields for unmarshalling a Pod.Spec.Containers
 type ContainerSpec struct {
-	Name  string              `json:"name"`
-	Image string              `json:"image,omitempty"`
-	Ports []ContainerPortSpec `json:"ports,omitempty"`
+	Name      string                 `json:"name"`
+	Image     string                 `json:"image,omitempty"`
+	Ports     []ContainerPortSpec    `json:"ports,omitempty"`
+	Env       []EnvVar               `json:"env,omitempty"`
+	EnvFrom   []EnvFromSource        `json:"envFrom,omitempty"`
+	Resources ContainerResourcesSpec `json:"resources,omitempty"`
+}
+
+// ContainerResourcesSpec contains fields for unmarshalling a Pod.Spec.Containers.Resources,
+// the quantities can be parsed with ParseQuantity
+type ContainerResourcesSpec struct {
+	Requests map[string]string `json:"requests,omitempty"`
+	Limits   map[string]string `json:"limits,omitempty"`
 }
 
 // ContainerSpec contains fields for unmarshalling a Pod.Spec.Containers.Ports
@@ -59,6 +70,21 @@ type ContainerPortSpec struct {
 	Protocol      string `json:"protocol"`
 }
 
+// EnvVar contains fields for unmarshalling a Pod.Spec.Containers.Env
+type EnvVar struct {
+	Name      string        `json:"name"`
+	Value     string        `json:"value,omitempty"`
+	ValueFrom *EnvVarSource `json:"valueFrom,omitempty"`
+}
+
+// EnvVarSource marks a Pod.Spec.Containers.Env.ValueFrom reference, the pod
+// spec doesn't hold the value it resolves to
+type EnvVarSource struct{}
+
+// EnvFromSource marks a Pod.Spec.Containers.EnvFrom ConfigMap or Secret, the
+// pod spec doesn't hold the variables it sets
+type EnvFromSource struct{}
+
 // Status contains fields for unmarshalling a Pod.Status
 type Status struct {
 	Phase      string            `json:"phase,omitempty"`
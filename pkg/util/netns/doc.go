@@ -0,0 +1,11 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+/*
+Package netns groups the processes by network namespace, to collect the
+network statistics of each namespace through one of its processes (Linux only).
+
+*/
+package netns
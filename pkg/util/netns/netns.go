@@ -0,0 +1,111 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build linux
+
+package netns
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	log "github.com/cihub/seelog"
+)
+
+// Namespace is a network namespace and the processes running in it
+type Namespace struct {
+	Inode uint64
+	// PIDs are sorted in ascending order
+	PIDs []int
+	// Name is the name the namespace is bound to in the names directory,
+	// as done by `ip netns add`, empty if there is none
+	Name string
+}
+
+// GetNamespaces groups the processes of procRoot by network namespace, and
+// names them after their bind mounts in namesDir. The namespaces are sorted
+// by inode.
+func GetNamespaces(procRoot, namesDir string) ([]*Namespace, error) {
+	entries, err := ioutil.ReadDir(procRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	byInode := make(map[uint64]*Namespace)
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		inode, err := GetInode(procRoot, pid)
+		if err != nil {
+			// the process exited, or belongs to another user
+			log.Tracef("cannot get the network namespace of pid %d: %s", pid, err)
+			continue
+		}
+		ns, found := byInode[inode]
+		if !found {
+			ns = &Namespace{Inode: inode}
+			byInode[inode] = ns
+		}
+		ns.PIDs = append(ns.PIDs, pid)
+	}
+
+	for name, inode := range namedInodes(namesDir) {
+		if ns, found := byInode[inode]; found {
+			ns.Name = name
+		}
+	}
+
+	namespaces := make([]*Namespace, 0, len(byInode))
+	for _, ns := range byInode {
+		sort.Ints(ns.PIDs)
+		namespaces = append(namespaces, ns)
+	}
+	sort.Slice(namespaces, func(i, j int) bool {
+		return namespaces[i].Inode < namespaces[j].Inode
+	})
+	return namespaces, nil
+}
+
+// GetInode returns the inode of the network namespace of a process, read
+// from the /proc/<pid>/ns/net link whose target is like net:[4026531993]
+func GetInode(procRoot string, pid int) (uint64, error) {
+	target, err := os.Readlink(filepath.Join(procRoot, strconv.Itoa(pid), "ns", "net"))
+	if err != nil {
+		return 0, err
+	}
+	if !strings.HasPrefix(target, "net:[") || !strings.HasSuffix(target, "]") {
+		return 0, fmt.Errorf("unexpected network namespace link %q", target)
+	}
+	return strconv.ParseUint(target[5:len(target)-1], 10, 64)
+}
+
+// namedInodes returns the inodes of the namespaces bound in namesDir, by name
+func namedInodes(namesDir string) map[string]uint64 {
+	inodes := make(map[string]uint64)
+	entries, err := ioutil.ReadDir(namesDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Debugf("cannot list the network namespace names in %s: %s", namesDir, err)
+		}
+		return inodes
+	}
+	for _, entry := range entries {
+		// ReadDir uses lstat, the bind mounts need a stat
+		var stat syscall.Stat_t
+		if err := syscall.Stat(filepath.Join(namesDir, entry.Name()), &stat); err != nil {
+			continue
+		}
+		inodes[entry.Name()] = stat.Ino
+	}
+	return inodes
+}
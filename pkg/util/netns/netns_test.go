@@ -0,0 +1,96 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// +build linux
+
+package netns
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProc creates a proc directory where the processes are in the
+// namespaces of the given inodes, by pid
+func fakeProc(t *testing.T, dir string, inodes map[int]string) string {
+	procRoot := filepath.Join(dir, "proc")
+	for pid, target := range inodes {
+		nsDir := filepath.Join(procRoot, strconv.Itoa(pid), "ns")
+		require.NoError(t, os.MkdirAll(nsDir, 0755))
+		require.NoError(t, os.Symlink(target, filepath.Join(nsDir, "net")))
+	}
+	// not a process
+	require.NoError(t, os.MkdirAll(filepath.Join(procRoot, "sys"), 0755))
+	return procRoot
+}
+
+func TestGetNamespaces(t *testing.T) {
+	dir, err := ioutil.TempDir("", "netns")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// regular files stand for the namespace bind mounts
+	namesDir := filepath.Join(dir, "netns")
+	require.NoError(t, os.MkdirAll(namesDir, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(namesDir, "blue"), nil, 0644))
+	var stat syscall.Stat_t
+	require.NoError(t, syscall.Stat(filepath.Join(namesDir, "blue"), &stat))
+	blue := stat.Ino
+
+	procRoot := fakeProc(t, dir, map[int]string{
+		1:    "net:[1000]",
+		20:   "net:[1000]",
+		300:  fmt.Sprintf("net:[%d]", blue),
+		4000: "net:[2000]",
+		4001: "net:[2000]",
+		5000: "mnt:[3000]",
+	})
+	// a process without namespace link
+	require.NoError(t, os.MkdirAll(filepath.Join(procRoot, "6000"), 0755))
+
+	namespaces, err := GetNamespaces(procRoot, namesDir)
+	require.NoError(t, err)
+
+	byInode := make(map[uint64]*Namespace)
+	for _, ns := range namespaces {
+		byInode[ns.Inode] = ns
+	}
+	require.Len(t, byInode, 3)
+	assert.Equal(t, &Namespace{Inode: 1000, PIDs: []int{1, 20}}, byInode[1000])
+	assert.Equal(t, &Namespace{Inode: 2000, PIDs: []int{4000, 4001}}, byInode[2000])
+	assert.Equal(t, &Namespace{Inode: blue, PIDs: []int{300}, Name: "blue"}, byInode[blue])
+
+	// a missing names directory is not an error
+	namespaces, err = GetNamespaces(procRoot, filepath.Join(dir, "nonexistent"))
+	require.NoError(t, err)
+	assert.Len(t, namespaces, 3)
+
+	_, err = GetNamespaces(filepath.Join(dir, "nonexistent"), namesDir)
+	assert.Error(t, err)
+}
+
+func TestGetInode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "netns")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	procRoot := fakeProc(t, dir, map[int]string{1: "net:[4026531993]", 2: "net:[abc]"})
+
+	inode, err := GetInode(procRoot, 1)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4026531993), inode)
+
+	_, err = GetInode(procRoot, 2)
+	assert.Error(t, err)
+	_, err = GetInode(procRoot, 3)
+	assert.Error(t, err)
+}
@@ -23,6 +23,7 @@ DEFAULT_BUILD_TAGS = [
     "apm",
     "consul",
     "cpython",
+    "cri",
     "docker",
     "ec2",
     "etcd",
@@ -32,6 +33,7 @@ DEFAULT_BUILD_TAGS = [
     "log",
     "process",
     "snmp",
+    "systemd",
     "zk",
     "zlib",
 ]
@@ -55,9 +57,10 @@ def build(ctx, rebuild=False, race=False, build_include=None, build_exclude=None
     }
 
     if invoke.platform.WINDOWS:
-        # Don't build Docker support
-        if "docker" not in build_exclude:
-            build_exclude.append("docker")
+        # Don't build Docker, CRI and systemd support
+        for tag in ["cri", "docker", "systemd"]:
+            if tag not in build_exclude:
+                build_exclude.append(tag)
 
         # This generates the manifest resource. The manifest resource is necessary for
         # being able to load the ancient C-runtime that comes along with Python 2.7
@@ -9,6 +9,7 @@ ALL_TAGS = set([
     "apm",
     "consul",
     "cpython",
+    "cri",
     "docker",
     "ec2",
     "etcd",
@@ -18,6 +19,7 @@ ALL_TAGS = set([
     "log",
     "process",
     "snmp",
+    "systemd",
     "zk",
     "zlib",
     "kubeapiserver",
@@ -37,7 +39,7 @@ def get_default_build_tags(puppy=False):
         return PUPPY_TAGS
 
     include = ["all"]
-    exclude = ["docker", "kubelet", "kubeapiserver"] if invoke.platform.WINDOWS else []
+    exclude = ["cri", "docker", "kubelet", "kubeapiserver", "systemd"] if invoke.platform.WINDOWS else []
     return get_build_tags(include, exclude)
 
 